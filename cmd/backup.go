@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zhenbah/cryoncode/internal/backup"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/db"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [session-id...]",
+	Short: "Export sessions to a JSON archive",
+	Long: `Export sessions, with their messages and task sessions, to a JSON archive
+that can be imported into another data directory. All sessions are exported
+when no session IDs are given.`,
+	Example: `
+  # Export every session
+  cryoncode export -o sessions.json
+
+  # Export one session
+  cryoncode export 3f2a9c1e-... -o session.json
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		conn, err := connectDataDirectory(cmd)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		q := db.New(conn)
+		archive, err := backup.Export(cmd.Context(), session.NewService(q), message.NewService(q), args...)
+		if err != nil {
+			return err
+		}
+		if err := backup.WriteFile(output, archive); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		fmt.Printf("Exported %d sessions to %s\n", len(archive.Sessions), output)
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Import sessions from a JSON archive",
+	Long: `Import the sessions of an archive written by the export command. Sessions
+and messages whose IDs are already in use are given new IDs. If the import
+fails, the sessions stored so far are removed again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archive, err := backup.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		conn, err := connectDataDirectory(cmd)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		q := db.New(conn)
+		imported, err := backup.Import(cmd.Context(), session.NewService(q), message.NewService(q), archive)
+		if err != nil {
+			return err
+		}
+		for _, s := range imported {
+			if s.ParentSessionID == "" {
+				fmt.Printf("Imported %s (%s, %d messages)\n", s.Title, s.ID, s.MessageCount)
+			}
+		}
+		return nil
+	},
+}
+
+// connectDataDirectory loads the configuration of the working directory given
+// by the cwd flag and opens its database.
+func connectDataDirectory(cmd *cobra.Command) (*sql.DB, error) {
+	cwd, _ := cmd.Flags().GetString("cwd")
+	if cwd == "" {
+		c, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current working directory: %v", err)
+		}
+		cwd = c
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return nil, err
+	}
+	return db.Connect()
+}
+
+func init() {
+	for _, c := range []*cobra.Command{exportCmd, importCmd} {
+		c.Flags().StringP("cwd", "c", "", "Working directory whose data directory is used")
+		rootCmd.AddCommand(c)
+	}
+	exportCmd.Flags().StringP("output", "o", "cryoncode-sessions.json", "File to write the archive to")
+}
@@ -3,7 +3,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,8 +19,11 @@ import (
 	"github.com/zhenbah/cryoncode/internal/logging"
 	"github.com/zhenbah/cryoncode/internal/pubsub"
 	"github.com/zhenbah/cryoncode/internal/tui"
+	"github.com/zhenbah/cryoncode/internal/tui/components/dialog"
+	"github.com/zhenbah/cryoncode/internal/tui/theme"
 	"github.com/zhenbah/cryoncode/internal/version"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
@@ -40,11 +45,23 @@ to assist developers in writing, debugging, and understanding code directly from
   # Print version
   cryoncode -v
 
+  # Continue where you left off
+  cryoncode -r
+
+  # Preview the agent's file changes without writing them
+  cryoncode --dry-run
+
   # Run a single non-interactive prompt
   cryoncode -p "Explain the use of context in Go"
 
   # Run a single non-interactive prompt with JSON output format
   cryoncode -p "Explain the use of context in Go" -f json
+
+  # Stream the events of the run as NDJSON
+  cryoncode -p "Explain the use of context in Go" -f ndjson
+
+  # Read the prompt from a file through stdin
+  cryoncode -q < task.md
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If the help flag is set, show the help message
@@ -63,6 +80,15 @@ to assist developers in writing, debugging, and understanding code directly from
 		prompt, _ := cmd.Flags().GetString("prompt")
 		outputFormat, _ := cmd.Flags().GetString("output-format")
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		resume, _ := cmd.Flags().GetBool("resume")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		prompt, err := readPrompt(prompt)
+		if err != nil {
+			return err
+		}
+		// The flags are valid, later errors shouldn't print the usage
+		cmd.SilenceUsage = true
 
 		// Validate format option
 		if !format.IsValid(outputFormat) {
@@ -82,10 +108,17 @@ to assist developers in writing, debugging, and understanding code directly from
 			}
 			cwd = c
 		}
-		_, err := config.Load(cwd, debug)
+		_, err = config.Load(cwd, debug)
 		if err != nil {
 			return err
 		}
+		// The flag overrides the config either way
+		if !cmd.Flags().Changed("resume") {
+			resume = config.Get().ResumeLastSession
+		}
+		if cmd.Flags().Changed("dry-run") {
+			config.Get().DryRun = dryRun
+		}
 
 		// Connect DB, this will also run migrations
 		conn, err := db.Connect()
@@ -118,7 +151,7 @@ to assist developers in writing, debugging, and understanding code directly from
 		// Set up the TUI
 		zone.NewGlobal()
 		program := tea.NewProgram(
-			tui.New(app),
+			tui.New(app, resume),
 			tea.WithAltScreen(),
 		)
 
@@ -169,6 +202,13 @@ to assist developers in writing, debugging, and understanding code directly from
 			logging.Info("All goroutines cleaned up")
 		}
 
+		// Ask the terminal to report background changes for the auto theme,
+		// unless the background is forced in the config
+		if config.Get().TUI.Background == config.BackgroundAuto {
+			fmt.Fprint(os.Stdout, theme.EnableBackgroundReports)
+			defer fmt.Fprint(os.Stdout, theme.DisableBackgroundReports)
+		}
+
 		// Run the TUI
 		result, err := program.Run()
 		cleanup()
@@ -246,6 +286,24 @@ func setupSubscriber[T any](
 	}()
 }
 
+// setupThemeWatcher applies theme changes made by editing the config file while
+// the TUI is running.
+func setupThemeWatcher(ctx context.Context, wg *sync.WaitGroup, outputCh chan<- tea.Msg) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer logging.RecoverPanic("theme-watcher", nil)
+
+		for name := range theme.WatchConfig(ctx) {
+			select {
+			case outputCh <- dialog.ThemeChangedMsg{ThemeName: name}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
 func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg, func()) {
 	ch := make(chan tea.Msg, 100)
 
@@ -257,6 +315,7 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "coderAgent", app.CoderAgent.Subscribe, ch)
+	setupThemeWatcher(ctx, &wg, ch)
 
 	cleanupFunc := func() {
 		logging.Info("Cancelling all subscriptions")
@@ -281,6 +340,30 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	return ch, cleanupFunc
 }
 
+// readPrompt returns the prompt of the non-interactive mode. A prompt of "-"
+// is read from stdin, as is the prompt when none is given and stdin is piped.
+// An empty pipe starts the TUI as usual.
+func readPrompt(prompt string) (string, error) {
+	if prompt != "" && prompt != "-" {
+		return prompt, nil
+	}
+	if prompt == "" {
+		info, err := os.Stdin.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return "", nil
+		}
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the prompt from stdin: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" && prompt == "-" {
+		return "", fmt.Errorf("no prompt on stdin")
+	}
+	return text, nil
+}
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -293,11 +376,21 @@ func init() {
 	rootCmd.Flags().BoolP("version", "v", false, "Version")
 	rootCmd.Flags().BoolP("debug", "d", false, "Debug")
 	rootCmd.Flags().StringP("cwd", "c", "", "Current working directory")
-	rootCmd.Flags().StringP("prompt", "p", "", "Prompt to run in non-interactive mode")
+	rootCmd.Flags().StringP("prompt", "p", "", "Prompt to run in non-interactive mode, - reads it from stdin")
+	rootCmd.Flags().BoolP("resume", "r", false, "Resume the most recently updated session")
+	rootCmd.Flags().Bool("dry-run", false, "Show the diffs of file changes without writing them, and run only read-only commands")
 
 	// Add format flag with validation logic
 	rootCmd.Flags().StringP("output-format", "f", format.Text.String(),
-		"Output format for non-interactive mode (text, json)")
+		"Output format for non-interactive mode (text, json, ndjson)")
+
+	// Accept --output as a shorter name for --output-format
+	rootCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "output" {
+			name = "output-format"
+		}
+		return pflag.NormalizedName(name)
+	})
 
 	// Add quiet flag to hide spinner in non-interactive mode
 	rootCmd.Flags().BoolP("quiet", "q", false, "Hide spinner in non-interactive mode")
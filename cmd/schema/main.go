@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 
@@ -24,10 +25,41 @@ type JSONSchemaType struct {
 }
 
 func main() {
+	output := flag.String("output", "", "Write the schema to this file instead of stdout")
+	validate := flag.String("validate", "", "Validate this config file against the schema instead of printing it")
+	flag.Parse()
+
 	schema := generateSchema()
 
+	if *validate != "" {
+		violations, err := validateConfigFile(schema, *validate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating config: %v\n", err)
+			os.Exit(1)
+		}
+		if len(violations) > 0 {
+			for _, violation := range violations {
+				fmt.Fprintln(os.Stderr, violation)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid\n", *validate)
+		return
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+
 	// Pretty print the schema
-	encoder := json.NewEncoder(os.Stdout)
+	encoder := json.NewEncoder(out)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(schema); err != nil {
 		fmt.Fprintf(os.Stderr, "Error encoding schema: %v\n", err)
@@ -77,9 +109,36 @@ func generateSchema() map[string]any {
 		"default":     false,
 	}
 
+	schema["properties"].(map[string]any)["log"] = map[string]any{
+		"type":        "object",
+		"description": "Logging settings",
+		"properties": map[string]any{
+			"level": map[string]any{
+				"type":        "string",
+				"description": "Minimum level of records to log; debug mode always logs at debug",
+				"enum":        []string{"debug", "info", "warn", "error"},
+				"default":     "info",
+			},
+			"format": map[string]any{
+				"type":        "string",
+				"description": "Format of log records: text (logfmt) or json, one object per record for log collectors",
+				"enum":        []string{"text", "json"},
+				"default":     "text",
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["appendContextPaths"] = map[string]any{
+		"type":        "array",
+		"description": "Context files, directories ending in / or glob patterns added to contextPaths instead of replacing the defaults",
+		"items": map[string]any{
+			"type": "string",
+		},
+	}
+
 	schema["properties"].(map[string]any)["contextPaths"] = map[string]any{
 		"type":        "array",
-		"description": "Context paths for the application",
+		"description": "Context files, directories ending in / or glob patterns such as docs/*.md, relative to the working directory",
 		"items": map[string]any{
 			"type": "string",
 		},
@@ -116,11 +175,323 @@ func generateSchema() map[string]any {
 					"onedark",
 					"tokyonight",
 					"tron",
+					"custom",
+					"auto",
+					"cryoncode-dark",
+					"cryoncode-light",
 				},
 			},
+			"customThemePath": map[string]any{
+				"type":        "string",
+				"description": "JSON or TOML file defining every theme color, registered as the custom theme",
+			},
+			"banner": map[string]any{
+				"type":        "string",
+				"description": "Text, or a file containing it, shown above the chat on the startup screen",
+			},
+			"promptHistorySize": map[string]any{
+				"type":        "integer",
+				"description": "Number of sent prompts kept for recall with the up and down keys; 0 disables the history",
+				"default":     100,
+				"minimum":     0,
+			},
+			"diffContextLines": map[string]any{
+				"type":        "integer",
+				"description": "Unchanged lines shown around each change of a diff, in permission prompts and tool results",
+				"default":     3,
+				"minimum":     0,
+			},
+			"diffView": map[string]any{
+				"type":        "string",
+				"description": "Layout of diffs: split shows them side by side, unified in one column, auto picks unified on narrow terminals",
+				"enum":        []string{"auto", "split", "unified"},
+				"default":     "auto",
+			},
+			"maxContentWidth": map[string]any{
+				"type":        "integer",
+				"description": "Maximum width of rendered messages; 0 uses the full width of the chat",
+				"minimum":     0,
+			},
+			"contentAlign": map[string]any{
+				"type":        "string",
+				"description": "Placement of messages narrower than the chat",
+				"enum":        []string{"center", "left"},
+				"default":     "center",
+			},
+			"background": map[string]any{
+				"type":        "string",
+				"description": "Terminal background that colors are chosen for; auto detects it, dark or light override a terminal that misreports it",
+				"enum":        []string{"auto", "dark", "light"},
+				"default":     "auto",
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["title"] = map[string]any{
+		"type":        "object",
+		"description": "Session title generation configuration",
+		"properties": map[string]any{
+			"trigger": map[string]any{
+				"type":        "string",
+				"description": "When to generate a session title",
+				"enum":        []string{"firstMessage", "afterMessages", "never"},
+				"default":     "firstMessage",
+			},
+			"afterMessages": map[string]any{
+				"type":        "integer",
+				"description": "Number of user messages before generating a title when trigger is afterMessages",
+				"minimum":     1,
+				"default":     1,
+			},
 		},
 	}
 
+	// Add tool call argument cap
+	schema["properties"].(map[string]any)["maxToolArgumentBytes"] = map[string]any{
+		"type":        "integer",
+		"description": "Maximum size in bytes of a single streamed tool call's arguments",
+		"default":     2 * 1024 * 1024,
+		"minimum":     1,
+	}
+
+	schema["properties"].(map[string]any)["maxContextFileBytes"] = map[string]any{
+		"type":        "integer",
+		"description": "Context files larger than this many bytes are truncated in the system prompt (0 for no limit)",
+		"default":     65536,
+		"minimum":     0,
+	}
+
+	schema["properties"].(map[string]any)["inlineFileMaxBytes"] = map[string]any{
+		"type":        "integer",
+		"description": "Files under the working directory mentioned as @path in a prompt are attached to it up to this size in bytes; 0 disables attaching them",
+		"default":     0,
+		"minimum":     0,
+	}
+
+	schema["properties"].(map[string]any)["promptTemplates"] = map[string]any{
+		"type":        "object",
+		"description": "Reusable prompts by name; {{variable}} placeholders are filled in when a template is used",
+		"additionalProperties": map[string]any{
+			"type": "string",
+		},
+	}
+
+	schema["properties"].(map[string]any)["contextStrategy"] = map[string]any{
+		"type":        "string",
+		"description": "How a long conversation is kept within the context window: summarize compacts it when autoCompact is on, truncate sends only the most recent messages",
+		"enum":        []string{"summarize", "truncate"},
+		"default":     "summarize",
+	}
+
+	schema["properties"].(map[string]any)["contextFraction"] = map[string]any{
+		"type":             "number",
+		"description":      "Share of the model's context window that the truncate strategy fills with conversation history",
+		"default":          0.75,
+		"exclusiveMinimum": 0,
+		"maximum":          1,
+	}
+
+	schema["properties"].(map[string]any)["summary"] = map[string]any{
+		"type":        "object",
+		"description": "Session summarization settings",
+		"properties": map[string]any{
+			"preserveCodeBlocks": map[string]any{
+				"type":        "boolean",
+				"description": "Keep fenced code blocks from the conversation verbatim in the summary",
+				"default":     false,
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["tools"] = map[string]any{
+		"type":        "object",
+		"description": "Tool presentation and execution settings",
+		"properties": map[string]any{
+			"order": map[string]any{
+				"type":        "array",
+				"description": "Tool names to list first, in this order; unlisted tools follow in their default order",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"retry": map[string]any{
+				"type":        "object",
+				"description": "Retry policies by tool name for transient failures, such as network errors of fetch; failed calls of unlisted tools are not retried",
+				"additionalProperties": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"attempts": map[string]any{
+							"type":        "integer",
+							"description": "Number of retries after the first failure",
+							"minimum":     0,
+						},
+						"backoffMs": map[string]any{
+							"type":        "integer",
+							"description": "Delay in milliseconds before the first retry, doubled for each later one",
+							"default":     500,
+							"minimum":     1,
+						},
+					},
+				},
+			},
+			"fetch": map[string]any{
+				"type":        "object",
+				"description": "Limits on the URLs fetched by the fetch and fetch_url tools",
+				"properties": map[string]any{
+					"allowedDomains": map[string]any{
+						"type":        "array",
+						"description": "Only these domains and their subdomains may be fetched; empty allows all that are not blocked",
+						"items": map[string]any{
+							"type": "string",
+						},
+					},
+					"blockedDomains": map[string]any{
+						"type":        "array",
+						"description": "Domains and their subdomains that are never fetched, even when allowed",
+						"items": map[string]any{
+							"type": "string",
+						},
+					},
+					"maxBodyBytes": map[string]any{
+						"type":        "integer",
+						"description": "Responses are read up to this many bytes",
+						"default":     5242880,
+						"minimum":     1,
+					},
+				},
+			},
+			"cache": map[string]any{
+				"type":        "object",
+				"description": "Reuse of fetch and search results within a session",
+				"properties": map[string]any{
+					"enabled": map[string]any{
+						"type":        "boolean",
+						"description": "Answer repeated identical fetches and searches in a session from the cache",
+						"default":     true,
+					},
+					"ttlSeconds": map[string]any{
+						"type":        "integer",
+						"description": "How long a cached result is reused, in seconds",
+						"default":     900,
+						"minimum":     1,
+					},
+					"persist": map[string]any{
+						"type":        "boolean",
+						"description": "Also keep cached results on disk under the data directory, so they survive a restart",
+						"default":     false,
+					},
+				},
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["hardDelete"] = map[string]any{
+		"type":        "boolean",
+		"description": "Delete files immediately instead of moving them to the session trash",
+		"default":     false,
+	}
+
+	keybindingProperties := map[string]any{}
+	for _, action := range config.KeybindingActions {
+		keybindingProperties[action] = map[string]any{
+			"type":        "string",
+			"description": "Key for the " + action + " action, e.g. ctrl+g",
+		}
+	}
+	schema["properties"].(map[string]any)["keybindings"] = map[string]any{
+		"type":                 "object",
+		"description":          "Keys for the global TUI actions; unset actions keep their defaults",
+		"properties":           keybindingProperties,
+		"additionalProperties": false,
+	}
+
+	schema["properties"].(map[string]any)["probeModels"] = map[string]any{
+		"type":        "boolean",
+		"description": "Send a tiny request at startup to check that each agent's model exists and calls tools",
+		"default":     false,
+	}
+
+	schema["properties"].(map[string]any)["resumeLastSession"] = map[string]any{
+		"type":        "boolean",
+		"description": "Open the most recently updated session on startup instead of a new one",
+		"default":     false,
+	}
+
+	schema["properties"].(map[string]any)["dryRun"] = map[string]any{
+		"type":        "boolean",
+		"description": "Have the file editing tools return the diff of a change without writing it, and the bash tool run only read-only commands",
+		"default":     false,
+	}
+
+	schema["properties"].(map[string]any)["reasoningEffortByTier"] = map[string]any{
+		"type":        "object",
+		"description": "Reasoning effort for agents that don't set one, by the tier of their model: mini, standard or pro",
+		"properties": map[string]any{
+			"mini":     map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}, "default": "low"},
+			"standard": map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}, "default": "medium"},
+			"pro":      map[string]any{"type": "string", "enum": []string{"low", "medium", "high"}, "default": "high"},
+		},
+		"additionalProperties": false,
+	}
+
+	schema["properties"].(map[string]any)["debugLogRetention"] = map[string]any{
+		"type":        "object",
+		"description": "Limits on the request and response logs written in debug mode; the oldest are removed at startup and as new ones are written. 0 disables a limit",
+		"properties": map[string]any{
+			"maxFiles": map[string]any{
+				"type":        "integer",
+				"description": "Number of the newest log files kept",
+				"default":     1000,
+				"minimum":     0,
+			},
+			"maxAgeDays": map[string]any{
+				"type":        "integer",
+				"description": "Log files older than this many days are removed",
+				"default":     7,
+				"minimum":     0,
+			},
+			"maxBytes": map[string]any{
+				"type":        "integer",
+				"description": "Total size of the kept log files, in bytes",
+				"default":     104857600,
+				"minimum":     0,
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["warnLocalOverrides"] = map[string]any{
+		"type":        "boolean",
+		"description": "Log a warning listing the global settings that the local config file changes",
+		"default":     false,
+	}
+
+	schema["properties"].(map[string]any)["sandboxRoot"] = map[string]any{
+		"type":        "string",
+		"description": "Directory that file edits and bash commands are confined to; relative paths resolve against the working directory, which is the default. Set it to \"/\" to lift the restriction",
+	}
+
+	schema["properties"].(map[string]any)["autoApproveBelow"] = map[string]any{
+		"type":        "string",
+		"description": "Tool calls with a lower risk run without asking for permission: fetches are low, file writes medium, shell commands and deletions high",
+		"enum":        []string{"low", "medium", "high"},
+	}
+
+	schema["properties"].(map[string]any)["toolPermissions"] = map[string]any{
+		"type":        "object",
+		"description": "Whether tool calls ask for permission, run without asking or are refused, by tool name or by category: read (fetches), write (file changes and commits) or execute (shell commands and MCP tools). Tool names take precedence over categories",
+		"additionalProperties": map[string]any{
+			"type": "string",
+			"enum": []string{"ask", "allow", "deny"},
+		},
+	}
+
+	schema["properties"].(map[string]any)["editDiagnostics"] = map[string]any{
+		"type":        "string",
+		"description": "Which LSP diagnostics to attach to the results of file editing tools",
+		"enum":        []string{"all", "new", "off"},
+		"default":     "all",
+	}
+
 	// Add MCP servers
 	schema["properties"].(map[string]any)["mcpServers"] = map[string]any{
 		"type":        "object",
@@ -164,6 +535,15 @@ func generateSchema() map[string]any {
 						"type": "string",
 					},
 				},
+				"toolPrefix": map[string]any{
+					"type":        "string",
+					"description": "Prefix for the server's tool names (defaults to the server name)",
+				},
+				"maxReconnectAttempts": map[string]any{
+					"type":        "integer",
+					"description": "Reconnect attempts with exponential backoff for SSE type MCP servers",
+					"default":     5,
+				},
 			},
 			"required": []string{"command"},
 		},
@@ -186,6 +566,46 @@ func generateSchema() map[string]any {
 					"description": "Whether the provider is disabled",
 					"default":     false,
 				},
+				"requestsPerMinute": map[string]any{
+					"type":        "integer",
+					"description": "Maximum requests per minute sent to the provider, shared by all agents (0 for no limit)",
+					"default":     0,
+					"minimum":     0,
+				},
+				"disablePromptCaching": map[string]any{
+					"type":        "boolean",
+					"description": "Don't mark the system prompt, tools and recent messages as cacheable (Anthropic)",
+					"default":     false,
+				},
+				"grounding": map[string]any{
+					"type":        "boolean",
+					"description": "Let the model search Google and cite the pages its answers are based on (Gemini and VertexAI)",
+					"default":     false,
+				},
+				"budget": map[string]any{
+					"type":        "object",
+					"description": "Caps the requests and tokens sent to the provider per hour or day; once spent, requests fail until the window resets",
+					"properties": map[string]any{
+						"window": map[string]any{
+							"type":        "string",
+							"description": "Period the budget is counted over, starting on the hour or at local midnight",
+							"enum":        []string{"hour", "day"},
+							"default":     "day",
+						},
+						"requests": map[string]any{
+							"type":        "integer",
+							"description": "Maximum requests per window (0 for no limit)",
+							"default":     0,
+							"minimum":     0,
+						},
+						"tokens": map[string]any{
+							"type":        "integer",
+							"description": "Maximum input and output tokens per window, including cached tokens (0 for no limit)",
+							"default":     0,
+							"minimum":     0,
+						},
+					},
+				},
 			},
 		},
 	}
@@ -232,6 +652,25 @@ func generateSchema() map[string]any {
 					"description": "Reasoning effort for models that support it (OpenAI, Anthropic)",
 					"enum":        []string{"low", "medium", "high"},
 				},
+				"stepByStep": map[string]any{
+					"type":        "boolean",
+					"description": "Ask models without native reasoning to think step by step",
+					"default":     false,
+				},
+				"prompt": map[string]any{
+					"type":        "string",
+					"description": "Instructions added to the agent's built-in system prompt, or replacing it with promptMode replace",
+				},
+				"promptFile": map[string]any{
+					"type":        "string",
+					"description": "File to read the prompt from instead, relative to the working directory; it must exist",
+				},
+				"promptMode": map[string]any{
+					"type":        "string",
+					"description": "Whether the prompt is appended to the built-in system prompt or replaces it",
+					"enum":        []string{"append", "replace"},
+					"default":     "append",
+				},
 			},
 			"required": []string{"model"},
 		},
@@ -250,6 +689,7 @@ func generateSchema() map[string]any {
 		string(config.AgentCoder),
 		string(config.AgentTask),
 		string(config.AgentTitle),
+		string(config.AgentSummarizer),
 	}
 
 	for _, agentName := range knownAgents {
@@ -271,6 +711,110 @@ func generateSchema() map[string]any {
 		"agent": agentSchema["additionalProperties"],
 	}
 
+	schema["properties"].(map[string]any)["maxConcurrentLspStarts"] = map[string]any{
+		"type":        "integer",
+		"description": "Maximum number of LSP servers initializing at once at launch; 0 starts them all together",
+		"default":     0,
+		"minimum":     0,
+	}
+
+	schema["properties"].(map[string]any)["shell"] = map[string]any{
+		"type":        "object",
+		"description": "Shell used by the bash tool",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path to the shell executable",
+			},
+			"args": map[string]any{
+				"type":        "array",
+				"description": "Arguments passed to the shell",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"allowedDirectories": map[string]any{
+				"type":        "array",
+				"description": "Directories the bash tool may work in; relative entries resolve against the working directory",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"timeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Timeout for bash tool commands that don't ask for one",
+				"default":     config.DefaultShellTimeoutSeconds,
+				"minimum":     1,
+			},
+			"maxOutputBytes": map[string]any{
+				"type":        "integer",
+				"description": "Maximum bytes of stdout and of stderr returned by the bash tool; the middle of longer output is cut",
+				"default":     config.DefaultShellMaxOutputBytes,
+				"minimum":     1,
+			},
+			"allowedCommands": map[string]any{
+				"type":        "array",
+				"description": "Commands the bash tool runs without asking for permission, e.g. \"git status\"; entries written as /pattern/ are regular expressions that each command of a chained line must match as a whole",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"blockedCommands": map[string]any{
+				"type":        "array",
+				"description": "Commands the bash tool always refuses, e.g. \"rm -rf\"; entries written as /pattern/ are regular expressions matched against the whole command",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"env": map[string]any{
+				"type":        "array",
+				"description": "KEY=VALUE variables set for each bash tool command; $VAR and ${VAR} in values are expanded",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+	}
+
+	// Add LSP fan-out configuration
+	schema["properties"].(map[string]any)["lspFanOut"] = map[string]any{
+		"type":        "object",
+		"description": "Limits for tools that query every LSP server at once",
+		"properties": map[string]any{
+			"maxConcurrency": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of LSP servers queried concurrently",
+				"default":     4,
+				"minimum":     1,
+			},
+			"clientTimeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Seconds to wait for each LSP server before returning partial results",
+				"default":     5,
+				"minimum":     1,
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["headless"] = map[string]any{
+		"type":        "object",
+		"description": "How permission requests are decided when no TUI is attached to answer them, as in non-interactive mode",
+		"properties": map[string]any{
+			"permissionDecision": map[string]any{
+				"type":        "string",
+				"description": "Whether unanswered permission requests are allowed or denied",
+				"enum":        []string{"allow", "deny"},
+				"default":     "allow",
+			},
+			"permissionTimeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Seconds to wait for an answer before deciding; 0 decides at once",
+				"default":     0,
+				"minimum":     0,
+			},
+		},
+	}
+
 	// Add LSP configuration
 	schema["properties"].(map[string]any)["lsp"] = map[string]any{
 		"type":        "object",
@@ -299,6 +843,19 @@ func generateSchema() map[string]any {
 					"type":        "object",
 					"description": "Additional options for the LSP server",
 				},
+				"env": map[string]any{
+					"type":        "array",
+					"description": "KEY=VALUE entries added to the environment of the LSP server; $VAR and ${VAR} in values are expanded",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"initTimeoutSeconds": map[string]any{
+					"type":        "integer",
+					"description": "Seconds to wait for the LSP server to initialize and become ready",
+					"default":     30,
+					"minimum":     1,
+				},
 			},
 			"required": []string{"command"},
 		},
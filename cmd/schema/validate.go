@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// validateConfigFile checks the JSON config file at path against schema and
+// returns one message per violation.
+func validateConfigFile(schema map[string]any, path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return validateConfig(schema, value)
+}
+
+// validateConfig checks value against schema. It supports the subset of JSON
+// Schema draft-07 used by generateSchema.
+func validateConfig(schema map[string]any, value any) ([]string, error) {
+	// Round-trip the schema so every node has the types encoding/json produces.
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	v := &validator{root: root}
+	v.validate(root, value, "")
+	return v.violations, nil
+}
+
+type validator struct {
+	root       map[string]any
+	violations []string
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.violations = append(v.violations, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) validate(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		schema = resolved
+	}
+
+	if typ, ok := schema["type"].(string); ok && !matchesType(typ, value) {
+		v.fail(path, "expected %s, got %s", typ, jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "value %v is not one of the allowed values", value)
+		}
+	}
+
+	if number, ok := value.(float64); ok {
+		if minimum, ok := schema["minimum"].(float64); ok && number < minimum {
+			v.fail(path, "value %v is less than the minimum %v", number, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && number > maximum {
+			v.fail(path, "value %v is greater than the maximum %v", number, maximum)
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, value, path)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+func (v *validator) validateObject(schema map[string]any, value map[string]any, path string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				v.fail(path, "missing required property %q", name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+		if property, ok := properties[key].(map[string]any); ok {
+			v.validate(property, value[key], childPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case map[string]any:
+			v.validate(additional, value[key], childPath)
+		case bool:
+			if !additional {
+				v.fail(path, "unknown property %q", key)
+			}
+		}
+	}
+}
+
+func (v *validator) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+	var node any = v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference %q", ref)
+		}
+		node = object[part]
+	}
+	resolved, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference %q", ref)
+	}
+	return resolved, nil
+}
+
+func matchesType(typ string, value any) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
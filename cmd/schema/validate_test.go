@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".cryoncode.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestValidateConfigFileValid(t *testing.T) {
+	path := writeConfig(t, `{
+  "data": {
+    "directory": ".cryoncode"
+  },
+  "debug": false,
+  "providers": {
+    "anthropic": {
+      "apiKey": "your-api-key"
+    }
+  },
+  "agents": {
+    "coder": {
+      "model": "claude-3.7-sonnet",
+      "maxTokens": 5000,
+      "reasoningEffort": "medium"
+    }
+  },
+  "lsp": {
+    "go": {
+      "command": "gopls",
+      "initTimeoutSeconds": 60
+    }
+  },
+  "tools": {
+    "order": ["grep", "view"]
+  }
+}`)
+
+	violations, err := validateConfigFile(generateSchema(), path)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestValidateConfigFileInvalid(t *testing.T) {
+	path := writeConfig(t, `{
+  "data": {},
+  "debug": "yes",
+  "agents": {
+    "coder": {
+      "model": "not-a-model",
+      "maxTokens": 0
+    }
+  },
+  "lsp": {
+    "go": {
+      "args": ["serve"]
+    }
+  },
+  "tools": {
+    "order": ["grep", 1]
+  }
+}`)
+
+	violations, err := validateConfigFile(generateSchema(), path)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		`data: missing required property "directory"`,
+		"debug: expected boolean, got string",
+		"agents.coder.model: value not-a-model is not one of the allowed values",
+		"agents.coder.maxTokens: value 0 is less than the minimum 1",
+		`lsp.go: missing required property "command"`,
+		"tools.order[1]: expected string, got number",
+	}, violations)
+}
+
+func TestValidateConfigFileMalformed(t *testing.T) {
+	path := writeConfig(t, `{"debug": `)
+
+	_, err := validateConfigFile(generateSchema(), path)
+	assert.Error(t, err)
+}
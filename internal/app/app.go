@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +21,7 @@ import (
 	"github.com/zhenbah/cryoncode/internal/lsp"
 	"github.com/zhenbah/cryoncode/internal/message"
 	"github.com/zhenbah/cryoncode/internal/permission"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
 	"github.com/zhenbah/cryoncode/internal/session"
 	"github.com/zhenbah/cryoncode/internal/tui/theme"
 )
@@ -34,7 +38,11 @@ type App struct {
 
 	clientsMutex sync.RWMutex
 
-	watcherCancelFuncs []context.CancelFunc
+	// ctx is the context the app was created with, which LSP clients
+	// restarted after their watcher crashed run under
+	ctx context.Context
+
+	watcherCancelFuncs map[string]context.CancelFunc // By LSP client name
 	cancelFuncsMutex   sync.Mutex
 	watcherWG          sync.WaitGroup
 }
@@ -51,6 +59,9 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		History:     files,
 		Permissions: permission.NewPermissionService(),
 		LSPClients:  make(map[string]*lsp.Client),
+
+		ctx:                ctx,
+		watcherCancelFuncs: make(map[string]context.CancelFunc),
 	}
 
 	// Initialize theme based on configuration
@@ -70,6 +81,7 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 			app.Messages,
 			app.History,
 			app.LSPClients,
+			app.LSPClientsSnapshot,
 		),
 	)
 	if err != nil {
@@ -77,13 +89,49 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		return nil, err
 	}
 
+	if config.Get().ProbeModels {
+		go func() {
+			defer logging.RecoverPanic("model-probe", nil)
+			agent.ProbeModels(ctx)
+		}()
+	}
+
 	return app, nil
 }
 
 // initTheme sets the application theme based on the configuration
 func (app *App) initTheme() {
 	cfg := config.Get()
-	if cfg == nil || cfg.TUI.Theme == "" {
+	if cfg == nil {
+		return // Use default theme
+	}
+
+	switch cfg.TUI.Background {
+	case config.BackgroundDark:
+		theme.ForceBackground(true)
+	case config.BackgroundLight:
+		theme.ForceBackground(false)
+	}
+
+	if cfg.TUI.CustomThemePath != "" {
+		path := cfg.TUI.CustomThemePath
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfg.WorkingDir, path)
+		}
+		customTheme, err := theme.LoadCustomTheme(path)
+		if err != nil {
+			logging.ErrorPersist(err.Error())
+		} else {
+			theme.RegisterTheme(theme.CustomThemeName, customTheme)
+		}
+	}
+
+	if cfg.TUI.Theme == "" {
 		return // Use default theme
 	}
 
@@ -96,13 +144,16 @@ func (app *App) initTheme() {
 	}
 }
 
-// RunNonInteractive handles the execution flow when a prompt is provided via CLI flag.
+// RunNonInteractive handles the execution flow when a prompt is provided via CLI flag or stdin.
 func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat string, quiet bool) error {
 	logging.Info("Running in non-interactive mode")
 
+	// NDJSON output follows the run as it happens, so it needs no spinner
+	streaming := outputFormat == format.NDJSON.String()
+
 	// Start spinner if not in quiet mode
 	var spinner *format.Spinner
-	if !quiet {
+	if !quiet && !streaming {
 		spinner = format.NewSpinner("Thinking...")
 		spinner.Start()
 		defer spinner.Stop()
@@ -125,41 +176,130 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 	}
 	logging.Info("Created session for non-interactive run", "session_id", sess.ID)
 
-	// Automatically approve all permission requests for this non-interactive session
-	a.Permissions.AutoApproveSession(sess.ID)
+	// No one is there to answer permission requests, including those of
+	// sub-agent sessions, so they are decided by the headless policy
+	headless := config.Get().Headless
+	a.Permissions.SetUnattended(headless.PermissionDecision, time.Duration(headless.PermissionTimeoutSeconds)*time.Second)
+
+	// Subscribe before the run starts so that no event is missed
+	var events <-chan pubsub.Event[agent.AgentEvent]
+	if streaming {
+		subCtx, cancelSub := context.WithCancel(ctx)
+		defer cancelSub()
+		events = a.CoderAgent.Subscribe(subCtx)
+	}
 
 	done, err := a.CoderAgent.Run(ctx, sess.ID, prompt)
 	if err != nil {
 		return fmt.Errorf("failed to start agent processing stream: %w", err)
 	}
 
-	result := <-done
+	var result agent.AgentEvent
+	if streaming {
+		result = streamEvents(os.Stdout, sess.ID, events, done)
+	} else {
+		result = <-done
+	}
+
+	// Stop spinner before printing output
+	if !quiet && spinner != nil {
+		spinner.Stop()
+	}
+
+	output := a.nonInteractiveResult(ctx, sess.ID)
 	if result.Error != nil {
 		if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
 			logging.Info("Agent processing cancelled", "session_id", sess.ID)
 			return nil
 		}
+		// Scripts reading JSON get the partial result along with the error
+		switch outputFormat {
+		case format.JSON.String():
+			output.Error = result.Error.Error()
+			fmt.Println(format.FormatOutput(output, outputFormat))
+		case format.NDJSON.String():
+			fmt.Println(format.FormatStreamEvent(format.StreamEvent{
+				Type:      format.StreamEventError,
+				SessionID: sess.ID,
+				Usage:     &output.Usage,
+				Error:     result.Error.Error(),
+			}))
+		}
 		return fmt.Errorf("agent processing failed: %w", result.Error)
 	}
 
-	// Stop spinner before printing output
-	if !quiet && spinner != nil {
-		spinner.Stop()
+	if streaming {
+		fmt.Println(format.FormatStreamEvent(format.StreamEvent{
+			Type:         format.StreamEventResponse,
+			SessionID:    sess.ID,
+			Content:      result.Message.Content().String(),
+			FinishReason: string(result.Message.FinishReason()),
+			Usage:        &output.Usage,
+		}))
+		logging.Info("Non-interactive run completed", "session_id", sess.ID)
+		return nil
 	}
 
 	// Get the text content from the response
-	content := "No content available"
+	output.Response = "No content available"
 	if result.Message.Content().String() != "" {
-		content = result.Message.Content().String()
+		output.Response = result.Message.Content().String()
 	}
 
-	fmt.Println(format.FormatOutput(content, outputFormat))
+	fmt.Println(format.FormatOutput(output, outputFormat))
 
 	logging.Info("Non-interactive run completed", "session_id", sess.ID)
 
 	return nil
 }
 
+// nonInteractiveResult collects the tool calls and token usage of a
+// non-interactive session. Tool calls of sub-agents are not included.
+func (a *App) nonInteractiveResult(ctx context.Context, sessionID string) format.Result {
+	output := format.Result{SessionID: sessionID, Model: string(a.CoderAgent.Model().ID)}
+	if sess, err := a.Sessions.Get(ctx, sessionID); err == nil {
+		output.Usage = format.Usage{
+			PromptTokens:     sess.PromptTokens,
+			CompletionTokens: sess.CompletionTokens,
+			Cost:             sess.Cost,
+		}
+	}
+
+	msgs, err := a.Messages.List(ctx, sessionID)
+	if err != nil {
+		logging.Warn("Failed to list the messages of the non-interactive session", "error", err)
+		return output
+	}
+	results := make(map[string]message.ToolResult)
+	for _, msg := range msgs {
+		for _, toolResult := range msg.ToolResults() {
+			results[toolResult.ToolCallID] = toolResult
+		}
+	}
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			toolResult := results[call.ID]
+			output.ToolCalls = append(output.ToolCalls, format.ToolCall{
+				Name:    call.Name,
+				Input:   call.Input,
+				Output:  toolResult.Content,
+				IsError: toolResult.IsError,
+			})
+		}
+	}
+	return output
+}
+
+// LSPClientsSnapshot returns a copy of the running LSP clients by name, safe
+// to iterate while clients are being restarted.
+func (app *App) LSPClientsSnapshot() map[string]*lsp.Client {
+	app.clientsMutex.RLock()
+	defer app.clientsMutex.RUnlock()
+	clients := make(map[string]*lsp.Client, len(app.LSPClients))
+	maps.Copy(clients, app.LSPClients)
+	return clients
+}
+
 // Shutdown performs a clean shutdown of the application
 func (app *App) Shutdown() {
 	// Cancel all watcher goroutines
@@ -171,12 +311,7 @@ func (app *App) Shutdown() {
 	app.watcherWG.Wait()
 
 	// Perform additional cleanup for LSP clients
-	app.clientsMutex.RLock()
-	clients := make(map[string]*lsp.Client, len(app.LSPClients))
-	maps.Copy(clients, app.LSPClients)
-	app.clientsMutex.RUnlock()
-
-	for name, client := range clients {
+	for name, client := range app.LSPClientsSnapshot() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		if err := client.Shutdown(shutdownCtx); err != nil {
 			logging.Error("Failed to shutdown LSP client", "name", name, "error", err)
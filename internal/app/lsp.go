@@ -2,6 +2,8 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/zhenbah/cryoncode/internal/config"
@@ -14,40 +16,80 @@ func (app *App) initLSPClients(ctx context.Context) {
 	cfg := config.Get()
 
 	// Initialize LSP clients
-	for name, clientConfig := range cfg.LSP {
-		// Start each client initialization in its own goroutine
-		go app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
-	}
+	startLSPClients(ctx, cfg.LSP, cfg.MaxConcurrentLSPStarts, func(ctx context.Context, name string, clientConfig config.LSPConfig) {
+		_ = app.createAndStartLSPClient(ctx, name, clientConfig)
+	})
 	logging.Info("LSP clients initialization started in background")
 }
 
+// startLSPClients runs start for every client in its own goroutine. When
+// maxConcurrency is positive, at most that many clients initialize at once and
+// the rest are queued in name order; it blocks until the last one is started.
+func startLSPClients(ctx context.Context, clients map[string]config.LSPConfig, maxConcurrency int, start func(ctx context.Context, name string, clientConfig config.LSPConfig)) {
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if maxConcurrency <= 0 {
+		for _, name := range names {
+			go start(ctx, name, clients[name])
+		}
+		return
+	}
+
+	sem := make(chan struct{}, maxConcurrency)
+	for _, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		go func() {
+			defer func() { <-sem }()
+			start(ctx, name, clients[name])
+		}()
+	}
+}
+
 // createAndStartLSPClient creates a new LSP client, initializes it, and starts its workspace watcher
-func (app *App) createAndStartLSPClient(ctx context.Context, name string, command string, args ...string) {
+func (app *App) createAndStartLSPClient(ctx context.Context, name string, clientConfig config.LSPConfig) error {
 	// Create a specific context for initialization with a timeout
-	logging.Info("Creating LSP client", "name", name, "command", command, "args", args)
+	logging.Info("Creating LSP client", "name", name, "command", clientConfig.Command, "args", clientConfig.Args)
 	
 	// Create the LSP client
-	lspClient, err := lsp.NewClient(ctx, command, args...)
+	lspClient, err := lsp.NewClient(ctx, clientConfig.Command, clientConfig.ExpandedEnv(), clientConfig.Args...)
 	if err != nil {
 		logging.Error("Failed to create LSP client for", name, err)
-		return
+		return err
 	}
 
 	// Create a longer timeout for initialization (some servers take time to start)
-	initCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	initTimeout := clientConfig.InitTimeoutSeconds
+	if initTimeout <= 0 {
+		initTimeout = config.DefaultLSPInitTimeoutSeconds
+	}
+	initCtx, cancel := context.WithTimeout(ctx, time.Duration(initTimeout)*time.Second)
 	defer cancel()
 	
 	// Initialize with the initialization context
 	_, err = lspClient.InitializeLSPClient(initCtx, config.WorkingDirectory())
 	if err != nil {
+		if initCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("initialization did not finish within initTimeoutSeconds (%d): %w", initTimeout, err)
+		}
 		logging.Error("Initialize failed", "name", name, "error", err)
 		// Clean up the client to prevent resource leaks
 		lspClient.Close()
-		return
+		return err
 	}
 
 	// Wait for the server to be ready
 	if err := lspClient.WaitForServerReady(initCtx); err != nil {
+		if initCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("%w within initTimeoutSeconds (%d)", err, initTimeout)
+		}
 		logging.Error("Server failed to become ready", "name", name, "error", err)
 		// We'll continue anyway, as some functionality might still work
 		lspClient.SetServerState(lsp.StateError)
@@ -69,7 +111,7 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, comman
 
 	// Store the cancel function to be called during cleanup
 	app.cancelFuncsMutex.Lock()
-	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
+	app.watcherCancelFuncs[name] = cancelFunc
 	app.cancelFuncsMutex.Unlock()
 
 	// Add the watcher to a WaitGroup to track active goroutines
@@ -81,6 +123,7 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, comman
 	app.clientsMutex.Unlock()
 
 	go app.runWorkspaceWatcher(watchCtx, name, workspaceWatcher)
+	return nil
 }
 
 // runWorkspaceWatcher executes the workspace watcher for an LSP client
@@ -88,39 +131,81 @@ func (app *App) runWorkspaceWatcher(ctx context.Context, name string, workspaceW
 	defer app.watcherWG.Done()
 	defer logging.RecoverPanic("LSP-"+name, func() {
 		// Try to restart the client
-		app.restartLSPClient(ctx, name)
+		app.restartLSPClient(name)
 	})
 
 	workspaceWatcher.WatchWorkspace(ctx, config.WorkingDirectory())
 	logging.Info("Workspace watcher stopped", "client", name)
 }
 
-// restartLSPClient attempts to restart a crashed or failed LSP client
-func (app *App) restartLSPClient(ctx context.Context, name string) {
-	// Get the original configuration
-	cfg := config.Get()
-	clientConfig, exists := cfg.LSP[name]
-	if !exists {
-		logging.Error("Cannot restart client, configuration not found", "client", name)
+// restartLSPClient attempts to restart a crashed or failed LSP client. The
+// new client runs under the app's context, since the restart cancels the
+// context of the crashed watcher.
+func (app *App) restartLSPClient(name string) {
+	if app.ctx.Err() != nil {
+		return
+	}
+	if err := app.RestartLSPClient(app.ctx, name); err != nil {
+		logging.Error("Failed to restart LSP client", "client", name, "error", err)
 		return
 	}
+	logging.Info("Successfully restarted LSP client", "client", name)
+}
 
-	// Clean up the old client if it exists
-	app.clientsMutex.Lock()
-	oldClient, exists := app.LSPClients[name]
-	if exists {
-		delete(app.LSPClients, name) // Remove from map before potentially slow shutdown
+// RestartLSPClient shuts down the LSP client called name, if it is running,
+// and starts it again from its configuration with a new workspace watcher.
+// The new client and its watcher run until ctx is done. While it restarts the
+// client reports StateStarting.
+func (app *App) RestartLSPClient(ctx context.Context, name string) error {
+	clientConfig, exists := config.Get().LSP[name]
+	if !exists {
+		return fmt.Errorf("no LSP server named %s is configured", name)
+	}
+	if clientConfig.Disabled {
+		return fmt.Errorf("LSP server %s is disabled", name)
 	}
-	app.clientsMutex.Unlock()
 
-	if exists && oldClient != nil {
-		// Try to shut it down gracefully, but don't block on errors
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		_ = oldClient.Shutdown(shutdownCtx)
+	app.cancelFuncsMutex.Lock()
+	if cancel, ok := app.watcherCancelFuncs[name]; ok {
 		cancel()
+		delete(app.watcherCancelFuncs, name)
 	}
+	app.cancelFuncsMutex.Unlock()
 
-	// Create a new client using the shared function
-	app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
-	logging.Info("Successfully restarted LSP client", "client", name)
+	// The old client stays in the map until the new one replaces it, so the
+	// status bar shows the server as starting
+	app.clientsMutex.RLock()
+	oldClient := app.LSPClients[name]
+	app.clientsMutex.RUnlock()
+	if oldClient != nil {
+		oldClient.SetServerState(lsp.StateStarting)
+		shutdownLSPClient(name, oldClient)
+	}
+
+	if err := app.createAndStartLSPClient(ctx, name, clientConfig); err != nil {
+		app.clientsMutex.Lock()
+		if app.LSPClients[name] == oldClient {
+			delete(app.LSPClients, name)
+		}
+		app.clientsMutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// shutdownLSPClient asks an LSP server to shut down and exit, then stops its
+// process. Errors are logged, since a server being restarted may well not
+// respond.
+func shutdownLSPClient(name string, client *lsp.Client) {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Shutdown(shutdownCtx); err != nil {
+		logging.Debug("LSP server did not shut down", "client", name, "error", err)
+	}
+	if err := client.Exit(shutdownCtx); err != nil {
+		logging.Debug("LSP server did not exit", "client", name, "error", err)
+	}
+	if err := client.Close(); err != nil {
+		logging.Debug("LSP server process did not stop cleanly", "client", name, "error", err)
+	}
 }
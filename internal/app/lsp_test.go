@@ -0,0 +1,187 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/lsp"
+)
+
+// TestMain runs the test binary as a fake language server when a test starts
+// it as one.
+func TestMain(m *testing.M) {
+	if os.Getenv("CRYONCODE_FAKE_LSP_SERVER") == "1" {
+		serveFakeLSP(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// serveFakeLSP answers initialize with support for workspace/symbol, which
+// the client pings to see that the server is ready, and every other request
+// with null, until the exit notification or the end of input.
+func serveFakeLSP(in io.Reader, out io.Writer) {
+	r := bufio.NewReader(in)
+	for {
+		length := 0
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			if value, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+				length, _ = strconv.Atoi(value)
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		var msg lsp.Message
+		if err := json.Unmarshal(body, &msg); err != nil || msg.Method == "exit" {
+			return
+		}
+		if msg.ID == 0 || msg.Method == "" {
+			continue
+		}
+		result := json.RawMessage("null")
+		if msg.Method == "initialize" {
+			result = json.RawMessage(`{"capabilities": {"workspaceSymbolProvider": true}}`)
+		}
+		data, _ := json.Marshal(lsp.Message{JSONRPC: "2.0", ID: msg.ID, Result: result})
+		fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+}
+
+func TestStartLSPClientsSequential(t *testing.T) {
+	clients := map[string]config.LSPConfig{
+		"typescript": {Command: "typescript-language-server"},
+		"go":         {Command: "gopls"},
+		"rust":       {Command: "rust-analyzer"},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var order []string
+	inFlight, maxInFlight := 0, 0
+	wg.Add(len(clients))
+	start := func(ctx context.Context, name string, clientConfig config.LSPConfig) {
+		defer wg.Done()
+		mu.Lock()
+		order = append(order, name)
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}
+
+	startLSPClients(context.Background(), clients, 1, start)
+	wg.Wait()
+
+	assert.Equal(t, 1, maxInFlight)
+	assert.Equal(t, []string{"go", "rust", "typescript"}, order)
+}
+
+func TestStartLSPClientsUnlimited(t *testing.T) {
+	clients := map[string]config.LSPConfig{
+		"go":   {Command: "gopls"},
+		"rust": {Command: "rust-analyzer"},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(clients))
+	release := make(chan struct{})
+	start := func(ctx context.Context, name string, clientConfig config.LSPConfig) {
+		wg.Done()
+		<-release
+	}
+
+	startLSPClients(context.Background(), clients, 0, start)
+	// Both servers must be initializing at the same time for this to return.
+	wg.Wait()
+	close(release)
+}
+
+func TestRestartLSPClientErrors(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	cfg, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg.LSP = map[string]config.LSPConfig{
+		"off":     {Disabled: true, Command: "gopls"},
+		"missing": {Command: filepath.Join(home, "no-such-server")},
+	}
+
+	app := &App{
+		LSPClients:         make(map[string]*lsp.Client),
+		watcherCancelFuncs: make(map[string]context.CancelFunc),
+	}
+	ctx := context.Background()
+	assert.EqualError(t, app.RestartLSPClient(ctx, "unknown"), "no LSP server named unknown is configured")
+	assert.EqualError(t, app.RestartLSPClient(ctx, "off"), "LSP server off is disabled")
+
+	// A server that fails to start is not left in the client map
+	assert.Error(t, app.RestartLSPClient(ctx, "missing"))
+	assert.Empty(t, app.LSPClients)
+}
+
+func TestWatcherCrashRestartsLSPClient(t *testing.T) {
+	// The panic log is written to the current directory
+	t.Chdir(t.TempDir())
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	cfg, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg.LSP = map[string]config.LSPConfig{
+		"fake": {Command: os.Args[0], Env: []string{"CRYONCODE_FAKE_LSP_SERVER=1"}, InitTimeoutSeconds: 5},
+	}
+
+	app := &App{
+		LSPClients:         make(map[string]*lsp.Client),
+		ctx:                context.Background(),
+		watcherCancelFuncs: make(map[string]context.CancelFunc),
+	}
+	t.Cleanup(func() {
+		app.Shutdown()
+		for _, client := range app.LSPClientsSnapshot() {
+			client.Cmd.Process.Kill()
+		}
+	})
+
+	// The restart cancels the crashed watcher's context, as it would that of a
+	// watcher started with the client
+	watchCtx, cancel := context.WithCancel(context.Background())
+	app.watcherCancelFuncs["fake"] = cancel
+	app.watcherWG.Add(1)
+	// A nil watcher panics, which the watcher recovers from by restarting
+	app.runWorkspaceWatcher(watchCtx, "fake", nil)
+
+	client := app.LSPClientsSnapshot()["fake"]
+	require.NotNil(t, client, "the client was not restarted")
+	assert.Equal(t, lsp.StateReady, client.GetServerState())
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zhenbah/cryoncode/internal/format"
+	"github.com/zhenbah/cryoncode/internal/llm/agent"
+	"github.com/zhenbah/cryoncode/internal/llm/provider"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
+)
+
+// streamEvents writes the events of a non-interactive session to w as NDJSON
+// until the run is done, and returns its result.
+func streamEvents(w io.Writer, sessionID string, events <-chan pubsub.Event[agent.AgentEvent], done <-chan agent.AgentEvent) agent.AgentEvent {
+	write := func(event agent.AgentEvent) {
+		if event.SessionID != sessionID {
+			return
+		}
+		for _, line := range streamEventLines(event) {
+			fmt.Fprintln(w, format.FormatStreamEvent(line))
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return <-done
+			}
+			write(event.Payload)
+		case result := <-done:
+			// The events of the run were all published before its result
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return result
+					}
+					write(event.Payload)
+				default:
+					return result
+				}
+			}
+		}
+	}
+}
+
+// streamEventLines converts an agent event to the lines of the NDJSON output.
+// The result of the run is written separately, once it is done.
+func streamEventLines(event agent.AgentEvent) []format.StreamEvent {
+	switch event.Type {
+	case agent.AgentEventTypeStream:
+		e := event.Stream
+		line := format.StreamEvent{Type: string(e.Type), SessionID: event.SessionID}
+		switch e.Type {
+		case provider.EventContentDelta, provider.EventThinkingDelta:
+			line.Content = e.Content
+		case provider.EventToolUseStart, provider.EventToolUseStop:
+			if e.ToolCall != nil {
+				line.ToolCall = &format.ToolCall{ID: e.ToolCall.ID, Name: e.ToolCall.Name}
+			}
+		case provider.EventComplete:
+			if e.Response != nil {
+				for _, call := range e.Response.ToolCalls {
+					line.ToolCalls = append(line.ToolCalls, format.ToolCall{ID: call.ID, Name: call.Name, Input: call.Input})
+				}
+				line.FinishReason = string(e.Response.FinishReason)
+			}
+		}
+		return []format.StreamEvent{line}
+	case agent.AgentEventTypeToolResults:
+		var lines []format.StreamEvent
+		for _, result := range event.Message.ToolResults() {
+			lines = append(lines, format.StreamEvent{
+				Type:      format.StreamEventToolResult,
+				SessionID: event.SessionID,
+				ToolCall: &format.ToolCall{
+					ID:      result.ToolCallID,
+					Name:    result.Name,
+					Output:  result.Content,
+					IsError: result.IsError,
+				},
+			})
+		}
+		return lines
+	case agent.AgentEventTypeUsage:
+		return []format.StreamEvent{{
+			Type:      string(event.Type),
+			SessionID: event.SessionID,
+			Usage:     streamUsage(event.Usage, event.Cost),
+		}}
+	}
+	return nil
+}
+
+// streamUsage counts tokens the way the session totals do.
+func streamUsage(usage provider.TokenUsage, cost float64) *format.Usage {
+	return &format.Usage{
+		PromptTokens:     usage.InputTokens + usage.CacheCreationTokens,
+		CompletionTokens: usage.OutputTokens + usage.CacheReadTokens,
+		Cost:             cost,
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/format"
+	"github.com/zhenbah/cryoncode/internal/llm/agent"
+	"github.com/zhenbah/cryoncode/internal/llm/provider"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
+)
+
+func TestStreamEvents(t *testing.T) {
+	toolCall := message.ToolCall{ID: "call-1", Name: "bash", Input: `{"command":"ls"}`}
+	published := []agent.AgentEvent{
+		{Type: agent.AgentEventTypeStream, SessionID: "s", Stream: &provider.ProviderEvent{Type: provider.EventContentDelta, Content: "Listing"}},
+		{Type: agent.AgentEventTypeStream, SessionID: "other", Stream: &provider.ProviderEvent{Type: provider.EventContentDelta, Content: "not this session"}},
+		{Type: agent.AgentEventTypeStream, SessionID: "s", Stream: &provider.ProviderEvent{Type: provider.EventToolUseStart, ToolCall: &toolCall}},
+		{Type: agent.AgentEventTypeStream, SessionID: "s", Stream: &provider.ProviderEvent{
+			Type:     provider.EventComplete,
+			Response: &provider.ProviderResponse{ToolCalls: []message.ToolCall{toolCall}, FinishReason: message.FinishReasonToolUse},
+		}},
+		{Type: agent.AgentEventTypeUsage, SessionID: "s", Usage: provider.TokenUsage{InputTokens: 100, OutputTokens: 20}, Cost: 0.5},
+		{Type: agent.AgentEventTypeToolResults, SessionID: "s", Message: message.Message{Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call-1", Name: "bash", Content: "go.mod"},
+		}}},
+		{Type: agent.AgentEventTypeResponse, SessionID: "s", Done: true},
+	}
+
+	events := make(chan pubsub.Event[agent.AgentEvent], len(published))
+	for _, event := range published {
+		events <- pubsub.Event[agent.AgentEvent]{Type: pubsub.CreatedEvent, Payload: event}
+	}
+	done := make(chan agent.AgentEvent, 1)
+	done <- published[len(published)-1]
+
+	var out bytes.Buffer
+	result := streamEvents(&out, "s", events, done)
+	assert.True(t, result.Done)
+
+	var lines []format.StreamEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event format.StreamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		lines = append(lines, event)
+	}
+	assert.Equal(t, []format.StreamEvent{
+		{Type: "content_delta", SessionID: "s", Content: "Listing"},
+		{Type: "tool_use_start", SessionID: "s", ToolCall: &format.ToolCall{ID: "call-1", Name: "bash"}},
+		{Type: "complete", SessionID: "s", ToolCalls: []format.ToolCall{{ID: "call-1", Name: "bash", Input: `{"command":"ls"}`}}, FinishReason: "tool_use"},
+		{Type: "usage", SessionID: "s", Usage: &format.Usage{PromptTokens: 100, CompletionTokens: 20, Cost: 0.5}},
+		{Type: "tool_result", SessionID: "s", ToolCall: &format.ToolCall{ID: "call-1", Name: "bash", Output: "go.mod"}},
+	}, lines)
+}
@@ -0,0 +1,254 @@
+// Package backup exports sessions with their messages to a portable JSON
+// archive and imports them into another data directory.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+// Version is the format version of the archives written by Export.
+const Version = 1
+
+// Archive holds exported sessions. Child sessions, such as those of the task
+// agent, follow the session they belong to.
+type Archive struct {
+	Version    int       `json:"version"`
+	ExportedAt int64     `json:"exportedAt"`
+	Sessions   []Session `json:"sessions"`
+}
+
+// Session is an exported session with its messages.
+type Session struct {
+	ID                     string    `json:"id"`
+	ParentSessionID        string    `json:"parentSessionId,omitempty"`
+	ContinuedFromSessionID string    `json:"continuedFromSessionId,omitempty"`
+	Title                  string    `json:"title"`
+	PromptTokens           int64     `json:"promptTokens"`
+	CompletionTokens       int64     `json:"completionTokens"`
+	Cost                   float64   `json:"cost"`
+	SummaryMessageID       string    `json:"summaryMessageId,omitempty"`
+	CreatedAt              int64     `json:"createdAt"`
+	UpdatedAt              int64     `json:"updatedAt"`
+	Messages               []Message `json:"messages"`
+}
+
+// Message is an exported message. Parts are kept in the form they are stored
+// in the database.
+type Message struct {
+	ID        string          `json:"id"`
+	Role      string          `json:"role"`
+	Model     string          `json:"model,omitempty"`
+	Parts     json.RawMessage `json:"parts"`
+	CreatedAt int64           `json:"createdAt"`
+	UpdatedAt int64           `json:"updatedAt"`
+}
+
+// Export archives the sessions with the given IDs, or every session if none
+// are given, along with their child sessions and all of their messages.
+func Export(ctx context.Context, sessions session.Service, messages message.Service, sessionIDs ...string) (Archive, error) {
+	var roots []session.Session
+	if len(sessionIDs) == 0 {
+		all, err := sessions.List(ctx)
+		if err != nil {
+			return Archive{}, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		roots = all
+	}
+	for _, id := range sessionIDs {
+		s, err := sessions.Get(ctx, id)
+		if err != nil {
+			return Archive{}, fmt.Errorf("failed to get session %s: %w", id, err)
+		}
+		roots = append(roots, s)
+	}
+
+	archive := Archive{Version: Version, ExportedAt: time.Now().Unix()}
+	exported := make(map[string]bool)
+	var export func(s session.Session) error
+	export = func(s session.Session) error {
+		if exported[s.ID] {
+			return nil
+		}
+		exported[s.ID] = true
+
+		msgs, err := messages.List(ctx, s.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list messages of session %s: %w", s.ID, err)
+		}
+		archived, err := exportSession(s, msgs)
+		if err != nil {
+			return err
+		}
+		archive.Sessions = append(archive.Sessions, archived)
+
+		children, err := sessions.ListChildren(ctx, s.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list child sessions of %s: %w", s.ID, err)
+		}
+		for _, child := range children {
+			if err := export(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := export(root); err != nil {
+			return Archive{}, err
+		}
+	}
+	return archive, nil
+}
+
+func exportSession(s session.Session, msgs []message.Message) (Session, error) {
+	archived := Session{
+		ID:                     s.ID,
+		ParentSessionID:        s.ParentSessionID,
+		ContinuedFromSessionID: s.ContinuedFromSessionID,
+		Title:                  s.Title,
+		PromptTokens:           s.PromptTokens,
+		CompletionTokens:       s.CompletionTokens,
+		Cost:                   s.Cost,
+		SummaryMessageID:       s.SummaryMessageID,
+		CreatedAt:              s.CreatedAt,
+		UpdatedAt:              s.UpdatedAt,
+		Messages:               make([]Message, 0, len(msgs)),
+	}
+	for _, msg := range msgs {
+		parts, err := message.MarshalParts(msg.Parts)
+		if err != nil {
+			return Session{}, fmt.Errorf("failed to encode message %s: %w", msg.ID, err)
+		}
+		archived.Messages = append(archived.Messages, Message{
+			ID:        msg.ID,
+			Role:      string(msg.Role),
+			Model:     string(msg.Model),
+			Parts:     parts,
+			CreatedAt: msg.CreatedAt,
+			UpdatedAt: msg.UpdatedAt,
+		})
+	}
+	return archived, nil
+}
+
+// Import stores the sessions of an archive and returns them as imported.
+// Sessions and messages whose ID is already taken get a new ID, and references
+// between the imported sessions and messages are updated to match. Either the
+// whole archive is imported or, on error, the sessions stored so far are
+// deleted again along with their messages.
+func Import(ctx context.Context, sessions session.Service, messages message.Service, archive Archive) (imported []session.Session, err error) {
+	if archive.Version < 1 || archive.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+
+	sessionIDs := make(map[string]string)
+	messageIDs := make(map[string]string)
+	for _, s := range archive.Sessions {
+		sessionIDs[s.ID] = s.ID
+		if _, err := sessions.Get(ctx, s.ID); err == nil {
+			sessionIDs[s.ID] = uuid.New().String()
+		}
+		for _, msg := range s.Messages {
+			messageIDs[msg.ID] = msg.ID
+			if _, err := messages.Get(ctx, msg.ID); err == nil {
+				messageIDs[msg.ID] = uuid.New().String()
+			}
+		}
+	}
+	remap := func(ids map[string]string, id string) string {
+		if newID, ok := ids[id]; ok {
+			return newID
+		}
+		return id
+	}
+
+	var created []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Child sessions follow their parent, so they are deleted first
+		for i := len(created) - 1; i >= 0; i-- {
+			if deleteErr := sessions.Delete(context.Background(), created[i]); deleteErr != nil {
+				err = fmt.Errorf("%w; failed to delete the partly imported session %s: %v", err, created[i], deleteErr)
+			}
+		}
+		imported = nil
+	}()
+
+	imported = make([]session.Session, 0, len(archive.Sessions))
+	for _, s := range archive.Sessions {
+		stored, err := sessions.Import(ctx, session.Session{
+			ID:                     sessionIDs[s.ID],
+			ParentSessionID:        remap(sessionIDs, s.ParentSessionID),
+			ContinuedFromSessionID: remap(sessionIDs, s.ContinuedFromSessionID),
+			Title:                  s.Title,
+			PromptTokens:           s.PromptTokens,
+			CompletionTokens:       s.CompletionTokens,
+			Cost:                   s.Cost,
+			SummaryMessageID:       remap(messageIDs, s.SummaryMessageID),
+			CreatedAt:              s.CreatedAt,
+			UpdatedAt:              s.UpdatedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to import session %s: %w", s.ID, err)
+		}
+		created = append(created, stored.ID)
+
+		for _, msg := range s.Messages {
+			parts, err := message.UnmarshalParts(msg.Parts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode message %s: %w", msg.ID, err)
+			}
+			if _, err := messages.Import(ctx, message.Message{
+				ID:        messageIDs[msg.ID],
+				SessionID: stored.ID,
+				Role:      message.MessageRole(msg.Role),
+				Model:     models.ModelID(msg.Model),
+				Parts:     parts,
+				CreatedAt: msg.CreatedAt,
+				UpdatedAt: msg.UpdatedAt,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to import message %s: %w", msg.ID, err)
+			}
+		}
+
+		// Read the session back for the message count kept by the database
+		if stored, err = sessions.Get(ctx, stored.ID); err != nil {
+			return nil, fmt.Errorf("failed to get imported session %s: %w", stored.ID, err)
+		}
+		imported = append(imported, stored)
+	}
+	return imported, nil
+}
+
+// WriteFile writes an archive to path as indented JSON.
+func WriteFile(path string, archive Archive) error {
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// ReadFile reads an archive written by WriteFile.
+func ReadFile(path string) (Archive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Archive{}, err
+	}
+	var archive Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return Archive{}, fmt.Errorf("invalid session archive %s: %w", path, err)
+	}
+	return archive, nil
+}
@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/db"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := config.Load(os.TempDir(), false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// openDataDirectory opens a database in a new data directory.
+func openDataDirectory(t *testing.T) (session.Service, message.Service) {
+	t.Helper()
+	previous := config.Get().Data.Directory
+	config.Get().Data.Directory = t.TempDir()
+	t.Cleanup(func() { config.Get().Data.Directory = previous })
+
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	return session.NewService(q), message.NewService(q)
+}
+
+// createConversation creates a session with a task session and a few messages.
+func createConversation(t *testing.T, sessions session.Service, messages message.Service) session.Session {
+	t.Helper()
+	ctx := context.Background()
+
+	s, err := sessions.Create(ctx, "Refactor the parser")
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, s.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Split the parser into files"}},
+	})
+	require.NoError(t, err)
+	reply, err := messages.Create(ctx, s.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Model: "claude-3.7-sonnet",
+		Parts: []message.ContentPart{
+			message.ReasoningContent{Thinking: "Look at the package first"},
+			message.ToolCall{ID: "call-1", Name: "agent", Input: `{"prompt":"find the parser"}`, Type: "tool_use", Finished: true},
+		},
+	})
+	require.NoError(t, err)
+	reply.AddFinish(message.FinishReasonToolUse)
+	require.NoError(t, messages.Update(ctx, reply))
+
+	task, err := sessions.CreateTaskSession(ctx, "call-1", s.ID, "Find the parser")
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, task.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "find the parser"}},
+	})
+	require.NoError(t, err)
+
+	s.PromptTokens, s.CompletionTokens, s.Cost, s.SummaryMessageID = 1200, 300, 0.42, reply.ID
+	s, err = sessions.Save(ctx, s)
+	require.NoError(t, err)
+	return s
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	sessions, messages := openDataDirectory(t)
+	original := createConversation(t, sessions, messages)
+
+	archive, err := Export(ctx, sessions, messages, original.ID)
+	require.NoError(t, err)
+	require.Len(t, archive.Sessions, 2, "the task session is exported with its parent")
+
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	require.NoError(t, WriteFile(path, archive))
+	archive, err = ReadFile(path)
+	require.NoError(t, err)
+
+	otherSessions, otherMessages := openDataDirectory(t)
+	imported, err := Import(ctx, otherSessions, otherMessages, archive)
+	require.NoError(t, err)
+	require.Len(t, imported, 2)
+
+	got := imported[0]
+	assert.Equal(t, original.ID, got.ID)
+	assert.Equal(t, original.Title, got.Title)
+	assert.Equal(t, original.PromptTokens, got.PromptTokens)
+	assert.Equal(t, original.CompletionTokens, got.CompletionTokens)
+	assert.Equal(t, original.Cost, got.Cost)
+	assert.Equal(t, original.SummaryMessageID, got.SummaryMessageID)
+	assert.Equal(t, original.CreatedAt, got.CreatedAt)
+	assert.EqualValues(t, 2, got.MessageCount)
+	assert.Equal(t, original.ID, imported[1].ParentSessionID)
+
+	for _, s := range []session.Session{original, imported[1]} {
+		want, err := messages.List(ctx, s.ID)
+		require.NoError(t, err)
+		have, err := otherMessages.List(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, want, have)
+	}
+}
+
+func TestImportRenamesCollidingIDs(t *testing.T) {
+	ctx := context.Background()
+	sessions, messages := openDataDirectory(t)
+	original := createConversation(t, sessions, messages)
+
+	archive, err := Export(ctx, sessions, messages)
+	require.NoError(t, err)
+
+	// Importing into the same data directory collides with every ID
+	imported, err := Import(ctx, sessions, messages, archive)
+	require.NoError(t, err)
+	require.Len(t, imported, 2)
+
+	parent, task := imported[0], imported[1]
+	assert.NotEqual(t, original.ID, parent.ID)
+	assert.Equal(t, parent.ID, task.ParentSessionID)
+	assert.EqualValues(t, 2, parent.MessageCount)
+
+	copied, err := messages.List(ctx, parent.ID)
+	require.NoError(t, err)
+	require.Len(t, copied, 2)
+	assert.Equal(t, copied[1].ID, parent.SummaryMessageID, "the summary points at the copied message")
+	assert.Equal(t, "Split the parser into files", copied[0].Content().Text)
+
+	originals, err := messages.List(ctx, original.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, originals[1].ID, copied[1].ID)
+}
+
+func TestImportFailureLeavesNothingBehind(t *testing.T) {
+	ctx := context.Background()
+	sessions, messages := openDataDirectory(t)
+	createConversation(t, sessions, messages)
+
+	archive, err := Export(ctx, sessions, messages)
+	require.NoError(t, err)
+	require.Len(t, archive.Sessions, 2)
+	// The task session, imported after its parent, can't be decoded
+	archive.Sessions[1].Messages[0].Parts = []byte(`[{"type":"unknown"}]`)
+
+	otherSessions, otherMessages := openDataDirectory(t)
+	imported, err := Import(ctx, otherSessions, otherMessages, archive)
+	require.Error(t, err)
+	assert.Empty(t, imported)
+
+	left, err := otherSessions.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, left, "the parent session imported before the failure is deleted")
+	for _, s := range archive.Sessions {
+		msgs, err := otherMessages.List(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Empty(t, msgs)
+	}
+}
@@ -4,11 +4,18 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/zhenbah/cryoncode/internal/llm/models"
 	"github.com/zhenbah/cryoncode/internal/logging"
@@ -32,6 +39,9 @@ type MCPServer struct {
 	Type    MCPType           `json:"type"`
 	URL     string            `json:"url"`
 	Headers map[string]string `json:"headers"`
+
+	ToolPrefix           string `json:"toolPrefix,omitempty"`           // Prefix for tool names; defaults to the server name
+	MaxReconnectAttempts int    `json:"maxReconnectAttempts,omitempty"` // SSE only; 0 uses the default
 }
 
 type AgentName string
@@ -47,13 +57,112 @@ const (
 type Agent struct {
 	Model           models.ModelID `json:"model"`
 	MaxTokens       int64          `json:"maxTokens"`
-	ReasoningEffort string         `json:"reasoningEffort"` // For openai models low,medium,heigh
+	ReasoningEffort string         `json:"reasoningEffort"`      // For openai models low,medium,heigh
+	StepByStep      bool           `json:"stepByStep,omitempty"` // Ask non-reasoning models to think step by step
+	Prompt          string         `json:"prompt,omitempty"`     // Added to or replacing the built-in system prompt
+	PromptFile      string         `json:"promptFile,omitempty"` // File to read the prompt from, relative to the working directory
+	PromptMode      PromptMode     `json:"promptMode,omitempty"` // Whether the prompt is appended to the built-in one or replaces it
+
+	// promptFileText is the content of PromptFile, read when the config is
+	// loaded.
+	promptFileText string
+
+	// reasoningEffortDefaulted is set when ReasoningEffort was filled in from
+	// the tier defaults rather than configured, so that it follows the model.
+	reasoningEffortDefaulted bool
+}
+
+// PromptMode is how the prompt of an agent is combined with its built-in
+// system prompt.
+type PromptMode string
+
+const (
+	PromptModeAppend  PromptMode = "append"
+	PromptModeReplace PromptMode = "replace"
+)
+
+// SystemPrompt returns the system prompt of the agent, given its built-in
+// prompt. The configured prompt is appended to the built-in one, or replaces
+// it with promptMode "replace".
+func (a Agent) SystemPrompt(builtIn string) string {
+	prompt := a.Prompt
+	if a.PromptFile != "" {
+		prompt = a.promptFileText
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return builtIn
+	}
+	if a.PromptMode == PromptModeReplace {
+		return prompt
+	}
+	return builtIn + "\n\n" + prompt
+}
+
+// ModelTier groups models by size, to pick a default reasoning effort.
+type ModelTier string
+
+const (
+	ModelTierMini     ModelTier = "mini"
+	ModelTierStandard ModelTier = "standard"
+	ModelTierPro      ModelTier = "pro"
+)
+
+// defaultReasoningEffortByTier is used for tiers missing from the
+// reasoningEffortByTier setting.
+var defaultReasoningEffortByTier = map[ModelTier]string{
+	ModelTierMini:     "low",
+	ModelTierStandard: "medium",
+	ModelTierPro:      "high",
+}
+
+// TierOf infers the tier of a model from the words of its ID and API name,
+// such as "mini" in o4-mini or "pro" in gemini-2.5-pro.
+func TierOf(model models.Model) ModelTier {
+	name := strings.ToLower(string(model.ID) + " " + model.APIModel)
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '.' || r == '/' || r == ' '
+	})
+	for _, word := range words {
+		switch word {
+		case "mini", "nano", "haiku", "flash", "lite":
+			return ModelTierMini
+		}
+	}
+	for _, word := range words {
+		switch word {
+		case "pro", "opus", "o1", "o3":
+			return ModelTierPro
+		}
+	}
+	return ModelTierStandard
 }
 
 // Provider defines configuration for an LLM provider.
 type Provider struct {
-	APIKey   string `json:"apiKey"`
-	Disabled bool   `json:"disabled"`
+	APIKey               string `json:"apiKey"`
+	Disabled             bool   `json:"disabled"`
+	RequestsPerMinute    int    `json:"requestsPerMinute,omitempty"`    // Shared by all agents using the provider; 0 is unlimited
+	DisablePromptCaching bool   `json:"disablePromptCaching,omitempty"` // Don't mark prompts as cacheable, for providers that support it
+	Budget               Budget `json:"budget,omitempty"`               // Caps the requests and tokens per hour or day, to control spend
+	Grounding            bool   `json:"grounding,omitempty"`            // Ground answers in Google Search results and cite them (Gemini and VertexAI)
+}
+
+// BudgetWindow is the period over which a provider budget is counted. Windows
+// follow the clock: an hour starts on the hour and a day at local midnight.
+type BudgetWindow string
+
+const (
+	BudgetWindowHour BudgetWindow = "hour"
+	BudgetWindowDay  BudgetWindow = "day"
+)
+
+// Budget caps the use of a provider. Once either limit is reached, requests to
+// the provider fail until the window resets.
+type Budget struct {
+	Window   BudgetWindow `json:"window,omitempty"`   // Defaults to day
+	Requests int          `json:"requests,omitempty"` // 0 is unlimited
+	Tokens   int64        `json:"tokens,omitempty"`   // Input and output tokens, including cached ones; 0 is unlimited
 }
 
 // Data defines storage configuration.
@@ -63,46 +172,354 @@ type Data struct {
 
 // LSPConfig defines configuration for Language Server Protocol integration.
 type LSPConfig struct {
-	Disabled bool     `json:"enabled"`
-	Command  string   `json:"command"`
-	Args     []string `json:"args"`
-	Options  any      `json:"options"`
+	Disabled           bool     `json:"enabled"`
+	Command            string   `json:"command"`
+	Args               []string `json:"args"`
+	Options            any      `json:"options"`
+	InitTimeoutSeconds int      `json:"initTimeoutSeconds,omitempty"`
+	Env                []string `json:"env,omitempty"` // KEY=VALUE entries added to the server's environment; $VAR and ${VAR} in values are expanded
+}
+
+// ExpandedEnv returns the Env entries with $VAR and ${VAR} in their values
+// replaced by the variables of our environment, so that an entry such as
+// PATH=$HOME/go/bin:$PATH extends a variable. Entries without = are dropped.
+func (c LSPConfig) ExpandedEnv() []string {
+	env := make([]string, 0, len(c.Env))
+	for _, entry := range c.Env {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			logging.Warn("ignoring LSP env entry that is not KEY=VALUE", "command", c.Command, "entry", entry)
+			continue
+		}
+		env = append(env, key+"="+os.ExpandEnv(value))
+	}
+	return env
+}
+
+// LSPFanOutConfig controls how tools query several LSP clients at once.
+type LSPFanOutConfig struct {
+	MaxConcurrency       int `json:"maxConcurrency,omitempty"`
+	ClientTimeoutSeconds int `json:"clientTimeoutSeconds,omitempty"` // Per-client timeout before partial results are returned
+}
+
+// HeadlessConfig controls how permission requests are decided when no TUI is
+// attached to answer them, as in non-interactive mode.
+type HeadlessConfig struct {
+	PermissionDecision       PermissionPolicy `json:"permissionDecision,omitempty"`       // allow or deny
+	PermissionTimeoutSeconds int              `json:"permissionTimeoutSeconds,omitempty"` // Wait for an answer this long before deciding; 0 decides at once
 }
 
 // TUIConfig defines the configuration for the Terminal User Interface.
 type TUIConfig struct {
-	Theme string `json:"theme,omitempty"`
+	Theme             string       `json:"theme,omitempty"`
+	CustomThemePath   string       `json:"customThemePath,omitempty"`   // JSON or TOML file registered as the "custom" theme
+	Banner            string       `json:"banner,omitempty"`            // Text, or a file containing it, shown on the startup screen
+	PromptHistorySize int          `json:"promptHistorySize,omitempty"` // Prompts kept for recall with up/down; 0 disables the history
+	MaxContentWidth   int          `json:"maxContentWidth,omitempty"`   // Maximum width of rendered messages; 0 uses the full width
+	ContentAlign      ContentAlign `json:"contentAlign,omitempty"`      // Placement of messages narrower than the chat
+	Background        Background   `json:"background,omitempty"`        // Terminal background colors are picked for; auto asks the terminal
+	DiffContextLines  int          `json:"diffContextLines,omitempty"`  // Unchanged lines shown around each change of a diff
+	DiffView          DiffView     `json:"diffView,omitempty"`          // Layout of diffs; auto picks unified on narrow terminals
+}
+
+// ContentAlign defines where messages are placed when they are narrower than
+// the chat.
+type ContentAlign string
+
+// Supported content alignments
+const (
+	ContentAlignCenter ContentAlign = "center"
+	ContentAlignLeft   ContentAlign = "left"
+)
+
+// Background defines which terminal background colors are chosen for.
+type Background string
+
+// Supported backgrounds
+const (
+	BackgroundAuto  Background = "auto"
+	BackgroundDark  Background = "dark"
+	BackgroundLight Background = "light"
+)
+
+// DiffView defines how diffs are laid out.
+type DiffView string
+
+// Supported diff views
+const (
+	DiffViewAuto    DiffView = "auto"
+	DiffViewSplit   DiffView = "split"
+	DiffViewUnified DiffView = "unified"
+)
+
+// TitleTrigger defines when a session title is generated.
+type TitleTrigger string
+
+// Supported title triggers
+const (
+	TitleTriggerFirstMessage  TitleTrigger = "firstMessage"
+	TitleTriggerAfterMessages TitleTrigger = "afterMessages"
+	TitleTriggerNever         TitleTrigger = "never"
+)
+
+// TitleConfig defines when the title agent is asked to name a session.
+type TitleConfig struct {
+	Trigger       TitleTrigger `json:"trigger,omitempty"`
+	AfterMessages int          `json:"afterMessages,omitempty"` // Number of user messages before generating when trigger is afterMessages
+}
+
+// EditDiagnosticsMode defines which diagnostics are attached to file edit results.
+type EditDiagnosticsMode string
+
+// Supported edit diagnostics modes
+const (
+	EditDiagnosticsAll EditDiagnosticsMode = "all" // File and project diagnostics after the edit
+	EditDiagnosticsNew EditDiagnosticsMode = "new" // Only errors and warnings the edit introduced in the file
+	EditDiagnosticsOff EditDiagnosticsMode = "off"
+)
+
+// ContextStrategy defines how a conversation is kept within the model's
+// context window.
+type ContextStrategy string
+
+// Supported context strategies
+const (
+	ContextStrategySummarize ContextStrategy = "summarize" // Compact the session with a summary when auto compact is on
+	ContextStrategyTruncate  ContextStrategy = "truncate"  // Send only the most recent messages that fit in contextFraction of the window
+)
+
+// LogFormat defines how log records are written.
+type LogFormat string
+
+// Supported log formats
+const (
+	LogFormatText LogFormat = "text" // logfmt key=value pairs
+	LogFormatJSON LogFormat = "json" // One JSON object per record, for log collectors
+)
+
+// LogConfig defines how the application logs.
+type LogConfig struct {
+	Level  string    `json:"level,omitempty"` // debug, info, warn or error; debug mode always logs at debug
+	Format LogFormat `json:"format,omitempty"`
+}
+
+// RiskLevel ranks how much harm a tool call could do.
+type RiskLevel string
+
+// Supported risk levels, lowest first
+const (
+	RiskLow    RiskLevel = "low"    // Reads, such as fetching a URL
+	RiskMedium RiskLevel = "medium" // File writes
+	RiskHigh   RiskLevel = "high"   // Shell commands, deletions and MCP tools
+)
+
+// Rank orders risk levels from 1 for low to 3 for high; unknown levels rank 0.
+func (r RiskLevel) Rank() int {
+	switch r {
+	case RiskLow:
+		return 1
+	case RiskMedium:
+		return 2
+	case RiskHigh:
+		return 3
+	}
+	return 0
+}
+
+// PermissionPolicy decides what happens when a tool asks for permission.
+type PermissionPolicy string
+
+// Supported permission policies
+const (
+	PermissionAsk   PermissionPolicy = "ask"   // Show the permission prompt
+	PermissionAllow PermissionPolicy = "allow" // Run without asking
+	PermissionDeny  PermissionPolicy = "deny"  // Refuse without asking
+)
+
+// KeybindingActions are the TUI actions whose key can be changed in the
+// keybindings section.
+var KeybindingActions = []string{"logs", "quit", "switchSession", "commands", "filepicker", "models", "switchTheme"}
+
+// namedKeys are the key names, besides single characters and f1-f20, that can
+// follow the ctrl+, alt+ and shift+ modifiers in a keybinding.
+var namedKeys = map[string]bool{
+	"enter": true, "tab": true, "esc": true, "space": true, "backspace": true,
+	"delete": true, "insert": true, "up": true, "down": true, "left": true,
+	"right": true, "home": true, "end": true, "pgup": true, "pgdown": true,
+}
+
+// SummaryConfig defines how sessions are summarized.
+type SummaryConfig struct {
+	PreserveCodeBlocks bool `json:"preserveCodeBlocks,omitempty"` // Keep fenced code blocks verbatim in summaries
+}
+
+// DebugLogRetention limits the request and response logs written in debug
+// mode. A limit of 0 is not applied.
+type DebugLogRetention struct {
+	MaxFiles   int `json:"maxFiles,omitempty"`   // Only the newest log files are kept
+	MaxAgeDays int `json:"maxAgeDays,omitempty"` // Log files older than this are removed
+	MaxBytes   int `json:"maxBytes,omitempty"`   // Total size of the kept log files
+}
+
+// ToolsConfig defines how tools are presented to the model and run.
+type ToolsConfig struct {
+	Order []string                   `json:"order,omitempty"` // Tool names listed first, in this order; unlisted tools follow
+	Retry map[string]ToolRetryConfig `json:"retry,omitempty"` // Tool names to their retry policy; unlisted tools are not retried
+	Fetch FetchConfig                `json:"fetch,omitempty"`
+	Cache ToolCacheConfig            `json:"cache,omitempty"`
+}
+
+// ToolRetryConfig defines how a failed tool call is retried.
+type ToolRetryConfig struct {
+	Attempts  int `json:"attempts,omitempty"`  // Retries after the first failure
+	BackoffMs int `json:"backoffMs,omitempty"` // Delay before the first retry, doubled for each later one
+}
+
+// FetchConfig defines which URLs the fetch and fetch_url tools may request.
+// Domains match themselves and their subdomains.
+type FetchConfig struct {
+	AllowedDomains []string `json:"allowedDomains,omitempty"` // Only these domains may be fetched; empty allows all that are not blocked
+	BlockedDomains []string `json:"blockedDomains,omitempty"` // Domains that are never fetched, even when allowed
+	MaxBodyBytes   int      `json:"maxBodyBytes,omitempty"`   // Responses are read up to this size
+}
+
+// ToolCacheConfig defines how the results of the fetch and search tools are
+// reused within a session.
+type ToolCacheConfig struct {
+	Enabled    bool `json:"enabled,omitempty"`    // Answer repeated identical fetches and searches from the cache
+	TTLSeconds int  `json:"ttlSeconds,omitempty"` // How long a result is reused
+	Persist    bool `json:"persist,omitempty"`    // Also keep results on disk, so they survive a restart
 }
 
 // ShellConfig defines the configuration for the shell used by the bash tool.
 type ShellConfig struct {
-	Path string   `json:"path,omitempty"`
-	Args []string `json:"args,omitempty"`
+	Path               string   `json:"path,omitempty"`
+	Args               []string `json:"args,omitempty"`
+	AllowedDirectories []string `json:"allowedDirectories,omitempty"` // Restrict commands to these directories; relative entries resolve against the working directory
+	TimeoutSeconds     int      `json:"timeoutSeconds,omitempty"`     // Default command timeout; the model may ask for up to ten minutes
+	MaxOutputBytes     int      `json:"maxOutputBytes,omitempty"`     // Output beyond this is cut from the middle of stdout and stderr
+	AllowedCommands    []string `json:"allowedCommands,omitempty"`    // Commands run without asking for permission; "/pattern/" entries are regular expressions
+	BlockedCommands    []string `json:"blockedCommands,omitempty"`    // Commands that are always refused; "/pattern/" entries are regular expressions
+	Env                []string `json:"env,omitempty"`                // KEY=VALUE entries set for each command; $VAR and ${VAR} in values are expanded
+}
+
+// ValidEnvName reports whether name can be used as a shell variable name.
+func ValidEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpandEnv returns the KEY=VALUE entries of env with $VAR and ${VAR} in
+// their values replaced by the variables of our environment, so that an entry
+// such as PATH=$HOME/bin:$PATH extends a variable. Entries without = or with
+// an invalid variable name are dropped.
+func ExpandEnv(env []string) []string {
+	expanded := make([]string, 0, len(env))
+	for _, entry := range env {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !ValidEnvName(key) {
+			continue
+		}
+		expanded = append(expanded, key+"="+os.ExpandEnv(value))
+	}
+	return expanded
+}
+
+// CommandPattern returns the regular expression of a shell.allowedCommands or
+// shell.blockedCommands entry written as /pattern/.
+func CommandPattern(entry string) (string, bool) {
+	if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+		return entry[1 : len(entry)-1], true
+	}
+	return "", false
 }
 
 // Config is the main configuration structure for the application.
 type Config struct {
-	Data         Data                              `json:"data"`
-	WorkingDir   string                            `json:"wd,omitempty"`
-	MCPServers   map[string]MCPServer              `json:"mcpServers,omitempty"`
-	Providers    map[models.ModelProvider]Provider `json:"providers,omitempty"`
-	LSP          map[string]LSPConfig              `json:"lsp,omitempty"`
-	Agents       map[AgentName]Agent               `json:"agents,omitempty"`
-	Debug        bool                              `json:"debug,omitempty"`
-	DebugLSP     bool                              `json:"debugLSP,omitempty"`
-	ContextPaths []string                          `json:"contextPaths,omitempty"`
-	TUI          TUIConfig                         `json:"tui"`
-	Shell        ShellConfig                       `json:"shell,omitempty"`
-	AutoCompact  bool                              `json:"autoCompact,omitempty"`
+	Data                   Data                              `json:"data"`
+	WorkingDir             string                            `json:"wd,omitempty"`
+	MCPServers             map[string]MCPServer              `json:"mcpServers,omitempty"`
+	Providers              map[models.ModelProvider]Provider `json:"providers,omitempty"`
+	LSP                    map[string]LSPConfig              `json:"lsp,omitempty"`
+	LSPFanOut              LSPFanOutConfig                   `json:"lspFanOut,omitempty"`
+	Headless               HeadlessConfig                    `json:"headless,omitempty"`
+	MaxConcurrentLSPStarts int                               `json:"maxConcurrentLspStarts,omitempty"`
+	Agents                 map[AgentName]Agent               `json:"agents,omitempty"`
+	Debug                  bool                              `json:"debug,omitempty"`
+	DebugLSP               bool                              `json:"debugLSP,omitempty"`
+	ContextPaths           []string                          `json:"contextPaths,omitempty"`
+	AppendContextPaths     []string                          `json:"appendContextPaths,omitempty"`  // Added to contextPaths instead of replacing the defaults
+	MaxContextFileBytes    int                               `json:"maxContextFileBytes,omitempty"` // Context files are cut to this size in the system prompt; 0 is unlimited
+	TUI                    TUIConfig                         `json:"tui"`
+	Shell                  ShellConfig                       `json:"shell,omitempty"`
+	AutoCompact            bool                              `json:"autoCompact,omitempty"`
+	ContextStrategy        ContextStrategy                   `json:"contextStrategy,omitempty"`
+	ContextFraction        float64                           `json:"contextFraction,omitempty"` // Share of the context window the truncate strategy fills with history
+	Title                  TitleConfig                       `json:"title,omitempty"`
+	MaxToolArgumentBytes   int                               `json:"maxToolArgumentBytes,omitempty"`
+	Summary                SummaryConfig                     `json:"summary,omitempty"`
+	Tools                  ToolsConfig                       `json:"tools,omitempty"`
+	HardDelete             bool                              `json:"hardDelete,omitempty"`
+	EditDiagnostics        EditDiagnosticsMode               `json:"editDiagnostics,omitempty"`
+	Keybindings            map[string]string                 `json:"keybindings,omitempty"`           // TUI action name to key, e.g. "logs": "ctrl+g"
+	ProbeModels            bool                              `json:"probeModels,omitempty"`           // Check at startup that each agent's model is available
+	SandboxRoot            string                            `json:"sandboxRoot,omitempty"`           // File edits and bash commands are confined to this directory; defaults to the working directory, "/" lifts it
+	AutoApproveBelow       RiskLevel                         `json:"autoApproveBelow,omitempty"`      // Tool calls of a lower risk run without asking for permission; empty asks for all
+	ToolPermissions        map[string]PermissionPolicy       `json:"toolPermissions,omitempty"`       // Tool name or category (read, write, execute) to ask, allow or deny
+	InlineFileMaxBytes     int                               `json:"inlineFileMaxBytes,omitempty"`    // Files under the working directory referenced as @path up to this size are attached to the prompt; 0, the default, disables
+	PromptTemplates        map[string]string                 `json:"promptTemplates,omitempty"`       // Template name to prompt text with {{variable}} placeholders
+	ResumeLastSession      bool                              `json:"resumeLastSession,omitempty"`     // Open the most recently updated session on startup
+	DryRun                 bool                              `json:"dryRun,omitempty"`                // File tools return the diff of a change without writing it, and bash only runs read-only commands
+	WarnLocalOverrides     bool                              `json:"warnLocalOverrides,omitempty"`    // Log the global settings that the local config file changes
+	ReasoningEffortByTier  map[ModelTier]string              `json:"reasoningEffortByTier,omitempty"` // Reasoning effort for agents that don't set one, by the tier of their model
+	DebugLogRetention      DebugLogRetention                 `json:"debugLogRetention,omitempty"`
+	Log                    LogConfig                         `json:"log,omitempty"`
 }
 
 // Application constants
 const (
 	defaultDataDirectory = ".cryoncode"
 	defaultLogLevel      = "info"
+	defaultTheme         = "cryoncode"
 	appName              = "cryoncode"
 
 	MaxTokensFallbackDefault = 4096
+
+	DefaultLSPInitTimeoutSeconds = 30
+
+	DefaultMCPMaxReconnectAttempts = 5
+
+	DefaultMaxToolArgumentBytes = 2 * 1024 * 1024
+
+	DefaultMaxContextFileBytes = 64 * 1024
+
+	DefaultToolRetryBackoffMs = 500
+
+	DefaultFetchMaxBodyBytes = 5 * 1024 * 1024
+
+	DefaultToolCacheTTLSeconds = 15 * 60
+
+	DefaultDebugLogMaxFiles   = 1000
+	DefaultDebugLogMaxAgeDays = 7
+	DefaultDebugLogMaxBytes   = 100 * 1024 * 1024
+
+	DefaultContextFraction = 0.75
+
+	DefaultLSPFanOutMaxConcurrency       = 4
+	DefaultLSPFanOutClientTimeoutSeconds = 5
+
+	DefaultPromptHistorySize = 100
+	DefaultDiffContextLines  = 3
+
+	DefaultShellTimeoutSeconds = 120
+	DefaultShellMaxOutputBytes = 256 * 1024
 )
 
 var defaultContextPaths = []string{
@@ -156,7 +573,7 @@ func Load(workingDir string, debug bool) (*Config, error) {
 	}
 
 	applyDefaultValues()
-	defaultLevel := slog.LevelInfo
+	defaultLevel, _ := parseLogLevel(cfg.Log.Level)
 	if cfg.Debug {
 		defaultLevel = slog.LevelDebug
 	}
@@ -186,13 +603,13 @@ func Load(workingDir string, debug bool) (*Config, error) {
 			return cfg, fmt.Errorf("failed to open log file: %w", err)
 		}
 		// Configure logger
-		logger := slog.New(slog.NewTextHandler(sloggingFileWriter, &slog.HandlerOptions{
+		logger := slog.New(newLogHandler(sloggingFileWriter, cfg.Log.Format, &slog.HandlerOptions{
 			Level: defaultLevel,
 		}))
 		slog.SetDefault(logger)
 	} else {
 		// Configure logger
-		logger := slog.New(slog.NewTextHandler(logging.NewWriter(), &slog.HandlerOptions{
+		logger := slog.New(newLogHandler(logging.NewWriter(), cfg.Log.Format, &slog.HandlerOptions{
 			Level: defaultLevel,
 		}))
 		slog.SetDefault(logger)
@@ -203,18 +620,60 @@ func Load(workingDir string, debug bool) (*Config, error) {
 		return cfg, fmt.Errorf("config validation failed: %w", err)
 	}
 
+	// Remove the oldest debug logs before this run adds more
+	if logging.MessageDir != "" {
+		logging.MessageLogRetention = logging.MessageRetention{
+			MaxFiles: cfg.DebugLogRetention.MaxFiles,
+			MaxAge:   time.Duration(cfg.DebugLogRetention.MaxAgeDays) * 24 * time.Hour,
+			MaxBytes: int64(cfg.DebugLogRetention.MaxBytes),
+		}
+		logging.PruneMessageLogs()
+	}
+
 	if cfg.Agents == nil {
 		cfg.Agents = make(map[AgentName]Agent)
 	}
 
 	// Override the max tokens for title agent
+	titleAgent := cfg.Agents[AgentTitle]
 	cfg.Agents[AgentTitle] = Agent{
-		Model:     cfg.Agents[AgentTitle].Model,
-		MaxTokens: 80,
+		Model:          titleAgent.Model,
+		MaxTokens:      80,
+		Prompt:         titleAgent.Prompt,
+		PromptFile:     titleAgent.PromptFile,
+		PromptMode:     titleAgent.PromptMode,
+		promptFileText: titleAgent.promptFileText,
 	}
 	return cfg, nil
 }
 
+// newLogHandler returns the slog handler for the log format. Formats other
+// than json are written as text; Validate warns about them once the logger
+// is set up.
+func newLogHandler(w io.Writer, format LogFormat, opts *slog.HandlerOptions) slog.Handler {
+	if format == LogFormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// parseLogLevel maps a configured log level to its slog level. Unknown levels
+// map to info and report false.
+func parseLogLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info", "":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
 // configureViper sets up viper's configuration paths and environment variables.
 func configureViper() {
 	viper.SetConfigName(fmt.Sprintf(".%s", appName))
@@ -230,8 +689,32 @@ func configureViper() {
 func setDefaults(debug bool) {
 	viper.SetDefault("data.directory", defaultDataDirectory)
 	viper.SetDefault("contextPaths", defaultContextPaths)
-	viper.SetDefault("tui.theme", "cryoncode")
+	viper.SetDefault("tui.theme", defaultTheme)
+	viper.SetDefault("tui.promptHistorySize", DefaultPromptHistorySize)
+	viper.SetDefault("tui.diffContextLines", DefaultDiffContextLines)
+	viper.SetDefault("tui.contentAlign", string(ContentAlignCenter))
+	viper.SetDefault("tui.background", string(BackgroundAuto))
+	viper.SetDefault("tui.diffView", string(DiffViewAuto))
 	viper.SetDefault("autoCompact", true)
+	viper.SetDefault("contextStrategy", string(ContextStrategySummarize))
+	viper.SetDefault("contextFraction", DefaultContextFraction)
+	viper.SetDefault("title.trigger", string(TitleTriggerFirstMessage))
+	viper.SetDefault("title.afterMessages", 1)
+	viper.SetDefault("editDiagnostics", string(EditDiagnosticsAll))
+	viper.SetDefault("maxToolArgumentBytes", DefaultMaxToolArgumentBytes)
+	viper.SetDefault("maxContextFileBytes", DefaultMaxContextFileBytes)
+	viper.SetDefault("tools.fetch.maxBodyBytes", DefaultFetchMaxBodyBytes)
+	viper.SetDefault("tools.cache.enabled", true)
+	viper.SetDefault("tools.cache.ttlSeconds", DefaultToolCacheTTLSeconds)
+	viper.SetDefault("debugLogRetention.maxFiles", DefaultDebugLogMaxFiles)
+	viper.SetDefault("debugLogRetention.maxAgeDays", DefaultDebugLogMaxAgeDays)
+	viper.SetDefault("debugLogRetention.maxBytes", DefaultDebugLogMaxBytes)
+	viper.SetDefault("lspFanOut.maxConcurrency", DefaultLSPFanOutMaxConcurrency)
+	viper.SetDefault("lspFanOut.clientTimeoutSeconds", DefaultLSPFanOutClientTimeoutSeconds)
+	viper.SetDefault("headless.permissionDecision", string(PermissionAllow))
+	for tier, effort := range defaultReasoningEffortByTier {
+		viper.SetDefault("reasoningEffortByTier."+string(tier), effort)
+	}
 
 	// Set default shell from environment or fallback to /bin/bash
 	shellPath := os.Getenv("SHELL")
@@ -240,6 +723,8 @@ func setDefaults(debug bool) {
 	}
 	viper.SetDefault("shell.path", shellPath)
 	viper.SetDefault("shell.args", []string{"-l"})
+	viper.SetDefault("shell.timeoutSeconds", DefaultShellTimeoutSeconds)
+	viper.SetDefault("shell.maxOutputBytes", DefaultShellMaxOutputBytes)
 
 	if debug {
 		viper.SetDefault("debug", true)
@@ -248,6 +733,7 @@ func setDefaults(debug bool) {
 		viper.SetDefault("debug", false)
 		viper.SetDefault("log.level", defaultLogLevel)
 	}
+	viper.SetDefault("log.format", string(LogFormatText))
 }
 
 // setProviderDefaults configures LLM provider defaults based on provider provided by
@@ -456,10 +942,56 @@ func mergeLocalConfig(workingDir string) {
 
 	// Merge local config if it exists
 	if err := local.ReadInConfig(); err == nil {
-		viper.MergeConfigMap(local.AllSettings())
+		localSettings := local.AllSettings()
+		if local.GetBool("warnLocalOverrides") || viper.GetBool("warnLocalOverrides") {
+			warnLocalOverrides(local.ConfigFileUsed(), localSettings)
+		}
+		viper.MergeConfigMap(localSettings)
 	}
 }
 
+// warnLocalOverrides logs the settings of the global config file that the
+// local config file replaces with a different value.
+func warnLocalOverrides(localFile string, localSettings map[string]any) {
+	globalFile := viper.ConfigFileUsed()
+	if globalFile == "" {
+		return
+	}
+	global := viper.New()
+	global.SetConfigFile(globalFile)
+	if err := global.ReadInConfig(); err != nil {
+		return
+	}
+	if keys := overriddenKeys(global.AllSettings(), localSettings, ""); len(keys) > 0 {
+		logging.Warn("local config overrides global settings", "global", globalFile, "local", localFile, "keys", strings.Join(keys, ", "))
+	}
+}
+
+// overriddenKeys returns the sorted dotted paths of the settings that are set
+// in both global and local to different values. Nested objects are compared
+// key by key, so a local object only overrides the keys it sets.
+func overriddenKeys(global, local map[string]any, prefix string) []string {
+	var keys []string
+	for key, localValue := range local {
+		globalValue, ok := global[key]
+		if !ok {
+			continue
+		}
+		path := prefix + key
+		globalMap, globalIsMap := globalValue.(map[string]any)
+		localMap, localIsMap := localValue.(map[string]any)
+		if globalIsMap && localIsMap {
+			keys = append(keys, overriddenKeys(globalMap, localMap, path+".")...)
+			continue
+		}
+		if !reflect.DeepEqual(globalValue, localValue) {
+			keys = append(keys, path)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // applyDefaultValues sets default values for configuration fields that need processing.
 func applyDefaultValues() {
 	// Set default MCP type if not specified
@@ -471,6 +1003,16 @@ func applyDefaultValues() {
 	}
 }
 
+// tierReasoningEffort returns the configured reasoning effort for the tier of
+// a model.
+func tierReasoningEffort(model models.Model) string {
+	tier := TierOf(model)
+	if effort, ok := cfg.ReasoningEffortByTier[tier]; ok {
+		return effort
+	}
+	return defaultReasoningEffortByTier[tier]
+}
+
 // It validates model IDs and providers, ensuring they are supported.
 func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	// Check if model exists
@@ -570,9 +1112,10 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 				"agent", name,
 				"model", agent.Model)
 
-			// Update the agent with default reasoning effort
+			// Update the agent with the default reasoning effort of the model's tier
 			updatedAgent := cfg.Agents[name]
-			updatedAgent.ReasoningEffort = "medium"
+			updatedAgent.ReasoningEffort = tierReasoningEffort(model)
+			updatedAgent.reasoningEffortDefaulted = true
 			cfg.Agents[name] = updatedAgent
 		} else {
 			// Check if reasoning effort is valid (low, medium, high)
@@ -605,17 +1148,81 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	return nil
 }
 
+// loadAgentPrompt validates the prompt settings of an agent and reads its
+// prompt file. The settings are taken from the configured agent, so they are
+// kept when validateAgent falls back to a default model.
+func loadAgentPrompt(cfg *Config, name AgentName, agent Agent) error {
+	updatedAgent := cfg.Agents[name]
+	updatedAgent.Prompt = agent.Prompt
+	updatedAgent.PromptFile = agent.PromptFile
+	updatedAgent.PromptMode = agent.PromptMode
+
+	switch updatedAgent.PromptMode {
+	case PromptModeAppend, PromptModeReplace:
+	case "":
+		updatedAgent.PromptMode = PromptModeAppend
+	default:
+		logging.Warn("unknown agent promptMode, using append", "agent", name, "promptMode", agent.PromptMode)
+		updatedAgent.PromptMode = PromptModeAppend
+	}
+
+	if agent.PromptFile != "" {
+		if agent.Prompt != "" {
+			logging.Warn("agent has both prompt and promptFile, using promptFile", "agent", name)
+		}
+		path := agent.PromptFile
+		if strings.HasPrefix(path, "~/") {
+			if homeDir, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(homeDir, path[2:])
+			}
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfg.WorkingDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read promptFile of agent %s: %w", name, err)
+		}
+		updatedAgent.promptFileText = string(data)
+	}
+
+	cfg.Agents[name] = updatedAgent
+	return nil
+}
+
 // Validate checks if the configuration is valid and applies defaults where needed.
 func Validate() error {
 	if cfg == nil {
 		return fmt.Errorf("config not loaded")
 	}
 
+	// Validate the tier reasoning efforts before the agents default to them
+	for tier, effort := range cfg.ReasoningEffortByTier {
+		if _, ok := defaultReasoningEffortByTier[tier]; !ok {
+			logging.Warn("unknown model tier in reasoningEffortByTier, ignoring", "tier", tier)
+			delete(cfg.ReasoningEffortByTier, tier)
+			continue
+		}
+		switch strings.ToLower(effort) {
+		case "low", "medium", "high":
+			cfg.ReasoningEffortByTier[tier] = strings.ToLower(effort)
+		default:
+			logging.Warn("invalid reasoning effort in reasoningEffortByTier, using the default",
+				"tier", tier,
+				"reasoning_effort", effort,
+				"default", defaultReasoningEffortByTier[tier])
+			cfg.ReasoningEffortByTier[tier] = defaultReasoningEffortByTier[tier]
+		}
+	}
+
 	// Validate agent models
 	for name, agent := range cfg.Agents {
 		if err := validateAgent(cfg, name, agent); err != nil {
 			return err
 		}
+		if err := loadAgentPrompt(cfg, name, agent); err != nil {
+			return err
+		}
 	}
 
 	// Validate providers
@@ -626,6 +1233,291 @@ func Validate() error {
 			providerCfg.Disabled = true
 			cfg.Providers[provider] = providerCfg
 		}
+		if providerCfg.RequestsPerMinute < 0 {
+			logging.Warn("invalid provider requestsPerMinute, removing the limit", "provider", provider, "requestsPerMinute", providerCfg.RequestsPerMinute)
+			providerCfg.RequestsPerMinute = 0
+			cfg.Providers[provider] = providerCfg
+		}
+		if providerCfg.Budget.Requests < 0 || providerCfg.Budget.Tokens < 0 {
+			logging.Warn("invalid provider budget, removing the negative limits", "provider", provider, "requests", providerCfg.Budget.Requests, "tokens", providerCfg.Budget.Tokens)
+			providerCfg.Budget.Requests = max(providerCfg.Budget.Requests, 0)
+			providerCfg.Budget.Tokens = max(providerCfg.Budget.Tokens, 0)
+			cfg.Providers[provider] = providerCfg
+		}
+		switch providerCfg.Budget.Window {
+		case BudgetWindowHour, BudgetWindowDay:
+		case "":
+			providerCfg.Budget.Window = BudgetWindowDay
+			cfg.Providers[provider] = providerCfg
+		default:
+			logging.Warn("invalid provider budget window, using day", "provider", provider, "window", providerCfg.Budget.Window)
+			providerCfg.Budget.Window = BudgetWindowDay
+			cfg.Providers[provider] = providerCfg
+		}
+	}
+
+	// Validate title generation trigger
+	switch cfg.Title.Trigger {
+	case TitleTriggerFirstMessage, TitleTriggerNever:
+	case TitleTriggerAfterMessages:
+		if cfg.Title.AfterMessages <= 0 {
+			logging.Warn("invalid title afterMessages, setting to 1", "afterMessages", cfg.Title.AfterMessages)
+			cfg.Title.AfterMessages = 1
+		}
+	default:
+		if cfg.Title.Trigger != "" {
+			logging.Warn("unknown title trigger, using firstMessage", "trigger", cfg.Title.Trigger)
+		}
+		cfg.Title.Trigger = TitleTriggerFirstMessage
+	}
+
+	// Validate the log level
+	if _, ok := parseLogLevel(cfg.Log.Level); !ok {
+		logging.Warn("unknown log level, using info", "level", cfg.Log.Level)
+		cfg.Log.Level = defaultLogLevel
+	}
+
+	// Validate the log format
+	switch cfg.Log.Format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		if cfg.Log.Format != "" {
+			logging.Warn("unknown log format, using text", "format", cfg.Log.Format)
+		}
+		cfg.Log.Format = LogFormatText
+	}
+
+	// Validate the context strategy
+	switch cfg.ContextStrategy {
+	case ContextStrategySummarize, ContextStrategyTruncate:
+	default:
+		if cfg.ContextStrategy != "" {
+			logging.Warn("unknown contextStrategy, using summarize", "contextStrategy", cfg.ContextStrategy)
+		}
+		cfg.ContextStrategy = ContextStrategySummarize
+	}
+	if cfg.ContextFraction <= 0 || cfg.ContextFraction > 1 {
+		logging.Warn("invalid contextFraction, using default", "contextFraction", cfg.ContextFraction, "default", DefaultContextFraction)
+		cfg.ContextFraction = DefaultContextFraction
+	}
+
+	// Validate the edit diagnostics mode
+	switch cfg.EditDiagnostics {
+	case EditDiagnosticsAll, EditDiagnosticsNew, EditDiagnosticsOff:
+	default:
+		if cfg.EditDiagnostics != "" {
+			logging.Warn("unknown editDiagnostics mode, using all", "editDiagnostics", cfg.EditDiagnostics)
+		}
+		cfg.EditDiagnostics = EditDiagnosticsAll
+	}
+
+	// Validate the auto-approval threshold
+	if cfg.AutoApproveBelow != "" && cfg.AutoApproveBelow.Rank() == 0 {
+		logging.Warn("unknown autoApproveBelow risk level, asking for every tool call", "autoApproveBelow", cfg.AutoApproveBelow)
+		cfg.AutoApproveBelow = ""
+	}
+
+	// Validate the tool permission policies
+	for name, policy := range cfg.ToolPermissions {
+		switch policy {
+		case PermissionAsk, PermissionAllow, PermissionDeny:
+		default:
+			logging.Warn("unknown toolPermissions policy, asking for permission", "tool", name, "policy", policy)
+			cfg.ToolPermissions[name] = PermissionAsk
+		}
+	}
+
+	// Validate keybindings; viper lowercases map keys, so actions are matched
+	// case-insensitively
+	if len(cfg.Keybindings) > 0 {
+		keybindings := make(map[string]string, len(cfg.Keybindings))
+		for name, k := range cfg.Keybindings {
+			action := ""
+			for _, candidate := range KeybindingActions {
+				if strings.EqualFold(name, candidate) {
+					action = candidate
+					break
+				}
+			}
+			switch {
+			case action == "":
+				logging.Warn("unknown keybinding action, ignoring", "action", name, "actions", strings.Join(KeybindingActions, ", "))
+			case !validKey(k):
+				logging.Warn("invalid key for keybinding, keeping the default", "action", action, "key", k)
+			default:
+				keybindings[action] = k
+			}
+		}
+		cfg.Keybindings = keybindings
+	}
+
+	// Validate the prompt history size; 0 disables the history
+	if cfg.TUI.PromptHistorySize < 0 {
+		logging.Warn("invalid tui promptHistorySize, using default", "promptHistorySize", cfg.TUI.PromptHistorySize, "default", DefaultPromptHistorySize)
+		cfg.TUI.PromptHistorySize = DefaultPromptHistorySize
+	}
+
+	// Validate the diff context; 0 shows only the changed lines
+	if cfg.TUI.DiffContextLines < 0 {
+		logging.Warn("invalid tui diffContextLines, using default", "diffContextLines", cfg.TUI.DiffContextLines, "default", DefaultDiffContextLines)
+		cfg.TUI.DiffContextLines = DefaultDiffContextLines
+	}
+
+	// Validate the message width cap and placement
+	if cfg.TUI.MaxContentWidth < 0 {
+		logging.Warn("invalid tui maxContentWidth, using the full width", "maxContentWidth", cfg.TUI.MaxContentWidth)
+		cfg.TUI.MaxContentWidth = 0
+	}
+	switch cfg.TUI.ContentAlign {
+	case ContentAlignCenter, ContentAlignLeft:
+	default:
+		if cfg.TUI.ContentAlign != "" {
+			logging.Warn("unknown tui contentAlign, using center", "contentAlign", cfg.TUI.ContentAlign)
+		}
+		cfg.TUI.ContentAlign = ContentAlignCenter
+	}
+
+	// Validate the background override
+	switch cfg.TUI.Background {
+	case BackgroundAuto, BackgroundDark, BackgroundLight:
+	default:
+		if cfg.TUI.Background != "" {
+			logging.Warn("unknown tui background, detecting it from the terminal", "background", cfg.TUI.Background)
+		}
+		cfg.TUI.Background = BackgroundAuto
+	}
+
+	// Validate the diff layout
+	switch cfg.TUI.DiffView {
+	case DiffViewAuto, DiffViewSplit, DiffViewUnified:
+	default:
+		if cfg.TUI.DiffView != "" {
+			logging.Warn("unknown tui diffView, using auto", "diffView", cfg.TUI.DiffView)
+		}
+		cfg.TUI.DiffView = DiffViewAuto
+	}
+
+	// Validate the headless permission decision
+	switch cfg.Headless.PermissionDecision {
+	case PermissionAllow, PermissionDeny:
+	default:
+		logging.Warn("invalid headless permissionDecision, allowing", "permissionDecision", cfg.Headless.PermissionDecision)
+		cfg.Headless.PermissionDecision = PermissionAllow
+	}
+	if cfg.Headless.PermissionTimeoutSeconds < 0 {
+		logging.Warn("invalid headless permissionTimeoutSeconds, deciding at once", "permissionTimeoutSeconds", cfg.Headless.PermissionTimeoutSeconds)
+		cfg.Headless.PermissionTimeoutSeconds = 0
+	}
+
+	// Validate the tool call argument cap
+	if cfg.MaxToolArgumentBytes <= 0 {
+		logging.Warn("invalid maxToolArgumentBytes, using default", "maxToolArgumentBytes", cfg.MaxToolArgumentBytes, "default", DefaultMaxToolArgumentBytes)
+		cfg.MaxToolArgumentBytes = DefaultMaxToolArgumentBytes
+	}
+
+	// Validate tool retry policies
+	for name, retry := range cfg.Tools.Retry {
+		if retry.Attempts < 0 {
+			logging.Warn("invalid tool retry attempts, not retrying", "tool", name, "attempts", retry.Attempts)
+			retry.Attempts = 0
+		}
+		if retry.BackoffMs <= 0 {
+			if retry.BackoffMs < 0 {
+				logging.Warn("invalid tool retry backoffMs, using default", "tool", name, "backoffMs", retry.BackoffMs, "default", DefaultToolRetryBackoffMs)
+			}
+			retry.BackoffMs = DefaultToolRetryBackoffMs
+		}
+		cfg.Tools.Retry[name] = retry
+	}
+
+	// Validate the size limit of fetched pages
+	if cfg.Tools.Fetch.MaxBodyBytes <= 0 {
+		logging.Warn("invalid tools.fetch.maxBodyBytes, using default", "maxBodyBytes", cfg.Tools.Fetch.MaxBodyBytes, "default", DefaultFetchMaxBodyBytes)
+		cfg.Tools.Fetch.MaxBodyBytes = DefaultFetchMaxBodyBytes
+	}
+
+	// Validate how long tool results are cached
+	if cfg.Tools.Cache.TTLSeconds <= 0 {
+		logging.Warn("invalid tools.cache.ttlSeconds, using default", "ttlSeconds", cfg.Tools.Cache.TTLSeconds, "default", DefaultToolCacheTTLSeconds)
+		cfg.Tools.Cache.TTLSeconds = DefaultToolCacheTTLSeconds
+	}
+
+	// Validate the retention of debug logs; 0 doesn't apply a limit
+	if cfg.DebugLogRetention.MaxFiles < 0 {
+		logging.Warn("invalid debugLogRetention.maxFiles, using default", "maxFiles", cfg.DebugLogRetention.MaxFiles, "default", DefaultDebugLogMaxFiles)
+		cfg.DebugLogRetention.MaxFiles = DefaultDebugLogMaxFiles
+	}
+	if cfg.DebugLogRetention.MaxAgeDays < 0 {
+		logging.Warn("invalid debugLogRetention.maxAgeDays, using default", "maxAgeDays", cfg.DebugLogRetention.MaxAgeDays, "default", DefaultDebugLogMaxAgeDays)
+		cfg.DebugLogRetention.MaxAgeDays = DefaultDebugLogMaxAgeDays
+	}
+	if cfg.DebugLogRetention.MaxBytes < 0 {
+		logging.Warn("invalid debugLogRetention.maxBytes, using default", "maxBytes", cfg.DebugLogRetention.MaxBytes, "default", DefaultDebugLogMaxBytes)
+		cfg.DebugLogRetention.MaxBytes = DefaultDebugLogMaxBytes
+	}
+
+	// Validate the size limit of context files; 0 leaves them whole
+	if cfg.MaxContextFileBytes < 0 {
+		logging.Warn("invalid maxContextFileBytes, using default", "maxContextFileBytes", cfg.MaxContextFileBytes, "default", DefaultMaxContextFileBytes)
+		cfg.MaxContextFileBytes = DefaultMaxContextFileBytes
+	}
+
+	// Validate the size limit of attached file references; 0, the default,
+	// disables them
+	if cfg.InlineFileMaxBytes < 0 {
+		logging.Warn("invalid inlineFileMaxBytes, not attaching referenced files", "inlineFileMaxBytes", cfg.InlineFileMaxBytes)
+		cfg.InlineFileMaxBytes = 0
+	}
+
+	// Resolve the sandbox root against the working directory, which is the
+	// default
+	if cfg.SandboxRoot == "" {
+		cfg.SandboxRoot = cfg.WorkingDir
+	} else if !filepath.IsAbs(cfg.SandboxRoot) {
+		cfg.SandboxRoot = filepath.Join(cfg.WorkingDir, cfg.SandboxRoot)
+	}
+	cfg.SandboxRoot = filepath.Clean(cfg.SandboxRoot)
+
+	// Validate shell limits
+	if cfg.Shell.TimeoutSeconds <= 0 {
+		logging.Warn("invalid shell timeoutSeconds, using default", "timeoutSeconds", cfg.Shell.TimeoutSeconds, "default", DefaultShellTimeoutSeconds)
+		cfg.Shell.TimeoutSeconds = DefaultShellTimeoutSeconds
+	}
+	if cfg.Shell.MaxOutputBytes <= 0 {
+		logging.Warn("invalid shell maxOutputBytes, using default", "maxOutputBytes", cfg.Shell.MaxOutputBytes, "default", DefaultShellMaxOutputBytes)
+		cfg.Shell.MaxOutputBytes = DefaultShellMaxOutputBytes
+	}
+
+	cfg.Shell.Env = validShellEnv(cfg.Shell.Env)
+	cfg.Shell.AllowedCommands = validCommandRules("allowedCommands", cfg.Shell.AllowedCommands)
+	cfg.Shell.BlockedCommands = validCommandRules("blockedCommands", cfg.Shell.BlockedCommands)
+
+	// Validate MCP reconnection limits
+	for name, server := range cfg.MCPServers {
+		if server.MaxReconnectAttempts < 0 {
+			logging.Warn("invalid mcp maxReconnectAttempts, using default", "name", name, "maxReconnectAttempts", server.MaxReconnectAttempts, "default", DefaultMCPMaxReconnectAttempts)
+			server.MaxReconnectAttempts = 0
+		}
+		if server.MaxReconnectAttempts == 0 {
+			server.MaxReconnectAttempts = DefaultMCPMaxReconnectAttempts
+			cfg.MCPServers[name] = server
+		}
+	}
+
+	// Validate LSP fan-out limits
+	if cfg.LSPFanOut.MaxConcurrency <= 0 {
+		logging.Warn("invalid lspFanOut maxConcurrency, using default", "maxConcurrency", cfg.LSPFanOut.MaxConcurrency, "default", DefaultLSPFanOutMaxConcurrency)
+		cfg.LSPFanOut.MaxConcurrency = DefaultLSPFanOutMaxConcurrency
+	}
+	if cfg.LSPFanOut.ClientTimeoutSeconds <= 0 {
+		logging.Warn("invalid lspFanOut clientTimeoutSeconds, using default", "clientTimeoutSeconds", cfg.LSPFanOut.ClientTimeoutSeconds, "default", DefaultLSPFanOutClientTimeoutSeconds)
+		cfg.LSPFanOut.ClientTimeoutSeconds = DefaultLSPFanOutClientTimeoutSeconds
+	}
+
+	// Validate the LSP startup limit; 0 starts every server at once
+	if cfg.MaxConcurrentLSPStarts < 0 {
+		logging.Warn("invalid maxConcurrentLspStarts, starting all LSP servers at once", "maxConcurrentLspStarts", cfg.MaxConcurrentLSPStarts)
+		cfg.MaxConcurrentLSPStarts = 0
 	}
 
 	// Validate LSP configurations
@@ -635,11 +1527,89 @@ func Validate() error {
 			lspConfig.Disabled = true
 			cfg.LSP[language] = lspConfig
 		}
+		if lspConfig.InitTimeoutSeconds < 0 {
+			logging.Warn("invalid LSP init timeout, using default", "language", language, "initTimeoutSeconds", lspConfig.InitTimeoutSeconds, "default", DefaultLSPInitTimeoutSeconds)
+		}
+		if lspConfig.InitTimeoutSeconds <= 0 {
+			lspConfig.InitTimeoutSeconds = DefaultLSPInitTimeoutSeconds
+			cfg.LSP[language] = lspConfig
+		}
 	}
 
 	return nil
 }
 
+// validCommandRules drops the entries of a shell command list whose regular
+// expression doesn't compile, and command entries with shell operators such as
+// "curl | sh", which can never match.
+func validCommandRules(field string, entries []string) []string {
+	valid := entries[:0]
+	for _, entry := range entries {
+		if pattern, ok := CommandPattern(entry); ok {
+			if _, err := regexp.Compile(pattern); err != nil {
+				logging.Warn("invalid shell "+field+" pattern, ignoring it", "entry", entry, "error", err)
+				continue
+			}
+		} else if strings.ContainsAny(entry, "|;&<>()`") {
+			// Command lines are split at these before entries are matched
+			logging.Warn("shell "+field+" entry contains a shell operator and can never match, ignoring it; use a /pattern/ instead", "entry", entry)
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	return valid
+}
+
+// validShellEnv drops the shell.env entries that aren't KEY=VALUE with a
+// valid variable name.
+func validShellEnv(entries []string) []string {
+	valid := entries[:0]
+	for _, entry := range entries {
+		key, _, ok := strings.Cut(entry, "=")
+		if !ok || !ValidEnvName(key) {
+			logging.Warn("invalid shell env entry, ignoring it", "entry", entry)
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	return valid
+}
+
+// validKey reports whether k names a key the TUI can match: a single
+// character, a named key or f1-f20, optionally preceded by ctrl+, alt+ and
+// shift+.
+func validKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	// The last character is never a separator, so that "ctrl++" binds "+"
+	var modifiers []string
+	base := k
+	if i := strings.LastIndex(k[:len(k)-1], "+"); i >= 0 {
+		modifiers = strings.Split(k[:i], "+")
+		base = k[i+1:]
+	}
+
+	seen := make(map[string]bool)
+	for _, modifier := range modifiers {
+		if (modifier != "ctrl" && modifier != "alt" && modifier != "shift") || seen[modifier] {
+			return false
+		}
+		seen[modifier] = true
+	}
+
+	if runes := []rune(base); len(runes) == 1 {
+		return unicode.IsPrint(runes[0]) && runes[0] != ' '
+	}
+	if namedKeys[base] {
+		return true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(base, "f")); err == nil && strings.HasPrefix(base, "f") {
+		return n >= 1 && n <= 20
+	}
+	return false
+}
+
 // getProviderAPIKey gets the API key for a provider from environment variables
 func getProviderAPIKey(provider models.ModelProvider) string {
 	switch provider {
@@ -711,13 +1681,14 @@ func setDefaultModelForAgent(agent AgentName) bool {
 
 		// Check if model supports reasoning
 		if modelInfo, ok := models.SupportedModels[model]; ok && modelInfo.CanReason {
-			reasoningEffort = "medium"
+			reasoningEffort = tierReasoningEffort(modelInfo)
 		}
 
 		cfg.Agents[agent] = Agent{
-			Model:           model,
-			MaxTokens:       maxTokens,
-			ReasoningEffort: reasoningEffort,
+			Model:                    model,
+			MaxTokens:                maxTokens,
+			ReasoningEffort:          reasoningEffort,
+			reasoningEffortDefaulted: reasoningEffort != "",
 		}
 		return true
 	}
@@ -739,13 +1710,14 @@ func setDefaultModelForAgent(agent AgentName) bool {
 
 		// Check if model supports reasoning
 		if modelInfo, ok := models.SupportedModels[model]; ok && modelInfo.CanReason {
-			reasoningEffort = "medium"
+			reasoningEffort = tierReasoningEffort(modelInfo)
 		}
 
 		cfg.Agents[agent] = Agent{
-			Model:           model,
-			MaxTokens:       maxTokens,
-			ReasoningEffort: reasoningEffort,
+			Model:                    model,
+			MaxTokens:                maxTokens,
+			ReasoningEffort:          reasoningEffort,
+			reasoningEffortDefaulted: reasoningEffort != "",
 		}
 		return true
 	}
@@ -788,9 +1760,10 @@ func setDefaultModelForAgent(agent AgentName) bool {
 		}
 
 		cfg.Agents[agent] = Agent{
-			Model:           models.BedrockClaude37Sonnet,
-			MaxTokens:       maxTokens,
-			ReasoningEffort: "medium", // Claude models support reasoning
+			Model:                    models.BedrockClaude37Sonnet,
+			MaxTokens:                maxTokens,
+			ReasoningEffort:          tierReasoningEffort(models.SupportedModels[models.BedrockClaude37Sonnet]), // Claude models support reasoning
+			reasoningEffortDefaulted: true,
 		}
 		return true
 	}
@@ -876,6 +1849,41 @@ func WorkingDirectory() string {
 	return cfg.WorkingDir
 }
 
+// TrashDirectory returns the directory files deleted during a session are moved
+// to when hard delete is disabled.
+func TrashDirectory(sessionID string) string {
+	return filepath.Join(dataDirectory(), "trash", sessionID)
+}
+
+// ToolCacheDirectory returns the directory the cached tool results of a
+// session are kept in when tools.cache.persist is set.
+func ToolCacheDirectory(sessionID string) string {
+	return filepath.Join(dataDirectory(), "cache", sessionID)
+}
+
+// PromptHistoryPath returns the file the prompts sent from the chat editor are
+// kept in.
+func PromptHistoryPath() string {
+	return filepath.Join(dataDirectory(), "prompt-history.json")
+}
+
+// ModelProbeCachePath returns the file the results of startup model probes are
+// cached in.
+func ModelProbeCachePath() string {
+	return filepath.Join(dataDirectory(), "model-probes.json")
+}
+
+func dataDirectory() string {
+	if cfg == nil {
+		panic("config not loaded")
+	}
+	dataDir := cfg.Data.Directory
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(cfg.WorkingDir, dataDir)
+	}
+	return dataDir
+}
+
 func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 	if cfg == nil {
 		panic("config not loaded")
@@ -893,10 +1901,22 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 		maxTokens = model.DefaultMaxTokens
 	}
 
+	// A defaulted effort is left empty to take the default of the new model's
+	// tier instead
+	reasoningEffort := existingAgentCfg.ReasoningEffort
+	if existingAgentCfg.reasoningEffortDefaulted {
+		reasoningEffort = ""
+	}
+
 	newAgentCfg := Agent{
 		Model:           modelID,
 		MaxTokens:       maxTokens,
-		ReasoningEffort: existingAgentCfg.ReasoningEffort,
+		ReasoningEffort: reasoningEffort,
+		StepByStep:      existingAgentCfg.StepByStep,
+		Prompt:          existingAgentCfg.Prompt,
+		PromptFile:      existingAgentCfg.PromptFile,
+		PromptMode:      existingAgentCfg.PromptMode,
+		promptFileText:  existingAgentCfg.promptFileText,
 	}
 	cfg.Agents[agentName] = newAgentCfg
 
@@ -914,6 +1934,33 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 	})
 }
 
+// UpdateAgentStepByStep enables or disables the step-by-step directive for an
+// agent and writes it to the config file.
+func UpdateAgentStepByStep(agentName AgentName, enabled bool) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	agentCfg, ok := cfg.Agents[agentName]
+	if !ok {
+		return fmt.Errorf("agent %s not found", agentName)
+	}
+	agentCfg.StepByStep = enabled
+	cfg.Agents[agentName] = agentCfg
+
+	return updateCfgFile(func(config *Config) {
+		if config.Agents == nil {
+			config.Agents = make(map[AgentName]Agent)
+		}
+		fileAgentCfg, ok := config.Agents[agentName]
+		if !ok {
+			fileAgentCfg = agentCfg
+		}
+		fileAgentCfg.StepByStep = enabled
+		config.Agents[agentName] = fileAgentCfg
+	})
+}
+
 // UpdateTheme updates the theme in the configuration and writes it to the config file.
 func UpdateTheme(themeName string) error {
 	if cfg == nil {
@@ -929,6 +1976,57 @@ func UpdateTheme(themeName string) error {
 	})
 }
 
+// UpdateDiffView sets how diffs are laid out, in memory and in the config
+// file.
+func UpdateDiffView(view DiffView) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	// Update the in-memory config
+	cfg.TUI.DiffView = view
+
+	// Update the file config
+	return updateCfgFile(func(config *Config) {
+		config.TUI.DiffView = view
+	})
+}
+
+// ConfigFiles returns the global and local config file paths. The local file is
+// included even if it doesn't exist yet.
+func ConfigFiles() []string {
+	if cfg == nil {
+		panic("config not loaded")
+	}
+	var files []string
+	if global := viper.ConfigFileUsed(); global != "" {
+		files = append(files, global)
+	}
+	return append(files, filepath.Join(cfg.WorkingDir, fmt.Sprintf(".%s.json", appName)))
+}
+
+// ReadTheme reads the theme from the config files on disk, with the local config
+// taking precedence as it does when loading, so that external edits can be
+// picked up without a restart. Missing files are skipped.
+func ReadTheme() (string, error) {
+	themeName := defaultTheme
+	for _, file := range ConfigFiles() {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		v := viper.New()
+		v.SetConfigFile(file)
+		v.SetConfigType("json")
+		if err := v.ReadInConfig(); err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if name := v.GetString("tui.theme"); name != "" {
+			themeName = name
+		}
+	}
+	return themeName, nil
+}
+
 // Tries to load Github token from all possible locations
 func LoadGitHubToken() (string, error) {
 	// First check environment variable
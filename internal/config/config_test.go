@@ -0,0 +1,224 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+)
+
+func TestValidKey(t *testing.T) {
+	for k, want := range map[string]bool{
+		"ctrl+l":        true,
+		"alt+ctrl+s":    true,
+		"ctrl+shift+up": true,
+		"f2":            true,
+		"f21":           false,
+		"q":             true,
+		"?":             true,
+		"ctrl++":        true,
+		"+":             true,
+		"":              false,
+		"ctrl+":         false,
+		"ctrl+ctrl+l":   false,
+		"meta+l":        false,
+		"ctrl+foo":      false,
+		" ":             false,
+	} {
+		if got := validKey(k); got != want {
+			t.Errorf("validKey(%q) = %v, want %v", k, got, want)
+		}
+	}
+}
+
+func TestValidCommandRules(t *testing.T) {
+	got := validCommandRules("blockedCommands", []string{"rm -rf", "/curl.*\\| *sh/", "/(unclosed/", "/", "curl | sh", "make; make install"})
+	want := []string{"rm -rf", "/curl.*\\| *sh/", "/"}
+	if len(got) != len(want) {
+		t.Fatalf("validCommandRules() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("validCommandRules()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for level, want := range map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		if got, ok := parseLogLevel(level); !ok || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v, want %v, true", level, got, ok, want)
+		}
+	}
+	if got, ok := parseLogLevel("verbose"); ok || got != slog.LevelInfo {
+		t.Errorf("parseLogLevel(%q) = %v, %v, want %v, false", "verbose", got, ok, slog.LevelInfo)
+	}
+}
+
+func TestOverriddenKeys(t *testing.T) {
+	global := map[string]any{
+		"debug":        false,
+		"autocompact":  true,
+		"contextpaths": []any{"CLAUDE.md"},
+		"agents": map[string]any{
+			"coder": map[string]any{"model": "claude-3.7-sonnet", "maxtokens": 5000},
+			"task":  map[string]any{"model": "gpt-4.1-mini"},
+		},
+		"providers": map[string]any{"anthropic": map[string]any{"apikey": "global-key"}},
+	}
+	local := map[string]any{
+		"debug":        true,
+		"autocompact":  true,
+		"contextpaths": []any{"CLAUDE.md", "AGENTS.md"},
+		"agents": map[string]any{
+			"coder": map[string]any{"model": "gpt-4.1", "maxtokens": 5000},
+			"title": map[string]any{"model": "gpt-4.1-nano"},
+		},
+		"shell":     map[string]any{"path": "/bin/zsh"},
+		"providers": "not an object",
+	}
+
+	got := overriddenKeys(global, local, "")
+	want := []string{"agents.coder.model", "contextpaths", "debug", "providers"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overriddenKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestTierOf(t *testing.T) {
+	for id, want := range map[models.ModelID]ModelTier{
+		models.O4Mini:                  ModelTierMini,
+		models.GPT41Nano:               ModelTierMini,
+		models.Gemini25Flash:           ModelTierMini,
+		models.Claude35Haiku:           ModelTierMini,
+		models.GPT41:                   ModelTierStandard,
+		models.Gemini25:                ModelTierPro,
+		models.Claude37Sonnet:          ModelTierStandard,
+		models.O3:                      ModelTierPro,
+		models.O1Pro:                   ModelTierPro,
+		models.Claude4Opus:             ModelTierPro,
+		models.OpenRouterO3:            ModelTierPro,
+		models.CopilotGemini25:         ModelTierPro,
+		models.OpenRouterClaude3Opus:   ModelTierPro,
+		models.OpenRouterGemini25Flash: ModelTierMini,
+	} {
+		if got := TierOf(models.SupportedModels[id]); got != want {
+			t.Errorf("TierOf(%s) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestUpdateAgentModelAppliesTierReasoningEffort(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	t.Cleanup(func() {
+		viper.Reset()
+		cfg = nil
+	})
+
+	cfg = &Config{
+		Providers: map[models.ModelProvider]Provider{models.ProviderOpenAI: {APIKey: "key"}},
+		Agents: map[AgentName]Agent{
+			AgentCoder: {Model: models.O3, MaxTokens: 5000},
+			AgentTask:  {Model: models.O3, MaxTokens: 5000, ReasoningEffort: "medium"},
+		},
+		ReasoningEffortByTier: map[ModelTier]string{ModelTierMini: "low", ModelTierPro: "high"},
+	}
+	if err := Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Agents[AgentCoder].ReasoningEffort; got != "high" {
+		t.Fatalf("coder reasoning effort = %q, want the pro tier's %q", got, "high")
+	}
+
+	for _, agent := range []AgentName{AgentCoder, AgentTask} {
+		if err := UpdateAgentModel(agent, models.O4Mini); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := cfg.Agents[AgentCoder].ReasoningEffort; got != "low" {
+		t.Errorf("coder reasoning effort after switching to %s = %q, want the mini tier's %q", models.O4Mini, got, "low")
+	}
+	if got := cfg.Agents[AgentTask].ReasoningEffort; got != "medium" {
+		t.Errorf("task reasoning effort after switching to %s = %q, want the configured %q", models.O4Mini, got, "medium")
+	}
+}
+
+func TestAgentPrompt(t *testing.T) {
+	workingDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workingDir, "coder.md"), []byte("Use tabs.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	t.Cleanup(func() {
+		viper.Reset()
+		cfg = nil
+	})
+
+	cfg = &Config{
+		WorkingDir: workingDir,
+		Providers:  map[models.ModelProvider]Provider{models.ProviderOpenAI: {APIKey: "key"}},
+		Agents: map[AgentName]Agent{
+			AgentCoder: {Model: models.GPT41, MaxTokens: 5000, PromptFile: "coder.md"},
+			AgentTask:  {Model: models.GPT41, MaxTokens: 5000, Prompt: "Be brief.", PromptMode: PromptModeReplace},
+			AgentTitle: {Model: models.GPT41, MaxTokens: 80, Prompt: "Use title case.", PromptMode: "prepend"},
+		},
+	}
+	if err := Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for agent, want := range map[AgentName]string{
+		AgentCoder: "Built in\n\nUse tabs.",
+		AgentTask:  "Be brief.",
+		AgentTitle: "Built in\n\nUse title case.",
+	} {
+		if got := cfg.Agents[agent].SystemPrompt("Built in"); got != want {
+			t.Errorf("%s system prompt = %q, want %q", agent, got, want)
+		}
+	}
+	if got := (Agent{}).SystemPrompt("Built in"); got != "Built in" {
+		t.Errorf("system prompt without a prompt = %q, want the built-in prompt", got)
+	}
+
+	if err := UpdateAgentModel(AgentCoder, models.GPT41Mini); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Agents[AgentCoder].SystemPrompt("Built in"); got != "Built in\n\nUse tabs." {
+		t.Errorf("coder system prompt after switching models = %q, want the prompt file kept", got)
+	}
+
+	cfg.Agents[AgentCoder] = Agent{Model: models.GPT41, MaxTokens: 5000, PromptFile: "missing.md"}
+	if err := Validate(); err == nil {
+		t.Error("Validate() with a missing promptFile succeeded, want an error")
+	}
+}
+
+func TestLSPConfigExpandedEnv(t *testing.T) {
+	t.Setenv("LSP_TEST_HOME", "/opt/jdk")
+	c := LSPConfig{Command: "jdtls", Env: []string{"JAVA_HOME=${LSP_TEST_HOME}/17", "PATH=$LSP_TEST_HOME/bin", "GOFLAGS=", "invalid"}}
+	got := c.ExpandedEnv()
+	want := []string{"JAVA_HOME=/opt/jdk/17", "PATH=/opt/jdk/bin", "GOFLAGS="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandedEnv() = %q, want %q", got, want)
+	}
+}
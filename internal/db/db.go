@@ -60,6 +60,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.importMessageStmt, err = db.PrepareContext(ctx, importMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportMessage: %w", err)
+	}
+	if q.importSessionStmt, err = db.PrepareContext(ctx, importSession); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportSession: %w", err)
+	}
+	if q.listChildSessionsStmt, err = db.PrepareContext(ctx, listChildSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListChildSessions: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
@@ -152,6 +161,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.importMessageStmt != nil {
+		if cerr := q.importMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importMessageStmt: %w", cerr)
+		}
+	}
+	if q.importSessionStmt != nil {
+		if cerr := q.importSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importSessionStmt: %w", cerr)
+		}
+	}
+	if q.listChildSessionsStmt != nil {
+		if cerr := q.listChildSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listChildSessionsStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -248,6 +272,9 @@ type Queries struct {
 	getFileByPathAndSessionStmt *sql.Stmt
 	getMessageStmt              *sql.Stmt
 	getSessionByIDStmt          *sql.Stmt
+	importMessageStmt           *sql.Stmt
+	importSessionStmt           *sql.Stmt
+	listChildSessionsStmt       *sql.Stmt
 	listFilesByPathStmt         *sql.Stmt
 	listFilesBySessionStmt      *sql.Stmt
 	listLatestSessionFilesStmt  *sql.Stmt
@@ -275,6 +302,9 @@ func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
 		getMessageStmt:              q.getMessageStmt,
 		getSessionByIDStmt:          q.getSessionByIDStmt,
+		importMessageStmt:           q.importMessageStmt,
+		importSessionStmt:           q.importSessionStmt,
+		listChildSessionsStmt:       q.listChildSessionsStmt,
 		listFilesByPathStmt:         q.listFilesByPathStmt,
 		listFilesBySessionStmt:      q.listFilesBySessionStmt,
 		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
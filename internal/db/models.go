@@ -30,14 +30,15 @@ type Message struct {
 }
 
 type Session struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
-	UpdatedAt        int64          `json:"updated_at"`
-	CreatedAt        int64          `json:"created_at"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	ID                     string         `json:"id"`
+	ParentSessionID        sql.NullString `json:"parent_session_id"`
+	Title                  string         `json:"title"`
+	MessageCount           int64          `json:"message_count"`
+	PromptTokens           int64          `json:"prompt_tokens"`
+	CompletionTokens       int64          `json:"completion_tokens"`
+	Cost                   float64        `json:"cost"`
+	UpdatedAt              int64          `json:"updated_at"`
+	CreatedAt              int64          `json:"created_at"`
+	SummaryMessageID       sql.NullString `json:"summary_message_id"`
+	ContinuedFromSessionID sql.NullString `json:"continued_from_session_id"`
 }
@@ -20,6 +20,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    continued_from_session_id,
     updated_at,
     created_at
 ) VALUES (
@@ -31,19 +32,21 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, continued_from_session_id
 `
 
 type CreateSessionParams struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
+	ID                     string         `json:"id"`
+	ParentSessionID        sql.NullString `json:"parent_session_id"`
+	Title                  string         `json:"title"`
+	MessageCount           int64          `json:"message_count"`
+	PromptTokens           int64          `json:"prompt_tokens"`
+	CompletionTokens       int64          `json:"completion_tokens"`
+	Cost                   float64        `json:"cost"`
+	ContinuedFromSessionID sql.NullString `json:"continued_from_session_id"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -55,6 +58,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.Cost,
+		arg.ContinuedFromSessionID,
 	)
 	var i Session
 	err := row.Scan(
@@ -68,6 +72,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.ContinuedFromSessionID,
 	)
 	return i, err
 }
@@ -83,7 +88,7 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 }
 
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, continued_from_session_id
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,12 +107,124 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.ContinuedFromSessionID,
 	)
 	return i, err
 }
 
+const importSession = `-- name: ImportSession :one
+INSERT INTO sessions (
+    id,
+    parent_session_id,
+    title,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    summary_message_id,
+    continued_from_session_id,
+    updated_at,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, continued_from_session_id
+`
+
+type ImportSessionParams struct {
+	ID                     string         `json:"id"`
+	ParentSessionID        sql.NullString `json:"parent_session_id"`
+	Title                  string         `json:"title"`
+	PromptTokens           int64          `json:"prompt_tokens"`
+	CompletionTokens       int64          `json:"completion_tokens"`
+	Cost                   float64        `json:"cost"`
+	SummaryMessageID       sql.NullString `json:"summary_message_id"`
+	ContinuedFromSessionID sql.NullString `json:"continued_from_session_id"`
+	UpdatedAt              int64          `json:"updated_at"`
+	CreatedAt              int64          `json:"created_at"`
+}
+
+func (q *Queries) ImportSession(ctx context.Context, arg ImportSessionParams) (Session, error) {
+	row := q.queryRow(ctx, q.importSessionStmt, importSession,
+		arg.ID,
+		arg.ParentSessionID,
+		arg.Title,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Cost,
+		arg.SummaryMessageID,
+		arg.ContinuedFromSessionID,
+		arg.UpdatedAt,
+		arg.CreatedAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.ContinuedFromSessionID,
+	)
+	return i, err
+}
+
+const listChildSessions = `-- name: ListChildSessions :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, continued_from_session_id
+FROM sessions
+WHERE parent_session_id = ?
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error) {
+	rows, err := q.query(ctx, q.listChildSessionsStmt, listChildSessions, parentSessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.ContinuedFromSessionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, continued_from_session_id
 FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC
@@ -133,6 +250,7 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.ContinuedFromSessionID,
 		); err != nil {
 			return nil, err
 		}
@@ -156,7 +274,7 @@ SET
     summary_message_id = ?,
     cost = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, continued_from_session_id
 `
 
 type UpdateSessionParams struct {
@@ -189,6 +307,7 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.ContinuedFromSessionID,
 	)
 	return i, err
 }
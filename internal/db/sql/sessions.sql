@@ -8,6 +8,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    continued_from_session_id,
     updated_at,
     created_at
 ) VALUES (
@@ -19,10 +20,36 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
 
+-- name: ImportSession :one
+INSERT INTO sessions (
+    id,
+    parent_session_id,
+    title,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    summary_message_id,
+    continued_from_session_id,
+    updated_at,
+    created_at
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+) RETURNING *;
+
 -- name: GetSessionByID :one
 SELECT *
 FROM sessions
@@ -34,6 +61,12 @@ FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC;
 
+-- name: ListChildSessions :many
+SELECT *
+FROM sessions
+WHERE parent_session_id = ?
+ORDER BY created_at ASC;
+
 -- name: UpdateSession :one
 UPDATE sessions
 SET
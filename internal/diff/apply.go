@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HunkFailure describes a hunk of a unified diff that doesn't match the
+// content it was applied to.
+type HunkFailure struct {
+	Index    int      // Position of the hunk in the diff, from 1
+	Header   string   // The hunk's @@ header
+	Line     int      // Line of the content where the hunk was expected, from 1
+	Expected []string // Context and removed lines of the hunk
+	Found    []string // Lines of the content at Line
+}
+
+func (f HunkFailure) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "hunk %d (%s) does not match the file at line %d\nexpected:\n", f.Index, f.Header, f.Line)
+	for _, line := range f.Expected {
+		b.WriteString("  " + line + "\n")
+	}
+	b.WriteString("found:\n")
+	for _, line := range f.Found {
+		b.WriteString("  " + line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ApplyUnifiedDiff applies the hunks of a parsed unified diff to content. A
+// hunk applies where its context and removed lines match the content, looked
+// for first at the line its header gives and then ever further from it, so
+// that diffs made against a slightly older version still apply. Hunks that
+// match nowhere are returned as failures, along with the content with the
+// other hunks applied.
+func ApplyUnifiedDiff(content string, diff DiffResult) (string, []HunkFailure) {
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	var failures []HunkFailure
+	// Hunks apply in order, after the end of the previous one. offset is how
+	// many lines the applied hunks have added so far.
+	next, offset := 0, 0
+	for i, hunk := range diff.Hunks {
+		var oldLines, newLines []string
+		oldStart := 0
+		for _, line := range hunk.Lines {
+			// ParseUnifiedDiff keeps the space that marks context lines
+			if line.Kind == LineContext {
+				line.Content = strings.TrimPrefix(line.Content, " ")
+			}
+			if line.Kind != LineAdded {
+				if len(oldLines) == 0 {
+					oldStart = line.OldLineNo
+				}
+				oldLines = append(oldLines, line.Content)
+			}
+			if line.Kind != LineRemoved {
+				newLines = append(newLines, line.Content)
+			}
+		}
+		if len(oldLines) == 0 {
+			oldStart = hunkOldStart(hunk.Header) + 1
+		}
+
+		expected := max(next, oldStart-1+offset)
+		at, ok := findLines(lines, oldLines, next, expected)
+		if !ok {
+			found := lines[min(expected, len(lines)):min(expected+len(oldLines), len(lines))]
+			failures = append(failures, HunkFailure{
+				Index:    i + 1,
+				Header:   hunk.Header,
+				Line:     expected + 1,
+				Expected: oldLines,
+				Found:    found,
+			})
+			continue
+		}
+
+		lines = append(lines[:at:at], append(newLines, lines[at+len(oldLines):]...)...)
+		next = at + len(newLines)
+		offset += len(newLines) - len(oldLines)
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		result += "\n"
+	}
+	return result, failures
+}
+
+// hunkOldStart returns the old start line of a hunk header, which for a hunk
+// that only adds lines is the line they come after.
+func hunkOldStart(header string) int {
+	var start int
+	fmt.Sscanf(header, "@@ -%d", &start)
+	return start
+}
+
+// findLines returns the index of the first line of want in lines at or after
+// from, preferring the match nearest to expected.
+func findLines(lines, want []string, from, expected int) (int, bool) {
+	matches := func(at int) bool {
+		if at < from || at+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if lines[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	expected = min(max(expected, from), len(lines))
+	for distance := 0; expected-distance >= from || expected+distance <= len(lines); distance++ {
+		if matches(expected - distance) {
+			return expected - distance, true
+		}
+		if distance > 0 && matches(expected+distance) {
+			return expected + distance, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyUnifiedDiff(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive\nsix\n"
+
+	tests := []struct {
+		name     string
+		diff     string
+		want     string
+		failures int
+	}{
+		{
+			name: "exact position",
+			diff: "--- a/f\n+++ b/f\n@@ -2,3 +2,3 @@\n two\n-three\n+THREE\n four\n",
+			want: "one\ntwo\nTHREE\nfour\nfive\nsix\n",
+		},
+		{
+			name: "shifted position",
+			diff: "@@ -1,2 +1,3 @@\n five\n+five and a half\n six\n",
+			want: "one\ntwo\nthree\nfour\nfive\nfive and a half\nsix\n",
+		},
+		{
+			name: "several hunks",
+			diff: "@@ -1,1 +1,2 @@\n+zero\n one\n@@ -6,1 +7,1 @@\n-six\n+SIX\n",
+			want: "zero\none\ntwo\nthree\nfour\nfive\nSIX\n",
+		},
+		{
+			name:     "mismatched context",
+			diff:     "@@ -2,2 +2,2 @@\n two\n-tree\n+THREE\n@@ -5,1 +5,1 @@\n-five\n+FIVE\n",
+			want:     "one\ntwo\nthree\nfour\nFIVE\nsix\n",
+			failures: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseUnifiedDiff(tt.diff)
+			require.NoError(t, err)
+			got, failures := ApplyUnifiedDiff(content, parsed)
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, failures, tt.failures)
+		})
+	}
+}
+
+func TestApplyUnifiedDiffReportsFailureContext(t *testing.T) {
+	parsed, err := ParseUnifiedDiff("@@ -2,2 +2,2 @@\n two\n-tree\n+THREE\n")
+	require.NoError(t, err)
+	_, failures := ApplyUnifiedDiff("one\ntwo\nthree\n", parsed)
+	require.Len(t, failures, 1)
+	assert.Equal(t, 1, failures[0].Index)
+	assert.Equal(t, 2, failures[0].Line)
+	assert.Equal(t, []string{"two", "tree"}, failures[0].Expected)
+	assert.Equal(t, []string{"two", "three"}, failures[0].Found)
+	assert.Contains(t, failures[0].Error(), "hunk 1 (@@ -2,2 +2,2 @@) does not match the file at line 2")
+}
+
+func TestApplyUnifiedDiffToEmptyContent(t *testing.T) {
+	parsed, err := ParseUnifiedDiff("--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,2 @@\n+package main\n+\n")
+	require.NoError(t, err)
+	got, failures := ApplyUnifiedDiff("", parsed)
+	assert.Empty(t, failures)
+	assert.Equal(t, "package main\n\n", got)
+}
@@ -2,6 +2,7 @@ package diff
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"io"
 	"regexp"
@@ -61,6 +62,10 @@ type DiffResult struct {
 	OldFile string
 	NewFile string
 	Hunks   []Hunk
+
+	// Set for the diffs of large files, which show only the changed regions
+	TotalLines int // Lines in the new file
+	TotalBytes int // Size of the new file
 }
 
 // linePair represents a pair of lines for side-by-side display
@@ -69,6 +74,20 @@ type linePair struct {
 	right *DiffLine
 }
 
+// -------------------------------------------------------------------------
+// Large Files
+// -------------------------------------------------------------------------
+
+const (
+	// LargeFileLines is the length above which a file's diff is shown as its
+	// changed regions with the size of the file and the lines left out.
+	LargeFileLines = 1000
+)
+
+// largeFileHeaderRe matches the header GenerateDiff writes before the diff of
+// a large file.
+var largeFileHeaderRe = regexp.MustCompile(`^# large file: (\d+) lines, (\d+) bytes$`)
+
 // -------------------------------------------------------------------------
 // Parse Configuration
 // -------------------------------------------------------------------------
@@ -97,6 +116,8 @@ func WithContextSize(size int) ParseOption {
 // SideBySideConfig configures the rendering of side-by-side diffs
 type SideBySideConfig struct {
 	TotalWidth int
+	Wrap       bool // Wrap long lines over several rows instead of truncating them
+	Unified    bool // Show removed and added lines in one column instead of side by side
 }
 
 // SideBySideOption modifies a SideBySideConfig
@@ -124,6 +145,45 @@ func WithTotalWidth(width int) SideBySideOption {
 	}
 }
 
+// WithWrap sets whether lines too long for their column are wrapped over
+// several rows instead of truncated
+func WithWrap(wrap bool) SideBySideOption {
+	return func(s *SideBySideConfig) {
+		s.Wrap = wrap
+	}
+}
+
+// WithUnified sets whether diffs are shown in one column, removed lines above
+// the added lines that replace them, instead of side by side
+func WithUnified(unified bool) SideBySideOption {
+	return func(s *SideBySideConfig) {
+		s.Unified = unified
+	}
+}
+
+// UnifiedBelowWidth is the width under which the auto diff view shows diffs in
+// one column, as side-by-side columns get too narrow to read.
+const UnifiedBelowWidth = 100
+
+// ViewOptions returns the options that lay out a diff width columns wide as
+// tui.diffView asks: split, unified, or auto, which is unified below
+// UnifiedBelowWidth.
+func ViewOptions(width int) []SideBySideOption {
+	view := config.DiffViewAuto
+	if cfg := config.Get(); cfg != nil {
+		view = cfg.TUI.DiffView
+	}
+	var unified bool
+	switch view {
+	case config.DiffViewSplit:
+	case config.DiffViewUnified:
+		unified = true
+	default:
+		unified = width < UnifiedBelowWidth
+	}
+	return []SideBySideOption{WithTotalWidth(width), WithUnified(unified)}
+}
+
 // -------------------------------------------------------------------------
 // Diff Parsing
 // -------------------------------------------------------------------------
@@ -134,7 +194,8 @@ func ParseUnifiedDiff(diff string) (DiffResult, error) {
 	var currentHunk *Hunk
 
 	hunkHeaderRe := regexp.MustCompile(`^@@ -(\d+),?(\d*) \+(\d+),?(\d*) @@`)
-	lines := strings.Split(diff, "\n")
+	// A final newline ends the last line rather than starting an empty one
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
 
 	var oldLine, newLine int
 	inFileHeader := true
@@ -142,6 +203,11 @@ func ParseUnifiedDiff(diff string) (DiffResult, error) {
 	for _, line := range lines {
 		// Parse file headers
 		if inFileHeader {
+			if matches := largeFileHeaderRe.FindStringSubmatch(line); matches != nil {
+				result.TotalLines, _ = strconv.Atoi(matches[1])
+				result.TotalBytes, _ = strconv.Atoi(matches[2])
+				continue
+			}
 			if strings.HasPrefix(line, "--- a/") {
 				result.OldFile = strings.TrimPrefix(line, "--- a/")
 				continue
@@ -151,10 +217,16 @@ func ParseUnifiedDiff(diff string) (DiffResult, error) {
 				inFileHeader = false
 				continue
 			}
+			// A deleted file has no new name
+			if line == "+++ /dev/null" {
+				inFileHeader = false
+				continue
+			}
 		}
 
 		// Parse hunk headers
 		if matches := hunkHeaderRe.FindStringSubmatch(line); matches != nil {
+			inFileHeader = false
 			if currentHunk != nil {
 				result.Hunks = append(result.Hunks, *currentHunk)
 			}
@@ -229,6 +301,25 @@ func ParseUnifiedDiff(diff string) (DiffResult, error) {
 	return result, nil
 }
 
+// SplitFileDiffs splits the output of git diff, which can cover many files,
+// into one diff per file for ParseUnifiedDiff. Text without "diff --git"
+// headers is returned as a single diff.
+func SplitFileDiffs(diffText string) []string {
+	var files []string
+	var current strings.Builder
+	for _, line := range strings.SplitAfter(diffText, "\n") {
+		if strings.HasPrefix(line, "diff --git ") && current.Len() > 0 {
+			files = append(files, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		files = append(files, current.String())
+	}
+	return files
+}
+
 // HighlightIntralineChanges updates lines in a hunk to show character-level differences
 func HighlightIntralineChanges(h *Hunk) {
 	var updated []DiffLine
@@ -677,13 +768,69 @@ func applyHighlighting(content string, segments []Segment, segmentType LineType,
 	return sb.String()
 }
 
+// diffColumn is one side of a line of a side-by-side diff: its rows, more
+// than one when the line is wrapped, and an empty row in its background to
+// line it up with a longer other side.
+type diffColumn struct {
+	rows  []string
+	blank string
+}
+
+// row returns the i-th row of the column, or a blank row past its end.
+func (c diffColumn) row(i int) string {
+	if i < len(c.rows) {
+		return c.rows[i]
+	}
+	return c.blank
+}
+
+// emptyColumn is the side of a side-by-side diff that has no line.
+func emptyColumn(colWidth int) diffColumn {
+	t := theme.CurrentTheme()
+	contextLineStyle := lipgloss.NewStyle().Background(t.DiffContextBg())
+	blank := contextLineStyle.Width(colWidth).Render("")
+	return diffColumn{rows: []string{blank}, blank: blank}
+}
+
+// renderColumn lays out the prefix and content of a line in a column of
+// colWidth. Content too long for the column is truncated, or with wrap
+// continued on further rows that leave the line number out.
+func renderColumn(prefix, content string, bgStyle, lineNumberStyle lipgloss.Style, colWidth int, wrap bool) diffColumn {
+	t := theme.CurrentTheme()
+	blank := bgStyle.Width(colWidth).Render("")
+
+	contentWidth := colWidth - ansi.StringWidth(prefix)
+	if !wrap || contentWidth <= 0 {
+		lineText := prefix + content
+		row := bgStyle.MaxHeight(1).Width(colWidth).Render(
+			ansi.Truncate(
+				lineText,
+				colWidth,
+				lipgloss.NewStyle().Background(bgStyle.GetBackground()).Foreground(t.TextMuted()).Render("..."),
+			),
+		)
+		return diffColumn{rows: []string{row}, blank: blank}
+	}
+
+	continuation := lineNumberStyle.Render(strings.Repeat(" ", ansi.StringWidth(prefix)))
+	lines := strings.Split(ansi.Hardwrap(content, contentWidth, true), "\n")
+	rows := make([]string, 0, len(lines))
+	for i, line := range lines {
+		linePrefix := prefix
+		if i > 0 {
+			linePrefix = continuation
+		}
+		rows = append(rows, bgStyle.MaxHeight(1).Width(colWidth).Render(linePrefix+line))
+	}
+	return diffColumn{rows: rows, blank: blank}
+}
+
 // renderLeftColumn formats the left side of a side-by-side diff
-func renderLeftColumn(fileName string, dl *DiffLine, colWidth int) string {
+func renderLeftColumn(fileName string, dl *DiffLine, colWidth int, wrap bool) diffColumn {
 	t := theme.CurrentTheme()
 
 	if dl == nil {
-		contextLineStyle := lipgloss.NewStyle().Background(t.DiffContextBg())
-		return contextLineStyle.Width(colWidth).Render("")
+		return emptyColumn(colWidth)
 	}
 
 	removedLineStyle, _, contextLineStyle, lineNumberStyle := createStyles(t)
@@ -726,24 +873,16 @@ func renderLeftColumn(fileName string, dl *DiffLine, colWidth int) string {
 		content = bgStyle.Render(" ") + content
 	}
 
-	// Create the final line and truncate if needed
-	lineText := prefix + content
-	return bgStyle.MaxHeight(1).Width(colWidth).Render(
-		ansi.Truncate(
-			lineText,
-			colWidth,
-			lipgloss.NewStyle().Background(bgStyle.GetBackground()).Foreground(t.TextMuted()).Render("..."),
-		),
-	)
+	// Create the final line, truncated or wrapped if needed
+	return renderColumn(prefix, content, bgStyle, lineNumberStyle, colWidth, wrap)
 }
 
 // renderRightColumn formats the right side of a side-by-side diff
-func renderRightColumn(fileName string, dl *DiffLine, colWidth int) string {
+func renderRightColumn(fileName string, dl *DiffLine, colWidth int, wrap bool) diffColumn {
 	t := theme.CurrentTheme()
 
 	if dl == nil {
-		contextLineStyle := lipgloss.NewStyle().Background(t.DiffContextBg())
-		return contextLineStyle.Width(colWidth).Render("")
+		return emptyColumn(colWidth)
 	}
 
 	_, addedLineStyle, contextLineStyle, lineNumberStyle := createStyles(t)
@@ -786,21 +925,98 @@ func renderRightColumn(fileName string, dl *DiffLine, colWidth int) string {
 		content = bgStyle.Render(" ") + content
 	}
 
-	// Create the final line and truncate if needed
-	lineText := prefix + content
-	return bgStyle.MaxHeight(1).Width(colWidth).Render(
-		ansi.Truncate(
-			lineText,
-			colWidth,
-			lipgloss.NewStyle().Background(bgStyle.GetBackground()).Foreground(t.TextMuted()).Render("..."),
-		),
-	)
+	// Create the final line, truncated or wrapped if needed
+	return renderColumn(prefix, content, bgStyle, lineNumberStyle, colWidth, wrap)
+}
+
+// renderUnifiedLine formats a line of a unified diff, with its old and new
+// line numbers and a +/- marker
+func renderUnifiedLine(fileName string, dl DiffLine, width int, wrap bool) diffColumn {
+	t := theme.CurrentTheme()
+	removedLineStyle, addedLineStyle, contextLineStyle, lineNumberStyle := createStyles(t)
+
+	// Determine line style based on line type
+	var marker string
+	var bgStyle lipgloss.Style
+	switch dl.Kind {
+	case LineRemoved:
+		marker = removedLineStyle.Foreground(t.DiffRemoved()).Render("-")
+		bgStyle = removedLineStyle
+		lineNumberStyle = lineNumberStyle.Foreground(t.DiffRemoved()).Background(t.DiffRemovedLineNumberBg())
+	case LineAdded:
+		marker = addedLineStyle.Foreground(t.DiffAdded()).Render("+")
+		bgStyle = addedLineStyle
+		lineNumberStyle = lineNumberStyle.Foreground(t.DiffAdded()).Background(t.DiffAddedLineNumberBg())
+	default:
+		marker = contextLineStyle.Render(" ")
+		bgStyle = contextLineStyle
+	}
+
+	// Format line numbers
+	oldNum, newNum := "", ""
+	if dl.OldLineNo > 0 {
+		oldNum = fmt.Sprint(dl.OldLineNo)
+	}
+	if dl.NewLineNo > 0 {
+		newNum = fmt.Sprint(dl.NewLineNo)
+	}
+
+	// Create the line prefix
+	prefix := lineNumberStyle.Render(fmt.Sprintf("%6s %6s ", oldNum, newNum) + marker)
+
+	// Apply syntax highlighting
+	content := highlightLine(fileName, dl.Content, bgStyle.GetBackground())
+
+	// Apply intra-line highlighting and a padding space for changed lines
+	switch dl.Kind {
+	case LineRemoved:
+		if len(dl.Segments) > 0 {
+			content = applyHighlighting(content, dl.Segments, LineRemoved, t.DiffHighlightRemoved())
+		}
+		content = bgStyle.Render(" ") + content
+	case LineAdded:
+		if len(dl.Segments) > 0 {
+			content = applyHighlighting(content, dl.Segments, LineAdded, t.DiffHighlightAdded())
+		}
+		content = bgStyle.Render(" ") + content
+	}
+
+	return renderColumn(prefix, content, bgStyle, lineNumberStyle, width, wrap)
 }
 
 // -------------------------------------------------------------------------
 // Public API
 // -------------------------------------------------------------------------
 
+// RenderUnifiedHunk formats a hunk for display in one column, each line with
+// its old and new line numbers
+func RenderUnifiedHunk(fileName string, h Hunk, opts ...SideBySideOption) string {
+	config := NewSideBySideConfig(opts...)
+
+	// Make a copy of the hunk so we don't modify the original
+	hunkCopy := Hunk{Lines: make([]DiffLine, len(h.Lines))}
+	copy(hunkCopy.Lines, h.Lines)
+
+	// Highlight changes within lines
+	HighlightIntralineChanges(&hunkCopy)
+
+	var sb strings.Builder
+	for _, line := range hunkCopy.Lines {
+		for _, row := range renderUnifiedLine(fileName, line, config.TotalWidth, config.Wrap).rows {
+			sb.WriteString(row + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// renderHunk formats a hunk side by side, or in one column with WithUnified
+func renderHunk(fileName string, h Hunk, opts ...SideBySideOption) string {
+	if NewSideBySideConfig(opts...).Unified {
+		return RenderUnifiedHunk(fileName, h, opts...)
+	}
+	return RenderSideBySideHunk(fileName, h, opts...)
+}
+
 // RenderSideBySideHunk formats a hunk for side-by-side display
 func RenderSideBySideHunk(fileName string, h Hunk, opts ...SideBySideOption) string {
 	// Apply options to create the configuration
@@ -823,29 +1039,106 @@ func RenderSideBySideHunk(fileName string, h Hunk, opts ...SideBySideOption) str
 	rightWidth := config.TotalWidth - colWidth
 	var sb strings.Builder
 	for _, p := range pairs {
-		leftStr := renderLeftColumn(fileName, p.left, leftWidth)
-		rightStr := renderRightColumn(fileName, p.right, rightWidth)
-		sb.WriteString(leftStr + rightStr + "\n")
+		left := renderLeftColumn(fileName, p.left, leftWidth, config.Wrap)
+		right := renderRightColumn(fileName, p.right, rightWidth, config.Wrap)
+		for i := range max(len(left.rows), len(right.rows)) {
+			sb.WriteString(left.row(i) + right.row(i) + "\n")
+		}
 	}
 
 	return sb.String()
 }
 
-// FormatDiff creates a side-by-side formatted view of a diff
+// FormatDiff creates a side-by-side formatted view of a diff, or a unified
+// one with WithUnified
 func FormatDiff(diffText string, opts ...SideBySideOption) (string, error) {
 	diffResult, err := ParseUnifiedDiff(diffText)
 	if err != nil {
 		return "", err
 	}
 
+	if diffResult.TotalLines > 0 {
+		return formatLargeFileDiff(diffResult, opts...), nil
+	}
+
+	// New files only have a name on the new side
+	fileName := cmp.Or(diffResult.OldFile, diffResult.NewFile)
 	var sb strings.Builder
 	for _, h := range diffResult.Hunks {
-		sb.WriteString(RenderSideBySideHunk(diffResult.OldFile, h, opts...))
+		sb.WriteString(renderHunk(fileName, h, opts...))
 	}
 
 	return sb.String(), nil
 }
 
+// formatLargeFileDiff renders the hunks of a large file under a header with
+// the size of the file, marking the unchanged lines left out around them.
+func formatLargeFileDiff(diffResult DiffResult, opts ...SideBySideOption) string {
+	config := NewSideBySideConfig(opts...)
+	t := theme.CurrentTheme()
+	noteStyle := lipgloss.NewStyle().
+		Width(config.TotalWidth).
+		Foreground(t.TextMuted()).
+		Background(t.DiffContextBg())
+	omitted := func(lines int) string {
+		if lines <= 0 {
+			return ""
+		}
+		unit := "lines"
+		if lines == 1 {
+			unit = "line"
+		}
+		return noteStyle.Align(lipgloss.Center).Render(fmt.Sprintf("⋯ %d unchanged %s ⋯", lines, unit)) + "\n"
+	}
+
+	var sb strings.Builder
+	regions := "regions"
+	if len(diffResult.Hunks) == 1 {
+		regions = "region"
+	}
+	sb.WriteString(noteStyle.Bold(true).Render(fmt.Sprintf("%s: %d lines, %s, showing %d changed %s",
+		diffResult.NewFile, diffResult.TotalLines, formatBytes(diffResult.TotalBytes), len(diffResult.Hunks), regions)) + "\n")
+
+	// next is the first line of the new file after the previous hunk
+	next := 1
+	for _, h := range diffResult.Hunks {
+		if start, end := newLineRange(h); start > 0 {
+			sb.WriteString(omitted(start - next))
+			next = max(next, end)
+		}
+		sb.WriteString(renderHunk(diffResult.OldFile, h, opts...))
+	}
+	sb.WriteString(omitted(diffResult.TotalLines - next + 1))
+	return sb.String()
+}
+
+// newLineRange returns the first line of a hunk in the new file and the line
+// after it, or zeros if the hunk has no lines in the new file.
+func newLineRange(h Hunk) (start, end int) {
+	for _, line := range h.Lines {
+		if line.NewLineNo == 0 {
+			continue
+		}
+		if start == 0 {
+			start = line.NewLineNo
+		}
+		end = line.NewLineNo + 1
+	}
+	return start, end
+}
+
+// formatBytes formats a size as B, KB or MB.
+func formatBytes(size int) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
 // GenerateDiff creates a unified diff from two file contents
 func GenerateDiff(beforeContent, afterContent, fileName string) (string, int, int) {
 	// remove the cwd prefix and ensure consistent path format
@@ -855,10 +1148,21 @@ func GenerateDiff(beforeContent, afterContent, fileName string) (string, int, in
 	fileName = strings.TrimPrefix(fileName, "/")
 
 	var (
-		unified   = udiff.Unified("a/"+fileName, "b/"+fileName, beforeContent, afterContent)
 		additions = 0
 		removals  = 0
 	)
+	edits := udiff.Strings(beforeContent, afterContent)
+	unified, err := udiff.ToUnified("a/"+fileName, "b/"+fileName, beforeContent, edits, contextLines())
+	if err != nil {
+		// Can't happen: the edits come from the same content
+		return "", 0, 0
+	}
+
+	// Only the changed regions of a large file are worth showing, with its size
+	// so that the formatter can mark the lines left out
+	if lines := countLines(afterContent); unified != "" && max(lines, countLines(beforeContent)) > LargeFileLines {
+		unified = fmt.Sprintf("# large file: %d lines, %d bytes\n%s", lines, len(afterContent), unified)
+	}
 
 	lines := strings.SplitSeq(unified, "\n")
 	for line := range lines {
@@ -871,3 +1175,22 @@ func GenerateDiff(beforeContent, afterContent, fileName string) (string, int, in
 
 	return unified, additions, removals
 }
+
+// contextLines returns the number of unchanged lines kept around each change
+// of a diff, from tui.diffContextLines.
+func contextLines() int {
+	if cfg := config.Get(); cfg != nil && cfg.TUI.DiffContextLines >= 0 {
+		return cfg.TUI.DiffContextLines
+	}
+	return config.DefaultDiffContextLines
+}
+
+// countLines returns the number of lines of content, counting a last line
+// without a newline.
+func countLines(content string) int {
+	lines := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	return lines
+}
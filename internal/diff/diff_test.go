@@ -0,0 +1,242 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/tui/theme"
+)
+
+func TestGetColorFollowsForcedBackground(t *testing.T) {
+	previous := lipgloss.HasDarkBackground()
+	t.Cleanup(func() { lipgloss.SetHasDarkBackground(previous) })
+
+	color := lipgloss.AdaptiveColor{Dark: "#ffffff", Light: "#000000"}
+
+	theme.ForceBackground(true)
+	assert.Equal(t, "#ffffff", getColor(color))
+
+	theme.ForceBackground(false)
+	assert.Equal(t, "#000000", getColor(color))
+
+	// Terminal reports no longer change the background once it is forced
+	assert.False(t, theme.SetDarkBackground(true))
+	assert.Equal(t, "#000000", getColor(color))
+}
+
+func TestLargeFileDiffIsCompact(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	require.NoError(t, theme.SetTheme("cryoncode"))
+
+	var before strings.Builder
+	for i := 1; i <= 2000; i++ {
+		fmt.Fprintf(&before, "line %d\n", i)
+	}
+	after := strings.Replace(before.String(), "line 1200\n", "changed 1200\n", 1)
+
+	unified, additions, removals := GenerateDiff(before.String(), after, "big.txt")
+	assert.Equal(t, 1, additions)
+	assert.Equal(t, 1, removals)
+
+	result, err := ParseUnifiedDiff(unified)
+	require.NoError(t, err)
+	assert.Equal(t, 2000, result.TotalLines)
+	assert.Equal(t, len(after), result.TotalBytes)
+	require.Len(t, result.Hunks, 1)
+
+	// The change keeps its context on both sides
+	var contents []string
+	for _, line := range result.Hunks[0].Lines {
+		contents = append(contents, strings.TrimSpace(line.Content))
+	}
+	assert.Equal(t, []string{"line 1197", "line 1198", "line 1199", "line 1200", "changed 1200", "line 1201", "line 1202", "line 1203"}, contents)
+
+	formatted, err := FormatDiff(unified, WithTotalWidth(120))
+	require.NoError(t, err)
+	plain := ansi.Strip(formatted)
+	assert.Contains(t, plain, "big.txt: 2000 lines, 18.5 KB, showing 1 changed region")
+	assert.Contains(t, plain, "⋯ 1196 unchanged lines ⋯")
+	assert.Contains(t, plain, "⋯ 797 unchanged lines ⋯")
+	assert.Less(t, strings.Count(plain, "\n"), 20)
+}
+
+func TestSmallFileDiffHasNoSizeHeader(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	unified, _, _ := GenerateDiff("a\nb\nc\n", "a\nB\nc\n", "small.txt")
+	assert.NotContains(t, unified, "# large file")
+	result, err := ParseUnifiedDiff(unified)
+	require.NoError(t, err)
+	assert.Zero(t, result.TotalLines)
+}
+
+func TestGenerateDiffUsesConfiguredContext(t *testing.T) {
+	cfg, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	t.Cleanup(func() { cfg.TUI.DiffContextLines = config.DefaultDiffContextLines })
+
+	var before strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&before, "line %d\n", i)
+	}
+	after := strings.Replace(before.String(), "line 10\n", "changed 10\n", 1)
+	contextLines := func() int {
+		unified, _, _ := GenerateDiff(before.String(), after, "context.txt")
+		result, err := ParseUnifiedDiff(unified)
+		require.NoError(t, err)
+		require.Len(t, result.Hunks, 1)
+		count := 0
+		for _, line := range result.Hunks[0].Lines {
+			if line.Kind == LineContext {
+				count++
+			}
+		}
+		return count
+	}
+
+	assert.Equal(t, 2*config.DefaultDiffContextLines, contextLines())
+	cfg.TUI.DiffContextLines = 6
+	assert.Equal(t, 12, contextLines())
+	cfg.TUI.DiffContextLines = 0
+	assert.Equal(t, 0, contextLines())
+}
+
+func TestRenderSideBySideHunkWrap(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	require.NoError(t, theme.SetTheme("cryoncode"))
+
+	long := strings.Repeat("word ", 20)
+	hunk := Hunk{Lines: []DiffLine{
+		{Kind: LineRemoved, OldLineNo: 7, Content: long + "old"},
+		{Kind: LineAdded, NewLineNo: 7, Content: "short"},
+	}}
+
+	// By default long lines are truncated to one row
+	rows := strings.Split(strings.TrimSuffix(ansi.Strip(RenderSideBySideHunk("file.txt", hunk, WithTotalWidth(80))), "\n"), "\n")
+	require.Len(t, rows, 1)
+	assert.Contains(t, rows[0], "...")
+
+	rows = strings.Split(strings.TrimSuffix(ansi.Strip(RenderSideBySideHunk("file.txt", hunk, WithTotalWidth(80), WithWrap(true))), "\n"), "\n")
+	require.Greater(t, len(rows), 1)
+	for _, row := range rows {
+		assert.Equal(t, 80, ansi.StringWidth(row))
+	}
+	joined := ""
+	for i, row := range rows {
+		left, right := row[:40], row[40:]
+		if i == 0 {
+			assert.True(t, strings.HasPrefix(left, "     7 -"), left)
+			assert.True(t, strings.HasPrefix(right, "     7 +"), right)
+			assert.Contains(t, right, "short")
+		} else {
+			assert.True(t, strings.HasPrefix(left, "        "), left)
+			assert.Empty(t, strings.TrimSpace(right))
+		}
+		joined += left[8:]
+	}
+	assert.NotContains(t, joined, "...")
+	assert.Equal(t, strings.Fields(long+"old"), strings.Fields(joined))
+}
+
+func TestFormatDiffUnified(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	require.NoError(t, theme.SetTheme("cryoncode"))
+
+	unified, _, _ := GenerateDiff("a\nb\nc\n", "a\nB\nc\n", "small.txt")
+	formatted, err := FormatDiff(unified, WithTotalWidth(60), WithUnified(true))
+	require.NoError(t, err)
+
+	rows := strings.Split(strings.TrimSuffix(ansi.Strip(formatted), "\n"), "\n")
+	for i, row := range rows {
+		assert.Equal(t, 60, ansi.StringWidth(row))
+		rows[i] = strings.TrimRight(row, " ")
+	}
+	assert.Equal(t, []string{
+		"     1      1   a",
+		"     2        - b",
+		"            2 + B",
+		"     3      3   c",
+	}, rows)
+}
+
+func TestViewOptions(t *testing.T) {
+	cfg, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	t.Cleanup(func() { cfg.TUI.DiffView = config.DiffViewAuto })
+
+	unified := func(width int) bool {
+		return NewSideBySideConfig(ViewOptions(width)...).Unified
+	}
+	assert.True(t, unified(UnifiedBelowWidth-1))
+	assert.False(t, unified(UnifiedBelowWidth))
+
+	cfg.TUI.DiffView = config.DiffViewSplit
+	assert.False(t, unified(40))
+	cfg.TUI.DiffView = config.DiffViewUnified
+	assert.True(t, unified(200))
+	assert.Equal(t, 200, NewSideBySideConfig(ViewOptions(200)...).TotalWidth)
+}
+
+func TestSplitFileDiffs(t *testing.T) {
+	gitDiff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package old
++package main
+diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+index 4444444..0000000
+--- a/gone.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+--- a/comment
+-package main
+`
+	files := SplitFileDiffs(gitDiff)
+	require.Len(t, files, 3)
+	assert.True(t, strings.HasPrefix(files[1], "diff --git a/new.go b/new.go\n"))
+
+	changed, err := ParseUnifiedDiff(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "main.go", changed.NewFile)
+	require.Len(t, changed.Hunks, 1)
+	assert.Len(t, changed.Hunks[0].Lines, 2)
+
+	created, err := ParseUnifiedDiff(files[1])
+	require.NoError(t, err)
+	assert.Equal(t, "", created.OldFile)
+	assert.Equal(t, "new.go", created.NewFile)
+	require.Len(t, created.Hunks, 1)
+	assert.Equal(t, LineAdded, created.Hunks[0].Lines[0].Kind)
+
+	deleted, err := ParseUnifiedDiff(files[2])
+	require.NoError(t, err)
+	assert.Equal(t, "gone.go", deleted.OldFile)
+	require.Len(t, deleted.Hunks, 1)
+	assert.Equal(t, []DiffLine{
+		{OldLineNo: 1, Kind: LineRemoved, Content: "-- a/comment"},
+		{OldLineNo: 2, Kind: LineRemoved, Content: "package main"},
+	}, deleted.Hunks[0].Lines)
+
+	assert.Equal(t, []string{"--- a/x\n"}, SplitFileDiffs("--- a/x\n"))
+	assert.Empty(t, SplitFileDiffs(""))
+}
@@ -0,0 +1,164 @@
+package fileutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const trashEntryFile = "entry.json"
+
+// TrashEntry describes a file that was moved to a trash directory.
+type TrashEntry struct {
+	ID           string    `json:"id"`
+	OriginalPath string    `json:"originalPath"`
+	DeletedAt    time.Time `json:"deletedAt"`
+}
+
+// MoveToTrash moves the file at path into trashDir so it can be restored later.
+func MoveToTrash(trashDir, path string) (TrashEntry, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	if info.IsDir() {
+		return TrashEntry{}, fmt.Errorf("%s is a directory", absPath)
+	}
+
+	entry := TrashEntry{
+		OriginalPath: absPath,
+		DeletedAt:    time.Now(),
+	}
+	entryDir, err := newTrashEntryDir(trashDir, entry.DeletedAt)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	entry.ID = filepath.Base(entryDir)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	if err := os.WriteFile(filepath.Join(entryDir, trashEntryFile), data, 0o644); err != nil {
+		os.RemoveAll(entryDir)
+		return TrashEntry{}, err
+	}
+	if err := moveFile(absPath, filepath.Join(entryDir, filepath.Base(absPath))); err != nil {
+		os.RemoveAll(entryDir)
+		return TrashEntry{}, fmt.Errorf("failed to move %s to trash: %w", absPath, err)
+	}
+	return entry, nil
+}
+
+// ListTrash returns the entries in trashDir, oldest first.
+func ListTrash(trashDir string) ([]TrashEntry, error) {
+	dirs, err := os.ReadDir(trashDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TrashEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(trashDir, dir.Name(), trashEntryFile))
+		if err != nil {
+			continue
+		}
+		var entry TrashEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.ID = dir.Name()
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+// RestoreFromTrash moves a trashed file back to its original path. It refuses
+// to overwrite a file that has since been created at that path.
+func RestoreFromTrash(trashDir string, entry TrashEntry) error {
+	if _, err := os.Stat(entry.OriginalPath); err == nil {
+		return fmt.Errorf("%s already exists", entry.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.OriginalPath), 0o755); err != nil {
+		return err
+	}
+	entryDir := filepath.Join(trashDir, entry.ID)
+	if err := moveFile(filepath.Join(entryDir, filepath.Base(entry.OriginalPath)), entry.OriginalPath); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", entry.OriginalPath, err)
+	}
+	return os.RemoveAll(entryDir)
+}
+
+// PurgeTrash permanently deletes everything in trashDir.
+func PurgeTrash(trashDir string) error {
+	return os.RemoveAll(trashDir)
+}
+
+func newTrashEntryDir(trashDir string, deletedAt time.Time) (string, error) {
+	if err := os.MkdirAll(trashDir, 0o755); err != nil {
+		return "", err
+	}
+	id := deletedAt.UnixNano()
+	for {
+		dir := filepath.Join(trashDir, strconv.FormatInt(id, 10))
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", err
+		}
+		id++
+	}
+}
+
+// moveFile renames src to dst, falling back to copy and remove when they are on
+// different filesystems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
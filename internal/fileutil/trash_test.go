@@ -0,0 +1,93 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveToTrash(t *testing.T) {
+	dir := t.TempDir()
+	trashDir := filepath.Join(dir, "trash", "session")
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	entry, err := MoveToTrash(trashDir, path)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, path)
+	assert.Equal(t, path, entry.OriginalPath)
+	trashed, err := os.ReadFile(filepath.Join(trashDir, entry.ID, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(trashed))
+
+	entries, err := ListTrash(trashDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, entry.ID, entries[0].ID)
+	assert.Equal(t, path, entries[0].OriginalPath)
+}
+
+func TestRestoreFromTrash(t *testing.T) {
+	dir := t.TempDir()
+	trashDir := filepath.Join(dir, "trash", "session")
+	path := filepath.Join(dir, "pkg", "util.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("package pkg\n"), 0o600))
+
+	entry, err := MoveToTrash(trashDir, path)
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(filepath.Dir(path)))
+
+	require.NoError(t, RestoreFromTrash(trashDir, entry))
+
+	restored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(restored))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	entries, err := ListTrash(trashDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRestoreFromTrashDoesNotOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	trashDir := filepath.Join(dir, "trash", "session")
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+	entry, err := MoveToTrash(trashDir, path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("new\n"), 0o644))
+
+	assert.Error(t, RestoreFromTrash(trashDir, entry))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(content))
+
+	entries, err := ListTrash(trashDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	dir := t.TempDir()
+	trashDir := filepath.Join(dir, "trash", "session")
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	_, err := MoveToTrash(trashDir, path)
+	require.NoError(t, err)
+	require.NoError(t, PurgeTrash(trashDir))
+
+	assert.NoDirExists(t, trashDir)
+	entries, err := ListTrash(trashDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
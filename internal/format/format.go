@@ -15,6 +15,10 @@ const (
 
 	// JSON format outputs the AI response wrapped in a JSON object.
 	JSON OutputFormat = "json"
+
+	// NDJSON format streams the events of the run as they happen, one JSON
+	// object per line.
+	NDJSON OutputFormat = "ndjson"
 )
 
 // String returns the string representation of the OutputFormat
@@ -26,6 +30,7 @@ func (f OutputFormat) String() string {
 var SupportedFormats = []string{
 	string(Text),
 	string(JSON),
+	string(NDJSON),
 }
 
 // Parse converts a string to an OutputFormat
@@ -37,6 +42,8 @@ func Parse(s string) (OutputFormat, error) {
 		return Text, nil
 	case string(JSON):
 		return JSON, nil
+	case string(NDJSON):
+		return NDJSON, nil
 	default:
 		return "", fmt.Errorf("invalid format: %s", s)
 	}
@@ -52,41 +59,96 @@ func IsValid(s string) bool {
 func GetHelpText() string {
 	return fmt.Sprintf(`Supported output formats:
 - %s: Plain text output (default)
-- %s: Output wrapped in a JSON object`,
-		Text, JSON)
+- %s: JSON object with the response, tool calls and token usage
+- %s: One JSON object per line for each event of the run, as it happens`,
+		Text, JSON, NDJSON)
+}
+
+// Result is the outcome of a non-interactive run.
+type Result struct {
+	Response  string     `json:"response"`
+	SessionID string     `json:"sessionId,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	ToolCalls []ToolCall `json:"toolCalls"`
+	Usage     Usage      `json:"usage"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ToolCall is a tool call made during a non-interactive run, with its result.
+type ToolCall struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+	IsError bool   `json:"isError,omitempty"`
+}
+
+// Usage is the token usage and cost of a non-interactive run.
+type Usage struct {
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// StreamEvent is a line of the NDJSON output. Type names the event; the
+// other fields are set as they apply to it.
+type StreamEvent struct {
+	Type         string     `json:"type"`
+	SessionID    string     `json:"sessionId,omitempty"`
+	Content      string     `json:"content,omitempty"`
+	ToolCall     *ToolCall  `json:"toolCall,omitempty"`
+	ToolCalls    []ToolCall `json:"toolCalls,omitempty"`
+	FinishReason string     `json:"finishReason,omitempty"`
+	Usage        *Usage     `json:"usage,omitempty"`
+	Error        string     `json:"error,omitempty"`
 }
 
-// FormatOutput formats the AI response according to the specified format
-func FormatOutput(content string, formatStr string) string {
+// Event types of the NDJSON output that are not named after an agent or
+// provider event.
+const (
+	StreamEventToolResult = "tool_result"
+	StreamEventResponse   = "response"
+	StreamEventError      = "error"
+)
+
+// FormatStreamEvent returns an event as a line of NDJSON, without the
+// trailing newline.
+func FormatStreamEvent(event StreamEvent) string {
+	jsonBytes, err := json.Marshal(event)
+	if err != nil {
+		jsonBytes, _ = json.Marshal(StreamEvent{Type: StreamEventError, Error: err.Error()})
+	}
+	return string(jsonBytes)
+}
+
+// FormatOutput formats the result of a run according to the specified format.
+// The text format is the response alone.
+func FormatOutput(result Result, formatStr string) string {
 	format, err := Parse(formatStr)
 	if err != nil {
 		// Default to text format on error
-		return content
+		format = Text
 	}
 
 	switch format {
 	case JSON:
-		return formatAsJSON(content)
+		return formatAsJSON(result)
 	case Text:
 		fallthrough
 	default:
-		return content
+		return result.Response
 	}
 }
 
-// formatAsJSON wraps the content in a simple JSON object
-func formatAsJSON(content string) string {
-	// Use the JSON package to properly escape the content
-	response := struct {
-		Response string `json:"response"`
-	}{
-		Response: content,
+// formatAsJSON writes the whole result as a JSON object
+func formatAsJSON(result Result) string {
+	if result.ToolCalls == nil {
+		result.ToolCalls = []ToolCall{}
 	}
-
-	jsonBytes, err := json.MarshalIndent(response, "", "  ")
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		// In case of an error, return a manually formatted JSON
-		jsonEscaped := strings.Replace(content, "\\", "\\\\", -1)
+		jsonEscaped := strings.Replace(result.Response, "\\", "\\\\", -1)
 		jsonEscaped = strings.Replace(jsonEscaped, "\"", "\\\"", -1)
 		jsonEscaped = strings.Replace(jsonEscaped, "\n", "\\n", -1)
 		jsonEscaped = strings.Replace(jsonEscaped, "\r", "\\r", -1)
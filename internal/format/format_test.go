@@ -0,0 +1,36 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOutput(t *testing.T) {
+	result := Result{
+		Response:  "The tests pass now.",
+		SessionID: "session",
+		Model:     "gpt-4.1",
+		ToolCalls: []ToolCall{
+			{Name: "bash", Input: `{"command":"go test ./..."}`, Output: "ok"},
+			{Name: "view", Input: `{"file_path":"missing.go"}`, Output: "file not found", IsError: true},
+		},
+		Usage: Usage{PromptTokens: 5120, CompletionTokens: 230, Cost: 0.0188},
+	}
+
+	assert.Equal(t, "The tests pass now.", FormatOutput(result, "text"))
+	assert.Equal(t, "The tests pass now.", FormatOutput(result, "unknown"))
+
+	var decoded Result
+	require.NoError(t, json.Unmarshal([]byte(FormatOutput(result, "json")), &decoded))
+	assert.Equal(t, result, decoded)
+}
+
+func TestFormatOutputJSONWithoutToolCalls(t *testing.T) {
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(FormatOutput(Result{Response: "hi", Error: "boom"}, "json")), &decoded))
+	assert.Equal(t, []any{}, decoded["toolCalls"])
+	assert.Equal(t, "boom", decoded["error"])
+}
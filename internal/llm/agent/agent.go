@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/llm/models"
@@ -22,8 +25,10 @@ import (
 
 // Common errors
 var (
-	ErrRequestCancelled = errors.New("request cancelled by user")
-	ErrSessionBusy      = errors.New("session is currently processing another request")
+	ErrRequestCancelled        = errors.New("request cancelled by user")
+	ErrPromptTooLarge          = errors.New("prompt is too large for the model's context window")
+	ErrSessionBusy             = errors.New("session is currently processing another request")
+	ErrAttachmentsNotSupported = errors.New("model does not support image attachments")
 )
 
 type AgentEventType string
@@ -32,6 +37,16 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	AgentEventTypeUsage     AgentEventType = "usage"
+	// AgentEventTypePromptTokens carries the estimated size of the prompt of
+	// a request, before it is sent.
+	AgentEventTypePromptTokens AgentEventType = "prompt_tokens"
+	// AgentEventTypeStream carries an event of the provider's response stream,
+	// other than usage updates and errors, which have their own events.
+	AgentEventTypeStream AgentEventType = "stream"
+	// AgentEventTypeToolResults carries the message with the results of the
+	// tool calls of a response.
+	AgentEventTypeToolResults AgentEventType = "tool_results"
 )
 
 type AgentEvent struct {
@@ -39,10 +54,20 @@ type AgentEvent struct {
 	Message message.Message
 	Error   error
 
-	// When summarizing
+	// When summarizing, or reporting usage
 	SessionID string
 	Progress  string
 	Done      bool
+
+	// Running usage and cost of the request being generated
+	Usage provider.TokenUsage
+	Cost  float64
+
+	// Estimated tokens of the prompt of the request about to be sent
+	PromptTokens int64
+
+	// The provider event of a stream event
+	Stream *provider.ProviderEvent
 }
 
 type Service interface {
@@ -54,6 +79,7 @@ type Service interface {
 	IsBusy() bool
 	Update(agentName config.AgentName, modelID models.ModelID) (models.Model, error)
 	Summarize(ctx context.Context, sessionID string) error
+	ContinueInNewSession(ctx context.Context, sessionID string) error
 }
 
 type agent struct {
@@ -62,8 +88,12 @@ type agent struct {
 	messages message.Service
 
 	tools    []tools.BaseTool
+	mcpTools bool // Whether MCP tools registered after startup are offered
 	provider provider.Provider
 
+	name               config.AgentName
+	systemPromptTokens int64 // Estimated tokens of the provider's system prompt
+
 	titleProvider     provider.Provider
 	summarizeProvider provider.Provider
 
@@ -102,9 +132,13 @@ func NewAgent(
 		messages:          messages,
 		sessions:          sessions,
 		tools:             agentTools,
+		mcpTools:          agentName == config.AgentCoder,
 		titleProvider:     titleProvider,
 		summarizeProvider: summarizeProvider,
 		activeRequests:    sync.Map{},
+
+		name:               agentName,
+		systemPromptTokens: systemPromptTokens(agentName, agentProvider.Model()),
 	}
 
 	return agent, nil
@@ -188,6 +222,33 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 	return err
 }
 
+// titleGenerationContent reports whether the incoming user message reaches the
+// configured title trigger and, if so, returns the prompts to title from.
+func titleGenerationContent(titleCfg config.TitleConfig, msgs []message.Message, content string) (string, bool) {
+	var prompts []string
+	for _, msg := range msgs {
+		if msg.Role == message.User {
+			prompts = append(prompts, msg.Content().String())
+		}
+	}
+	prompts = append(prompts, content)
+
+	switch titleCfg.Trigger {
+	case config.TitleTriggerNever:
+		return "", false
+	case config.TitleTriggerAfterMessages:
+		if len(prompts) != max(titleCfg.AfterMessages, 1) {
+			return "", false
+		}
+		return strings.Join(prompts, "\n\n"), true
+	default:
+		if len(msgs) != 0 {
+			return "", false
+		}
+		return content, true
+	}
+}
+
 func (a *agent) err(err error) AgentEvent {
 	return AgentEvent{
 		Type:  AgentEventTypeError,
@@ -196,8 +257,9 @@ func (a *agent) err(err error) AgentEvent {
 }
 
 func (a *agent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
-	if !a.provider.Model().SupportsAttachments && attachments != nil {
-		attachments = nil
+	content, attachmentParts, err := prepareAttachments(a.provider.Model(), content, attachments)
+	if err != nil {
+		return nil, err
 	}
 	events := make(chan AgentEvent)
 	if a.IsSessionBusy(sessionID) {
@@ -212,10 +274,6 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 		defer logging.RecoverPanic("agent.Run", func() {
 			events <- a.err(fmt.Errorf("panic while running the agent"))
 		})
-		var attachmentParts []message.ContentPart
-		for _, attachment := range attachments {
-			attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
-		}
 		result := a.processGeneration(genCtx, sessionID, content, attachmentParts)
 		if result.Error != nil && !errors.Is(result.Error, ErrRequestCancelled) && !errors.Is(result.Error, context.Canceled) {
 			logging.ErrorPersist(result.Error.Error())
@@ -232,17 +290,18 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 
 func (a *agent) processGeneration(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
 	cfg := config.Get()
-	// List existing messages; if none, start title generation asynchronously.
+	// List existing messages; start title generation asynchronously when the
+	// configured trigger is reached.
 	msgs, err := a.messages.List(ctx, sessionID)
 	if err != nil {
 		return a.err(fmt.Errorf("failed to list messages: %w", err))
 	}
-	if len(msgs) == 0 {
+	if titleContent, ok := titleGenerationContent(cfg.Title, msgs, content); ok {
 		go func() {
 			defer logging.RecoverPanic("agent.Run", func() {
 				logging.ErrorPersist("panic while generating title")
 			})
-			titleErr := a.generateTitle(context.Background(), sessionID, content)
+			titleErr := a.generateTitle(context.Background(), sessionID, titleContent)
 			if titleErr != nil {
 				logging.ErrorPersist(fmt.Sprintf("failed to generate title: %v", titleErr))
 			}
@@ -266,6 +325,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		}
 	}
 
+	attachmentParts = append(attachmentParts, referencedFiles(content, cfg.WorkingDir, cfg.InlineFileMaxBytes)...)
 	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
 	if err != nil {
 		return a.err(fmt.Errorf("failed to create user message: %w", err))
@@ -273,7 +333,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	// Append the new user message to the conversation history.
 	msgHistory := append(msgs, userMsg)
 
-	for {
+	for firstRequest := true; ; firstRequest = false {
 		// Check for cancellation before each iteration
 		select {
 		case <-ctx.Done():
@@ -281,13 +341,21 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
-		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, a.contextHistory(msgHistory))
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				agentMessage.AddFinish(message.FinishReasonCanceled)
 				a.messages.Update(context.Background(), agentMessage)
 				return a.err(ErrRequestCancelled)
 			}
+			if errors.Is(err, ErrPromptTooLarge) && firstRequest {
+				// Nothing was sent, so the message is dropped rather than left
+				// to make every later prompt too large as well
+				if deleteErr := a.messages.Delete(context.Background(), userMsg.ID); deleteErr != nil {
+					logging.Warn("Failed to delete the message that was too large to send", "error", deleteErr)
+				}
+				return a.err(fmt.Errorf("message not sent: %w", err))
+			}
 			return a.err(fmt.Errorf("failed to process events: %w", err))
 		}
 		if cfg.Debug {
@@ -310,6 +378,40 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	}
 }
 
+// availableTools returns the agent's tools, with the tools of MCP servers that
+// reconnected after the agent was created in place of the ones they had before.
+func (a *agent) availableTools() []tools.BaseTool {
+	available := a.tools
+	if a.mcpTools {
+		if reconnected := reconnectedMcpTools(); len(reconnected) > 0 {
+			available = slices.DeleteFunc(slices.Clone(a.tools), func(tool tools.BaseTool) bool {
+				m, ok := tool.(*mcpTool)
+				return ok && reconnected[m.mcpName] != nil
+			})
+			for _, serverTools := range reconnected {
+				available = append(available, serverTools...)
+			}
+		}
+	}
+	return orderTools(available, config.Get().Tools.Order)
+}
+
+// contextHistory returns the part of the history sent to the model. With the
+// truncate context strategy the oldest turns are dropped to fit the configured
+// share of the model's context window.
+func (a *agent) contextHistory(msgHistory []message.Message) []message.Message {
+	cfg := config.Get()
+	model := a.provider.Model()
+	if cfg.ContextStrategy != config.ContextStrategyTruncate || model.ContextWindow <= 0 {
+		return msgHistory
+	}
+	history := truncateHistory(model, msgHistory, int64(float64(model.ContextWindow)*cfg.ContextFraction))
+	if len(history) < len(msgHistory) {
+		logging.Debug("Truncated conversation history", "dropped", len(msgHistory)-len(history), "kept", len(history))
+	}
+	return history
+}
+
 func (a *agent) createUserMessage(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) (message.Message, error) {
 	parts := []message.ContentPart{message.TextContent{Text: content}}
 	parts = append(parts, attachmentParts...)
@@ -321,7 +423,11 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+	agentTools := a.availableTools()
+	if err := a.checkPromptSize(sessionID, msgHistory, agentTools); err != nil {
+		return message.Message{}, nil, err
+	}
+	eventChan := a.provider.StreamResponse(ctx, msgHistory, agentTools)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
@@ -335,8 +441,33 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 	// Add the session and message ID into the context if needed by tools.
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
 
-	// Process each event in the stream.
+	// Process each event in the stream. Deltas can split a multi-byte character
+	// across chunks, so incomplete trailing bytes are held back until the next
+	// chunk completes them.
+	var content, reasoning utf8DeltaBuffer
 	for event := range eventChan {
+		switch event.Type {
+		case provider.EventContentDelta:
+			if event.Content = content.Write(event.Content); event.Content == "" {
+				continue
+			}
+		case provider.EventThinkingDelta:
+			if event.Content = reasoning.Write(event.Content); event.Content == "" {
+				continue
+			}
+		case provider.EventComplete:
+			if pending := reasoning.Flush(); pending != "" {
+				assistantMsg.AppendReasoningContent(pending)
+				a.publishStream(sessionID, provider.ProviderEvent{Type: provider.EventThinkingDelta, Content: pending})
+			}
+			if pending := content.Flush(); pending != "" {
+				assistantMsg.AppendContent(pending)
+				a.publishStream(sessionID, provider.ProviderEvent{Type: provider.EventContentDelta, Content: pending})
+			}
+		}
+		if event.Type != provider.EventUsageUpdate && event.Type != provider.EventError {
+			a.publishStream(sessionID, event)
+		}
 		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
 			a.finishMessage(ctx, &assistantMsg, message.FinishReasonCanceled)
 			return assistantMsg, nil, processErr
@@ -365,7 +496,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for _, availableTool := range a.tools {
+			for _, availableTool := range agentTools {
 				if availableTool.Info().Name == toolCall.Name {
 					tool = availableTool
 					break
@@ -387,11 +518,30 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				}
 				continue
 			}
-			toolResult, toolErr := tool.Run(ctx, tools.ToolCall{
+			input, err := tools.RepairJSON(toolCall.Input)
+			if err != nil {
+				logging.Warn("Rejected truncated tool call arguments", "tool", toolCall.Name, "model", a.provider.Model().ID)
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    fmt.Sprintf("%s, probably because the response reached its output token limit. Nothing was run; send the tool call again, splitting large content over several calls", err),
+					IsError:    true,
+				}
+				continue
+			}
+			if input != toolCall.Input {
+				logging.Info("Repaired malformed tool call arguments", "tool", toolCall.Name, "model", a.provider.Model().ID)
+				// Store the arguments the tool actually runs with
+				toolCall.Input = input
+				assistantMsg.AddToolCall(toolCall)
+				if err := a.messages.Update(ctx, assistantMsg); err != nil {
+					logging.Error("Failed to store repaired tool call arguments", "error", err)
+				}
+			}
+			toolResult, toolErr := runToolWithRetry(ctx, tool, tools.ToolCall{
 				ID:    toolCall.ID,
 				Name:  toolCall.Name,
-				Input: toolCall.Input,
-			})
+				Input: input,
+			}, toolRetryPolicy(config.Get().Tools.Retry, toolCall.Name))
 			if toolErr != nil {
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
 					toolResults[i] = message.ToolResult{
@@ -433,15 +583,60 @@ out:
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create cancelled tool message: %w", err)
 	}
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:      AgentEventTypeToolResults,
+		SessionID: sessionID,
+		Message:   msg,
+	})
 
 	return assistantMsg, &msg, err
 }
 
+// publishStream publishes an event of the provider's response stream, for
+// subscribers that follow a response as it is generated.
+func (a *agent) publishStream(sessionID string, event provider.ProviderEvent) {
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:      AgentEventTypeStream,
+		SessionID: sessionID,
+		Stream:    &event,
+	})
+}
+
 func (a *agent) finishMessage(ctx context.Context, msg *message.Message, finishReson message.FinishReason) {
 	msg.AddFinish(finishReson)
 	_ = a.messages.Update(ctx, *msg)
 }
 
+// utf8DeltaBuffer holds back an incomplete multi-byte character at the end of a
+// streamed delta until a later delta completes it.
+type utf8DeltaBuffer struct {
+	pending string
+}
+
+// Write returns the text that is safe to emit after appending delta.
+func (b *utf8DeltaBuffer) Write(delta string) string {
+	text := b.pending + delta
+	cut := len(text)
+	for i := len(text) - 1; i >= 0 && i >= len(text)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(text[i]) {
+			if !utf8.FullRuneInString(text[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	b.pending = text[cut:]
+	return text[:cut]
+}
+
+// Flush returns whatever is still held back, even if it is not a complete
+// character.
+func (b *utf8DeltaBuffer) Flush() string {
+	pending := b.pending
+	b.pending = ""
+	return pending
+}
+
 func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
 	select {
 	case <-ctx.Done():
@@ -472,6 +667,14 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 	case provider.EventToolUseStop:
 		assistantMsg.FinishToolCall(event.ToolCall.ID)
 		return a.messages.Update(ctx, *assistantMsg)
+	case provider.EventUsageUpdate:
+		a.Publish(pubsub.CreatedEvent, AgentEvent{
+			Type:      AgentEventTypeUsage,
+			SessionID: sessionID,
+			Usage:     *event.Usage,
+			Cost:      a.provider.Model().Cost(*event.Usage),
+		})
+		return nil
 	case provider.EventError:
 		if errors.Is(event.Error, context.Canceled) {
 			logging.InfoPersist(fmt.Sprintf("Event processing canceled for session: %s", sessionID))
@@ -481,6 +684,7 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		return event.Error
 	case provider.EventComplete:
 		assistantMsg.SetToolCalls(event.Response.ToolCalls)
+		assistantMsg.SetCitations(event.Response.Citations)
 		assistantMsg.AddFinish(event.Response.FinishReason)
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
@@ -497,12 +701,7 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.M
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-
-	sess.Cost += cost
+	sess.Cost += model.Cost(usage)
 	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
 	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
 
@@ -528,11 +727,61 @@ func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (mode
 	}
 
 	a.provider = provider
+	a.systemPromptTokens = systemPromptTokens(agentName, provider.Model())
 
 	return a.provider.Model(), nil
 }
 
 func (a *agent) Summarize(ctx context.Context, sessionID string) error {
+	return a.startSummarize(ctx, sessionID, func(ctx context.Context, oldSession session.Session, summary string, response *provider.ProviderResponse) (string, error) {
+		// Create a message in the session with the summary
+		msg, err := a.createSummaryMessage(ctx, oldSession.ID, summary)
+		if err != nil {
+			return "", err
+		}
+		oldSession.SummaryMessageID = msg.ID
+		oldSession.CompletionTokens = response.Usage.OutputTokens
+		oldSession.PromptTokens = 0
+		oldSession.Cost += a.summarizeCost(response.Usage)
+		if _, err := a.sessions.Save(ctx, oldSession); err != nil {
+			return "", fmt.Errorf("failed to save session: %w", err)
+		}
+		return oldSession.ID, nil
+	})
+}
+
+// ContinueInNewSession summarizes a session into a new session that links back
+// to it, leaving the original untouched. The done event carries the new
+// session's ID.
+func (a *agent) ContinueInNewSession(ctx context.Context, sessionID string) error {
+	return a.startSummarize(ctx, sessionID, func(ctx context.Context, oldSession session.Session, summary string, response *provider.ProviderResponse) (string, error) {
+		newSession, err := a.sessions.CreateContinuedSession(ctx, oldSession.ID, oldSession.Title)
+		if err != nil {
+			return "", fmt.Errorf("failed to create session: %w", err)
+		}
+		summary = fmt.Sprintf("Continued from session %q (%s). Summary of that session:\n\n%s", oldSession.Title, oldSession.ID, summary)
+		msg, err := a.createSummaryMessage(ctx, newSession.ID, summary)
+		if err != nil {
+			return "", err
+		}
+		newSession.SummaryMessageID = msg.ID
+		newSession.CompletionTokens = response.Usage.OutputTokens
+		newSession.Cost = a.summarizeCost(response.Usage)
+		if _, err := a.sessions.Save(ctx, newSession); err != nil {
+			return "", fmt.Errorf("failed to save session: %w", err)
+		}
+		return newSession.ID, nil
+	})
+}
+
+// startSummarize summarizes a session in the background, publishing progress
+// events. store saves the summary and returns the ID of the session that holds
+// it, which is reported in the final event.
+func (a *agent) startSummarize(
+	ctx context.Context,
+	sessionID string,
+	store func(ctx context.Context, oldSession session.Session, summary string, response *provider.ProviderResponse) (string, error),
+) error {
 	if a.summarizeProvider == nil {
 		return fmt.Errorf("summarize provider not available")
 	}
@@ -551,43 +800,42 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	go func() {
 		defer a.activeRequests.Delete(sessionID + "-summarize")
 		defer cancel()
-		event := AgentEvent{
-			Type:     AgentEventTypeSummarize,
-			Progress: "Starting summarization...",
+		fail := func(err error) {
+			a.Publish(pubsub.CreatedEvent, AgentEvent{
+				Type:  AgentEventTypeError,
+				Error: err,
+				Done:  true,
+			})
+		}
+		progress := func(text string) {
+			a.Publish(pubsub.CreatedEvent, AgentEvent{
+				Type:     AgentEventTypeSummarize,
+				Progress: text,
+			})
 		}
 
-		a.Publish(pubsub.CreatedEvent, event)
+		progress("Starting summarization...")
 		// Get all messages from the session
 		msgs, err := a.messages.List(summarizeCtx, sessionID)
 		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to list messages: %w", err),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
+			fail(fmt.Errorf("failed to list messages: %w", err))
 			return
 		}
 		summarizeCtx = context.WithValue(summarizeCtx, tools.SessionIDContextKey, sessionID)
 
 		if len(msgs) == 0 {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("no messages to summarize"),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
+			fail(fmt.Errorf("no messages to summarize"))
 			return
 		}
 
-		event = AgentEvent{
-			Type:     AgentEventTypeSummarize,
-			Progress: "Analyzing conversation...",
-		}
-		a.Publish(pubsub.CreatedEvent, event)
+		progress("Analyzing conversation...")
 
-		// Add a system message to guide the summarization
-		summarizePrompt := "Provide a detailed but concise summary of our conversation above. Focus on information that would be helpful for continuing the conversation, including what we did, what we're doing, which files we're working on, and what we're going to do next."
+		// Ask for the summary; the summarizer's system prompt describes it
+		summarizePrompt := prompt.SummarizeRequest
+		preserveCode := config.Get().Summary.PreserveCodeBlocks
+		if preserveCode {
+			summarizePrompt += " " + preserveCodeBlocksPrompt
+		}
 
 		// Create a new message with the summarize prompt
 		promptMsg := message.Message{
@@ -598,12 +846,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		// Append the prompt to the messages
 		msgsWithPrompt := append(msgs, promptMsg)
 
-		event = AgentEvent{
-			Type:     AgentEventTypeSummarize,
-			Progress: "Generating summary...",
-		}
-
-		a.Publish(pubsub.CreatedEvent, event)
+		progress("Generating summary...")
 
 		// Send the messages to the summarize provider
 		response, err := a.summarizeProvider.SendMessages(
@@ -612,98 +855,103 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			make([]tools.BaseTool, 0),
 		)
 		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to summarize: %w", err),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
+			fail(fmt.Errorf("failed to summarize: %w", err))
 			return
 		}
 
 		summary := strings.TrimSpace(response.Content)
 		if summary == "" {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("empty summary returned"),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
+			fail(fmt.Errorf("empty summary returned"))
 			return
 		}
-		event = AgentEvent{
-			Type:     AgentEventTypeSummarize,
-			Progress: "Creating new session...",
+		if preserveCode {
+			summary = preserveCodeBlocks(summary, msgs)
 		}
 
-		a.Publish(pubsub.CreatedEvent, event)
+		progress("Creating new session...")
 		oldSession, err := a.sessions.Get(summarizeCtx, sessionID)
 		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to get session: %w", err),
-				Done:  true,
-			}
-
-			a.Publish(pubsub.CreatedEvent, event)
+			fail(fmt.Errorf("failed to get session: %w", err))
 			return
 		}
-		// Create a message in the new session with the summary
-		msg, err := a.messages.Create(summarizeCtx, oldSession.ID, message.CreateMessageParams{
-			Role: message.Assistant,
-			Parts: []message.ContentPart{
-				message.TextContent{Text: summary},
-				message.Finish{
-					Reason: message.FinishReasonEndTurn,
-					Time:   time.Now().Unix(),
-				},
-			},
-			Model: a.summarizeProvider.Model().ID,
-		})
+		summarySessionID, err := store(summarizeCtx, oldSession, summary, response)
 		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to create summary message: %w", err),
-				Done:  true,
-			}
-
-			a.Publish(pubsub.CreatedEvent, event)
+			fail(err)
 			return
 		}
-		oldSession.SummaryMessageID = msg.ID
-		oldSession.CompletionTokens = response.Usage.OutputTokens
-		oldSession.PromptTokens = 0
-		model := a.summarizeProvider.Model()
-		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
-		_, err = a.sessions.Save(summarizeCtx, oldSession)
-		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to save session: %w", err),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
-		}
 
-		event = AgentEvent{
+		a.Publish(pubsub.CreatedEvent, AgentEvent{
 			Type:      AgentEventTypeSummarize,
-			SessionID: oldSession.ID,
+			SessionID: summarySessionID,
 			Progress:  "Summary complete",
 			Done:      true,
-		}
-		a.Publish(pubsub.CreatedEvent, event)
-		// Send final success event with the new session ID
+		})
 	}()
 
 	return nil
 }
 
-func createAgentProvider(agentName config.AgentName) (provider.Provider, error) {
+func (a *agent) createSummaryMessage(ctx context.Context, sessionID, summary string) (message.Message, error) {
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: summary},
+			message.Finish{
+				Reason: message.FinishReasonEndTurn,
+				Time:   time.Now().Unix(),
+			},
+		},
+		Model: a.summarizeProvider.Model().ID,
+	})
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to create summary message: %w", err)
+	}
+	return msg, nil
+}
+
+func (a *agent) summarizeCost(usage provider.TokenUsage) float64 {
+	return a.summarizeProvider.Model().Cost(usage)
+}
+
+const preserveCodeBlocksPrompt = "Copy every fenced code block from the conversation into the summary verbatim, without shortening or rewriting it, and condense only the surrounding prose."
+
+// fencedCodeBlock matches a ``` fenced code block, including its fences.
+var fencedCodeBlock = regexp.MustCompile("(?ms)^[ \t]*```[^\n]*\n.*?^[ \t]*```[ \t]*$")
+
+// preserveCodeBlocks appends the conversation's fenced code blocks that the
+// summary left out, so that code survives summarization verbatim.
+func preserveCodeBlocks(summary string, msgs []message.Message) string {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, msg := range msgs {
+		for _, block := range fencedCodeBlock.FindAllString(msg.Content().String(), -1) {
+			block = strings.TrimSpace(block)
+			if seen[block] || strings.Contains(summary, block) {
+				continue
+			}
+			seen[block] = true
+			missing = append(missing, block)
+		}
+	}
+	if len(missing) == 0 {
+		return summary
+	}
+	return fmt.Sprintf("%s\n\n## Code blocks from the conversation\n\n%s", summary, strings.Join(missing, "\n\n"))
+}
+
+// agentSystemPrompt builds the system prompt for an agent, appending the
+// step-by-step directive when the agent opts in and the model can't reason natively.
+func agentSystemPrompt(agentName config.AgentName, agentConfig config.Agent, model models.Model) string {
+	systemPrompt := prompt.GetAgentPrompt(agentName, model.Provider)
+	if agentConfig.StepByStep && !model.CanReason {
+		systemPrompt = fmt.Sprintf("%s\n\n%s", systemPrompt, prompt.StepByStepDirective)
+	}
+	return systemPrompt
+}
+
+// createAgentProvider creates the provider for an agent's model. extraOpts are
+// applied last and override the agent's defaults.
+func createAgentProvider(agentName config.AgentName, extraOpts ...provider.ProviderClientOption) (provider.Provider, error) {
 	cfg := config.Get()
 	agentConfig, ok := cfg.Agents[agentName]
 	if !ok {
@@ -728,8 +976,11 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 	opts := []provider.ProviderClientOption{
 		provider.WithAPIKey(providerCfg.APIKey),
 		provider.WithModel(model),
-		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
+		provider.WithSystemMessage(agentSystemPrompt(agentName, agentConfig, model)),
 		provider.WithMaxTokens(maxTokens),
+		provider.WithMaxToolArgumentBytes(cfg.MaxToolArgumentBytes),
+		provider.WithRequestsPerMinute(providerCfg.RequestsPerMinute),
+		provider.WithBudget(providerCfg.Budget),
 	}
 	if model.Provider == models.ProviderOpenAI || model.Provider == models.ProviderLocal && model.CanReason {
 		opts = append(
@@ -738,14 +989,18 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 				provider.WithReasoningEffort(agentConfig.ReasoningEffort),
 			),
 		)
-	} else if model.Provider == models.ProviderAnthropic && model.CanReason && agentName == config.AgentCoder {
-		opts = append(
-			opts,
-			provider.WithAnthropicOptions(
-				provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn),
-			),
-		)
+	} else if model.Provider == models.ProviderAnthropic {
+		anthropicOpts := []provider.AnthropicOption{
+			provider.WithPromptCaching(!providerCfg.DisablePromptCaching),
+		}
+		if model.CanReason && agentName == config.AgentCoder {
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn))
+		}
+		opts = append(opts, provider.WithAnthropicOptions(anthropicOpts...))
+	} else if (model.Provider == models.ProviderGemini || model.Provider == models.ProviderVertexAI) && providerCfg.Grounding {
+		opts = append(opts, provider.WithGeminiOptions(provider.WithGeminiGrounding()))
 	}
+	opts = append(opts, extraOpts...)
 	agentProvider, err := provider.NewProvider(
 		model.Provider,
 		opts...,
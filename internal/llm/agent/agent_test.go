@@ -0,0 +1,757 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/llm/prompt"
+	"github.com/zhenbah/cryoncode/internal/llm/provider"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+type fakeSessions struct {
+	*pubsub.Broker[session.Session]
+	mu       sync.Mutex
+	sessions map[string]session.Session
+}
+
+func newFakeSessions() *fakeSessions {
+	return &fakeSessions{
+		Broker:   pubsub.NewBroker[session.Session](),
+		sessions: make(map[string]session.Session),
+	}
+}
+
+func (s *fakeSessions) Create(ctx context.Context, title string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := session.Session{ID: fmt.Sprintf("session-%d", len(s.sessions)+1), Title: title}
+	s.sessions[sess.ID] = sess
+	return sess, nil
+}
+
+func (s *fakeSessions) CreateTitleSession(ctx context.Context, parentSessionID string) (session.Session, error) {
+	return s.Create(ctx, "Generate a title")
+}
+
+func (s *fakeSessions) CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := session.Session{ID: toolCallID, ParentSessionID: parentSessionID, Title: title}
+	s.sessions[sess.ID] = sess
+	return sess, nil
+}
+
+func (s *fakeSessions) ListChildren(ctx context.Context, parentSessionID string) ([]session.Session, error) {
+	panic("not used by agent tests")
+}
+
+func (s *fakeSessions) Import(ctx context.Context, sess session.Session) (session.Session, error) {
+	panic("not used by agent tests")
+}
+
+func (s *fakeSessions) CreateContinuedSession(ctx context.Context, fromSessionID, title string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := session.Session{ID: fmt.Sprintf("session-%d", len(s.sessions)+1), ContinuedFromSessionID: fromSessionID, Title: title}
+	s.sessions[sess.ID] = sess
+	return sess, nil
+}
+
+func (s *fakeSessions) Get(ctx context.Context, id string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return session.Session{}, fmt.Errorf("session not found: %s", id)
+	}
+	return sess, nil
+}
+
+func (s *fakeSessions) List(ctx context.Context) ([]session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := make([]session.Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions, nil
+}
+
+func (s *fakeSessions) Save(ctx context.Context, sess session.Session) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sess.ID] = sess
+	return sess, nil
+}
+
+func (s *fakeSessions) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+type fakeMessages struct {
+	*pubsub.Broker[message.Message]
+	mu       sync.Mutex
+	messages []message.Message
+	updates  []string
+}
+
+func newFakeMessages() *fakeMessages {
+	return &fakeMessages{Broker: pubsub.NewBroker[message.Message]()}
+}
+
+func (m *fakeMessages) Create(ctx context.Context, sessionID string, params message.CreateMessageParams) (message.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msg := message.Message{
+		ID:        fmt.Sprintf("message-%d", len(m.messages)+1),
+		SessionID: sessionID,
+		Role:      params.Role,
+		Parts:     params.Parts,
+		Model:     params.Model,
+	}
+	m.messages = append(m.messages, msg)
+	return msg, nil
+}
+
+func (m *fakeMessages) Update(ctx context.Context, msg message.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates = append(m.updates, msg.Content().Text)
+	for i, existing := range m.messages {
+		if existing.ID == msg.ID {
+			m.messages[i] = msg
+			return nil
+		}
+	}
+	return fmt.Errorf("message not found: %s", msg.ID)
+}
+
+func (m *fakeMessages) Get(ctx context.Context, id string) (message.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.messages {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return message.Message{}, fmt.Errorf("message not found: %s", id)
+}
+
+func (m *fakeMessages) List(ctx context.Context, sessionID string) ([]message.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []message.Message
+	for _, msg := range m.messages {
+		if msg.SessionID == sessionID {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (m *fakeMessages) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, msg := range m.messages {
+		if msg.ID == id {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *fakeMessages) Import(ctx context.Context, msg message.Message) (message.Message, error) {
+	panic("not used by agent tests")
+}
+
+func (m *fakeMessages) DeleteSessionMessages(ctx context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.messages[:0]
+	for _, msg := range m.messages {
+		if msg.SessionID != sessionID {
+			kept = append(kept, msg)
+		}
+	}
+	m.messages = kept
+	return nil
+}
+
+// fakeProvider answers every request with a fixed text response, streamed as
+// deltas when set.
+type fakeProvider struct {
+	response string
+	deltas   []string
+	calls    atomic.Int32
+	sent     chan []message.Message
+	tools    []tools.BaseTool
+
+	// usage is streamed as usage updates before finalUsage completes the response
+	usage      []provider.TokenUsage
+	finalUsage provider.TokenUsage
+
+	// toolCalls are returned with the completed response
+	toolCalls []message.ToolCall
+}
+
+func (p *fakeProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*provider.ProviderResponse, error) {
+	p.calls.Add(1)
+	if p.sent != nil {
+		p.sent <- messages
+	}
+	return &provider.ProviderResponse{
+		Content:      p.response,
+		FinishReason: message.FinishReasonEndTurn,
+	}, nil
+}
+
+func (p *fakeProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan provider.ProviderEvent {
+	p.calls.Add(1)
+	p.tools = tools
+	deltas := p.deltas
+	if deltas == nil {
+		deltas = []string{p.response}
+	}
+	events := make(chan provider.ProviderEvent, len(deltas)+len(p.usage)+1)
+	for _, delta := range deltas {
+		events <- provider.ProviderEvent{Type: provider.EventContentDelta, Content: delta}
+	}
+	for _, usage := range p.usage {
+		events <- provider.ProviderEvent{Type: provider.EventUsageUpdate, Usage: &usage}
+	}
+	events <- provider.ProviderEvent{
+		Type: provider.EventComplete,
+		Response: &provider.ProviderResponse{
+			Content:      p.response,
+			Usage:        p.finalUsage,
+			ToolCalls:    p.toolCalls,
+			FinishReason: message.FinishReasonEndTurn,
+		},
+	}
+	close(events)
+	return events
+}
+
+func (p *fakeProvider) Model() models.Model {
+	return models.Model{ID: "fake", Provider: models.ProviderMock}
+}
+
+func loadTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	return cfg
+}
+
+func newTestAgent(sessions session.Service, messages message.Service, p provider.Provider) *agent {
+	return &agent{
+		Broker:   pubsub.NewBroker[AgentEvent](),
+		sessions: sessions,
+		messages: messages,
+		provider: p,
+	}
+}
+
+func TestTitleGenerationNever(t *testing.T) {
+	cfg := loadTestConfig(t)
+	original := cfg.Title
+	t.Cleanup(func() { cfg.Title = original })
+	cfg.Title = config.TitleConfig{Trigger: config.TitleTriggerNever}
+
+	sessions := newFakeSessions()
+	messages := newFakeMessages()
+	titleProvider := &fakeProvider{response: "Generated title"}
+	a := newTestAgent(sessions, messages, &fakeProvider{response: "hello"})
+	a.titleProvider = titleProvider
+
+	sess, err := sessions.Create(context.Background(), "New Session")
+	require.NoError(t, err)
+
+	result := a.processGeneration(context.Background(), sess.ID, "first prompt", nil)
+	require.NoError(t, result.Error)
+	result = a.processGeneration(context.Background(), sess.ID, "second prompt", nil)
+	require.NoError(t, result.Error)
+
+	assert.Zero(t, titleProvider.calls.Load())
+	updated, err := sessions.Get(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "New Session", updated.Title)
+}
+
+func TestTitleGenerationContent(t *testing.T) {
+	history := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "first"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "answer"}}},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.TitleConfig
+		msgs     []message.Message
+		expected string
+		ok       bool
+	}{
+		{"first message", config.TitleConfig{Trigger: config.TitleTriggerFirstMessage}, nil, "second", true},
+		{"first message already sent", config.TitleConfig{Trigger: config.TitleTriggerFirstMessage}, history, "", false},
+		{"after two messages", config.TitleConfig{Trigger: config.TitleTriggerAfterMessages, AfterMessages: 2}, history, "first\n\nsecond", true},
+		{"after three messages", config.TitleConfig{Trigger: config.TitleTriggerAfterMessages, AfterMessages: 3}, history, "", false},
+		{"never", config.TitleConfig{Trigger: config.TitleTriggerNever}, nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, ok := titleGenerationContent(tt.cfg, tt.msgs, "second")
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, content)
+		})
+	}
+}
+
+func TestAgentSystemPromptStepByStep(t *testing.T) {
+	loadTestConfig(t)
+
+	nonReasoning := models.Model{ID: "fake", Provider: models.ProviderMock}
+	reasoning := models.Model{ID: "fake-reasoning", Provider: models.ProviderMock, CanReason: true}
+
+	tests := []struct {
+		name     string
+		agentCfg config.Agent
+		model    models.Model
+		expected bool
+	}{
+		{"enabled", config.Agent{StepByStep: true}, nonReasoning, true},
+		{"disabled", config.Agent{}, nonReasoning, false},
+		{"enabled on reasoning model", config.Agent{StepByStep: true}, reasoning, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			systemPrompt := agentSystemPrompt(config.AgentTitle, tt.agentCfg, tt.model)
+			if tt.expected {
+				assert.Contains(t, systemPrompt, prompt.StepByStepDirective)
+			} else {
+				assert.NotContains(t, systemPrompt, prompt.StepByStepDirective)
+			}
+		})
+	}
+}
+
+func TestSummarizerSystemPrompt(t *testing.T) {
+	cfg := loadTestConfig(t)
+	model := models.Model{ID: "fake", Provider: models.ProviderMock}
+	original, configured := cfg.Agents[config.AgentSummarizer]
+	t.Cleanup(func() {
+		if configured {
+			cfg.Agents[config.AgentSummarizer] = original
+		} else {
+			delete(cfg.Agents, config.AgentSummarizer)
+		}
+	})
+	delete(cfg.Agents, config.AgentSummarizer)
+	systemPrompt := agentSystemPrompt(config.AgentSummarizer, config.Agent{}, model)
+	assert.Equal(t, prompt.SummarizerPrompt(model.Provider), systemPrompt)
+
+	cfg.Agents[config.AgentSummarizer] = config.Agent{Prompt: "List the changed files.", PromptMode: config.PromptModeReplace}
+	systemPrompt = agentSystemPrompt(config.AgentSummarizer, cfg.Agents[config.AgentSummarizer], model)
+	assert.Equal(t, "List the changed files.", systemPrompt)
+}
+
+func TestSummarizePreservesCodeBlocks(t *testing.T) {
+	cfg := loadTestConfig(t)
+	original := cfg.Summary
+	t.Cleanup(func() { cfg.Summary = original })
+	cfg.Summary = config.SummaryConfig{PreserveCodeBlocks: true}
+
+	quoted := "```go\nfunc kept() {}\n```"
+	dropped := "```bash\ngo test ./...\n```"
+
+	sessions := newFakeSessions()
+	messages := newFakeMessages()
+	summarizer := &fakeProvider{
+		response: "We added a helper:\n\n" + quoted,
+		sent:     make(chan []message.Message, 1),
+	}
+	a := newTestAgent(sessions, messages, &fakeProvider{response: "hello"})
+	a.summarizeProvider = summarizer
+
+	sess, err := sessions.Create(context.Background(), "New Session")
+	require.NoError(t, err)
+	_, err = messages.Create(context.Background(), sess.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Add a helper and run the tests:\n\n" + dropped}},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(context.Background(), sess.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "Done:\n\n" + quoted + "\n\nAll tests pass."}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := a.Subscribe(ctx)
+	require.NoError(t, a.Summarize(ctx, sess.ID))
+
+	sent := <-summarizer.sent
+	assert.True(t, strings.HasPrefix(sent[len(sent)-1].Content().String(), prompt.SummarizeRequest))
+	assert.Contains(t, sent[len(sent)-1].Content().String(), preserveCodeBlocksPrompt)
+
+	for {
+		select {
+		case ev := <-events:
+			require.NoError(t, ev.Payload.Error)
+			if !ev.Payload.Done {
+				continue
+			}
+		case <-ctx.Done():
+			t.Fatal("summarization did not finish")
+		}
+		break
+	}
+
+	updated, err := sessions.Get(context.Background(), sess.ID)
+	require.NoError(t, err)
+	summaryMsg, err := messages.Get(context.Background(), updated.SummaryMessageID)
+	require.NoError(t, err)
+	summary := summaryMsg.Content().String()
+	assert.Contains(t, summary, quoted)
+	assert.Contains(t, summary, dropped)
+	assert.Equal(t, 1, strings.Count(summary, "func kept() {}"))
+}
+
+func TestContinueInNewSession(t *testing.T) {
+	loadTestConfig(t)
+
+	sessions := newFakeSessions()
+	messages := newFakeMessages()
+	a := newTestAgent(sessions, messages, &fakeProvider{response: "hello"})
+	a.summarizeProvider = &fakeProvider{response: "We renamed the config loader."}
+
+	sess, err := sessions.Create(context.Background(), "Refactor config")
+	require.NoError(t, err)
+	_, err = messages.Create(context.Background(), sess.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Rename the config loader"}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := a.Subscribe(ctx)
+	require.NoError(t, a.ContinueInNewSession(ctx, sess.ID))
+
+	var newSessionID string
+	for newSessionID == "" {
+		select {
+		case ev := <-events:
+			require.NoError(t, ev.Payload.Error)
+			if ev.Payload.Done {
+				newSessionID = ev.Payload.SessionID
+			}
+		case <-ctx.Done():
+			t.Fatal("summarization did not finish")
+		}
+	}
+
+	require.NotEqual(t, sess.ID, newSessionID)
+	newSession, err := sessions.Get(context.Background(), newSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, sess.ID, newSession.ContinuedFromSessionID)
+	assert.Empty(t, newSession.ParentSessionID, "the new session is listed like any other")
+
+	newMessages, err := messages.List(context.Background(), newSessionID)
+	require.NoError(t, err)
+	require.Len(t, newMessages, 1)
+	assert.Equal(t, newMessages[0].ID, newSession.SummaryMessageID)
+	assert.Contains(t, newMessages[0].Content().String(), "We renamed the config loader.")
+	assert.Contains(t, newMessages[0].Content().String(), sess.ID)
+
+	// The original session is kept as it was
+	original, err := sessions.Get(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.Empty(t, original.SummaryMessageID)
+	originalMessages, err := messages.List(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.Len(t, originalMessages, 1)
+}
+
+// fakeTool is a tool that only reports its name.
+type fakeTool struct {
+	name string
+}
+
+func (t fakeTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: t.name}
+}
+
+func (t fakeTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse(t.name), nil
+}
+
+func toolNames(toolList []tools.BaseTool) []string {
+	names := make([]string, 0, len(toolList))
+	for _, tool := range toolList {
+		names = append(names, tool.Info().Name)
+	}
+	return names
+}
+
+func TestToolOrder(t *testing.T) {
+	cfg := loadTestConfig(t)
+	original := cfg.Tools
+	t.Cleanup(func() { cfg.Tools = original })
+
+	defaultTools := []tools.BaseTool{fakeTool{"bash"}, fakeTool{"edit"}, fakeTool{"grep"}, fakeTool{"view"}}
+
+	tests := []struct {
+		name     string
+		order    []string
+		expected []string
+	}{
+		{"default", nil, []string{"bash", "edit", "grep", "view"}},
+		{"listed first", []string{"view", "grep"}, []string{"view", "grep", "bash", "edit"}},
+		{"unknown and duplicate names", []string{"missing", "grep", "grep"}, []string{"grep", "bash", "edit", "view"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Tools = config.ToolsConfig{Order: tt.order}
+
+			sessions := newFakeSessions()
+			p := &fakeProvider{response: "hello"}
+			a := newTestAgent(sessions, newFakeMessages(), p)
+			a.tools = defaultTools
+
+			sess, err := sessions.Create(context.Background(), "New Session")
+			require.NoError(t, err)
+			result := a.processGeneration(context.Background(), sess.ID, "prompt", nil)
+			require.NoError(t, result.Error)
+
+			assert.Equal(t, tt.expected, toolNames(p.tools))
+			assert.Equal(t, []string{"bash", "edit", "grep", "view"}, toolNames(defaultTools))
+		})
+	}
+}
+
+// recordingTool records the input it is run with.
+type recordingTool struct {
+	inputs []string
+}
+
+func (t *recordingTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "write"}
+}
+
+func (t *recordingTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	t.inputs = append(t.inputs, call.Input)
+	return tools.NewTextResponse("written"), nil
+}
+
+func TestToolCallArgumentRepair(t *testing.T) {
+	loadTestConfig(t)
+
+	tests := []struct {
+		name          string
+		input         string
+		ran           []string
+		stored        string
+		resultIsError bool
+	}{
+		{"repaired", `{"file_path": "a.go", "content": "x",}`, []string{`{"file_path": "a.go", "content": "x"}`}, `{"file_path": "a.go", "content": "x"}`, false},
+		{"truncated", `{"file_path": "a.go", "content": "package main`, nil, `{"file_path": "a.go", "content": "package main`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := newFakeMessages()
+			p := &fakeProvider{toolCalls: []message.ToolCall{{ID: "call", Name: "write", Input: tt.input, Finished: true}}}
+			sessions := newFakeSessions()
+			a := newTestAgent(sessions, messages, p)
+			tool := &recordingTool{}
+			a.tools = []tools.BaseTool{tool}
+
+			sess, err := sessions.Create(context.Background(), "New Session")
+			require.NoError(t, err)
+			assistantMsg, toolMsg, err := a.streamAndHandleEvents(context.Background(), sess.ID, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ran, tool.inputs)
+
+			stored, err := messages.Get(context.Background(), assistantMsg.ID)
+			require.NoError(t, err)
+			require.Len(t, stored.ToolCalls(), 1)
+			assert.Equal(t, tt.stored, stored.ToolCalls()[0].Input)
+
+			require.NotNil(t, toolMsg)
+			result := toolMsg.ToolResults()[0]
+			assert.Equal(t, tt.resultIsError, result.IsError)
+			if tt.resultIsError {
+				assert.Contains(t, result.Content, "truncated")
+			}
+		})
+	}
+}
+
+// flakyTool fails until it has been run succeedOn times, with a transient
+// error unless errorResult is set.
+type flakyTool struct {
+	succeedOn   int32
+	errorResult bool
+	runs        atomic.Int32
+}
+
+func (t *flakyTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "fetch"}
+}
+
+func (t *flakyTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	if t.runs.Add(1) < t.succeedOn {
+		if t.errorResult {
+			return tools.NewTextErrorResponse("Request failed with status code: 404"), nil
+		}
+		return tools.ToolResponse{}, errors.New("connection reset by peer")
+	}
+	return tools.NewTextResponse("fetched"), nil
+}
+
+func (t *flakyTool) Retryable(err error) bool {
+	return err.Error() == "connection reset by peer"
+}
+
+func TestRunToolWithRetry(t *testing.T) {
+	policies := map[string]config.ToolRetryConfig{"fetch": {Attempts: 2, BackoffMs: 1}}
+	call := tools.ToolCall{ID: "1", Name: "fetch", Input: "{}"}
+
+	tool := &flakyTool{succeedOn: 2}
+	response, err := runToolWithRetry(context.Background(), tool, call, toolRetryPolicy(policies, "fetch"))
+	require.NoError(t, err)
+	assert.Equal(t, "fetched", response.Content)
+	assert.EqualValues(t, 2, tool.runs.Load())
+
+	// Tools without a policy fail on the first error
+	tool = &flakyTool{succeedOn: 2}
+	_, err = runToolWithRetry(context.Background(), tool, call, toolRetryPolicy(nil, "fetch"))
+	assert.EqualError(t, err, "connection reset by peer")
+	assert.EqualValues(t, 1, tool.runs.Load())
+
+	// Retries stop once the policy's attempts are used up
+	tool = &flakyTool{succeedOn: 10}
+	_, err = runToolWithRetry(context.Background(), tool, call, toolRetryPolicy(policies, "fetch"))
+	assert.Error(t, err)
+	assert.EqualValues(t, 3, tool.runs.Load())
+
+	// Error results are the tool's answer, not a transient failure
+	tool = &flakyTool{succeedOn: 2, errorResult: true}
+	response, err = runToolWithRetry(context.Background(), tool, call, toolRetryPolicy(policies, "fetch"))
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.EqualValues(t, 1, tool.runs.Load())
+
+	// Tools that can't tell transient errors apart are never retried
+	tool = &flakyTool{succeedOn: 2}
+	_, err = runToolWithRetry(context.Background(), struct{ tools.BaseTool }{tool}, call, toolRetryPolicy(policies, "fetch"))
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, tool.runs.Load())
+}
+
+func TestStreamingSplitMultiByteCharacter(t *testing.T) {
+	loadTestConfig(t)
+
+	response := "café 世界"
+	split := strings.Index(response, "é") + 1
+	worldSplit := strings.Index(response, "界") + 2
+
+	sessions := newFakeSessions()
+	messages := newFakeMessages()
+	p := &fakeProvider{
+		response: response,
+		deltas:   []string{response[:split], response[split:worldSplit], response[worldSplit:]},
+	}
+	a := newTestAgent(sessions, messages, p)
+
+	sess, err := sessions.Create(context.Background(), "New Session")
+	require.NoError(t, err)
+	result := a.processGeneration(context.Background(), sess.ID, "prompt", nil)
+	require.NoError(t, result.Error)
+
+	assert.Equal(t, response, result.Message.Content().Text)
+	require.NotEmpty(t, messages.updates)
+	for _, update := range messages.updates {
+		assert.True(t, utf8.ValidString(update), "invalid UTF-8 in update %q", update)
+	}
+}
+
+func TestStreamingUsageUpdates(t *testing.T) {
+	loadTestConfig(t)
+
+	sessions := newFakeSessions()
+	messages := newFakeMessages()
+	p := &fakeProvider{
+		response: "done",
+		usage: []provider.TokenUsage{
+			{InputTokens: 100},
+			{InputTokens: 100, OutputTokens: 20},
+		},
+		finalUsage: provider.TokenUsage{InputTokens: 100, OutputTokens: 25},
+	}
+	a := newTestAgent(sessions, messages, p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := a.Subscribe(ctx)
+
+	sess, err := sessions.Create(context.Background(), "New Session")
+	require.NoError(t, err)
+	result := a.processGeneration(context.Background(), sess.ID, "prompt", nil)
+	require.NoError(t, result.Error)
+
+	var updates []provider.TokenUsage
+	for len(events) > 0 {
+		event := <-events
+		if event.Payload.Type == AgentEventTypeUsage {
+			assert.Equal(t, sess.ID, event.Payload.SessionID)
+			updates = append(updates, event.Payload.Usage)
+		}
+	}
+	assert.Equal(t, p.usage, updates)
+
+	// The usage of the completed response is what the session keeps
+	saved, err := sessions.Get(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, saved.PromptTokens)
+	assert.EqualValues(t, 25, saved.CompletionTokens)
+}
+
+func TestRunRejectsAttachmentsWithoutVision(t *testing.T) {
+	loadTestConfig(t)
+
+	a := newTestAgent(newFakeSessions(), newFakeMessages(), &fakeProvider{response: "hello"})
+	_, err := a.Run(context.Background(), "session", "what's in this screenshot?", message.Attachment{
+		FilePath: "shot.png",
+		MimeType: "image/png",
+		Content:  []byte("png"),
+	})
+	require.ErrorIs(t, err, ErrAttachmentsNotSupported)
+}
+
+func TestUTF8DeltaBuffer(t *testing.T) {
+	var b utf8DeltaBuffer
+	assert.Equal(t, "a", b.Write("a\xe4\xb8"))
+	assert.Equal(t, "\u4e16", b.Write("\x96"))
+	assert.Equal(t, "", b.Write("\xf0\x9f"))
+	assert.Equal(t, "\xf0\x9f", b.Flush())
+	assert.Equal(t, "\xffb", b.Write("\xffb"))
+}
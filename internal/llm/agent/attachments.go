@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+// maxAttachmentTextBytes caps the text extracted from a document attachment,
+// so that a large file doesn't fill the context window.
+const maxAttachmentTextBytes = 100 * 1024
+
+// documentProviders are the providers whose models read PDFs sent as
+// documents themselves.
+var documentProviders = []models.ModelProvider{
+	models.ProviderAnthropic,
+	models.ProviderBedrock,
+	models.ProviderGemini,
+	models.ProviderVertexAI,
+}
+
+// prepareAttachments splits attachments into the images sent to the model as
+// they are and the documents whose text is added to content instead. Images
+// need a model with attachment support; documents need a text extractor.
+// A PDF whose text can't be extracted, or is longer than
+// maxAttachmentTextBytes, is sent as a document to models that read PDFs;
+// other models get the text cut to that length.
+func prepareAttachments(model models.Model, content string, attachments []message.Attachment) (string, []message.ContentPart, error) {
+	var parts []message.ContentPart
+	var extracted strings.Builder
+	for _, attachment := range attachments {
+		if attachment.IsImage() {
+			if !model.SupportsAttachments {
+				return "", nil, fmt.Errorf("%w: %s", ErrAttachmentsNotSupported, model.Name)
+			}
+			parts = append(parts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
+			continue
+		}
+
+		text, err := attachment.ExtractText()
+		if attachment.IsPDF() && supportsDocuments(model) && (err != nil || len(text) > maxAttachmentTextBytes) {
+			parts = append(parts, message.BinaryContent{Path: attachment.FilePath, MIMEType: "application/pdf", Data: attachment.Content})
+			continue
+		}
+		if errors.Is(err, message.ErrNoTextExtractor) {
+			return "", nil, fmt.Errorf("can't attach %s: %w", attachment.FileName, err)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read the text of %s: %w", attachment.FileName, err)
+		}
+		fmt.Fprintf(&extracted, "\n\n<attachment name=%q>\n%s\n</attachment>", attachment.FileName, strings.TrimSuffix(truncateAttachmentText(text), "\n"))
+	}
+	return content + extracted.String(), parts, nil
+}
+
+// supportsDocuments reports whether model is sent PDF attachments as
+// documents instead of their text.
+func supportsDocuments(model models.Model) bool {
+	return model.SupportsAttachments && slices.Contains(documentProviders, model.Provider)
+}
+
+// truncateAttachmentText cuts text to maxAttachmentTextBytes, at a character
+// boundary, and notes that the rest was left out.
+func truncateAttachmentText(text string) string {
+	if len(text) <= maxAttachmentTextBytes {
+		return text
+	}
+	cut := maxAttachmentTextBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n[truncated: the first %d of %d bytes are shown]", text[:cut], cut, len(text))
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+func TestPrepareAttachments(t *testing.T) {
+	notes := message.Attachment{FileName: "notes.txt", MimeType: "text/plain; charset=utf-8", Content: []byte("Ship on Friday\n")}
+	shot := message.Attachment{FilePath: "/tmp/shot.png", FileName: "shot.png", MimeType: "image/png", Content: []byte("png")}
+	archive := message.Attachment{FileName: "logs.zip", MimeType: "application/zip", Content: []byte("PK")}
+
+	textOnly := models.Model{Name: "Text Model"}
+	content, parts, err := prepareAttachments(textOnly, "Summarize this", []message.Attachment{notes})
+	require.NoError(t, err)
+	assert.Empty(t, parts)
+	assert.Equal(t, "Summarize this\n\n<attachment name=\"notes.txt\">\nShip on Friday\n</attachment>", content)
+
+	_, _, err = prepareAttachments(textOnly, "What's this?", []message.Attachment{shot})
+	assert.ErrorIs(t, err, ErrAttachmentsNotSupported)
+
+	vision := models.Model{Name: "Vision Model", SupportsAttachments: true}
+	content, parts, err = prepareAttachments(vision, "Compare", []message.Attachment{shot, notes})
+	require.NoError(t, err)
+	assert.Equal(t, []message.ContentPart{message.BinaryContent{Path: "/tmp/shot.png", MIMEType: "image/png", Data: []byte("png")}}, parts)
+	assert.Contains(t, content, "Ship on Friday")
+
+	_, _, err = prepareAttachments(vision, "Unpack", []message.Attachment{archive})
+	assert.ErrorIs(t, err, message.ErrNoTextExtractor)
+}
+
+func TestPrepareAttachmentsPDFs(t *testing.T) {
+	scanned := message.Attachment{FilePath: "/tmp/scan.pdf", FileName: "scan.pdf", MimeType: "application/pdf", Content: []byte("%PDF-1.4\n%%EOF\n")}
+
+	claude := models.Model{Name: "Claude", Provider: models.ProviderAnthropic, SupportsAttachments: true}
+	content, parts, err := prepareAttachments(claude, "Read this", []message.Attachment{scanned})
+	require.NoError(t, err)
+	assert.Equal(t, "Read this", content)
+	assert.Equal(t, []message.ContentPart{message.BinaryContent{Path: "/tmp/scan.pdf", MIMEType: "application/pdf", Data: scanned.Content}}, parts)
+
+	// Models that don't read documents need the text
+	gpt := models.Model{Name: "GPT", Provider: models.ProviderOpenAI, SupportsAttachments: true}
+	_, _, err = prepareAttachments(gpt, "Read this", []message.Attachment{scanned})
+	assert.ErrorContains(t, err, "no text found")
+}
+
+func TestPrepareAttachmentsCapsText(t *testing.T) {
+	long := message.Attachment{FileName: "log.txt", MimeType: "text/plain", Content: []byte(strings.Repeat("é", maxAttachmentTextBytes))}
+
+	content, parts, err := prepareAttachments(models.Model{Name: "Text Model"}, "Why?", []message.Attachment{long})
+	require.NoError(t, err)
+	assert.Empty(t, parts)
+	assert.Less(t, len(content), maxAttachmentTextBytes+200)
+	assert.Contains(t, content, fmt.Sprintf("[truncated: the first %d of %d bytes are shown]", maxAttachmentTextBytes, 2*maxAttachmentTextBytes))
+}
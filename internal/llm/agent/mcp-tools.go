@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/llm/tools"
@@ -27,6 +31,7 @@ type MCPClient interface {
 		ctx context.Context,
 		request mcp.InitializeRequest,
 	) (*mcp.InitializeResult, error)
+	Ping(ctx context.Context) error
 	ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error)
 	CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
 	Close() error
@@ -38,13 +43,25 @@ func (b *mcpTool) Info() tools.ToolInfo {
 		required = make([]string, 0)
 	}
 	return tools.ToolInfo{
-		Name:        fmt.Sprintf("%s_%s", b.mcpName, b.tool.Name),
+		Name:        mcpToolName(b.mcpName, b.mcpConfig, b.tool.Name),
 		Description: b.tool.Description,
 		Parameters:  b.tool.InputSchema.Properties,
 		Required:    required,
 	}
 }
 
+// mcpToolName is the name a server's tool is advertised under. It is prefixed
+// with the server's toolPrefix, or its name when no prefix is configured, so
+// tools from different servers don't collide. Calls are routed back to the
+// server using the raw tool name.
+func mcpToolName(mcpName string, mcpConfig config.MCPServer, toolName string) string {
+	prefix := mcpConfig.ToolPrefix
+	if prefix == "" {
+		prefix = mcpName
+	}
+	return fmt.Sprintf("%s_%s", prefix, toolName)
+}
+
 func runTool(ctx context.Context, c MCPClient, toolName string, input string) (tools.ToolResponse, error) {
 	defer c.Close()
 	initRequest := mcp.InitializeRequest{}
@@ -94,7 +111,7 @@ func (b *mcpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 			SessionID:   sessionID,
 			Path:        config.WorkingDirectory(),
 			ToolName:    b.Info().Name,
-			Action:      "execute",
+			Action:      permission.ActionExecute,
 			Description: permissionDescription,
 			Params:      params.Input,
 		},
@@ -115,12 +132,11 @@ func (b *mcpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 		}
 		return runTool(ctx, c, b.tool.Name, params.Input)
 	case config.MCPSse:
-		c, err := client.NewSSEMCPClient(
-			b.mcpConfig.URL,
-			client.WithHeaders(b.mcpConfig.Headers),
-		)
+		c, err := connectSSE(ctx, b.mcpConfig)
 		if err != nil {
-			return tools.NewTextErrorResponse(err.Error()), nil
+			logging.Warn("mcp server connection lost, reconnecting in background", "name", b.mcpName, "error", err)
+			reconnectSSEServerInBackground(b.mcpName, b.mcpConfig, b.permissions, err)
+			return tools.NewTextErrorResponse(fmt.Sprintf("mcp server %s is unreachable and is being reconnected, try again later: %s", b.mcpName, err)), nil
 		}
 		return runTool(ctx, c, b.tool.Name, params.Input)
 	}
@@ -139,8 +155,39 @@ func NewMcpTool(name string, tool mcp.Tool, permissions permission.Service, mcpC
 
 var mcpTools []tools.BaseTool
 
-func getTools(ctx context.Context, name string, m config.MCPServer, permissions permission.Service, c MCPClient) []tools.BaseTool {
-	var stdioTools []tools.BaseTool
+// mcpHealthCheckTimeout bounds the startup handshake with an MCP server.
+const mcpHealthCheckTimeout = 10 * time.Second
+
+var (
+	failedMcpServersMu sync.RWMutex
+	failedMcpServers   = make(map[string]error)
+)
+
+// FailedMcpServers returns the names of the MCP servers that failed to start
+// or did not answer their health check, sorted by name.
+func FailedMcpServers() []string {
+	failedMcpServersMu.RLock()
+	defer failedMcpServersMu.RUnlock()
+	names := make([]string, 0, len(failedMcpServers))
+	for name := range failedMcpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func recordMcpServerHealth(name string, err error) {
+	failedMcpServersMu.Lock()
+	defer failedMcpServersMu.Unlock()
+	if err != nil {
+		failedMcpServers[name] = err
+	} else {
+		delete(failedMcpServers, name)
+	}
+}
+
+// checkMcpServer performs the initialize handshake and pings the server.
+func checkMcpServer(ctx context.Context, c MCPClient) error {
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
 	initRequest.Params.ClientInfo = mcp.Implementation{
@@ -148,22 +195,44 @@ func getTools(ctx context.Context, name string, m config.MCPServer, permissions
 		Version: version.Version,
 	}
 
-	_, err := c.Initialize(ctx, initRequest)
-	if err != nil {
-		logging.Error("error initializing mcp client", "error", err)
-		return stdioTools
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		return fmt.Errorf("initialize: %w", err)
 	}
+	if err := c.Ping(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	return nil
+}
+
+// getTools lists the server's tools once it has passed its health check. A
+// server that fails the check contributes no tools and is reported by
+// FailedMcpServers.
+func getTools(ctx context.Context, name string, m config.MCPServer, permissions permission.Service, c MCPClient) []tools.BaseTool {
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, mcpHealthCheckTimeout)
+	defer cancel()
+
+	if err := checkMcpServer(ctx, c); err != nil {
+		logging.Warn("mcp server failed health check, skipping its tools", "name", name, "error", err)
+		recordMcpServerHealth(name, err)
+		return nil
+	}
+
 	toolsRequest := mcp.ListToolsRequest{}
-	tools, err := c.ListTools(ctx, toolsRequest)
+	result, err := c.ListTools(ctx, toolsRequest)
 	if err != nil {
-		logging.Error("error listing tools", "error", err)
-		return stdioTools
+		logging.Warn("error listing mcp tools, skipping server", "name", name, "error", err)
+		recordMcpServerHealth(name, err)
+		return nil
 	}
-	for _, t := range tools.Tools {
-		stdioTools = append(stdioTools, NewMcpTool(name, t, permissions, m))
+	recordMcpServerHealth(name, nil)
+
+	var serverTools []tools.BaseTool
+	for _, t := range result.Tools {
+		serverTools = append(serverTools, NewMcpTool(name, t, permissions, m))
 	}
-	defer c.Close()
-	return stdioTools
+	return serverTools
 }
 
 func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.BaseTool {
@@ -180,17 +249,16 @@ func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.Ba
 			)
 			if err != nil {
 				logging.Error("error creating mcp client", "error", err)
+				recordMcpServerHealth(name, err)
 				continue
 			}
 
 			mcpTools = append(mcpTools, getTools(ctx, name, m, permissions, c)...)
 		case config.MCPSse:
-			c, err := client.NewSSEMCPClient(
-				m.URL,
-				client.WithHeaders(m.Headers),
-			)
+			c, err := connectSSE(ctx, m)
 			if err != nil {
-				logging.Error("error creating mcp client", "error", err)
+				logging.Warn("mcp server unreachable, reconnecting in background", "name", name, "error", err)
+				reconnectSSEServerInBackground(name, m, permissions, err)
 				continue
 			}
 			mcpTools = append(mcpTools, getTools(ctx, name, m, permissions, c)...)
@@ -199,3 +267,104 @@ func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.Ba
 
 	return mcpTools
 }
+
+const (
+	mcpReconnectInitialBackoff = time.Second
+	mcpReconnectMaxBackoff     = 30 * time.Second
+)
+
+var (
+	reconnectedMcpToolsMu  sync.RWMutex
+	reconnectedServerTools = make(map[string][]tools.BaseTool)
+	reconnectingMcpServers = make(map[string]bool)
+)
+
+// reconnectedMcpTools returns the tools of SSE servers that have been
+// reconnected since startup, keyed by server name. They replace any tools the
+// server registered before it dropped.
+func reconnectedMcpTools() map[string][]tools.BaseTool {
+	reconnectedMcpToolsMu.RLock()
+	defer reconnectedMcpToolsMu.RUnlock()
+	return maps.Clone(reconnectedServerTools)
+}
+
+// connectSSE opens an SSE connection to the MCP server and waits for its endpoint.
+func connectSSE(ctx context.Context, m config.MCPServer) (*client.SSEMCPClient, error) {
+	c, err := client.NewSSEMCPClient(
+		m.URL,
+		client.WithHeaders(m.Headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connectSSEWithBackoff connects to an SSE MCP server, retrying with exponential
+// backoff up to the server's maxReconnectAttempts.
+func connectSSEWithBackoff(ctx context.Context, name string, m config.MCPServer) (*client.SSEMCPClient, error) {
+	backoff := mcpReconnectInitialBackoff
+	for attempt := 0; ; attempt++ {
+		c, err := connectSSE(ctx, m)
+		if err == nil {
+			if attempt > 0 {
+				logging.Info("reconnected to mcp server", "name", name, "attempts", attempt)
+			}
+			return c, nil
+		}
+		if attempt >= m.MaxReconnectAttempts {
+			return nil, fmt.Errorf("mcp server %s unreachable after %d reconnect attempts: %w", name, attempt, err)
+		}
+
+		logging.Warn("mcp server connection failed, retrying", "name", name, "attempt", attempt+1, "maxReconnectAttempts", m.MaxReconnectAttempts, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, mcpReconnectMaxBackoff)
+	}
+}
+
+// reconnectSSEServerInBackground marks an SSE server as unreachable and starts
+// reconnecting to it, unless that is already under way.
+func reconnectSSEServerInBackground(name string, m config.MCPServer, permissions permission.Service, err error) {
+	recordMcpServerHealth(name, err)
+
+	reconnectedMcpToolsMu.Lock()
+	defer reconnectedMcpToolsMu.Unlock()
+	if reconnectingMcpServers[name] {
+		return
+	}
+	reconnectingMcpServers[name] = true
+	go reconnectSSEServer(context.Background(), name, m, permissions)
+}
+
+// reconnectSSEServer keeps trying to reach an unreachable SSE server and
+// registers its tools again once it answers.
+func reconnectSSEServer(ctx context.Context, name string, m config.MCPServer, permissions permission.Service) {
+	defer func() {
+		reconnectedMcpToolsMu.Lock()
+		delete(reconnectingMcpServers, name)
+		reconnectedMcpToolsMu.Unlock()
+	}()
+
+	c, err := connectSSEWithBackoff(ctx, name, m)
+	if err != nil {
+		logging.Error("giving up on mcp server", "name", name, "error", err)
+		return
+	}
+
+	serverTools := getTools(ctx, name, m, permissions, c)
+	if serverTools == nil {
+		return
+	}
+	reconnectedMcpToolsMu.Lock()
+	reconnectedServerTools[name] = serverTools
+	reconnectedMcpToolsMu.Unlock()
+	logging.Info("registered tools from reconnected mcp server", "name", name, "tools", len(serverTools))
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+type fakeMCPClient struct {
+	pingErr error
+	tools   []mcp.Tool
+	closed  bool
+}
+
+func (c *fakeMCPClient) Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{}, nil
+}
+
+func (c *fakeMCPClient) Ping(ctx context.Context) error {
+	return c.pingErr
+}
+
+func (c *fakeMCPClient) ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	return &mcp.ListToolsResult{Tools: c.tools}, nil
+}
+
+func (c *fakeMCPClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeMCPClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMcpToolName(t *testing.T) {
+	assert.Equal(t, "github_search", mcpToolName("github", config.MCPServer{}, "search"))
+	assert.Equal(t, "ghe_search", mcpToolName("github", config.MCPServer{ToolPrefix: "ghe"}, "search"))
+}
+
+func TestGetToolsHealthCheck(t *testing.T) {
+	healthy := &fakeMCPClient{tools: []mcp.Tool{{Name: "search"}}}
+	serverTools := getTools(context.Background(), "healthy", config.MCPServer{}, nil, healthy)
+	assert.Len(t, serverTools, 1)
+	assert.True(t, healthy.closed)
+
+	dead := &fakeMCPClient{pingErr: errors.New("broken pipe"), tools: []mcp.Tool{{Name: "search"}}}
+	serverTools = getTools(context.Background(), "dead", config.MCPServer{}, nil, dead)
+	assert.Empty(t, serverTools)
+	assert.True(t, dead.closed)
+
+	assert.Contains(t, FailedMcpServers(), "dead")
+	assert.NotContains(t, FailedMcpServers(), "healthy")
+}
+
+// droppingHandler serves requests only while up is set, like a remote server
+// that goes away and comes back.
+type droppingHandler struct {
+	up      atomic.Bool
+	handler http.Handler
+}
+
+func (h *droppingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.up.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+func TestSSEServerReconnectsAfterDropping(t *testing.T) {
+	loadTestConfig(t)
+	t.Cleanup(func() {
+		reconnectedMcpToolsMu.Lock()
+		delete(reconnectedServerTools, "remote")
+		reconnectedMcpToolsMu.Unlock()
+	})
+
+	mcpServer := server.NewMCPServer("remote", "1.0.0")
+	mcpServer.AddTool(mcp.NewTool("search"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("found"), nil
+	})
+	remote := &droppingHandler{}
+	ts := httptest.NewServer(remote)
+	defer func() {
+		// The SSE streams stay open until the server drops them
+		ts.CloseClientConnections()
+		ts.Close()
+	}()
+	remote.handler = server.NewSSEServer(mcpServer, server.WithBaseURL(ts.URL))
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession("session")
+	m := config.MCPServer{Type: config.MCPSse, URL: ts.URL + "/sse", MaxReconnectAttempts: 5}
+	tool := NewMcpTool("remote", mcp.Tool{Name: "search"}, permissions, m)
+	a := &agent{tools: []tools.BaseTool{fakeTool{"bash"}, tool}, mcpTools: true}
+
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, tools.MessageIDContextKey, "message")
+	call := tools.ToolCall{Name: "remote_search", Input: "{}"}
+
+	start := time.Now()
+	response, err := tool.Run(ctx, call)
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "being reconnected")
+	assert.Less(t, time.Since(start), time.Second, "a call to a server that is down should not wait for it")
+	assert.Contains(t, FailedMcpServers(), "remote")
+
+	remote.up.Store(true)
+	require.Eventually(t, func() bool {
+		return len(reconnectedMcpTools()["remote"]) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.NotContains(t, FailedMcpServers(), "remote")
+	assert.ElementsMatch(t, []string{"bash", "remote_search"}, toolNames(a.availableTools()))
+
+	response, err = reconnectedMcpTools()["remote"][0].Run(ctx, call)
+	require.NoError(t, err)
+	assert.False(t, response.IsError, response.Content)
+	assert.Equal(t, "found", response.Content)
+}
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/llm/provider"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/logging"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+const (
+	probeToolName  = "probe"
+	probeTimeout   = 30 * time.Second
+	probeMaxTokens = 1024
+
+	// probeCacheTTL is how long a probe result is trusted before the model is
+	// probed again on startup.
+	probeCacheTTL = 24 * time.Hour
+)
+
+// ModelProbe is the result of probing a model with a tiny request.
+type ModelProbe struct {
+	Model     models.ModelID `json:"model"`
+	Available bool           `json:"available"`
+	ToolCalls bool           `json:"toolCalls"` // The model called the probe tool when asked to
+	Error     string         `json:"error,omitempty"`
+	CheckedAt time.Time      `json:"checkedAt"`
+}
+
+// probeTool is offered to the model during a probe. A call to it shows that
+// tool calling works; it is never run.
+type probeTool struct{}
+
+func (probeTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        probeToolName,
+		Description: "Confirms that tool calling works. Call it when asked to.",
+		Parameters: map[string]any{
+			"ok": map[string]any{
+				"type":        "boolean",
+				"description": "Always true",
+			},
+		},
+		Required: []string{"ok"},
+	}
+}
+
+func (probeTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse("ok"), nil
+}
+
+// ProbeModels checks that the model of each configured agent exists and, for
+// agents that use tools, that it calls tools. Problems are reported as
+// persistent warnings. Results are cached in the data directory so that
+// restarts within probeCacheTTL don't repeat the requests.
+func ProbeModels(ctx context.Context) {
+	newProvider := func(agentName config.AgentName) (provider.Provider, error) {
+		return createAgentProvider(
+			agentName,
+			provider.WithSystemMessage("You are checking that tool calling works."),
+			provider.WithMaxTokens(probeMaxTokens),
+		)
+	}
+	probeModels(ctx, config.Get().Agents, config.ModelProbeCachePath(), newProvider, time.Now())
+}
+
+func probeModels(
+	ctx context.Context,
+	agents map[config.AgentName]config.Agent,
+	cachePath string,
+	newProvider func(config.AgentName) (provider.Provider, error),
+	now time.Time,
+) map[models.ModelID]ModelProbe {
+	cache := loadProbeCache(cachePath)
+	results := make(map[models.ModelID]ModelProbe)
+
+	names := make([]config.AgentName, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	changed := false
+	for _, name := range names {
+		modelID := agents[name].Model
+		result, ok := results[modelID]
+		if !ok {
+			result, ok = cache[modelID]
+			if !ok || now.Sub(result.CheckedAt) > probeCacheTTL {
+				p, err := newProvider(name)
+				if err != nil {
+					// Misconfigured agents are reported when the agent is created
+					logging.Debug("Skipping model probe", "agent", name, "model", modelID, "error", err)
+					continue
+				}
+				result = probeModel(ctx, p, now)
+				if ctx.Err() != nil {
+					return results
+				}
+				cache[modelID] = result
+				changed = true
+			}
+			results[modelID] = result
+		}
+
+		switch {
+		case !result.Available:
+			logging.WarnPersist(fmt.Sprintf("Model %s for the %s agent is not available: %s", modelID, name, result.Error))
+		case !result.ToolCalls && usesTools(name):
+			logging.WarnPersist(fmt.Sprintf("Model %s for the %s agent did not call a tool when asked to; tools may not work", modelID, name))
+		}
+	}
+
+	if changed {
+		if err := saveProbeCache(cachePath, cache); err != nil {
+			logging.Warn("Failed to cache model probe results", "error", err)
+		}
+	}
+	return results
+}
+
+func probeModel(ctx context.Context, p provider.Provider, now time.Time) ModelProbe {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	result := ModelProbe{Model: p.Model().ID, CheckedAt: now}
+	response, err := p.SendMessages(ctx, []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Call the probe tool with ok set to true."}},
+	}}, []tools.BaseTool{probeTool{}})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Available = true
+	for _, call := range response.ToolCalls {
+		if call.Name == probeToolName {
+			result.ToolCalls = true
+			break
+		}
+	}
+	return result
+}
+
+// usesTools reports whether an agent is given tools.
+func usesTools(agentName config.AgentName) bool {
+	return agentName == config.AgentCoder || agentName == config.AgentTask
+}
+
+func loadProbeCache(path string) map[models.ModelID]ModelProbe {
+	cache := make(map[models.ModelID]ModelProbe)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.Debug("Failed to read model probe cache", "error", err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		logging.Debug("Ignoring invalid model probe cache", "error", err)
+		return make(map[models.ModelID]ModelProbe)
+	}
+	return cache
+}
+
+func saveProbeCache(path string, cache map[models.ModelID]ModelProbe) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
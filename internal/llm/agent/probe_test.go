@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/llm/provider"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+// probeProvider answers probes with err, or with the given tool calls.
+type probeProvider struct {
+	model     models.ModelID
+	err       error
+	toolCalls []message.ToolCall
+	calls     atomic.Int32
+}
+
+func (p *probeProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*provider.ProviderResponse, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.ProviderResponse{ToolCalls: p.toolCalls, FinishReason: message.FinishReasonToolUse}, nil
+}
+
+func (p *probeProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan provider.ProviderEvent {
+	panic("not used by probes")
+}
+
+func (p *probeProvider) Model() models.Model {
+	return models.Model{ID: p.model, Provider: models.ProviderMock}
+}
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &logs
+}
+
+func TestProbeModelsWarnsAboutUnavailableModel(t *testing.T) {
+	logs := captureLogs(t)
+	cachePath := filepath.Join(t.TempDir(), "model-probes.json")
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	agents := map[config.AgentName]config.Agent{
+		config.AgentCoder: {Model: "gone-model"},
+		config.AgentTitle: {Model: "small-model"},
+	}
+	providers := map[config.AgentName]*probeProvider{
+		config.AgentCoder: {model: "gone-model", err: errors.New("404 model not found")},
+		config.AgentTitle: {model: "small-model"},
+	}
+	newProvider := func(name config.AgentName) (provider.Provider, error) {
+		return providers[name], nil
+	}
+
+	results := probeModels(context.Background(), agents, cachePath, newProvider, now)
+
+	require.Contains(t, results, models.ModelID("gone-model"))
+	assert.False(t, results["gone-model"].Available)
+	assert.Contains(t, results["gone-model"].Error, "404 model not found")
+	assert.True(t, results["small-model"].Available)
+	assert.Contains(t, logs.String(), "Model gone-model for the coder agent is not available: 404 model not found")
+	assert.NotContains(t, logs.String(), "small-model", "the title agent doesn't use tools")
+
+	// Cached results are reused until they expire
+	logs.Reset()
+	probeModels(context.Background(), agents, cachePath, newProvider, now.Add(time.Hour))
+	assert.EqualValues(t, 1, providers[config.AgentCoder].calls.Load())
+	assert.Contains(t, logs.String(), "gone-model for the coder agent is not available")
+
+	probeModels(context.Background(), agents, cachePath, newProvider, now.Add(probeCacheTTL+time.Hour))
+	assert.EqualValues(t, 2, providers[config.AgentCoder].calls.Load())
+}
+
+func TestProbeModelsWarnsWhenToolsAreNotCalled(t *testing.T) {
+	logs := captureLogs(t)
+	cachePath := filepath.Join(t.TempDir(), "model-probes.json")
+
+	agents := map[config.AgentName]config.Agent{
+		config.AgentCoder: {Model: "chat-only"},
+		config.AgentTask:  {Model: "tool-model"},
+	}
+	providers := map[config.AgentName]*probeProvider{
+		config.AgentCoder: {model: "chat-only"},
+		config.AgentTask:  {model: "tool-model", toolCalls: []message.ToolCall{{ID: "1", Name: probeToolName, Input: `{"ok":true}`}}},
+	}
+	newProvider := func(name config.AgentName) (provider.Provider, error) {
+		return providers[name], nil
+	}
+
+	results := probeModels(context.Background(), agents, cachePath, newProvider, time.Now())
+
+	assert.False(t, results["chat-only"].ToolCalls)
+	assert.True(t, results["tool-model"].ToolCalls)
+	assert.Contains(t, logs.String(), "Model chat-only for the coder agent did not call a tool")
+	assert.NotContains(t, logs.String(), "tool-model")
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+// fileReference matches an @path mention at the start of the prompt or after
+// whitespace.
+var fileReference = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+// referencedFiles returns the contents of files mentioned as @path in content
+// as parts to attach to the message, so the model doesn't need a tool call to
+// read them while the user's text is sent as written. Paths are resolved
+// against workingDir, and files outside it are left out. Files larger than
+// maxBytes are only noted, leaving the model to read them on demand, and
+// binary files are left out. A maxBytes of zero or less disables attaching.
+func referencedFiles(content, workingDir string, maxBytes int) []message.ContentPart {
+	if maxBytes <= 0 {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(workingDir)
+	if err != nil {
+		return nil
+	}
+
+	var parts []message.ContentPart
+	seen := make(map[string]bool)
+	for _, match := range fileReference.FindAllStringSubmatch(content, -1) {
+		reference, path, info, ok := resolveReference(match[1], workingDir)
+		if !ok || seen[path] || !withinDir(path, root) {
+			continue
+		}
+		seen[path] = true
+
+		if info.Size() > int64(maxBytes) {
+			parts = append(parts, message.FileContent{
+				Path: reference,
+				Text: fmt.Sprintf("Too large to include (%d bytes); read it with the view tool if needed.", info.Size()),
+			})
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data, 0) != -1 || !utf8.Valid(data) {
+			continue
+		}
+		parts = append(parts, message.FileContent{Path: reference, Text: string(data)})
+	}
+	return parts
+}
+
+// withinDir reports whether path, once its symlinks are resolved, is inside
+// root, which must already be resolved.
+func withinDir(path, root string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveReference finds the regular file an @path mention refers to. Trailing
+// punctuation is dropped when the path as written doesn't exist, so a
+// reference can end a sentence.
+func resolveReference(reference, workingDir string) (string, string, os.FileInfo, bool) {
+	for reference != "" {
+		path := reference
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		if info, err := os.Stat(path); err == nil {
+			return reference, path, info, info.Mode().IsRegular()
+		}
+		trimmed := strings.TrimRight(reference, ".,;:!?)]}'\"`")
+		if trimmed == reference {
+			break
+		}
+		reference = trimmed
+	}
+	return "", "", nil, false
+}
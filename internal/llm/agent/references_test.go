@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+func TestReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.go"), []byte("package small\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.txt"), []byte(strings.Repeat("x", 2048)), 0o644))
+
+	parts := referencedFiles("Compare @small.go with @large.txt, and @missing.go.", dir, 1024)
+
+	require.Len(t, parts, 2)
+	assert.Equal(t, message.FileContent{Path: "small.go", Text: "package small\n"}, parts[0])
+	large := parts[1].(message.FileContent)
+	assert.Equal(t, "large.txt", large.Path)
+	assert.Contains(t, large.Text, "Too large to include (2048 bytes)")
+}
+
+func TestReferencedFilesSkipsDuplicatesAndEmailAddresses(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "example.com"), []byte("not a reference"), 0o644))
+
+	parts := referencedFiles("@a.txt and @a.txt again, mail me@example.com", dir, 1024)
+
+	assert.Equal(t, []message.ContentPart{message.FileContent{Path: "a.txt", Text: "hello"}}, parts)
+}
+
+func TestReferencedFilesStayInWorkingDir(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("token"), 0o644))
+	dir := t.TempDir()
+	require.NoError(t, os.Symlink(secret, filepath.Join(dir, "link.txt")))
+
+	parts := referencedFiles("@"+secret+" @../"+filepath.Base(outside)+"/secret.txt @link.txt", dir, 1024)
+
+	assert.Empty(t, parts)
+}
+
+func TestReferencedFilesDisabled(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644))
+
+	assert.Empty(t, referencedFiles("read @a.txt", dir, 0))
+}
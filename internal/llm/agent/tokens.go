@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
+)
+
+// systemPromptTokens estimates the tokens of the system prompt that the
+// provider of an agent sends with each request.
+func systemPromptTokens(agentName config.AgentName, model models.Model) int64 {
+	agentConfig := config.Get().Agents[agentName]
+	return model.EstimateTokens(agentSystemPrompt(agentName, agentConfig, model))
+}
+
+// estimatePromptTokens estimates the tokens of the prompt of a request: the
+// system prompt, the definitions of the tools and the history.
+func (a *agent) estimatePromptTokens(model models.Model, history []message.Message, agentTools []tools.BaseTool) int64 {
+	tokens := a.systemPromptTokens
+	for _, tool := range agentTools {
+		info, _ := json.Marshal(tool.Info())
+		tokens += model.EstimateTokens(string(info))
+	}
+	for _, msg := range history {
+		tokens += estimateTokens(model, msg)
+	}
+	return tokens
+}
+
+// checkPromptSize publishes the estimated size of the prompt of a request, and
+// returns ErrPromptTooLarge if it doesn't fit in the model's context window,
+// instead of leaving the provider to reject it after a round trip.
+func (a *agent) checkPromptSize(sessionID string, history []message.Message, agentTools []tools.BaseTool) error {
+	model := a.provider.Model()
+	tokens := a.estimatePromptTokens(model, history, agentTools)
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:         AgentEventTypePromptTokens,
+		SessionID:    sessionID,
+		PromptTokens: tokens,
+	})
+	if model.ContextWindow <= 0 || tokens <= model.ContextWindow {
+		return nil
+	}
+	return fmt.Errorf("%w: it is about %d tokens, and %s takes at most %d. Shorten the message, remove attachments, compact the session or switch to a model with a larger context window",
+		ErrPromptTooLarge, tokens, model.Name, model.ContextWindow)
+}
@@ -2,9 +2,14 @@ package agent
 
 import (
 	"context"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/history"
 	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/logging"
 	"github.com/zhenbah/cryoncode/internal/lsp"
 	"github.com/zhenbah/cryoncode/internal/message"
 	"github.com/zhenbah/cryoncode/internal/permission"
@@ -17,24 +22,37 @@ func CoderAgentTools(
 	messages message.Service,
 	history history.Service,
 	lspClients map[string]*lsp.Client,
+	lspClientsSnapshot func() map[string]*lsp.Client,
 ) []tools.BaseTool {
 	ctx := context.Background()
 	otherTools := GetMcpTools(ctx, permissions)
 	if len(lspClients) > 0 {
 		otherTools = append(otherTools, tools.NewDiagnosticsTool(lspClients))
 	}
+	if len(config.Get().LSP) > 0 {
+		otherTools = append(otherTools,
+			tools.NewLspStatusTool(lspClientsSnapshot),
+			tools.NewHoverTool(lspClients),
+			tools.NewCallHierarchyTool(lspClients),
+		)
+	}
 	return append(
 		[]tools.BaseTool{
 			tools.NewBashTool(permissions),
 			tools.NewEditTool(lspClients, permissions, history),
 			tools.NewFetchTool(permissions),
+			tools.NewFetchURLTool(permissions),
 			tools.NewGlobTool(),
 			tools.NewGrepTool(),
 			tools.NewLsTool(),
 			tools.NewSourcegraphTool(),
 			tools.NewViewTool(lspClients),
+			tools.NewViewRevisionTool(),
 			tools.NewPatchTool(lspClients, permissions, history),
+			tools.NewApplyPatchTool(lspClients, permissions, history),
 			tools.NewWriteTool(lspClients, permissions, history),
+			tools.NewUndoLastEditTool(permissions, history),
+			tools.NewGitCommitTool(permissions, sessions, messages, history),
 			NewAgentTool(sessions, messages, lspClients),
 		}, otherTools...,
 	)
@@ -47,5 +65,74 @@ func TaskAgentTools(lspClients map[string]*lsp.Client) []tools.BaseTool {
 		tools.NewLsTool(),
 		tools.NewSourcegraphTool(),
 		tools.NewViewTool(lspClients),
+		tools.NewViewRevisionTool(),
+	}
+}
+
+// orderTools moves the tools named in order to the front of the list, in that
+// order, since some models favor earlier-listed tools. Unlisted tools keep their
+// default order after them and unknown names are ignored.
+func orderTools(toolList []tools.BaseTool, order []string) []tools.BaseTool {
+	if len(order) == 0 {
+		return toolList
+	}
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+	ordered := slices.Clone(toolList)
+	slices.SortStableFunc(ordered, func(a, b tools.BaseTool) int {
+		ra, okA := rank[a.Info().Name]
+		rb, okB := rank[b.Info().Name]
+		switch {
+		case okA && okB:
+			return ra - rb
+		case okA:
+			return -1
+		case okB:
+			return 1
+		}
+		return 0
+	})
+	return ordered
+}
+
+// toolRetryPolicy returns the tools.retry policy for a tool. Viper lowercases
+// map keys, so names are matched case-insensitively.
+func toolRetryPolicy(policies map[string]config.ToolRetryConfig, name string) config.ToolRetryConfig {
+	for toolName, policy := range policies {
+		if strings.EqualFold(toolName, name) {
+			return policy
+		}
+	}
+	return config.ToolRetryConfig{}
+}
+
+// runToolWithRetry runs a tool call, retrying failed attempts as the policy allows with
+// an exponential backoff. Only errors that a tools.RetryableTool reports as
+// transient are retried; error responses, permission denials and cancellation
+// are returned at once. Retries of a call reuse the permission granted to its
+// first attempt.
+func runToolWithRetry(ctx context.Context, tool tools.BaseTool, call tools.ToolCall, policy config.ToolRetryConfig) (tools.ToolResponse, error) {
+	retryable, ok := tool.(tools.RetryableTool)
+	if !ok {
+		return tool.Run(ctx, call)
+	}
+	backoff := time.Duration(policy.BackoffMs) * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		response, err := tool.Run(ctx, call)
+		if err == nil || attempt >= policy.Attempts || ctx.Err() != nil || !retryable.Retryable(err) {
+			return response, err
+		}
+
+		logging.Info("Retrying failed tool call", "tool", call.Name, "retry", attempt+1, "retries", policy.Attempts, "error", err)
+		select {
+		case <-ctx.Done():
+			return response, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
 }
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+// imageTokens approximates the tokens of an attached image.
+const imageTokens = 1000
+
+// truncateHistory returns the most recent messages whose estimated tokens fit
+// in budget, for the truncate context strategy. The system prompt is sent by
+// the provider and isn't part of the history. The result always starts at a
+// user message, so tool results are never separated from their calls, and it
+// keeps at least the last user turn even if that alone exceeds the budget.
+func truncateHistory(model models.Model, msgs []message.Message, budget int64) []message.Message {
+	start := len(msgs)
+	var tokens int64
+	for i := len(msgs) - 1; i >= 0; i-- {
+		tokens += estimateTokens(model, msgs[i])
+		if tokens > budget {
+			break
+		}
+		start = i
+	}
+
+	// Move the cut forward to the start of a turn, or back to the last one if
+	// no complete turn fits
+	for i := start; i < len(msgs); i++ {
+		if msgs[i].Role == message.User {
+			return msgs[i:]
+		}
+	}
+	for i := start - 1; i >= 0; i-- {
+		if msgs[i].Role == message.User {
+			return msgs[i:]
+		}
+	}
+	return msgs
+}
+
+// estimateTokens roughly counts the tokens a message takes up in the context
+// of model.
+func estimateTokens(model models.Model, msg message.Message) int64 {
+	var tokens int64
+	for _, part := range msg.Parts {
+		switch part := part.(type) {
+		case message.TextContent:
+			tokens += model.EstimateTokens(part.Text)
+		case message.FileContent:
+			tokens += model.EstimateTokens(part.String())
+		case message.ReasoningContent:
+			tokens += model.EstimateTokens(part.Thinking)
+		case message.ToolCall:
+			tokens += model.EstimateTokens(part.Name + " " + part.Input)
+		case message.ToolResult:
+			tokens += model.EstimateTokens(part.Content)
+		case message.BinaryContent, message.ImageURLContent:
+			tokens += imageTokens
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+// textMessage is a message whose text is tokens tokens long by estimateTokens,
+// for models estimated from the length of the text.
+func textMessage(id string, role message.MessageRole, tokens int) message.Message {
+	return message.Message{
+		ID:    id,
+		Role:  role,
+		Parts: []message.ContentPart{message.TextContent{Text: strings.Repeat("x", tokens*4)}},
+	}
+}
+
+func messageIDs(msgs []message.Message) []string {
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestTruncateHistory(t *testing.T) {
+	history := []message.Message{
+		textMessage("u1", message.User, 100),
+		textMessage("a1", message.Assistant, 100),
+		textMessage("u2", message.User, 100),
+		textMessage("a2", message.Assistant, 50),
+		textMessage("t2", message.Tool, 50),
+		textMessage("a3", message.Assistant, 50),
+		textMessage("u3", message.User, 100),
+	}
+
+	tests := []struct {
+		name     string
+		budget   int64
+		expected []string
+	}{
+		{"everything fits", 1000, []string{"u1", "a1", "u2", "a2", "t2", "a3", "u3"}},
+		{"drops the oldest turn", 400, []string{"u2", "a2", "t2", "a3", "u3"}},
+		{"doesn't split a turn", 300, []string{"u3"}},
+		{"keeps the last turn over budget", 10, []string{"u3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, messageIDs(truncateHistory(models.Model{}, history, tt.budget)))
+		})
+	}
+}
+
+func TestContextHistoryStrategy(t *testing.T) {
+	cfg := loadTestConfig(t)
+	strategy, fraction := cfg.ContextStrategy, cfg.ContextFraction
+	t.Cleanup(func() { cfg.ContextStrategy, cfg.ContextFraction = strategy, fraction })
+
+	a := newTestAgent(newFakeSessions(), newFakeMessages(), &probeProvider{model: "small"})
+	history := []message.Message{
+		textMessage("u1", message.User, 1000),
+		textMessage("a1", message.Assistant, 1000),
+		textMessage("u2", message.User, 10),
+	}
+
+	cfg.ContextStrategy = config.ContextStrategySummarize
+	assert.Len(t, a.contextHistory(history), 3)
+
+	// probeProvider's model has no known context window
+	cfg.ContextStrategy = config.ContextStrategyTruncate
+	assert.Len(t, a.contextHistory(history), 3)
+
+	a.provider = &windowProvider{probeProvider: probeProvider{model: "small"}, contextWindow: 2000}
+	cfg.ContextFraction = 0.5
+	assert.Equal(t, []string{"u2"}, messageIDs(a.contextHistory(history)))
+}
+
+// windowProvider is a probeProvider whose model has a context window.
+type windowProvider struct {
+	probeProvider
+	contextWindow int64
+}
+
+func (p *windowProvider) Model() models.Model {
+	model := p.probeProvider.Model()
+	model.ContextWindow = p.contextWindow
+	return model
+}
+
+func TestPromptTooLargeFailsFast(t *testing.T) {
+	loadTestConfig(t)
+
+	sessions := newFakeSessions()
+	messages := newFakeMessages()
+	// probeProvider panics if the request is streamed
+	a := newTestAgent(sessions, messages, &windowProvider{probeProvider: probeProvider{model: "small"}, contextWindow: 100})
+	sess, err := sessions.Create(context.Background(), "New Session")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events := a.Subscribe(ctx)
+
+	result := a.processGeneration(ctx, sess.ID, strings.Repeat("x", 1000), nil)
+	require.ErrorIs(t, result.Error, ErrPromptTooLarge)
+	assert.Contains(t, result.Error.Error(), "about 250 tokens")
+
+	msgs, err := messages.List(context.Background(), sess.ID)
+	require.NoError(t, err)
+	assert.Empty(t, msgs, "the message that didn't fit is dropped")
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, AgentEventTypePromptTokens, ev.Payload.Type)
+		assert.Equal(t, int64(250), ev.Payload.PromptTokens)
+	case <-ctx.Done():
+		t.Fatal("the prompt estimate was not published")
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+// TokenUsage counts the tokens of a request. InputTokens excludes the tokens
+// counted as cache writes or reads.
+type TokenUsage struct {
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+}
+
+// HasPricing reports whether token prices are known for the model.
+func (m Model) HasPricing() bool {
+	return m.CostPer1MIn > 0 || m.CostPer1MOut > 0
+}
+
+// EstimateCost prices usage with the rates of a supported model. Models
+// without known pricing cost nothing; check HasPricing to tell them apart from
+// free ones.
+func EstimateCost(modelID ModelID, usage TokenUsage) float64 {
+	return SupportedModels[modelID].Cost(usage)
+}
+
+// Cost prices usage with the model's rates.
+//
+// Cached token prices are recorded in two ways. Anthropic models list cache
+// writes in CostPer1MInCached and cache reads in CostPer1MOutCached, while
+// OpenAI-style models only list the discounted price of cached input in
+// CostPer1MInCached. Cache reads use the first of these that is set, falling
+// back to the input price, so providers that split cached tokens out of the
+// input, such as OpenAI and Copilot, are charged the discount.
+func (m Model) Cost(usage TokenUsage) float64 {
+	cacheWrite := m.CostPer1MIn
+	if m.CostPer1MInCached > 0 {
+		cacheWrite = m.CostPer1MInCached
+	}
+	cacheRead := m.CostPer1MIn
+	switch {
+	case m.CostPer1MOutCached > 0:
+		cacheRead = m.CostPer1MOutCached
+	case m.CostPer1MInCached > 0:
+		cacheRead = m.CostPer1MInCached
+	}
+
+	return (m.CostPer1MIn*float64(usage.InputTokens) +
+		m.CostPer1MOut*float64(usage.OutputTokens) +
+		cacheWrite*float64(usage.CacheCreationTokens) +
+		cacheRead*float64(usage.CacheReadTokens)) / 1e6
+}
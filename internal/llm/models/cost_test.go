@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCost(t *testing.T) {
+	million := int64(1_000_000)
+
+	// Anthropic prices cache writes and reads separately
+	assert.InDelta(t, 3.0+15.0+3.75+0.30, EstimateCost(Claude37Sonnet, TokenUsage{
+		InputTokens:         million,
+		OutputTokens:        million,
+		CacheCreationTokens: million,
+		CacheReadTokens:     million,
+	}), 1e-9)
+
+	// OpenAI only lists the discounted price of cached input
+	assert.InDelta(t, 2.00+0.50, EstimateCost(GPT41, TokenUsage{
+		InputTokens:     million,
+		CacheReadTokens: million,
+	}), 1e-9)
+
+	assert.Zero(t, EstimateCost("unknown-model", TokenUsage{InputTokens: million}))
+}
+
+func TestHasPricing(t *testing.T) {
+	assert.True(t, SupportedModels[Claude37Sonnet].HasPricing())
+	assert.False(t, SupportedModels["unknown-model"].HasPricing())
+	assert.False(t, convertLocalModel(localModel{ID: "qwen"}).HasPricing())
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// charsPerToken approximates the tokens of text from its length, for models
+// whose tokenizer isn't approximated more closely.
+const charsPerToken = 4
+
+// EstimateTokens approximates the number of tokens text takes up in the
+// model's context, without a request to its provider. Models that use
+// OpenAI's tokenizers are estimated by splitting the text into the pieces
+// tiktoken splits it into before encoding them, and the rest from the length
+// of the text.
+func (m Model) EstimateTokens(text string) int64 {
+	if m.usesOpenAITokenizer() {
+		return estimateTiktokenTokens(text)
+	}
+	var ascii, other int64
+	for _, r := range text {
+		if r < unicode.MaxASCII {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	// Characters outside ASCII, such as CJK, take up more of a token each
+	return (ascii+charsPerToken-1)/charsPerToken + (other+1)/2
+}
+
+// usesOpenAITokenizer reports whether the model is one of OpenAI's, whether it
+// is served by OpenAI or by a provider such as Azure, Copilot or OpenRouter.
+func (m Model) usesOpenAITokenizer() bool {
+	switch m.Provider {
+	case ProviderOpenAI, ProviderAzure:
+		return true
+	}
+	apiModel := strings.TrimPrefix(strings.ToLower(m.APIModel), "openai/")
+	for _, prefix := range []string{"gpt-", "o1", "o3", "o4"} {
+		if strings.HasPrefix(apiModel, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateTiktokenTokens splits text into words, numbers, punctuation and
+// whitespace the way tiktoken's cl100k and o200k encodings do, and counts the
+// tokens each piece is likely to be encoded as. Common words are a single
+// token, long words are split, and numbers are encoded in groups of three
+// digits.
+func estimateTiktokenTokens(text string) int64 {
+	runes := []rune(text)
+	var tokens int64
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		j := i + 1
+		switch {
+		case isWordRune(r):
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			tokens += wordTokens(runes[i:j])
+		case unicode.IsNumber(r):
+			for j < len(runes) && unicode.IsNumber(runes[j]) {
+				j++
+			}
+			tokens += int64(j-i+2) / 3
+		case unicode.IsSpace(r):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+			// A single space is encoded with the piece that follows it
+			if j-i > 1 || r != ' ' || j == len(runes) {
+				tokens++
+			}
+		default:
+			for j < len(runes) && !isWordRune(runes[j]) && !unicode.IsNumber(runes[j]) && !unicode.IsSpace(runes[j]) {
+				j++
+			}
+			tokens += int64(j-i+1) / 2
+		}
+		i = j
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// wordTokens estimates the tokens of a word. ASCII words up to eight letters
+// are usually a single token, while other scripts take about a token for
+// every two characters.
+func wordTokens(word []rune) int64 {
+	var ascii, other int64
+	for _, r := range word {
+		if r < unicode.MaxASCII {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	var tokens int64
+	if ascii > 0 {
+		tokens = 1 + (ascii-1)/8
+	}
+	return tokens + (other+1)/2
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	gpt := SupportedModels[GPT41]
+	claude := SupportedModels[Claude37Sonnet]
+
+	tests := []struct {
+		name  string
+		model Model
+		text  string
+		want  int64
+	}{
+		{"words and punctuation", gpt, "Hello, world!", 4},
+		{"numbers in groups of three digits", gpt, "12345678", 3},
+		{"indentation", gpt, "if x {\n\treturn\n}", 7},
+		{"long words are split", gpt, "internationalization", 3},
+		{"empty", gpt, "", 0},
+		{"length heuristic", claude, "Hello, world!", 4},
+		{"other scripts", claude, "你好世界", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.model.EstimateTokens(tt.text))
+		})
+	}
+}
+
+func TestUsesOpenAITokenizer(t *testing.T) {
+	for id, want := range map[ModelID]bool{
+		GPT41:                    true,
+		AzureGPT41:               true,
+		CopilotGPT4o:             true,
+		OpenRouterO3:             true,
+		Claude37Sonnet:           false,
+		OpenRouterClaude37Sonnet: false,
+		Gemini25:                 false,
+	} {
+		assert.Equal(t, want, SupportedModels[id].usesOpenAITokenizer(), id)
+	}
+}
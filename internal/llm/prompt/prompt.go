@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/llm/models"
 	"github.com/zhenbah/cryoncode/internal/logging"
@@ -26,6 +29,9 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 	default:
 		basePrompt = "You are a helpful assistant"
 	}
+	if cfg := config.Get(); cfg != nil {
+		basePrompt = cfg.Agents[agentName].SystemPrompt(basePrompt)
+	}
 
 	if agentName == config.AgentCoder || agentName == config.AgentTask {
 		// Add context from project-specific instruction files if they exist
@@ -38,6 +44,11 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 	return basePrompt
 }
 
+// StepByStepDirective is appended to the system prompt of agents that opt in to
+// step-by-step reasoning on models without native reasoning support.
+const StepByStepDirective = `# Reasoning
+Before answering or calling tools, think through the problem step by step. Break the task into smaller steps, work through each one in order, and check your reasoning before giving the final answer.`
+
 var (
 	onceContext    sync.Once
 	contextContent string
@@ -48,16 +59,19 @@ func getContextFromPaths() string {
 		var (
 			cfg          = config.Get()
 			workDir      = cfg.WorkingDir
-			contextPaths = cfg.ContextPaths
+			contextPaths = append(slices.Clone(cfg.ContextPaths), cfg.AppendContextPaths...)
 		)
 
-		contextContent = processContextPaths(workDir, contextPaths)
+		contextContent = processContextPaths(workDir, contextPaths, cfg.MaxContextFileBytes)
+		logging.Debug("Injected context files", "bytes", len(contextContent))
 	})
 
 	return contextContent
 }
 
-func processContextPaths(workDir string, paths []string) string {
+// processContextPaths returns the content of the context files. Files larger
+// than maxFileBytes are truncated; 0 leaves them whole.
+func processContextPaths(workDir string, paths []string, maxFileBytes int) string {
 	var (
 		wg       sync.WaitGroup
 		resultCh = make(chan string)
@@ -67,50 +81,50 @@ func processContextPaths(workDir string, paths []string) string {
 	processedFiles := make(map[string]bool)
 	var processedMutex sync.Mutex
 
+	// processOnce sends the content of a file unless it was already processed
+	processOnce := func(path string) {
+		// Check if we've already processed this file (case-insensitive)
+		processedMutex.Lock()
+		lowerPath := strings.ToLower(path)
+		if processedFiles[lowerPath] {
+			processedMutex.Unlock()
+			return
+		}
+		processedFiles[lowerPath] = true
+		processedMutex.Unlock()
+
+		if result := processFile(path, maxFileBytes); result != "" {
+			resultCh <- result
+		}
+	}
+
 	for _, path := range paths {
 		wg.Add(1)
 		go func(p string) {
 			defer wg.Done()
 
-			if strings.HasSuffix(p, "/") {
+			switch {
+			case strings.HasSuffix(p, "/"):
 				filepath.WalkDir(filepath.Join(workDir, p), func(path string, d os.DirEntry, err error) error {
 					if err != nil {
 						return err
 					}
 					if !d.IsDir() {
-						// Check if we've already processed this file (case-insensitive)
-						processedMutex.Lock()
-						lowerPath := strings.ToLower(path)
-						if !processedFiles[lowerPath] {
-							processedFiles[lowerPath] = true
-							processedMutex.Unlock()
-
-							if result := processFile(path); result != "" {
-								resultCh <- result
-							}
-						} else {
-							processedMutex.Unlock()
-						}
+						processOnce(path)
 					}
 					return nil
 				})
-			} else {
-				fullPath := filepath.Join(workDir, p)
-
-				// Check if we've already processed this file (case-insensitive)
-				processedMutex.Lock()
-				lowerPath := strings.ToLower(fullPath)
-				if !processedFiles[lowerPath] {
-					processedFiles[lowerPath] = true
-					processedMutex.Unlock()
-
-					result := processFile(fullPath)
-					if result != "" {
-						resultCh <- result
-					}
-				} else {
-					processedMutex.Unlock()
+			case isGlobPattern(p):
+				matches, err := doublestar.Glob(os.DirFS(workDir), filepath.ToSlash(p), doublestar.WithFilesOnly())
+				if err != nil {
+					logging.Warn("Invalid context path pattern", "pattern", p, "error", err)
+					return
 				}
+				for _, match := range matches {
+					processOnce(filepath.Join(workDir, filepath.FromSlash(match)))
+				}
+			default:
+				processOnce(filepath.Join(workDir, p))
 			}
 		}(path)
 	}
@@ -128,10 +142,30 @@ func processContextPaths(workDir string, paths []string) string {
 	return strings.Join(results, "\n")
 }
 
-func processFile(filePath string) string {
+// isGlobPattern reports whether a context path is a pattern such as
+// "docs/*.md" or "**/AGENT.md" rather than a plain path.
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[{")
+}
+
+func processFile(filePath string, maxBytes int) string {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return ""
 	}
-	return "# From:" + filePath + "\n" + string(content)
+	return "# From:" + filePath + "\n" + truncateContextFile(filePath, content, maxBytes)
+}
+
+// truncateContextFile cuts content to maxBytes, at a character boundary, and
+// notes that the rest was left out.
+func truncateContextFile(filePath string, content []byte, maxBytes int) string {
+	if maxBytes <= 0 || len(content) <= maxBytes {
+		return string(content)
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	logging.Warn("Context file truncated", "path", filePath, "bytes", len(content), "maxContextFileBytes", maxBytes)
+	return fmt.Sprintf("%s\n[truncated: the first %d of %d bytes are shown]", content[:cut], cut, len(content))
 }
@@ -55,3 +55,33 @@ func createTestFiles(t *testing.T, tmpDir string, testFiles []string) {
 		}
 	}
 }
+
+func TestProcessContextPathsGlob(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	createTestFiles(t, tmpDir, []string{
+		"docs/AGENT.md",
+		"docs/guide.txt",
+		"services/api/AGENT.md",
+	})
+
+	context := processContextPaths(tmpDir, []string{"**/AGENT.md"}, 0)
+	expectedContext := fmt.Sprintf("# From:%s/docs/AGENT.md\ndocs/AGENT.md: test content\n# From:%s/services/api/AGENT.md\nservices/api/AGENT.md: test content", tmpDir, tmpDir)
+	assert.Equal(t, expectedContext, context)
+}
+
+func TestProcessContextPathsTruncatesLargeFiles(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "CLAUDE.md"), []byte("# Rules\nUse tabs. Héllo"), 0644))
+
+	// The limit falls inside é, which is kept out whole
+	context := processContextPaths(tmpDir, []string{"CLAUDE.md"}, 20)
+	expectedContext := fmt.Sprintf("# From:%s/CLAUDE.md\n# Rules\nUse tabs. H\n[truncated: the first 19 of 24 bytes are shown]", tmpDir)
+	assert.Equal(t, expectedContext, context)
+
+	context = processContextPaths(tmpDir, []string{"CLAUDE.md"}, 24)
+	assert.NotContains(t, context, "[truncated")
+}
@@ -14,3 +14,8 @@ Focus on information that would be helpful for continuing the conversation, incl
 
 Your summary should be comprehensive enough to provide context but concise enough to be quickly understood.`
 }
+
+// SummarizeRequest asks the summarizer agent for a summary of the conversation
+// before it. What the summary covers is left to the agent's system prompt, so
+// that it follows a prompt set in the summarizer's config.
+const SummarizeRequest = "Summarize our conversation above."
@@ -41,7 +41,9 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 		o(&anthropicOpts)
 	}
 
-	anthropicClientOptions := []option.RequestOption{}
+	anthropicClientOptions := []option.RequestOption{
+		option.WithHTTPClient(newHookedHTTPClient(opts.model.Provider)),
+	}
 	if opts.apiKey != "" {
 		anthropicClientOptions = append(anthropicClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -66,14 +68,21 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 		switch msg.Role {
 		case message.User:
 			content := anthropic.NewTextBlock(msg.Content().String())
-			if cache && !a.options.disableCache {
-				content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				}
+			if cache {
+				content.OfText.CacheControl = a.cacheControl()
 			}
 			var contentBlocks []anthropic.ContentBlockParamUnion
 			contentBlocks = append(contentBlocks, content)
+			for _, file := range msg.FileContent() {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(file.String()))
+			}
 			for _, binaryContent := range msg.BinaryContent() {
+				if binaryContent.MIMEType == "application/pdf" {
+					contentBlocks = append(contentBlocks, anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{
+						Data: binaryContent.String(models.ProviderAnthropic),
+					}))
+					continue
+				}
 				base64Image := binaryContent.String(models.ProviderAnthropic)
 				imageBlock := anthropic.NewImageBlockBase64(binaryContent.MIMEType, base64Image)
 				contentBlocks = append(contentBlocks, imageBlock)
@@ -84,10 +93,8 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 			blocks := []anthropic.ContentBlockParamUnion{}
 			if msg.Content().String() != "" {
 				content := anthropic.NewTextBlock(msg.Content().String())
-				if cache && !a.options.disableCache {
-					content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
-						Type: "ephemeral",
-					}
+				if cache {
+					content.OfText.CacheControl = a.cacheControl()
 				}
 				blocks = append(blocks, content)
 			}
@@ -132,10 +139,8 @@ func (a *anthropicClient) convertTools(tools []toolsPkg.BaseTool) []anthropic.To
 			},
 		}
 
-		if i == len(tools)-1 && !a.options.disableCache {
-			toolParam.CacheControl = anthropic.CacheControlEphemeralParam{
-				Type: "ephemeral",
-			}
+		if i == len(tools)-1 {
+			toolParam.CacheControl = a.cacheControl()
 		}
 
 		anthropicTools[i] = anthropic.ToolUnionParam{OfTool: &toolParam}
@@ -186,15 +191,25 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		Thinking:    thinkingParam,
 		System: []anthropic.TextBlockParam{
 			{
-				Text: a.providerOptions.systemMessage,
-				CacheControl: anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				},
+				// The system prompt includes the project's context files,
+				// so they are cached along with it
+				Text:         a.providerOptions.systemMessage,
+				CacheControl: a.cacheControl(),
 			},
 		},
 	}
 }
 
+// cacheControl returns the marker that ends a cacheable prompt prefix, or no
+// marker when prompt caching is disabled. Anthropic allows four markers per
+// request: the last tool, the system prompt and the last two messages.
+func (a *anthropicClient) cacheControl() anthropic.CacheControlEphemeralParam {
+	if a.options.disableCache {
+		return anthropic.CacheControlEphemeralParam{}
+	}
+	return anthropic.NewCacheControlEphemeralParam()
+}
+
 func (a *anthropicClient) send(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) (resposne *ProviderResponse, err error) {
 	preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools))
 	cfg := config.Get()
@@ -212,7 +227,7 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 		)
 		// If there is an error we are going to see if we can retry the call
 		if err != nil {
-			logging.Error("Error in Anthropic API call", "error", err)
+			logging.Error("Error in Anthropic API call", "error", logging.Redact(err))
 			retry, after, retryErr := a.shouldRetry(attempts, err)
 			if retryErr != nil {
 				return nil, retryErr
@@ -222,7 +237,7 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-a.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -275,6 +290,9 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 			accumulatedMessage := anthropic.Message{}
 
 			currentToolCallID := ""
+			currentToolCallName := ""
+			toolArgs := newToolArgumentLimiter(a.providerOptions.maxToolArgumentBytes)
+			var argsErr error
 			for anthropicStream.Next() {
 				event := anthropicStream.Current()
 				err := accumulatedMessage.Accumulate(event)
@@ -284,11 +302,28 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				}
 
 				switch event := event.AsAny().(type) {
+				case anthropic.MessageStartEvent, anthropic.MessageDeltaEvent:
+					// Input tokens arrive with the start of the message and
+					// output tokens with its deltas. The accumulator keeps only
+					// the output tokens of a delta, so take the cache split
+					// from deltas that report it too.
+					if delta, ok := event.(anthropic.MessageDeltaEvent); ok {
+						if delta.Usage.CacheCreationInputTokens > 0 {
+							accumulatedMessage.Usage.CacheCreationInputTokens = delta.Usage.CacheCreationInputTokens
+						}
+						if delta.Usage.CacheReadInputTokens > 0 {
+							accumulatedMessage.Usage.CacheReadInputTokens = delta.Usage.CacheReadInputTokens
+						}
+					}
+					usage := a.usage(accumulatedMessage)
+					eventChan <- ProviderEvent{Type: EventUsageUpdate, Usage: &usage}
+
 				case anthropic.ContentBlockStartEvent:
 					if event.ContentBlock.Type == "text" {
 						eventChan <- ProviderEvent{Type: EventContentStart}
 					} else if event.ContentBlock.Type == "tool_use" {
 						currentToolCallID = event.ContentBlock.ID
+						currentToolCallName = event.ContentBlock.Name
 						eventChan <- ProviderEvent{
 							Type: EventToolUseStart,
 							ToolCall: &message.ToolCall{
@@ -302,8 +337,8 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				case anthropic.ContentBlockDeltaEvent:
 					if event.Delta.Type == "thinking_delta" && event.Delta.Thinking != "" {
 						eventChan <- ProviderEvent{
-							Type:     EventThinkingDelta,
-							Thinking: event.Delta.Thinking,
+							Type:    EventThinkingDelta,
+							Content: event.Delta.Thinking,
 						}
 					} else if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
 						eventChan <- ProviderEvent{
@@ -312,6 +347,9 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 						}
 					} else if event.Delta.Type == "input_json_delta" {
 						if currentToolCallID != "" {
+							if argsErr = toolArgs.add(currentToolCallID, currentToolCallName, event.Delta.JSON.PartialJSON.Raw()); argsErr != nil {
+								break
+							}
 							eventChan <- ProviderEvent{
 								Type: EventToolUseDelta,
 								ToolCall: &message.ToolCall{
@@ -353,6 +391,16 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 						},
 					}
 				}
+				if argsErr != nil {
+					break
+				}
+			}
+
+			if argsErr != nil {
+				anthropicStream.Close()
+				eventChan <- ProviderEvent{Type: EventError, Error: argsErr}
+				close(eventChan)
+				return
 			}
 
 			err := anthropicStream.Err()
@@ -377,7 +425,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 					}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-a.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -461,6 +509,14 @@ func WithAnthropicDisableCache() AnthropicOption {
 	}
 }
 
+// WithPromptCaching turns the cache markers on the system prompt, tools and
+// recent messages on or off. Caching is on by default.
+func WithPromptCaching(enabled bool) AnthropicOption {
+	return func(options *anthropicOptions) {
+		options.disableCache = !enabled
+	}
+}
+
 func DefaultShouldThinkFn(s string) bool {
 	return strings.Contains(strings.ToLower(s), "think")
 }
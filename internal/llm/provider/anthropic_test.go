@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	toolsPkg "github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+func TestAnthropicPromptCaching(t *testing.T) {
+	history := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "first question"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "first answer"}}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "second question"}}},
+	}
+	tools := []toolsPkg.BaseTool{toolsPkg.NewLsTool(), toolsPkg.NewGlobTool()}
+
+	for _, enabled := range []bool{true, false} {
+		client := newAnthropicClient(providerClientOptions{
+			model:            models.Model{APIModel: "claude-test"},
+			systemMessage:    "You are a coding assistant\n\n# Project-Specific Context\nUse tabs",
+			anthropicOptions: []AnthropicOption{WithPromptCaching(enabled)},
+		}).(*anthropicClient)
+		params := client.preparedMessages(client.convertMessages(history), client.convertTools(tools))
+
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+		markers := strings.Count(string(data), `"cache_control"`)
+		if !enabled {
+			assert.Zero(t, markers)
+			continue
+		}
+
+		// Anthropic rejects requests with more than four markers
+		assert.Equal(t, 4, markers)
+		assert.Equal(t, anthropic.NewCacheControlEphemeralParam(), params.System[0].CacheControl)
+		assert.Zero(t, params.Tools[0].OfTool.CacheControl)
+		assert.Equal(t, anthropic.NewCacheControlEphemeralParam(), params.Tools[1].OfTool.CacheControl)
+		assert.Zero(t, params.Messages[0].Content[0].OfText.CacheControl)
+		assert.Equal(t, anthropic.NewCacheControlEphemeralParam(), params.Messages[2].Content[0].OfText.CacheControl)
+	}
+}
+
+func TestAnthropicUsageReportsCacheTokens(t *testing.T) {
+	var msg anthropic.Message
+	require.NoError(t, json.Unmarshal([]byte(`{"usage":{
+		"input_tokens": 12,
+		"output_tokens": 40,
+		"cache_creation_input_tokens": 300,
+		"cache_read_input_tokens": 5000
+	}}`), &msg))
+
+	client := &anthropicClient{}
+	assert.Equal(t, TokenUsage{
+		InputTokens:         12,
+		OutputTokens:        40,
+		CacheCreationTokens: 300,
+		CacheReadTokens:     5000,
+	}, client.usage(msg))
+}
+
+func TestAnthropicSendsPDFsAsDocuments(t *testing.T) {
+	client := newAnthropicClient(providerClientOptions{model: models.Model{APIModel: "claude-test"}}).(*anthropicClient)
+	msgs := client.convertMessages([]message.Message{{Role: message.User, Parts: []message.ContentPart{
+		message.TextContent{Text: "summarize"},
+		message.BinaryContent{MIMEType: "application/pdf", Data: []byte("%PDF-1.4")},
+		message.BinaryContent{MIMEType: "image/png", Data: []byte("png")},
+	}}})
+
+	require.Len(t, msgs[0].Content, 3)
+	require.NotNil(t, msgs[0].Content[1].OfDocument)
+	assert.Equal(t, "JVBERi0xLjQ=", msgs[0].Content[1].OfDocument.Source.OfBase64.Data)
+	assert.NotNil(t, msgs[0].Content[2].OfImage)
+}
@@ -25,6 +25,7 @@ func newAzureClient(opts providerClientOptions) AzureClient {
 	}
 
 	reqOpts := []option.RequestOption{
+		option.WithHTTPClient(newHookedHTTPClient(opts.model.Provider)),
 		azure.WithEndpoint(endpoint, apiVersion),
 	}
 
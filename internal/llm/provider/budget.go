@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+)
+
+// ErrBudgetExceeded is returned for requests to a provider whose budget for
+// the current window is spent.
+var ErrBudgetExceeded = errors.New("provider budget exceeded")
+
+// budget counts the requests and tokens of a provider in the current window
+// and refuses requests once a limit is reached.
+type budget struct {
+	mu       sync.Mutex
+	provider models.ModelProvider
+	limits   config.Budget
+	start    time.Time
+	requests int
+	tokens   int64
+	now      func() time.Time
+}
+
+var budgets providerRegistry[budget]
+
+// sharedBudget returns the budget of a provider, shared by every client of
+// that provider so that all agents draw from it. It returns nil when the
+// budget has no limits.
+func sharedBudget(providerName models.ModelProvider, limits config.Budget) *budget {
+	if limits.Requests <= 0 && limits.Tokens <= 0 {
+		return nil
+	}
+	return budgets.get(providerName,
+		func() *budget { return newBudget(providerName, limits, time.Now) },
+		func(b *budget) {
+			b.mu.Lock()
+			b.limits = limits
+			b.mu.Unlock()
+		},
+	)
+}
+
+func newBudget(providerName models.ModelProvider, limits config.Budget, now func() time.Time) *budget {
+	return &budget{
+		provider: providerName,
+		limits:   limits,
+		start:    windowStart(limits.Window, now()),
+		now:      now,
+	}
+}
+
+// windowStart returns the start of the window that t falls in.
+func windowStart(window config.BudgetWindow, t time.Time) time.Time {
+	if window == config.BudgetWindowHour {
+		return t.Truncate(time.Hour)
+	}
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// windowEnd returns when the current window resets.
+func (b *budget) windowEnd() time.Time {
+	if b.limits.Window == config.BudgetWindowHour {
+		return b.start.Add(time.Hour)
+	}
+	return b.start.AddDate(0, 0, 1)
+}
+
+// rollover starts a new window if the current one has ended. The caller must
+// hold mu.
+func (b *budget) rollover() {
+	if now := b.now(); !now.Before(b.windowEnd()) {
+		b.start = windowStart(b.limits.Window, now)
+		b.requests = 0
+		b.tokens = 0
+	}
+}
+
+// take counts a request, or returns an error wrapping ErrBudgetExceeded if the
+// budget is spent. A nil budget allows every request.
+func (b *budget) take() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	window := b.limits.Window
+	if window == "" {
+		window = config.BudgetWindowDay
+	}
+	resets := b.windowEnd().Format("Jan 2 15:04")
+	if b.limits.Requests > 0 && b.requests >= b.limits.Requests {
+		return fmt.Errorf("%w: %s has used its %d requests for this %s, requests are allowed again at %s",
+			ErrBudgetExceeded, b.provider, b.limits.Requests, window, resets)
+	}
+	if b.limits.Tokens > 0 && b.tokens >= b.limits.Tokens {
+		return fmt.Errorf("%w: %s has used its %d tokens for this %s, requests are allowed again at %s",
+			ErrBudgetExceeded, b.provider, b.limits.Tokens, window, resets)
+	}
+	b.requests++
+	return nil
+}
+
+// spend counts the tokens of a response. A response can take the budget over
+// its token limit, which blocks the requests after it.
+func (b *budget) spend(usage TokenUsage) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	b.tokens += usage.InputTokens + usage.OutputTokens + usage.CacheCreationTokens + usage.CacheReadTokens
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+)
+
+func TestBudgetBlocksRequestsUntilWindowResets(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 20, 0, 0, time.UTC)
+	b := newBudget(models.ProviderOpenAI, config.Budget{Window: config.BudgetWindowHour, Requests: 2}, func() time.Time { return now })
+
+	require.NoError(t, b.take())
+	require.NoError(t, b.take())
+	err := b.take()
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Contains(t, err.Error(), "13:00")
+
+	// Still blocked later in the same hour
+	now = now.Add(39 * time.Minute)
+	assert.ErrorIs(t, b.take(), ErrBudgetExceeded)
+
+	// The next hour starts a new window
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.take())
+}
+
+func TestBudgetCountsTokens(t *testing.T) {
+	now := time.Date(2025, 5, 1, 23, 0, 0, 0, time.UTC)
+	b := newBudget(models.ProviderAnthropic, config.Budget{Window: config.BudgetWindowDay, Tokens: 1000}, func() time.Time { return now })
+
+	require.NoError(t, b.take())
+	b.spend(TokenUsage{InputTokens: 800, OutputTokens: 100, CacheReadTokens: 150})
+	assert.ErrorIs(t, b.take(), ErrBudgetExceeded)
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, b.take())
+}
+
+func TestSharedBudget(t *testing.T) {
+	assert.Nil(t, sharedBudget(models.ProviderGROQ, config.Budget{Window: config.BudgetWindowDay}))
+	var unlimited *budget
+	assert.NoError(t, unlimited.take(), "a nil budget allows every request")
+
+	first := sharedBudget(models.ProviderXAI, config.Budget{Requests: 10})
+	assert.Same(t, first, sharedBudget(models.ProviderXAI, config.Budget{Requests: 20}))
+	assert.Equal(t, 20, first.limits.Requests)
+}
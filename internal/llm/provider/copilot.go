@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/openai/openai-go"
@@ -119,7 +120,7 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 			var err error
 			githubToken, err = config.LoadGitHubToken()
 			if err != nil {
-				logging.Debug("Failed to load GitHub token from standard locations", "error", err)
+				logging.Debug("Failed to load GitHub token from standard locations", "error", logging.Redact(err))
 			}
 		}
 
@@ -143,7 +144,7 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 		var err error
 		bearerToken, err = tempClient.exchangeGitHubToken(githubToken)
 		if err != nil {
-			logging.Error("Failed to exchange GitHub token for Copilot bearer token", "error", err)
+			logging.Error("Failed to exchange GitHub token for Copilot bearer token", "error", logging.Redact(err))
 			return &copilotClient{
 				providerOptions: opts,
 				options:         copilotOpts,
@@ -158,6 +159,7 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 	baseURL := "https://api.githubcopilot.com"
 
 	openaiClientOptions := []option.RequestOption{
+		option.WithHTTPClient(newHookedHTTPClient(opts.model.Provider)),
 		option.WithBaseURL(baseURL),
 		option.WithAPIKey(bearerToken), // Use bearer token as API key
 	}
@@ -196,8 +198,16 @@ func (c *copilotClient) convertMessages(messages []message.Message) (copilotMess
 			var content []openai.ChatCompletionContentPartUnionParam
 			textBlock := openai.ChatCompletionContentPartTextParam{Text: msg.Content().String()}
 			content = append(content, openai.ChatCompletionContentPartUnionParam{OfText: &textBlock})
+			for _, file := range msg.FileContent() {
+				fileBlock := openai.ChatCompletionContentPartTextParam{Text: file.String()}
+				content = append(content, openai.ChatCompletionContentPartUnionParam{OfText: &fileBlock})
+			}
 
 			for _, binaryContent := range msg.BinaryContent() {
+				// PDFs are only sent to providers that read documents
+				if !strings.HasPrefix(binaryContent.MIMEType, "image/") {
+					continue
+				}
 				imageURL := openai.ChatCompletionContentPartImageImageURLParam{URL: binaryContent.String(models.ProviderCopilot)}
 				imageBlock := openai.ChatCompletionContentPartImageParam{ImageURL: imageURL}
 				content = append(content, openai.ChatCompletionContentPartUnionParam{OfImageURL: &imageBlock})
@@ -345,7 +355,7 @@ func (c *copilotClient) send(ctx context.Context, messages []message.Message, to
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-c.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -414,8 +424,13 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 			var currentToolCallId string
 			var currentToolCall openai.ChatCompletionMessageToolCall
 			var msgToolCalls []openai.ChatCompletionMessageToolCall
+			toolArgs := newToolArgumentLimiter(c.providerOptions.maxToolArgumentBytes)
+			var argsErr error
 			for copilotStream.Next() {
 				chunk := copilotStream.Current()
+				if argsErr = addToolArgumentChunk(toolArgs, chunk); argsErr != nil {
+					break
+				}
 				acc.AddChunk(chunk)
 
 				if cfg.Debug {
@@ -479,6 +494,13 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 				}
 			}
 
+			if argsErr != nil {
+				copilotStream.Close()
+				eventChan <- ProviderEvent{Type: EventError, Error: argsErr}
+				close(eventChan)
+				return
+			}
+
 			err := copilotStream.Err()
 			if err == nil || errors.Is(err, io.EOF) {
 				if cfg.Debug {
@@ -530,7 +552,7 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 					}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-c.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -567,7 +589,7 @@ func (c *copilotClient) shouldRetry(attempts int, err error) (bool, int64, error
 			var err error
 			githubToken, err = config.LoadGitHubToken()
 			if err != nil {
-				logging.Debug("Failed to load GitHub token from standard locations during retry", "error", err)
+				logging.Debug("Failed to load GitHub token from standard locations during retry", "error", logging.Redact(err))
 			}
 		}
 
@@ -581,18 +603,18 @@ func (c *copilotClient) shouldRetry(attempts int, err error) (bool, int64, error
 				logging.Info("Refreshed Copilot bearer token")
 				return true, 1000, nil // Retry immediately with new token
 			}
-			logging.Error("Failed to refresh Copilot bearer token", "error", tokenErr)
+			logging.Error("Failed to refresh Copilot bearer token", "error", logging.Redact(tokenErr))
 		}
 		return false, 0, fmt.Errorf("authentication failed: %w", err)
 	}
-	logging.Debug("Copilot API Error", "status", apierr.StatusCode, "headers", apierr.Response.Header, "body", apierr.RawJSON())
+	logging.Debug("Copilot API Error", "status", apierr.StatusCode, "headers", logging.Redact(apierr.Response.Header), "body", logging.Redact(apierr.RawJSON()))
 
 	if apierr.StatusCode != 429 && apierr.StatusCode != 500 {
 		return false, 0, err
 	}
 
 	if apierr.StatusCode == 500 {
-		logging.Warn("Copilot API returned 500 error, retrying", "error", err)
+		logging.Warn("Copilot API returned 500 error, retrying", "error", logging.Redact(err))
 	}
 
 	if attempts > maxRetries {
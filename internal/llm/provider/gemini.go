@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 
 type geminiOptions struct {
 	disableCache bool
+	grounding    bool
 }
 
 type GeminiOption func(*geminiOptions)
@@ -37,9 +39,13 @@ func newGeminiClient(opts providerClientOptions) GeminiClient {
 		o(&geminiOpts)
 	}
 
-	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: opts.apiKey, Backend: genai.BackendGeminiAPI})
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:     opts.apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: newHookedHTTPClient(opts.model.Provider),
+	})
 	if err != nil {
-		logging.Error("Failed to create Gemini client", "error", err)
+		logging.Error("Failed to create Gemini client", "error", logging.Redact(err))
 		return nil
 	}
 
@@ -57,7 +63,17 @@ func (g *geminiClient) convertMessages(messages []message.Message) []*genai.Cont
 		case message.User:
 			var parts []*genai.Part
 			parts = append(parts, &genai.Part{Text: msg.Content().String()})
+			for _, file := range msg.FileContent() {
+				parts = append(parts, &genai.Part{Text: file.String()})
+			}
 			for _, binaryContent := range msg.BinaryContent() {
+				if binaryContent.MIMEType == "application/pdf" {
+					parts = append(parts, &genai.Part{InlineData: &genai.Blob{
+						MIMEType: binaryContent.MIMEType,
+						Data:     binaryContent.Data,
+					}})
+					continue
+				}
 				imageFormat := strings.Split(binaryContent.MIMEType, "/")
 				parts = append(parts, &genai.Part{InlineData: &genai.Blob{
 					MIMEType: imageFormat[1],
@@ -183,9 +199,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
 	}
-	if len(tools) > 0 {
-		config.Tools = g.convertTools(tools)
-	}
+	config.Tools = g.requestTools(tools)
 	chat, _ := g.client.Chats.Create(ctx, g.providerOptions.model.APIModel, config, history)
 
 	attempts := 0
@@ -209,7 +223,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-g.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -249,6 +263,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 			ToolCalls:    toolCalls,
 			Usage:        g.usage(resp),
 			FinishReason: finishReason,
+			Citations:    groundingCitations(resp),
 		}, nil
 	}
 }
@@ -271,8 +286,9 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
 	}
-	if len(tools) > 0 {
-		config.Tools = g.convertTools(tools)
+	config.Tools = g.requestTools(tools)
+	if g.providerOptions.model.CanReason {
+		config.ThinkingConfig = &genai.ThinkingConfig{IncludeThoughts: true}
 	}
 	chat, _ := g.client.Chats.Create(ctx, g.providerOptions.model.APIModel, config, history)
 
@@ -288,6 +304,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 			currentContent := ""
 			toolCalls := []message.ToolCall{}
 			var finalResp *genai.GenerateContentResponse
+			var citations []string
 
 			eventChan <- ProviderEvent{Type: EventContentStart}
 
@@ -312,7 +329,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 							}
 
 							return
-						case <-time.After(time.Duration(after) * time.Millisecond):
+						case <-g.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 							break
 						}
 					} else {
@@ -322,10 +339,18 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 				}
 
 				finalResp = resp
+				if respCitations := groundingCitations(resp); len(respCitations) > 0 {
+					citations = respCitations
+				}
 
 				if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 					for _, part := range resp.Candidates[0].Content.Parts {
 						switch {
+						case part.Thought && part.Text != "":
+							eventChan <- ProviderEvent{
+								Type:    EventThinkingDelta,
+								Content: part.Text,
+							}
 						case part.Text != "":
 							delta := string(part.Text)
 							if delta != "" {
@@ -380,6 +405,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 						ToolCalls:    toolCalls,
 						Usage:        g.usage(finalResp),
 						FinishReason: finishReason,
+						Citations:    citations,
 					},
 				}
 				return
@@ -463,6 +489,43 @@ func WithGeminiDisableCache() GeminiOption {
 	}
 }
 
+// WithGeminiGrounding lets the model search Google and returns the pages it
+// based its answer on as citations.
+func WithGeminiGrounding() GeminiOption {
+	return func(options *geminiOptions) {
+		options.grounding = true
+	}
+}
+
+// requestTools returns the tools of a request: the function declarations and,
+// with grounding, Google Search.
+func (g *geminiClient) requestTools(baseTools []tools.BaseTool) []*genai.Tool {
+	var requestTools []*genai.Tool
+	if len(baseTools) > 0 {
+		requestTools = g.convertTools(baseTools)
+	}
+	if g.options.grounding {
+		requestTools = append(requestTools, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+	}
+	return requestTools
+}
+
+// groundingCitations returns the web pages in the grounding metadata of a
+// response, in the order the model cites them.
+func groundingCitations(resp *genai.GenerateContentResponse) []string {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].GroundingMetadata == nil {
+		return nil
+	}
+	var citations []string
+	for _, chunk := range resp.Candidates[0].GroundingMetadata.GroundingChunks {
+		if chunk == nil || chunk.Web == nil || chunk.Web.URI == "" || slices.Contains(citations, chunk.Web.URI) {
+			continue
+		}
+		citations = append(citations, chunk.Web.URI)
+	}
+	return citations
+}
+
 // Helper functions
 func parseJsonToMap(jsonStr string) (map[string]interface{}, error) {
 	var result map[string]interface{}
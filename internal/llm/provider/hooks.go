@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+)
+
+// Hook observes or changes the HTTP traffic between the provider clients and
+// their APIs, for logging, header injection or response filtering. Either
+// function may be nil. Hooks apply to every provider except Vertex AI, whose
+// client manages its own authenticated transport.
+type Hook struct {
+	// Request is called before a request is sent and may change it. Returning
+	// an error fails the request without sending it.
+	Request func(provider models.ModelProvider, req *http.Request) error
+	// Response is called with each response before the client reads it and
+	// may replace its body. Returning an error fails the request.
+	Response func(provider models.ModelProvider, resp *http.Response) error
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []*Hook
+)
+
+// RegisterHook adds a hook to the requests of all provider clients, including
+// those created before it was registered. Hooks run in the order they were
+// registered. The returned function removes the hook.
+func RegisterHook(hook Hook) func() {
+	h := &hook
+	hooksMu.Lock()
+	hooks = append(hooks, h)
+	hooksMu.Unlock()
+
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		hooks = slices.DeleteFunc(hooks, func(registered *Hook) bool { return registered == h })
+	}
+}
+
+func registeredHooks() []*Hook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return slices.Clone(hooks)
+}
+
+// hookTransport runs the registered hooks around the requests of a provider.
+type hookTransport struct {
+	provider models.ModelProvider
+	base     http.RoundTripper
+}
+
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	active := registeredHooks()
+	if len(active) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	for _, hook := range active {
+		if hook.Request == nil {
+			continue
+		}
+		if err := hook.Request(t.provider, req); err != nil {
+			return nil, fmt.Errorf("%s request hook: %w", t.provider, err)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range active {
+		if hook.Response == nil {
+			continue
+		}
+		if err := hook.Response(t.provider, resp); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s response hook: %w", t.provider, err)
+		}
+	}
+	return resp, nil
+}
+
+// newHookedHTTPClient returns an HTTP client whose requests go through the
+// registered hooks.
+func newHookedHTTPClient(provider models.ModelProvider) *http.Client {
+	return &http.Client{Transport: &hookTransport{provider: provider, base: http.DefaultTransport}}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+)
+
+const testCompletion = `{"id":"1","object":"chat.completion","created":0,"model":"gpt-test",
+	"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`
+
+func TestHookInjectsHeader(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testCompletion))
+	}))
+	defer server.Close()
+
+	var hookedProvider models.ModelProvider
+	var status int
+	unregister := RegisterHook(Hook{
+		Request: func(provider models.ModelProvider, req *http.Request) error {
+			hookedProvider = provider
+			req.Header.Set("X-Trace-Id", "trace-42")
+			return nil
+		},
+		Response: func(provider models.ModelProvider, resp *http.Response) error {
+			status = resp.StatusCode
+			return nil
+		},
+	})
+
+	client := newOpenAIClient(providerClientOptions{
+		apiKey:        "key",
+		model:         models.Model{Provider: models.ProviderOpenAI, APIModel: "gpt-test"},
+		openaiOptions: []OpenAIOption{WithOpenAIBaseURL(server.URL)},
+	}).(*openaiClient)
+	params := openai.ChatCompletionNewParams{
+		Model:    "gpt-test",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hello")},
+	}
+	_, err := client.client.Chat.Completions.New(context.Background(), params)
+	require.NoError(t, err)
+	assert.Equal(t, "trace-42", received.Get("X-Trace-Id"))
+	assert.Equal(t, models.ProviderOpenAI, hookedProvider)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Removed hooks no longer run
+	unregister()
+	_, err = client.client.Chat.Completions.New(context.Background(), params)
+	require.NoError(t, err)
+	assert.Empty(t, received.Get("X-Trace-Id"))
+}
+
+func TestHookErrorFailsRequest(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	blocked := errors.New("blocked by policy")
+	defer RegisterHook(Hook{
+		Request: func(models.ModelProvider, *http.Request) error { return blocked },
+	})()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	_, err = newHookedHTTPClient(models.ProviderAnthropic).Do(req)
+	require.ErrorIs(t, err, blocked)
+	assert.Zero(t, requests)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/openai/openai-go"
@@ -43,7 +44,9 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 		o(&openaiOpts)
 	}
 
-	openaiClientOptions := []option.RequestOption{}
+	openaiClientOptions := []option.RequestOption{
+		option.WithHTTPClient(newHookedHTTPClient(opts.model.Provider)),
+	}
 	if opts.apiKey != "" {
 		openaiClientOptions = append(openaiClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -75,11 +78,23 @@ func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessag
 			var content []openai.ChatCompletionContentPartUnionParam
 			textBlock := openai.ChatCompletionContentPartTextParam{Text: msg.Content().String()}
 			content = append(content, openai.ChatCompletionContentPartUnionParam{OfText: &textBlock})
-			for _, binaryContent := range msg.BinaryContent() {
-				imageURL := openai.ChatCompletionContentPartImageImageURLParam{URL: binaryContent.String(models.ProviderOpenAI)}
-				imageBlock := openai.ChatCompletionContentPartImageParam{ImageURL: imageURL}
+			for _, file := range msg.FileContent() {
+				fileBlock := openai.ChatCompletionContentPartTextParam{Text: file.String()}
+				content = append(content, openai.ChatCompletionContentPartUnionParam{OfText: &fileBlock})
+			}
+			// Images attached while the session used a vision model are left
+			// out for models that would reject them
+			if o.providerOptions.model.SupportsAttachments {
+				for _, binaryContent := range msg.BinaryContent() {
+					// PDFs are only sent to providers that read documents
+					if !strings.HasPrefix(binaryContent.MIMEType, "image/") {
+						continue
+					}
+					imageURL := openai.ChatCompletionContentPartImageImageURLParam{URL: binaryContent.String(models.ProviderOpenAI)}
+					imageBlock := openai.ChatCompletionContentPartImageParam{ImageURL: imageURL}
 
-				content = append(content, openai.ChatCompletionContentPartUnionParam{OfImageURL: &imageBlock})
+					content = append(content, openai.ChatCompletionContentPartUnionParam{OfImageURL: &imageBlock})
+				}
 			}
 
 			openaiMessages = append(openaiMessages, openai.UserMessage(content))
@@ -210,7 +225,7 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 				select {
 				case <-ctx.Done():
 					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-o.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -234,6 +249,7 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 			ToolCalls:    toolCalls,
 			Usage:        o.usage(*openaiResponse),
 			FinishReason: finishReason,
+			Citations:    citations(openaiResponse.RawJSON()),
 		}, nil
 	}
 }
@@ -264,12 +280,32 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 			acc := openai.ChatCompletionAccumulator{}
 			currentContent := ""
 			toolCalls := make([]message.ToolCall, 0)
+			toolArgs := newToolArgumentLimiter(o.providerOptions.maxToolArgumentBytes)
+			var argsErr error
+			var sources []string
 
 			for openaiStream.Next() {
 				chunk := openaiStream.Current()
+				if argsErr = addToolArgumentChunk(toolArgs, chunk); argsErr != nil {
+					break
+				}
 				acc.AddChunk(chunk)
+				if chunkSources := citations(chunk.RawJSON()); len(chunkSources) > 0 {
+					sources = chunkSources
+				}
+
+				if chunk.Usage.TotalTokens > 0 {
+					usage := o.usage(acc.ChatCompletion)
+					eventChan <- ProviderEvent{Type: EventUsageUpdate, Usage: &usage}
+				}
 
 				for _, choice := range chunk.Choices {
+					if thinking := reasoningDelta(choice.Delta.RawJSON()); thinking != "" {
+						eventChan <- ProviderEvent{
+							Type:    EventThinkingDelta,
+							Content: thinking,
+						}
+					}
 					if choice.Delta.Content != "" {
 						eventChan <- ProviderEvent{
 							Type:    EventContentDelta,
@@ -280,6 +316,13 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 				}
 			}
 
+			if argsErr != nil {
+				openaiStream.Close()
+				eventChan <- ProviderEvent{Type: EventError, Error: argsErr}
+				close(eventChan)
+				return
+			}
+
 			err := openaiStream.Err()
 			if err == nil || errors.Is(err, io.EOF) {
 				// Stream completed successfully
@@ -298,6 +341,7 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 						ToolCalls:    toolCalls,
 						Usage:        o.usage(acc.ChatCompletion),
 						FinishReason: finishReason,
+						Citations:    sources,
 					},
 				}
 				close(eventChan)
@@ -321,7 +365,7 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 					}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
+				case <-o.providerOptions.retryAfter(ctx, time.Duration(after)*time.Millisecond):
 					continue
 				}
 			}
@@ -334,6 +378,64 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 	return eventChan
 }
 
+// citations returns the URLs in the "citations" field that xAI adds to
+// responses that used Live Search. Other providers leave it out.
+func citations(rawJSON string) []string {
+	if !strings.Contains(rawJSON, `"citations"`) {
+		return nil
+	}
+	var response struct {
+		Citations []string `json:"citations"`
+	}
+	if err := json.Unmarshal([]byte(rawJSON), &response); err != nil {
+		return nil
+	}
+	return response.Citations
+}
+
+// reasoningDelta returns the reasoning in a streamed delta. OpenAI doesn't
+// return the reasoning of its o-series models, but compatible APIs such as
+// DeepSeek and OpenRouter stream it in a "reasoning_content" or "reasoning"
+// field next to the content.
+func reasoningDelta(rawJSON string) string {
+	if !strings.Contains(rawJSON, `"reasoning`) {
+		return ""
+	}
+	var delta struct {
+		ReasoningContent string `json:"reasoning_content"`
+		Reasoning        string `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(rawJSON), &delta); err != nil {
+		return ""
+	}
+	if delta.ReasoningContent != "" {
+		return delta.ReasoningContent
+	}
+	return delta.Reasoning
+}
+
+// addToolArgumentChunk records the tool call argument fragments of a streamed
+// chunk. Only the first fragment of a call carries its ID, so later fragments
+// are attributed to the last ID seen at the same choice and index.
+func addToolArgumentChunk(toolArgs *toolArgumentLimiter, chunk openai.ChatCompletionChunk) error {
+	for _, choice := range chunk.Choices {
+		for _, toolCall := range choice.Delta.ToolCalls {
+			slot := fmt.Sprintf("%d:%d", choice.Index, toolCall.Index)
+			if toolCall.ID != "" {
+				toolArgs.ids[slot] = toolCall.ID
+			}
+			key := slot
+			if id, ok := toolArgs.ids[slot]; ok {
+				key = id
+			}
+			if err := toolArgs.add(key, toolCall.Function.Name, toolCall.Function.Arguments); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
 	var apierr *openai.Error
 	if !errors.As(err, &apierr) {
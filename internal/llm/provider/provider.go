@@ -2,9 +2,11 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/llm/models"
 	"github.com/zhenbah/cryoncode/internal/llm/tools"
 	"github.com/zhenbah/cryoncode/internal/message"
@@ -22,33 +24,35 @@ const (
 	EventContentDelta  EventType = "content_delta"
 	EventThinkingDelta EventType = "thinking_delta"
 	EventContentStop   EventType = "content_stop"
+	EventUsageUpdate   EventType = "usage_update"
 	EventComplete      EventType = "complete"
 	EventError         EventType = "error"
 	EventWarning       EventType = "warning"
 )
 
-type TokenUsage struct {
-	InputTokens         int64
-	OutputTokens        int64
-	CacheCreationTokens int64
-	CacheReadTokens     int64
-}
+type TokenUsage = models.TokenUsage
 
 type ProviderResponse struct {
 	Content      string
 	ToolCalls    []message.ToolCall
 	Usage        TokenUsage
 	FinishReason message.FinishReason
+	Citations    []string // Sources of the answer, reported by xAI Live Search
 }
 
 type ProviderEvent struct {
 	Type EventType
 
+	// Content holds the text of EventContentDelta and the reasoning of
+	// EventThinkingDelta events.
 	Content  string
-	Thinking string
 	Response *ProviderResponse
 	ToolCall *message.ToolCall
 	Error    error
+
+	// Usage holds the running token counts of the request in EventUsageUpdate
+	// events. The usage in the EventComplete response is authoritative.
+	Usage *TokenUsage
 }
 type Provider interface {
 	SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error)
@@ -64,6 +68,14 @@ type providerClientOptions struct {
 	maxTokens     int64
 	systemMessage string
 
+	maxToolArgumentBytes int
+
+	requestsPerMinute int
+	rateLimiter       *rateLimiter
+
+	budgetLimits config.Budget
+	budget       *budget
+
 	anthropicOptions []AnthropicOption
 	openaiOptions    []OpenAIOption
 	geminiOptions    []GeminiOption
@@ -88,6 +100,8 @@ func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption
 	for _, o := range opts {
 		o(&clientOptions)
 	}
+	clientOptions.rateLimiter = sharedRateLimiter(providerName, clientOptions.requestsPerMinute)
+	clientOptions.budget = sharedBudget(providerName, clientOptions.budgetLimits)
 	switch providerName {
 	case models.ProviderCopilot:
 		return &baseProvider[CopilotClient]{
@@ -180,7 +194,17 @@ func (p *baseProvider[C]) cleanMessages(messages []message.Message) (cleaned []m
 
 func (p *baseProvider[C]) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
 	messages = p.cleanMessages(messages)
-	return p.client.send(ctx, messages, tools)
+	if err := p.options.budget.take(); err != nil {
+		return nil, err
+	}
+	if err := p.options.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	response, err := p.client.send(ctx, messages, tools)
+	if err == nil {
+		p.options.budget.spend(response.Usage)
+	}
+	return response, err
 }
 
 func (p *baseProvider[C]) Model() models.Model {
@@ -189,7 +213,48 @@ func (p *baseProvider[C]) Model() models.Model {
 
 func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
 	messages = p.cleanMessages(messages)
-	return p.client.stream(ctx, messages, tools)
+	if p.options.rateLimiter == nil && p.options.budget == nil {
+		return p.client.stream(ctx, messages, tools)
+	}
+
+	// Check the budget and wait for the rate limit without blocking the
+	// caller, then relay the stream
+	events := make(chan ProviderEvent)
+	go func() {
+		defer close(events)
+		send := func(event ProviderEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if err := p.options.budget.take(); err != nil {
+			send(ProviderEvent{Type: EventError, Error: err})
+			return
+		}
+		if err := p.options.rateLimiter.Wait(ctx); err != nil {
+			send(ProviderEvent{Type: EventError, Error: err})
+			return
+		}
+		stream := p.client.stream(ctx, messages, tools)
+		for event := range stream {
+			if event.Type == EventComplete && event.Response != nil {
+				p.options.budget.spend(event.Response.Usage)
+			}
+			if !send(event) {
+				// The caller stopped reading, drain the client so that it
+				// does not block either
+				go func() {
+					for range stream {
+					}
+				}()
+				return
+			}
+		}
+	}()
+	return events
 }
 
 func WithAPIKey(apiKey string) ProviderClientOption {
@@ -216,6 +281,30 @@ func WithSystemMessage(systemMessage string) ProviderClientOption {
 	}
 }
 
+// WithMaxToolArgumentBytes caps the accumulated size of a single streamed tool
+// call's arguments. Zero or less disables the cap.
+func WithMaxToolArgumentBytes(maxBytes int) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.maxToolArgumentBytes = maxBytes
+	}
+}
+
+// WithRequestsPerMinute limits the requests sent to the provider. The limit is
+// shared by every client of the same provider. Zero or less disables it.
+func WithRequestsPerMinute(perMinute int) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.requestsPerMinute = perMinute
+	}
+}
+
+// WithBudget caps the requests and tokens sent to the provider per hour or
+// day. The budget is shared by every client of the same provider.
+func WithBudget(budget config.Budget) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.budgetLimits = budget
+	}
+}
+
 func WithAnthropicOptions(anthropicOptions ...AnthropicOption) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.anthropicOptions = anthropicOptions
@@ -245,3 +334,46 @@ func WithCopilotOptions(copilotOptions ...CopilotOption) ProviderClientOption {
 		options.copilotOptions = copilotOptions
 	}
 }
+
+// ErrToolArgumentsTooLarge is returned when a streamed tool call's arguments
+// grow past the configured maxToolArgumentBytes.
+var ErrToolArgumentsTooLarge = errors.New("tool call arguments too large")
+
+// toolArgumentLimiter tracks the accumulated argument size of each streamed
+// tool call so that a runaway argument aborts the stream instead of growing
+// without bound.
+type toolArgumentLimiter struct {
+	limit int
+	sizes map[string]int
+	names map[string]string
+	ids   map[string]string // Current tool call ID per streamed slot, for OpenAI-style chunks
+}
+
+func newToolArgumentLimiter(limit int) *toolArgumentLimiter {
+	return &toolArgumentLimiter{
+		limit: limit,
+		sizes: make(map[string]int),
+		names: make(map[string]string),
+		ids:   make(map[string]string),
+	}
+}
+
+// add records an argument fragment for the tool call identified by key and
+// returns an error once the call's total size exceeds the limit.
+func (l *toolArgumentLimiter) add(key, name, fragment string) error {
+	if l.limit <= 0 {
+		return nil
+	}
+	if name != "" {
+		l.names[key] = name
+	}
+	l.sizes[key] += len(fragment)
+	if l.sizes[key] > l.limit {
+		name := l.names[key]
+		if name == "" {
+			name = "unknown"
+		}
+		return fmt.Errorf("%w: arguments for tool %s exceeded %d bytes, the tool call was aborted (raise maxToolArgumentBytes to allow larger arguments)", ErrToolArgumentsTooLarge, name, l.limit)
+	}
+	return nil
+}
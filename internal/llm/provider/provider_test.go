@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"google.golang.org/genai"
+)
+
+func TestToolArgumentLimiter(t *testing.T) {
+	limiter := newToolArgumentLimiter(16)
+
+	require.NoError(t, limiter.add("call_1", "write", `{"content":`))
+	err := limiter.add("call_1", "", `"aaaaaaaaaa"}`)
+	require.ErrorIs(t, err, ErrToolArgumentsTooLarge)
+	assert.Contains(t, err.Error(), "write")
+	assert.Contains(t, err.Error(), "16 bytes")
+
+	// Other tool calls are counted separately
+	require.NoError(t, limiter.add("call_2", "view", `{"a":1}`))
+}
+
+func TestToolArgumentLimiterDisabled(t *testing.T) {
+	limiter := newToolArgumentLimiter(0)
+	require.NoError(t, limiter.add("call_1", "write", strings.Repeat("a", 1<<20)))
+}
+
+func TestAddToolArgumentChunk(t *testing.T) {
+	chunk := func(id, name, args string) openai.ChatCompletionChunk {
+		return openai.ChatCompletionChunk{
+			Choices: []openai.ChatCompletionChunkChoice{{
+				Delta: openai.ChatCompletionChunkChoiceDelta{
+					ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+						ID:       id,
+						Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Name: name, Arguments: args},
+					}},
+				},
+			}},
+		}
+	}
+
+	limiter := newToolArgumentLimiter(32)
+	require.NoError(t, addToolArgumentChunk(limiter, chunk("call_1", "write", `{"content":"`)))
+	require.NoError(t, addToolArgumentChunk(limiter, chunk("", "", strings.Repeat("a", 16))))
+	// A new call reusing the same index starts from zero
+	require.NoError(t, addToolArgumentChunk(limiter, chunk("call_2", "write", strings.Repeat("b", 30))))
+
+	var err error
+	for range 4 {
+		if err = addToolArgumentChunk(limiter, chunk("", "", strings.Repeat("c", 8))); err != nil {
+			break
+		}
+	}
+	require.ErrorIs(t, err, ErrToolArgumentsTooLarge)
+}
+
+func TestOpenAIImageAttachments(t *testing.T) {
+	history := []message.Message{{
+		Role: message.User,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "what's in this screenshot?"},
+			message.BinaryContent{Path: "shot.png", MIMEType: "image/png", Data: []byte("png")},
+		},
+	}}
+
+	for _, vision := range []bool{true, false} {
+		client := newOpenAIClient(providerClientOptions{
+			model: models.Model{APIModel: "gpt-test", SupportsAttachments: vision},
+		}).(*openaiClient)
+		converted := client.convertMessages(history)
+		require.Len(t, converted, 2, "the system message comes first")
+
+		parts := converted[1].OfUser.Content.OfArrayOfContentParts
+		require.NotEmpty(t, parts)
+		assert.Equal(t, "what's in this screenshot?", parts[0].OfText.Text)
+		if !vision {
+			assert.Len(t, parts, 1)
+			continue
+		}
+		require.Len(t, parts, 2)
+		assert.Equal(t, "data:image/png;base64,cG5n", parts[1].OfImageURL.ImageURL.URL)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	assert.Equal(t, []string{"https://x.ai/news", "https://example.com"},
+		citations(`{"id":"1","choices":[],"citations":["https://x.ai/news","https://example.com"]}`))
+	assert.Nil(t, citations(`{"id":"1","choices":[]}`), "other providers leave citations out")
+	assert.Nil(t, citations(`{"citations":`), "malformed responses are ignored")
+}
+
+func TestReasoningDelta(t *testing.T) {
+	assert.Equal(t, "Let me check", reasoningDelta(`{"role":"assistant","content":null,"reasoning_content":"Let me check"}`))
+	assert.Equal(t, "the docs", reasoningDelta(`{"content":"","reasoning":"the docs"}`))
+	assert.Empty(t, reasoningDelta(`{"content":"Hello"}`), "models without reasoning leave it out")
+	assert.Empty(t, reasoningDelta(`{"reasoning_content":`), "malformed deltas are ignored")
+}
+
+func TestGroundingCitations(t *testing.T) {
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		GroundingMetadata: &genai.GroundingMetadata{GroundingChunks: []*genai.GroundingChunk{
+			{Web: &genai.GroundingChunkWeb{URI: "https://go.dev/blog", Title: "go.dev"}},
+			{RetrievedContext: &genai.GroundingChunkRetrievedContext{URI: "gs://bucket/doc"}},
+			{Web: &genai.GroundingChunkWeb{URI: "https://pkg.go.dev"}},
+			{Web: &genai.GroundingChunkWeb{URI: "https://go.dev/blog"}},
+		}},
+	}}}
+	assert.Equal(t, []string{"https://go.dev/blog", "https://pkg.go.dev"}, groundingCitations(resp))
+	assert.Nil(t, groundingCitations(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{}}}))
+}
+
+func TestGeminiRequestTools(t *testing.T) {
+	ungrounded := &geminiClient{}
+	assert.Empty(t, ungrounded.requestTools(nil))
+
+	opts := geminiOptions{}
+	WithGeminiGrounding()(&opts)
+	grounded := &geminiClient{options: opts}
+	requestTools := grounded.requestTools(nil)
+	require.Len(t, requestTools, 1)
+	assert.NotNil(t, requestTools[0].GoogleSearch)
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/logging"
+)
+
+// rateLimiter is a token bucket that allows perMinute requests a minute,
+// spread evenly, with bursts of up to perMinute requests after a quiet period.
+type rateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	tokens    float64
+	last      time.Time
+	now       func() time.Time
+}
+
+var rateLimiters providerRegistry[rateLimiter]
+
+// sharedRateLimiter returns the rate limiter of a provider, shared by every
+// client of that provider so that concurrent agents draw from one budget. It
+// returns nil when perMinute is zero or less.
+func sharedRateLimiter(providerName models.ModelProvider, perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return rateLimiters.get(providerName,
+		func() *rateLimiter { return newRateLimiter(perMinute, time.Now) },
+		func(limiter *rateLimiter) { limiter.setPerMinute(perMinute) },
+	)
+}
+
+func newRateLimiter(perMinute int, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		perMinute: perMinute,
+		tokens:    float64(perMinute),
+		last:      now(),
+		now:       now,
+	}
+}
+
+func (l *rateLimiter) setPerMinute(perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.perMinute = perMinute
+	l.tokens = min(l.tokens, float64(perMinute))
+}
+
+// refill adds the tokens earned since the last call. The caller must hold mu.
+func (l *rateLimiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.last)
+	l.last = now
+	if elapsed <= 0 {
+		return
+	}
+	l.tokens = min(float64(l.perMinute), l.tokens+elapsed.Minutes()*float64(l.perMinute))
+}
+
+// reserve takes a token and returns how long to wait before using it. Tokens
+// go negative while requests are queued, so waiters are served in turn.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.perMinute) * float64(time.Minute))
+}
+
+// cancel returns a reserved token that was not used.
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(float64(l.perMinute), l.tokens+1)
+}
+
+// Wait blocks until a request may be sent or ctx is done. A nil limiter never
+// blocks.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	logging.Debug("Waiting for the provider rate limit", "delay", delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// retryAfter returns a channel that is closed once the backoff of a retried
+// request has passed and the rate limiter allows it, so that the retries of a
+// client count against the limit like first attempts. The channel is never
+// closed if ctx is done first.
+func (o providerClientOptions) retryAfter(ctx context.Context, backoff time.Duration) <-chan struct{} {
+	ready := make(chan struct{})
+	go func() {
+		timer := time.NewTimer(backoff)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+		if o.rateLimiter.Wait(ctx) == nil {
+			close(ready)
+		}
+	}()
+	return ready
+}
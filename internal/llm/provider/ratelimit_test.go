@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
+	"github.com/zhenbah/cryoncode/internal/message"
+)
+
+func TestRateLimiterSpreadsRequests(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(60, func() time.Time { return now })
+
+	// A full bucket allows a burst
+	for range 60 {
+		assert.Zero(t, limiter.reserve())
+	}
+	// Then requests are queued a second apart
+	assert.Equal(t, time.Second, limiter.reserve())
+	assert.Equal(t, 2*time.Second, limiter.reserve())
+
+	// Time refills the bucket
+	now = now.Add(3 * time.Second)
+	assert.Zero(t, limiter.reserve())
+}
+
+func TestRateLimiterWaitIsCancelled(t *testing.T) {
+	limiter := newRateLimiter(1, time.Now)
+	require.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestSharedRateLimiterIsPerProvider(t *testing.T) {
+	first := sharedRateLimiter(models.ProviderXAI, 30)
+	second := sharedRateLimiter(models.ProviderXAI, 30)
+	assert.Same(t, first, second, "clients of one provider share a limiter")
+	assert.NotSame(t, first, sharedRateLimiter(models.ProviderGROQ, 30))
+	assert.Nil(t, sharedRateLimiter(models.ProviderOpenAI, 0))
+}
+
+func TestRetryWaitsForRateLimit(t *testing.T) {
+	limiter := newRateLimiter(1, time.Now)
+	require.NoError(t, limiter.Wait(context.Background()))
+	options := providerClientOptions{rateLimiter: limiter}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	select {
+	case <-options.retryAfter(ctx, time.Millisecond):
+		t.Fatal("the retry did not wait for the rate limit")
+	case <-ctx.Done():
+	}
+
+	select {
+	case <-providerClientOptions{}.retryAfter(context.Background(), time.Millisecond):
+	case <-time.After(time.Second):
+		t.Fatal("a retry without a rate limit should only wait for the backoff")
+	}
+}
+
+// streamingClient streams the events sent on its channel.
+type streamingClient struct {
+	events chan ProviderEvent
+}
+
+func (c streamingClient) send(context.Context, []message.Message, []tools.BaseTool) (*ProviderResponse, error) {
+	return nil, nil
+}
+
+func (c streamingClient) stream(context.Context, []message.Message, []tools.BaseTool) <-chan ProviderEvent {
+	return c.events
+}
+
+func TestStreamRelayStopsWhenCancelled(t *testing.T) {
+	client := streamingClient{events: make(chan ProviderEvent)}
+	p := &baseProvider[streamingClient]{
+		options: providerClientOptions{rateLimiter: newRateLimiter(60, time.Now)},
+		client:  client,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := p.StreamResponse(ctx, nil, nil)
+	client.events <- ProviderEvent{Type: EventContentDelta, Content: "a"}
+	cancel()
+
+	// Nobody reads the relay anymore, the client must still be drained
+	for range 3 {
+		select {
+		case client.events <- ProviderEvent{Type: EventContentDelta, Content: "b"}:
+		case <-time.After(time.Second):
+			t.Fatal("the client stream is blocked")
+		}
+	}
+	close(client.events)
+
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the relay was not closed")
+	}
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"sync"
+
+	"github.com/zhenbah/cryoncode/internal/llm/models"
+)
+
+// providerRegistry holds one value per provider, shared by every client of
+// that provider so that concurrent agents draw from the same state.
+type providerRegistry[T any] struct {
+	mu     sync.Mutex
+	values map[models.ModelProvider]*T
+}
+
+// get returns the value of a provider. The first call creates it with create,
+// later calls pass it to update so that it picks up changed settings.
+func (r *providerRegistry[T]) get(providerName models.ModelProvider, create func() *T, update func(*T)) *T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if value, ok := r.values[providerName]; ok {
+		update(value)
+		return value
+	}
+	if r.values == nil {
+		r.values = make(map[models.ModelProvider]*T)
+	}
+	value := create()
+	r.values[providerName] = value
+	return value
+}
@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/diff"
+	"github.com/zhenbah/cryoncode/internal/history"
+	"github.com/zhenbah/cryoncode/internal/logging"
+	"github.com/zhenbah/cryoncode/internal/lsp"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+type ApplyPatchParams struct {
+	FilePath string `json:"file_path"`
+	Diff     string `json:"diff"`
+}
+
+type applyPatchTool struct {
+	lspClients  map[string]*lsp.Client
+	permissions permission.Service
+	files       history.Service
+}
+
+const (
+	ApplyPatchToolName    = "apply_patch"
+	applyPatchDescription = `Applies a unified diff to a file. For small changes to a large file this is much shorter than rewriting the file with the Write tool.
+
+WHEN TO USE THIS TOOL:
+- Use to change a few places in a file in one call
+- Use to create a file, with a diff whose hunk header is @@ -0,0 +1,N @@
+
+HOW TO USE:
+- Provide the path of the file and a unified diff of the change, as produced by diff -u or git diff
+- --- and +++ file headers are optional; the diff applies to file_path
+- Context lines start with a space, removed lines with -, added lines with +
+- Include a few lines of unchanged context around each change
+
+FEATURES:
+- Each hunk applies where its context and removed lines match the file; a hunk whose line numbers are slightly off still applies
+- The result is returned as a diff of the file before and after
+
+LIMITATIONS:
+- You must read an existing file with the View tool before patching it
+- The patch applies to a single file
+- If any hunk doesn't match the file, nothing is written and the failing hunks are reported with the lines found in the file
+
+TIPS:
+- Read the file again after a failed patch and make the context match it exactly, including whitespace`
+)
+
+func NewApplyPatchTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service) BaseTool {
+	return &applyPatchTool{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+	}
+}
+
+func (a *applyPatchTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ApplyPatchToolName,
+		Description: applyPatchDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file to patch",
+			},
+			"diff": map[string]any{
+				"type":        "string",
+				"description": "The unified diff to apply to the file",
+			},
+		},
+		Required: []string{"file_path", "diff"},
+	}
+}
+
+func (a *applyPatchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ApplyPatchParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	if params.Diff == "" {
+		return NewTextErrorResponse("diff is required"), nil
+	}
+
+	filePath := params.FilePath
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+	}
+	if err := checkSandbox(filePath); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	oldContent := ""
+	fileInfo, err := os.Stat(filePath)
+	if err == nil {
+		if fileInfo.IsDir() {
+			return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
+		}
+		lastRead := getLastReadTime(filePath)
+		if lastRead.IsZero() {
+			return NewTextErrorResponse("you must read the file before patching it. Use the View tool first"), nil
+		}
+		if modTime := fileInfo.ModTime(); modTime.After(lastRead) {
+			return NewTextErrorResponse(
+				fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
+					filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
+				)), nil
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
+		}
+		oldContent = string(content)
+	} else if !os.IsNotExist(err) {
+		return ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
+	}
+
+	parsed, err := diff.ParseUnifiedDiff(params.Diff)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing diff: %s", err)), nil
+	}
+	if len(parsed.Hunks) == 0 {
+		return NewTextErrorResponse("the diff has no hunks; each change must start with an @@ -start,count +start,count @@ header"), nil
+	}
+
+	newContent, failures := diff.ApplyUnifiedDiff(oldContent, parsed)
+	if len(failures) > 0 {
+		messages := make([]string, 0, len(failures))
+		for _, failure := range failures {
+			messages = append(messages, failure.Error())
+		}
+		return NewTextErrorResponse(fmt.Sprintf("%d of %d hunks failed to apply, no changes made:\n\n%s",
+			len(failures), len(parsed.Hunks), strings.Join(messages, "\n\n"))), nil
+	}
+	if newContent == oldContent {
+		return NewTextErrorResponse("the diff makes no changes to the file"), nil
+	}
+
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for patching a file")
+	}
+
+	patchDiff, additions, removals := diff.GenerateDiff(oldContent, newContent, filePath)
+	if dryRun() {
+		return WithResponseMetadata(
+			NewTextResponse(dryRunResult(filePath, patchDiff)),
+			EditResponseMetadata{
+				Diff:      patchDiff,
+				Additions: additions,
+				Removals:  removals,
+			},
+		), nil
+	}
+
+	rootDir := config.WorkingDirectory()
+	permissionPath := filepath.Dir(filePath)
+	if strings.HasPrefix(filePath, rootDir) {
+		permissionPath = rootDir
+	}
+	p := a.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:    sessionID,
+			Path:         permissionPath,
+			ResourcePath: filePath,
+			ToolName:     ApplyPatchToolName,
+			Action:       permission.ActionWrite,
+			Description:  fmt.Sprintf("Apply patch to file %s", filePath),
+			Params: EditPermissionsParams{
+				FilePath: filePath,
+				Diff:     patchDiff,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	diagnosticsBefore := diagnosticsBeforeEdit(filePath, a.lspClients)
+	snapshot := snapshotFiles(filePath)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	if err := os.WriteFile(filePath, []byte(newContent), 0o644); err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
+	}
+	recordEdit(sessionID, snapshot)
+
+	// Check if file exists in history
+	file, err := a.files.GetByPathAndSession(ctx, filePath, sessionID)
+	if err != nil {
+		if _, err = a.files.Create(ctx, sessionID, filePath, oldContent); err != nil {
+			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+		}
+	}
+	if file.Content != oldContent {
+		// User Manually changed the content store an intermediate version
+		if _, err = a.files.CreateVersion(ctx, sessionID, filePath, oldContent); err != nil {
+			logging.Debug("Error creating file history version", "error", err)
+		}
+	}
+	if _, err = a.files.CreateVersion(ctx, sessionID, filePath, newContent); err != nil {
+		logging.Debug("Error creating file history version", "error", err)
+	}
+
+	recordFileWrite(filePath)
+	recordFileRead(filePath)
+
+	result := fmt.Sprintf("<result>\nPatch applied to %s: %d hunks, %d additions, %d removals\n</result>\n\n%s",
+		filePath, len(parsed.Hunks), additions, removals, patchDiff)
+	result += editDiagnostics(ctx, filePath, a.lspClients, diagnosticsBefore)
+	return WithResponseMetadata(
+		NewTextResponse(result),
+		EditResponseMetadata{
+			Diff:      patchDiff,
+			Additions: additions,
+			Removals:  removals,
+		},
+	), nil
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+func applyPatch(t *testing.T, filePath, patch string) ToolResponse {
+	t.Helper()
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(t.Name())
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, t.Name())
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+
+	input, err := json.Marshal(ApplyPatchParams{FilePath: filePath, Diff: patch})
+	require.NoError(t, err)
+	response, err := NewApplyPatchTool(nil, permissions, &versionRecorder{}).Run(ctx, ToolCall{Name: ApplyPatchToolName, Input: string(input)})
+	require.NoError(t, err)
+	return response
+}
+
+func TestApplyPatchTool(t *testing.T) {
+	root := t.TempDir()
+	useSandboxRoot(t, root)
+	filePath := filepath.Join(root, "main.go")
+	require.NoError(t, os.WriteFile(filePath, []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0o644))
+	recordFileRead(filePath)
+
+	response := applyPatch(t, filePath, "--- a/main.go\n+++ b/main.go\n@@ -3,3 +3,3 @@\n func main() {\n-\tprintln(\"hi\")\n+\tprintln(\"hello\")\n }\n")
+	require.False(t, response.IsError, response.Content)
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n", string(content))
+
+	var metadata EditResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	assert.Equal(t, 1, metadata.Additions)
+	assert.Equal(t, 1, metadata.Removals)
+}
+
+func TestApplyPatchToolReportsFailedHunksWithoutWriting(t *testing.T) {
+	root := t.TempDir()
+	useSandboxRoot(t, root)
+	filePath := filepath.Join(root, "main.go")
+	original := "one\ntwo\nthree\n"
+	require.NoError(t, os.WriteFile(filePath, []byte(original), 0o644))
+	recordFileRead(filePath)
+
+	response := applyPatch(t, filePath, "@@ -1,1 +1,1 @@\n-one\n+ONE\n@@ -3,1 +3,1 @@\n-tree\n+THREE\n")
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "1 of 2 hunks failed to apply")
+	assert.Contains(t, response.Content, "hunk 2 (@@ -3,1 +3,1 @@)")
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(content))
+}
@@ -33,9 +33,9 @@ type bashTool struct {
 const (
 	BashToolName = "bash"
 
-	DefaultTimeout  = 1 * 60 * 1000  // 1 minutes in milliseconds
-	MaxTimeout      = 10 * 60 * 1000 // 10 minutes in milliseconds
-	MaxOutputLength = 30000
+	MaxTimeout = 10 * 60 * 1000 // 10 minutes in milliseconds
+
+	outputTruncatedMarker = "[output truncated]"
 )
 
 var bannedCommands = []string{
@@ -73,7 +73,7 @@ Before executing the command, please follow these steps:
  - Capture the output of the command.
 
 4. Output Processing:
- - If the output exceeds %d characters, output will be truncated before being returned to you.
+ - If the output exceeds %d bytes, output will be truncated before being returned to you.
  - Prepare the output for display to the user.
 
 5. Return Result:
@@ -82,7 +82,7 @@ Before executing the command, please follow these steps:
 
 Usage notes:
 - The command argument is required.
-- You can specify an optional timeout in milliseconds (up to 600000ms / 10 minutes). If not specified, commands will timeout after 30 minutes.
+- You can specify an optional timeout in milliseconds (up to %dms). If not specified, commands will timeout after %dms.
 - VERY IMPORTANT: You MUST avoid using search commands like 'find' and 'grep'. Instead use Grep, Glob, or Agent tools to search. You MUST avoid read tools like 'cat', 'head', 'tail', and 'ls', and use FileRead and LS tools to read files.
 - When issuing multiple commands, use the ';' or '&&' operator to separate them. DO NOT use newlines (newlines are ok in quoted strings).
 - IMPORTANT: All commands share the same shell session. Shell state (environment variables, virtual environments, current directory, etc.) persist between commands. For example, if you set an environment variable as part of a command, the environment variable will persist for subsequent commands.
@@ -200,7 +200,7 @@ EOF
 
 Important:
 - Return an empty response - the user will see the gh output directly
-- Never update git config`, bannedCommandsStr, MaxOutputLength)
+- Never update git config`, bannedCommandsStr, maxOutputBytes(), maxTimeout(), defaultTimeout())
 }
 
 func NewBashTool(permission permission.Service) BaseTool {
@@ -220,7 +220,7 @@ func (b *bashTool) Info() ToolInfo {
 			},
 			"timeout": map[string]any{
 				"type":        "number",
-				"description": "Optional timeout in milliseconds (max 600000)",
+				"description": fmt.Sprintf("Optional timeout in milliseconds (max %d)", maxTimeout()),
 			},
 		},
 		Required: []string{"command"},
@@ -233,10 +233,10 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("invalid parameters"), nil
 	}
 
-	if params.Timeout > MaxTimeout {
-		params.Timeout = MaxTimeout
+	if params.Timeout > maxTimeout() {
+		params.Timeout = maxTimeout()
 	} else if params.Timeout <= 0 {
-		params.Timeout = DefaultTimeout
+		params.Timeout = defaultTimeout()
 	}
 
 	if params.Command == "" {
@@ -250,6 +250,18 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		}
 	}
 
+	shellConfig := config.Get().Shell
+	if entry, blocked := blockedCommand(params.Command, parseCommandRules(shellConfig.BlockedCommands)); blocked {
+		return NewTextErrorResponse(fmt.Sprintf("command is blocked by the shell.blockedCommands entry %q", entry)), nil
+	}
+
+	if roots := allowedBashRoots(); roots != nil {
+		cwd := shell.GetPersistentShell(config.WorkingDirectory()).Cwd()
+		if err := checkAllowedDirectories(params.Command, cwd, roots); err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+	}
+
 	isSafeReadOnly := false
 	cmdLower := strings.ToLower(params.Command)
 
@@ -266,13 +278,18 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
 	}
-	if !isSafeReadOnly {
+	if dryRun() && !dryRunAllowed(params.Command, isSafeReadOnly) {
+		return NewTextResponse(dryRunCommandResult(params.Command)), nil
+	}
+	preApproved := !hasSubstitutionOrRedirection(params.Command) &&
+		(isSafeReadOnly || allowedCommand(params.Command, parseCommandRules(shellConfig.AllowedCommands)))
+	if !preApproved {
 		p := b.permissions.Request(
 			permission.CreatePermissionRequest{
 				SessionID:   sessionID,
 				Path:        config.WorkingDirectory(),
 				ToolName:    BashToolName,
-				Action:      "execute",
+				Action:      permission.ActionExecute,
 				Description: fmt.Sprintf("Execute command: %s", params.Command),
 				Params: BashPermissionsParams{
 					Command: params.Command,
@@ -285,13 +302,13 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	}
 	startTime := time.Now()
 	shell := shell.GetPersistentShell(config.WorkingDirectory())
-	stdout, stderr, exitCode, interrupted, err := shell.Exec(ctx, params.Command, params.Timeout)
+	stdout, stderr, exitCode, interrupted, err := shell.ExecWithEnv(ctx, params.Command, bashEnv(sessionID), params.Timeout)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error executing command: %w", err)
 	}
 
-	stdout = truncateOutput(stdout)
-	stderr = truncateOutput(stderr)
+	stdout = truncateOutput(stdout, maxOutputBytes())
+	stderr = truncateOutput(stderr, maxOutputBytes())
 
 	errorMessage := stderr
 	if interrupted {
@@ -326,17 +343,35 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	return WithResponseMetadata(NewTextResponse(stdout), metadata), nil
 }
 
-func truncateOutput(content string) string {
-	if len(content) <= MaxOutputLength {
+// defaultTimeout returns the timeout, in milliseconds, for commands that
+// don't ask for one.
+func defaultTimeout() int {
+	return config.Get().Shell.TimeoutSeconds * 1000
+}
+
+// maxTimeout returns the longest timeout, in milliseconds, a command may ask
+// for. A configured default above MaxTimeout raises the limit to match.
+func maxTimeout() int {
+	return max(MaxTimeout, defaultTimeout())
+}
+
+func maxOutputBytes() int {
+	return config.Get().Shell.MaxOutputBytes
+}
+
+// truncateOutput keeps the start and end of content so that at most limit
+// bytes of it remain, and marks where the middle was cut.
+func truncateOutput(content string, limit int) string {
+	if len(content) <= limit {
 		return content
 	}
 
-	halfLength := MaxOutputLength / 2
-	start := content[:halfLength]
-	end := content[len(content)-halfLength:]
+	halfLength := limit / 2
+	start := strings.ToValidUTF8(content[:halfLength], "")
+	end := strings.ToValidUTF8(content[len(content)-halfLength:], "")
 
 	truncatedLinesCount := countLines(content[halfLength : len(content)-halfLength])
-	return fmt.Sprintf("%s\n\n... [%d lines truncated] ...\n\n%s", start, truncatedLinesCount, end)
+	return fmt.Sprintf("%s\n\n... %s %d lines omitted ...\n\n%s", start, outputTruncatedMarker, truncatedLinesCount, end)
 }
 
 func countLines(s string) int {
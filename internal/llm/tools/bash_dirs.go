@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// devicePaths can always be used in redirections, even when the bash tool is
+// restricted to a set of directories.
+var devicePaths = []string{"/dev/null", "/dev/stdin", "/dev/stdout", "/dev/stderr"}
+
+// allowedBashRoots resolves shell.allowedDirectories to absolute paths, with
+// their symlinks resolved. Without allowed directories the sandbox root, unless
+// it is lifted, is the only one. It returns nil when the bash tool is not
+// restricted.
+func allowedBashRoots() []string {
+	dirs := config.Get().Shell.AllowedDirectories
+	if root := sandboxRoot(); len(dirs) == 0 && root != "" {
+		dirs = []string{root}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	roots := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		roots = append(roots, resolveShellLink(resolveShellPath(dir, config.WorkingDirectory())))
+	}
+	return roots
+}
+
+// checkAllowedDirectories rejects commands that cd outside roots or refer to
+// absolute, home-relative or ".." paths outside them, once symlinks are
+// resolved. cwd is the directory the command starts in. The check is lexical:
+// paths built at runtime, for example from variables, are not seen.
+func checkAllowedDirectories(command, cwd string, roots []string) error {
+	cwd = resolveShellLink(cwd)
+	if !withinRoots(cwd, roots) {
+		return fmt.Errorf("the shell's current directory %s is outside the allowed directories: %s", cwd, strings.Join(roots, ", "))
+	}
+
+	dir := cwd
+	for _, words := range splitShellCommands(command) {
+		if words[0] == "cd" || words[0] == "pushd" {
+			target := "~"
+			for _, arg := range words[1:] {
+				if arg == "-" || !strings.HasPrefix(arg, "-") {
+					target = arg
+					break
+				}
+			}
+			if target == "-" {
+				return fmt.Errorf("'%s -' is not allowed when the bash tool is restricted to the allowed directories", words[0])
+			}
+			dir = resolveShellLink(resolveShellPath(target, dir))
+			if !withinRoots(dir, roots) {
+				return fmt.Errorf("cannot change directory to %s, it is outside the allowed directories: %s", dir, strings.Join(roots, ", "))
+			}
+			continue
+		}
+
+		for _, word := range words {
+			// Check the value of assignments and flags such as --out=/tmp/x.
+			if _, value, ok := strings.Cut(word, "="); ok && !isPathLike(word) {
+				word = value
+			}
+			if !isPathLike(word) {
+				continue
+			}
+			path := resolveShellLink(resolveShellPath(word, dir))
+			if !withinRoots(path, roots) {
+				return fmt.Errorf("path %s is outside the allowed directories: %s", path, strings.Join(roots, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func isPathLike(word string) bool {
+	if strings.HasPrefix(word, "/") || word == "~" || strings.HasPrefix(word, "~/") {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(word), "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveShellPath(path, dir string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// resolveShellLink resolves the symlinks in path, so that a link can't be used
+// to reach a directory outside the allowed ones. Paths that can't be resolved
+// are returned as they are.
+func resolveShellLink(path string) string {
+	if resolved, err := resolveExisting(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+func withinRoots(path string, roots []string) bool {
+	for _, device := range devicePaths {
+		if path == device {
+			return true
+		}
+	}
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitShellCommands splits a command line into the words of each simple
+// command, honoring quotes and escapes. Control operators, subshells and
+// redirections separate words.
+func splitShellCommands(command string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			switch {
+			case r == quote:
+				quote = 0
+			case r == '\\' && quote == '"' && i+1 < len(runes):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == ';' || r == '&' || r == '|' || r == '\n' || r == '(' || r == ')' || r == '`':
+			endCommand()
+		case r == '>' || r == '<' || unicode.IsSpace(r):
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
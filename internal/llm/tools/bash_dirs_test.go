@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func TestCheckAllowedDirectories(t *testing.T) {
+	roots := []string{"/work/project", "/work/shared"}
+
+	tests := []struct {
+		name    string
+		command string
+		allowed bool
+	}{
+		{"relative paths", "go test ./... && cat internal/app.go", true},
+		{"cd within workspace", "cd internal && ls ../cmd", true},
+		{"absolute path inside", "cat /work/shared/notes.txt", true},
+		{"second root", "cd /work/shared && ls", true},
+		{"device redirection", "go build ./... 2>/dev/null", true},
+		{"quoted text is not split", `echo "a;b" '| c'`, true},
+		{"cd outside", "cd /etc", false},
+		{"cd to parent", "cd ..", false},
+		{"cd home", "cd", false},
+		{"cd back", "cd -", false},
+		{"cd outside after a command", "ls; cd /tmp && rm -rf build", false},
+		{"absolute path outside", "cat /etc/passwd", false},
+		{"relative path escaping", "cat internal/../../other/secret", false},
+		{"home path", "cat ~/.ssh/id_rsa", false},
+		{"redirection outside", "echo hi >/tmp/out", false},
+		{"flag value outside", "go build -o=/usr/local/bin/app .", false},
+		{"subshell", "echo $(cd /; ls)", false},
+		{"quoted path", `cat "/etc/hosts"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAllowedDirectories(tt.command, "/work/project", roots)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckAllowedDirectoriesCwdOutside(t *testing.T) {
+	assert.Error(t, checkAllowedDirectories("ls", "/etc", []string{"/work/project"}))
+}
+
+func TestBashToolRejectsCommandOutsideAllowedDirectories(t *testing.T) {
+	cfg := config.Get()
+	original := cfg.Shell
+	t.Cleanup(func() { cfg.Shell = original })
+	cfg.Shell.AllowedDirectories = []string{"."}
+
+	input, err := json.Marshal(BashParams{Command: "cd .. && ls"})
+	require.NoError(t, err)
+
+	response, err := NewBashTool(nil).Run(context.Background(), ToolCall{Name: BashToolName, Input: string(input)})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "outside the allowed directories")
+	assert.Contains(t, response.Content, config.WorkingDirectory())
+}
+
+func TestCheckAllowedDirectoriesResolvesSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	roots := []string{resolveShellLink(root)}
+
+	assert.NoError(t, checkAllowedDirectories("ls "+filepath.Join(root, "main.go"), root, roots))
+	assert.Error(t, checkAllowedDirectories("cd escape && ls", root, roots))
+	assert.Error(t, checkAllowedDirectories("cat "+filepath.Join(root, "escape", "secret"), root, roots))
+	assert.Error(t, checkAllowedDirectories("ls", filepath.Join(root, "escape"), roots))
+}
+
+func TestAllowedBashRootsFallsBackToSandboxRoot(t *testing.T) {
+	cfg := config.Get()
+	original := cfg.Shell
+	t.Cleanup(func() { cfg.Shell = original })
+	cfg.Shell.AllowedDirectories = nil
+
+	useSandboxRoot(t, "")
+	assert.Nil(t, allowedBashRoots())
+	useSandboxRoot(t, "/")
+	assert.Nil(t, allowedBashRoots())
+
+	root := t.TempDir()
+	useSandboxRoot(t, root)
+	assert.Equal(t, []string{resolveShellLink(root)}, allowedBashRoots())
+
+	cfg.Shell.AllowedDirectories = []string{"/work/project"}
+	assert.Equal(t, []string{"/work/project"}, allowedBashRoots())
+}
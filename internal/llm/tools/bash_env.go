@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// sessionEnv holds the KEY=VALUE entries set for the bash commands of a
+// session on top of shell.env. It lives for as long as the app runs.
+var sessionEnv = struct {
+	mu      sync.Mutex
+	entries map[string][]string
+}{entries: make(map[string][]string)}
+
+// SetSessionEnv sets the KEY=VALUE entries added to the environment of the
+// bash commands run in a session, replacing any set before. $VAR and ${VAR}
+// in values are expanded when a command runs. No entries clear the override.
+func SetSessionEnv(sessionID string, env []string) {
+	sessionEnv.mu.Lock()
+	defer sessionEnv.mu.Unlock()
+	if len(env) == 0 {
+		delete(sessionEnv.entries, sessionID)
+		return
+	}
+	sessionEnv.entries[sessionID] = slices.Clone(env)
+}
+
+// SessionEnv returns the entries set for a session with SetSessionEnv.
+func SessionEnv(sessionID string) []string {
+	sessionEnv.mu.Lock()
+	defer sessionEnv.mu.Unlock()
+	return slices.Clone(sessionEnv.entries[sessionID])
+}
+
+// bashEnv returns the environment entries for a bash command run in a
+// session: shell.env followed by the session's own entries, which win when
+// both set a variable.
+func bashEnv(sessionID string) []string {
+	env := slices.Concat(config.Get().Shell.Env, SessionEnv(sessionID))
+	return config.ExpandEnv(env)
+}
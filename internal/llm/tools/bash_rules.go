@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// commandWrappers run the command that follows them, so blocked commands are
+// matched after them too.
+var commandWrappers = []string{"sudo", "doas", "command", "exec", "nohup", "time", "env", "xargs"}
+
+// commandRule is an entry of shell.allowedCommands or shell.blockedCommands:
+// either a regular expression written as /pattern/, matched against the
+// command line, or a command name followed by arguments.
+type commandRule struct {
+	entry string
+	re    *regexp.Regexp
+	// whole is re anchored at both ends, for allowed commands, each of whose
+	// simple commands must match it as a whole
+	whole *regexp.Regexp
+	words []string
+}
+
+func parseCommandRules(entries []string) []commandRule {
+	rules := make([]commandRule, 0, len(entries))
+	for _, entry := range entries {
+		if pattern, ok := config.CommandPattern(entry); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				// Invalid patterns are reported when the config is loaded
+				continue
+			}
+			whole := regexp.MustCompile(`^(?:` + pattern + `)$`)
+			rules = append(rules, commandRule{entry: entry, re: re, whole: whole})
+			continue
+		}
+		words := strings.Fields(entry)
+		if len(words) == 0 {
+			continue
+		}
+		rules = append(rules, commandRule{entry: entry, words: words})
+	}
+	return rules
+}
+
+// blockedCommand returns the rule that blocks command, if any. A command is
+// blocked when any of its simple commands runs the rule's command, directly
+// or through a wrapper such as sudo, with all of the rule's arguments in any
+// order. Short flags are compared letter by letter, so "rm -rf" also blocks
+// "rm -f -r" and "rm -vfr".
+func blockedCommand(command string, rules []commandRule) (string, bool) {
+	for _, rule := range rules {
+		if rule.re != nil {
+			if rule.re.MatchString(command) {
+				return rule.entry, true
+			}
+			continue
+		}
+		for _, words := range splitShellCommands(command) {
+			if hasArguments(unwrapCommand(words), rule.words) {
+				return rule.entry, true
+			}
+		}
+	}
+	return "", false
+}
+
+// allowedCommand reports whether command is pre-approved. Every simple command
+// in it must start with the words of an allowed rule or match an allowed
+// pattern as a whole, so "ls && rm -r build" is not approved by "ls", nor
+// "git status; rm -r build" by /git status.*/. Lines that substitute commands
+// or redirect input or output are never pre-approved, since "cat" would
+// otherwise approve cat "$(rm -r build)" and cat /dev/null > go.mod.
+func allowedCommand(command string, rules []commandRule) bool {
+	if len(rules) == 0 || hasSubstitutionOrRedirection(command) {
+		return false
+	}
+
+	commands := splitShellCommands(command)
+	if len(commands) == 0 {
+		return false
+	}
+	for _, words := range commands {
+		if !startsWithAllowedRule(rules, words) && !matchesAllowedPattern(rules, words) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAllowedPattern reports whether the words of a simple command, joined
+// by spaces, match an allowed pattern as a whole.
+func matchesAllowedPattern(rules []commandRule, words []string) bool {
+	command := strings.Join(words, " ")
+	for _, rule := range rules {
+		if rule.whole != nil && rule.whole.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSubstitutionOrRedirection reports whether command contains command or
+// process substitution, or a redirection. Quotes are not taken into account,
+// so quoted text such as 'a > b' also counts.
+func hasSubstitutionOrRedirection(command string) bool {
+	return strings.Contains(command, "$(") || strings.ContainsAny(command, "`<>")
+}
+
+func startsWithAllowedRule(rules []commandRule, words []string) bool {
+	for _, rule := range rules {
+		if rule.words == nil || len(words) < len(rule.words) {
+			continue
+		}
+		matched := true
+		for i, word := range rule.words {
+			if words[i] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapCommand drops leading variable assignments and wrapper commands, along
+// with the wrappers' flags.
+func unwrapCommand(words []string) []string {
+	for len(words) > 0 {
+		word := words[0]
+		switch {
+		case isAssignment(word):
+			words = words[1:]
+		case isWrapper(word):
+			words = words[1:]
+			for len(words) > 0 && (strings.HasPrefix(words[0], "-") || isAssignment(words[0])) {
+				words = words[1:]
+			}
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+func isWrapper(word string) bool {
+	for _, wrapper := range commandWrappers {
+		if filepath.Base(word) == wrapper {
+			return true
+		}
+	}
+	return false
+}
+
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && (i == 0 || !(r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasArguments reports whether words runs the command named by ruleWords[0]
+// with all of the remaining rule words among its arguments.
+func hasArguments(words, ruleWords []string) bool {
+	if len(words) == 0 || filepath.Base(words[0]) != ruleWords[0] {
+		return false
+	}
+
+	args := words[1:]
+	shortFlags := make(map[rune]bool)
+	for _, arg := range args {
+		if isShortFlags(arg) {
+			for _, flag := range arg[1:] {
+				shortFlags[flag] = true
+			}
+		}
+	}
+
+	for _, want := range ruleWords[1:] {
+		if isShortFlags(want) {
+			for _, flag := range want[1:] {
+				if !shortFlags[flag] {
+					return false
+				}
+			}
+			continue
+		}
+		found := false
+		for _, arg := range args {
+			if arg == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// isShortFlags reports whether word is a cluster of single-letter flags such
+// as -rf.
+func isShortFlags(word string) bool {
+	if len(word) < 2 || word[0] != '-' || word[1] == '-' {
+		return false
+	}
+	for _, r := range word[1:] {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
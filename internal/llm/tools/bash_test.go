@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateOutput(t *testing.T) {
+	assert.Equal(t, "short", truncateOutput("short", 10))
+
+	content := strings.Repeat("head\n", 10) + strings.Repeat("middle\n", 100) + strings.Repeat("tail\n", 10)
+	truncated := truncateOutput(content, 100)
+
+	assert.Contains(t, truncated, outputTruncatedMarker)
+	assert.True(t, strings.HasPrefix(truncated, strings.Repeat("head\n", 10)))
+	assert.True(t, strings.HasSuffix(truncated, strings.Repeat("tail\n", 10)))
+	assert.NotContains(t, truncated, strings.Repeat("middle\n", 10))
+}
@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/logging"
+)
+
+// resultCache keeps the successful results of the tools that reach the
+// network, such as fetch and sourcegraph, so that an identical call later in
+// the same session is answered without another request. Results are kept in
+// memory and, with tools.cache.persist, on disk under the data directory.
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResult
+	now     func() time.Time
+}
+
+type cachedResult struct {
+	Tool     string       `json:"tool"`
+	Request  string       `json:"request"`
+	Response ToolResponse `json:"response"`
+	Expires  time.Time    `json:"expires"`
+}
+
+var toolResultCache = &resultCache{
+	entries: make(map[string]cachedResult),
+	now:     time.Now,
+}
+
+// cacheKey identifies a request to a tool within a session. It is also the
+// name of the file the result is persisted in.
+func cacheKey(sessionID, tool, request string) string {
+	sum := sha256.Sum256([]byte(sessionID + "\x00" + tool + "\x00" + request))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached result of a request made earlier in the session of
+// ctx, if caching is enabled and the result has not expired.
+func (c *resultCache) get(ctx context.Context, tool, request string) (ToolResponse, bool) {
+	sessionID, _ := GetContextValues(ctx)
+	cfg := config.Get()
+	if sessionID == "" || cfg == nil || !cfg.Tools.Cache.Enabled {
+		return ToolResponse{}, false
+	}
+	key := cacheKey(sessionID, tool, request)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok && cfg.Tools.Cache.Persist {
+		entry, ok = readCachedResult(filepath.Join(config.ToolCacheDirectory(sessionID), key+".json"))
+	}
+	if !ok || entry.Tool != tool || entry.Request != request {
+		return ToolResponse{}, false
+	}
+	if !c.now().Before(entry.Expires) {
+		delete(c.entries, key)
+		if cfg.Tools.Cache.Persist {
+			os.Remove(filepath.Join(config.ToolCacheDirectory(sessionID), key+".json"))
+		}
+		return ToolResponse{}, false
+	}
+	c.entries[key] = entry
+
+	logging.Debug("Using cached tool result", "tool", tool, "request", request, "session", sessionID, "expires", entry.Expires)
+	return entry.Response, true
+}
+
+// put caches the result of a request. Error results are not cached, so that
+// a failed request is tried again.
+func (c *resultCache) put(ctx context.Context, tool, request string, response ToolResponse) {
+	sessionID, _ := GetContextValues(ctx)
+	cfg := config.Get()
+	if sessionID == "" || cfg == nil || !cfg.Tools.Cache.Enabled || response.IsError {
+		return
+	}
+	key := cacheKey(sessionID, tool, request)
+	entry := cachedResult{
+		Tool:     tool,
+		Request:  request,
+		Response: response,
+		Expires:  c.now().Add(time.Duration(cfg.Tools.Cache.TTLSeconds) * time.Second),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	if cfg.Tools.Cache.Persist {
+		if err := writeCachedResult(filepath.Join(config.ToolCacheDirectory(sessionID), key+".json"), entry); err != nil {
+			logging.Warn("Failed to persist cached tool result", "tool", tool, "error", err)
+		}
+	}
+}
+
+func readCachedResult(path string) (cachedResult, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cachedResult{}, false
+	}
+	var entry cachedResult
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return cachedResult{}, false
+	}
+	return entry, true
+}
+
+func writeCachedResult(path string, entry cachedResult) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func withToolCacheConfig(t *testing.T, cache config.ToolCacheConfig) {
+	t.Helper()
+	cfg := config.Get()
+	original, originalData := cfg.Tools.Cache, cfg.Data
+	t.Cleanup(func() { cfg.Tools.Cache, cfg.Data = original, originalData })
+	cfg.Tools.Cache = cache
+}
+
+func sessionContext(sessionID string) context.Context {
+	return context.WithValue(context.Background(), SessionIDContextKey, sessionID)
+}
+
+func TestResultCache(t *testing.T) {
+	withToolCacheConfig(t, config.ToolCacheConfig{Enabled: true, TTLSeconds: 60})
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache := &resultCache{entries: make(map[string]cachedResult), now: func() time.Time { return now }}
+	ctx := sessionContext("first")
+
+	cache.put(ctx, FetchToolName, "markdown https://go.dev", NewTextResponse("Go"))
+	cache.put(ctx, FetchToolName, "markdown https://fails.example", NewTextErrorResponse("Request failed"))
+
+	response, ok := cache.get(ctx, FetchToolName, "markdown https://go.dev")
+	require.True(t, ok)
+	assert.Equal(t, "Go", response.Content)
+
+	_, ok = cache.get(ctx, FetchToolName, "text https://go.dev")
+	assert.False(t, ok, "a different request")
+	_, ok = cache.get(ctx, SourcegraphToolName, "markdown https://go.dev")
+	assert.False(t, ok, "a different tool")
+	_, ok = cache.get(sessionContext("second"), FetchToolName, "markdown https://go.dev")
+	assert.False(t, ok, "a different session")
+	_, ok = cache.get(ctx, FetchToolName, "markdown https://fails.example")
+	assert.False(t, ok, "errors are not cached")
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get(ctx, FetchToolName, "markdown https://go.dev")
+	assert.False(t, ok, "expired")
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	withToolCacheConfig(t, config.ToolCacheConfig{Enabled: false, TTLSeconds: 60})
+	cache := &resultCache{entries: make(map[string]cachedResult), now: time.Now}
+	ctx := sessionContext("session")
+
+	cache.put(ctx, FetchToolName, "https://go.dev", NewTextResponse("Go"))
+	_, ok := cache.get(ctx, FetchToolName, "https://go.dev")
+	assert.False(t, ok)
+}
+
+func TestResultCachePersist(t *testing.T) {
+	withToolCacheConfig(t, config.ToolCacheConfig{Enabled: true, TTLSeconds: 60, Persist: true})
+	config.Get().Data.Directory = t.TempDir()
+	ctx := sessionContext("session")
+
+	first := &resultCache{entries: make(map[string]cachedResult), now: time.Now}
+	first.put(ctx, FetchURLToolName, "https://go.dev", NewTextResponse("Go"))
+
+	// A new cache, as after a restart, reads the result from disk
+	second := &resultCache{entries: make(map[string]cachedResult), now: time.Now}
+	response, ok := second.get(ctx, FetchURLToolName, "https://go.dev")
+	require.True(t, ok)
+	assert.Equal(t, "Go", response.Content)
+	assert.DirExists(t, config.ToolCacheDirectory("session"))
+}
@@ -0,0 +1,318 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/lsp"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+type CallHierarchyParams struct {
+	FilePath  string `json:"file_path"`
+	Line      int    `json:"line"`
+	Character int    `json:"character"`
+	Direction string `json:"direction,omitempty"`
+	Depth     int    `json:"depth,omitempty"`
+}
+
+const (
+	callDirectionIncoming = "incoming"
+	callDirectionOutgoing = "outgoing"
+	callDirectionBoth     = "both"
+)
+
+const (
+	// maxCallHierarchyDepth is the deepest the tree of calls is followed.
+	maxCallHierarchyDepth = 3
+	// maxCallHierarchyCalls is how many calls are listed in each direction.
+	maxCallHierarchyCalls = 50
+)
+
+type callHierarchyTool struct {
+	lspClients map[string]*lsp.Client
+}
+
+const (
+	CallHierarchyToolName    = "call_hierarchy"
+	callHierarchyDescription = `Shows who calls the function at a position in a file (incoming calls) and what it calls (outgoing calls), as an indented tree with locations.
+WHEN TO USE THIS TOOL:
+- Use before changing a function's signature or behavior, to find every caller that could be affected
+- Helpful for understanding what a function depends on without reading all of it
+HOW TO USE:
+- Provide the path of the file and the line and character of the function's name, both counted from 1 as the View tool shows them
+- direction is incoming, outgoing or both (the default)
+- depth is how many levels of calls to follow, from 1 (the default) to 3
+FEATURES:
+- Each call is listed with the file, line and character of the function, and the lines where the call is made
+- Functions already listed higher up in the tree are not expanded again
+LIMITATIONS:
+- Needs a language server for the file's language that supports call hierarchy
+- At most 50 calls are listed in each direction
+- Calls through interfaces, function values or reflection may be missing
+`
+)
+
+func NewCallHierarchyTool(lspClients map[string]*lsp.Client) BaseTool {
+	return &callHierarchyTool{
+		lspClients,
+	}
+}
+
+func (c *callHierarchyTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        CallHierarchyToolName,
+		Description: callHierarchyDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file containing the function",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The line of the function's name, counted from 1",
+			},
+			"character": map[string]any{
+				"type":        "integer",
+				"description": "The character of the function's name within the line, counted from 1",
+			},
+			"direction": map[string]any{
+				"type":        "string",
+				"description": "Which calls to show: incoming, outgoing or both (default)",
+				"enum":        []string{callDirectionIncoming, callDirectionOutgoing, callDirectionBoth},
+			},
+			"depth": map[string]any{
+				"type":        "integer",
+				"description": "How many levels of calls to follow, from 1 (default) to 3",
+			},
+		},
+		Required: []string{"file_path", "line", "character"},
+	}
+}
+
+func (c *callHierarchyTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params CallHierarchyParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	switch params.Direction {
+	case "":
+		params.Direction = callDirectionBoth
+	case callDirectionIncoming, callDirectionOutgoing, callDirectionBoth:
+	default:
+		return NewTextErrorResponse("direction must be incoming, outgoing or both"), nil
+	}
+	if params.Depth <= 0 {
+		params.Depth = 1
+	}
+	params.Depth = min(params.Depth, maxCallHierarchyDepth)
+	if len(c.lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	filePath, err := lspFilePath(params.FilePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	position, err := lspPosition(filePath, params.Line, params.Character)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	clients := lspClientsSupporting(c.lspClients, "textDocument/prepareCallHierarchy")
+	if len(clients) == 0 {
+		return NewTextErrorResponse("no LSP server supports call hierarchy"), nil
+	}
+
+	result := fanOutLSP(ctx, clients, func(ctx context.Context, _ string, client *lsp.Client) (string, error) {
+		if err := client.OpenFile(ctx, filePath); err != nil {
+			return "", err
+		}
+		items, err := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+				Position:     position,
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		trees := make([]string, 0, len(items))
+		for _, item := range items {
+			tree, err := callHierarchyTree(ctx, client, item, params.Direction, params.Depth)
+			if err != nil {
+				return "", err
+			}
+			trees = append(trees, tree)
+		}
+		return strings.Join(trees, "\n\n"), nil
+	})
+
+	names := make([]string, 0, len(result.Results))
+	for name, tree := range result.Results {
+		if tree != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var output string
+	switch len(names) {
+	case 0:
+		output = fmt.Sprintf("No function found at %s:%d:%d", filePath, params.Line, params.Character)
+	case 1:
+		output = result.Results[names[0]]
+	default:
+		sections := make([]string, 0, len(names))
+		for _, name := range names {
+			sections = append(sections, fmt.Sprintf("[%s]\n%s", name, result.Results[name]))
+		}
+		output = strings.Join(sections, "\n\n")
+	}
+	if note := result.Note(); note != "" {
+		output += "\n\n" + note
+	}
+	return NewTextResponse(output), nil
+}
+
+// callHierarchyClient is the part of an LSP client that follows calls.
+type callHierarchyClient interface {
+	IncomingCalls(ctx context.Context, params protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error)
+	OutgoingCalls(ctx context.Context, params protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error)
+}
+
+// callHierarchyTree formats the calls of item in direction, followed depth
+// levels deep, as an indented tree.
+func callHierarchyTree(ctx context.Context, client callHierarchyClient, item protocol.CallHierarchyItem, direction string, depth int) (string, error) {
+	var b strings.Builder
+	b.WriteString(callHierarchyItemLine(item, nil))
+	b.WriteString("\n")
+
+	if direction != callDirectionOutgoing {
+		w := &callTreeWriter{b: &b, client: client, incoming: true, depth: depth}
+		fmt.Fprintf(&b, "Incoming calls (who calls %s):\n", item.Name)
+		if err := w.write(ctx, item, 1, map[string]bool{callHierarchyItemKey(item): true}); err != nil {
+			return "", err
+		}
+		w.finish()
+	}
+	if direction != callDirectionIncoming {
+		w := &callTreeWriter{b: &b, client: client, incoming: false, depth: depth}
+		fmt.Fprintf(&b, "Outgoing calls (what %s calls):\n", item.Name)
+		if err := w.write(ctx, item, 1, map[string]bool{callHierarchyItemKey(item): true}); err != nil {
+			return "", err
+		}
+		w.finish()
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// callTreeWriter writes the calls of one direction, counting them against
+// maxCallHierarchyCalls.
+type callTreeWriter struct {
+	b        *strings.Builder
+	client   callHierarchyClient
+	incoming bool
+	depth    int
+	written  int
+	omitted  int
+}
+
+// write lists the calls of item at level, and theirs down to the writer's
+// depth. ancestors holds the items above in the tree, which aren't expanded
+// again so that recursion ends.
+func (w *callTreeWriter) write(ctx context.Context, item protocol.CallHierarchyItem, level int, ancestors map[string]bool) error {
+	calls, err := w.calls(ctx, item)
+	if err != nil {
+		return err
+	}
+	if level == 1 && len(calls) == 0 {
+		w.b.WriteString("  (none)\n")
+		return nil
+	}
+	for _, call := range calls {
+		if w.written >= maxCallHierarchyCalls {
+			w.omitted++
+			continue
+		}
+		w.written++
+		w.b.WriteString(strings.Repeat("  ", level))
+		w.b.WriteString(callHierarchyItemLine(call.item, call.fromRanges))
+		w.b.WriteString("\n")
+
+		key := callHierarchyItemKey(call.item)
+		if level >= w.depth || ancestors[key] {
+			continue
+		}
+		ancestors[key] = true
+		if err := w.write(ctx, call.item, level+1, ancestors); err != nil {
+			return err
+		}
+		delete(ancestors, key)
+	}
+	return nil
+}
+
+// finish notes the calls left out of the tree.
+func (w *callTreeWriter) finish() {
+	if w.omitted > 0 {
+		fmt.Fprintf(w.b, "  ... %d more calls not shown\n", w.omitted)
+	}
+}
+
+type hierarchyCall struct {
+	item       protocol.CallHierarchyItem
+	fromRanges []protocol.Range
+}
+
+func (w *callTreeWriter) calls(ctx context.Context, item protocol.CallHierarchyItem) ([]hierarchyCall, error) {
+	var calls []hierarchyCall
+	if w.incoming {
+		incoming, err := w.client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{Item: item})
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range incoming {
+			calls = append(calls, hierarchyCall{item: call.From, fromRanges: call.FromRanges})
+		}
+		return calls, nil
+	}
+	outgoing, err := w.client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{Item: item})
+	if err != nil {
+		return nil, err
+	}
+	for _, call := range outgoing {
+		calls = append(calls, hierarchyCall{item: call.To, fromRanges: call.FromRanges})
+	}
+	return calls, nil
+}
+
+// callHierarchyItemLine formats a function with its location and, for a call,
+// the lines where the call is made.
+func callHierarchyItemLine(item protocol.CallHierarchyItem, fromRanges []protocol.Range) string {
+	line := item.Name
+	if item.Detail != "" {
+		line += " " + item.Detail
+	}
+	start := item.SelectionRange.Start
+	line += fmt.Sprintf(" - %s:%d:%d", item.URI.Path(), start.Line+1, start.Character+1)
+	if len(fromRanges) > 0 {
+		lines := make([]string, 0, len(fromRanges))
+		for _, r := range fromRanges {
+			lines = append(lines, fmt.Sprint(r.Start.Line+1))
+		}
+		line += fmt.Sprintf(" (call at line %s)", strings.Join(lines, ", "))
+	}
+	return line
+}
+
+// callHierarchyItemKey identifies a function in the tree.
+func callHierarchyItemKey(item protocol.CallHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
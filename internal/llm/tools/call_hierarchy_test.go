@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+// stubCallHierarchy answers calls from a graph of function names: callers
+// lists who calls each function.
+type stubCallHierarchy struct {
+	callers map[string][]string
+}
+
+func hierarchyItem(name string, line uint32) protocol.CallHierarchyItem {
+	position := protocol.Position{Line: line, Character: 5}
+	return protocol.CallHierarchyItem{
+		Name:           name,
+		URI:            "file:///project/main.go",
+		SelectionRange: protocol.Range{Start: position, End: position},
+	}
+}
+
+func (s stubCallHierarchy) line(name string) uint32 {
+	return uint32(name[len(name)-1]-'a') * 10
+}
+
+func (s stubCallHierarchy) IncomingCalls(ctx context.Context, params protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	var calls []protocol.CallHierarchyIncomingCall
+	for _, caller := range s.callers[params.Item.Name] {
+		line := s.line(caller) + 1
+		calls = append(calls, protocol.CallHierarchyIncomingCall{
+			From:       hierarchyItem(caller, s.line(caller)),
+			FromRanges: []protocol.Range{{Start: protocol.Position{Line: line}}},
+		})
+	}
+	return calls, nil
+}
+
+func (s stubCallHierarchy) OutgoingCalls(ctx context.Context, params protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	var calls []protocol.CallHierarchyOutgoingCall
+	for callee, callers := range s.callers {
+		for _, caller := range callers {
+			if caller == params.Item.Name {
+				calls = append(calls, protocol.CallHierarchyOutgoingCall{
+					To:         hierarchyItem(callee, s.line(callee)),
+					FromRanges: []protocol.Range{{Start: protocol.Position{Line: s.line(caller) + 2}}},
+				})
+			}
+		}
+	}
+	return calls, nil
+}
+
+func TestCallHierarchyTree(t *testing.T) {
+	// a calls b, b calls c, and c calls b again
+	client := stubCallHierarchy{callers: map[string][]string{
+		"fn_b": {"fn_a", "fn_c"},
+		"fn_c": {"fn_b"},
+	}}
+
+	tree, err := callHierarchyTree(context.Background(), client, hierarchyItem("fn_c", 20), callDirectionBoth, 3)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"fn_c - /project/main.go:21:6",
+		"Incoming calls (who calls fn_c):",
+		"  fn_b - /project/main.go:11:6 (call at line 12)",
+		"    fn_a - /project/main.go:1:6 (call at line 2)",
+		"    fn_c - /project/main.go:21:6 (call at line 22)",
+		"Outgoing calls (what fn_c calls):",
+		"  fn_b - /project/main.go:11:6 (call at line 23)",
+		"    fn_c - /project/main.go:21:6 (call at line 13)",
+	}, "\n"), tree)
+}
+
+func TestCallHierarchyTreeDepthAndLimit(t *testing.T) {
+	callers := make([]string, maxCallHierarchyCalls+5)
+	for i := range callers {
+		callers[i] = fmt.Sprintf("caller%d_a", i)
+	}
+	client := stubCallHierarchy{callers: map[string][]string{
+		"fn_b":      callers,
+		"caller0_a": {"deep_a"},
+	}}
+
+	tree, err := callHierarchyTree(context.Background(), client, hierarchyItem("fn_b", 10), callDirectionIncoming, 1)
+	require.NoError(t, err)
+	assert.NotContains(t, tree, "deep_a")
+	assert.NotContains(t, tree, "Outgoing calls")
+	assert.Equal(t, maxCallHierarchyCalls, strings.Count(tree, "\n  caller"))
+	assert.True(t, strings.HasSuffix(tree, "  ... 5 more calls not shown"))
+
+	tree, err = callHierarchyTree(context.Background(), client, hierarchyItem("fn_b", 10), callDirectionOutgoing, 1)
+	require.NoError(t, err)
+	assert.Contains(t, tree, "Outgoing calls (what fn_b calls):\n  (none)")
+}
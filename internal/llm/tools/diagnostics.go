@@ -33,7 +33,7 @@ HOW TO USE:
 - Results are displayed in a structured format with severity levels
 FEATURES:
 - Displays errors, warnings, and hints
-- Groups diagnostics by severity
+- Groups diagnostics by severity, errors first, with a count summary
 - Provides detailed information about each diagnostic
 LIMITATIONS:
 - Results are limited to the diagnostics provided by the LSP clients
@@ -88,12 +88,9 @@ func (b *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 }
 
 func notifyLspOpenFile(ctx context.Context, filePath string, lsps map[string]*lsp.Client) {
-	for _, client := range lsps {
-		err := client.OpenFile(ctx, filePath)
-		if err != nil {
-			continue
-		}
-	}
+	fanOutLSP(ctx, lsps, func(ctx context.Context, _ string, client *lsp.Client) (struct{}, error) {
+		return struct{}{}, client.OpenFile(ctx, filePath)
+	})
 }
 
 func waitForLspDiagnostics(ctx context.Context, filePath string, lsps map[string]*lsp.Client) {
@@ -123,19 +120,14 @@ func waitForLspDiagnostics(ctx context.Context, filePath string, lsps map[string
 		}
 
 		client.RegisterNotificationHandler("textDocument/publishDiagnostics", handler)
+	}
 
+	fanOutLSP(ctx, lsps, func(ctx context.Context, _ string, client *lsp.Client) (struct{}, error) {
 		if client.IsFileOpen(filePath) {
-			err := client.NotifyChange(ctx, filePath)
-			if err != nil {
-				continue
-			}
-		} else {
-			err := client.OpenFile(ctx, filePath)
-			if err != nil {
-				continue
-			}
+			return struct{}{}, client.NotifyChange(ctx, filePath)
 		}
-	}
+		return struct{}{}, client.OpenFile(ctx, filePath)
+	})
 
 	select {
 	case <-diagChan:
@@ -154,142 +146,183 @@ func hasDiagnosticsChanged(current, original map[protocol.DocumentUri][]protocol
 	return false
 }
 
+// diagnosticEntry is a formatted diagnostic together with its severity so that
+// results can be grouped and ordered without re-parsing the formatted text.
+type diagnosticEntry struct {
+	severity protocol.DiagnosticSeverity
+	text     string
+}
+
+// severityRank orders diagnostics errors first, then warnings, info and hints.
+// Diagnostics without a severity are treated as info, matching how they are labelled.
+func severityRank(severity protocol.DiagnosticSeverity) int {
+	switch severity {
+	case protocol.SeverityError:
+		return 0
+	case protocol.SeverityWarning:
+		return 1
+	case protocol.SeverityHint:
+		return 3
+	default:
+		return 2
+	}
+}
+
 func getDiagnostics(filePath string, lsps map[string]*lsp.Client) string {
-	fileDiagnostics := []string{}
-	projectDiagnostics := []string{}
-
-	formatDiagnostic := func(pth string, diagnostic protocol.Diagnostic, source string) string {
-		severity := "Info"
-		switch diagnostic.Severity {
-		case protocol.SeverityError:
-			severity = "Error"
-		case protocol.SeverityWarning:
-			severity = "Warn"
-		case protocol.SeverityHint:
-			severity = "Hint"
-		}
+	return formatDiagnostics(filePath, collectDiagnostics(lsps))
+}
 
-		location := fmt.Sprintf("%s:%d:%d", pth, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1)
+// collectDiagnostics gathers the cached diagnostics of every LSP client. They
+// are read from memory, so there is no server to wait for.
+func collectDiagnostics(lsps map[string]*lsp.Client) map[string]map[protocol.DocumentUri][]protocol.Diagnostic {
+	diagnostics := make(map[string]map[protocol.DocumentUri][]protocol.Diagnostic, len(lsps))
+	for lspName, client := range lsps {
+		diagnostics[lspName] = client.GetDiagnostics()
+	}
+	return diagnostics
+}
 
-		sourceInfo := ""
-		if diagnostic.Source != "" {
-			sourceInfo = diagnostic.Source
-		} else if source != "" {
-			sourceInfo = source
-		}
+// formatDiagnostics renders diagnostics keyed by LSP name, splitting them into
+// the current file and the rest of the project, with a severity summary first.
+func formatDiagnostics(filePath string, diagnostics map[string]map[protocol.DocumentUri][]protocol.Diagnostic) string {
+	fileDiagnostics := []diagnosticEntry{}
+	projectDiagnostics := []diagnosticEntry{}
 
-		codeInfo := ""
-		if diagnostic.Code != nil {
-			codeInfo = fmt.Sprintf("[%v]", diagnostic.Code)
-		}
+	for lspName, clientDiagnostics := range diagnostics {
+		for location, diags := range clientDiagnostics {
+			isCurrentFile := location.Path() == filePath
 
-		tagsInfo := ""
-		if len(diagnostic.Tags) > 0 {
-			tags := []string{}
-			for _, tag := range diagnostic.Tags {
-				switch tag {
-				case protocol.Unnecessary:
-					tags = append(tags, "unnecessary")
-				case protocol.Deprecated:
-					tags = append(tags, "deprecated")
+			for _, diag := range diags {
+				entry := diagnosticEntry{
+					severity: diag.Severity,
+					text:     formatDiagnostic(location.Path(), diag, lspName),
 				}
-			}
-			if len(tags) > 0 {
-				tagsInfo = fmt.Sprintf(" (%s)", strings.Join(tags, ", "))
-			}
-		}
-
-		return fmt.Sprintf("%s: %s [%s]%s%s %s",
-			severity,
-			location,
-			sourceInfo,
-			codeInfo,
-			tagsInfo,
-			diagnostic.Message)
-	}
 
-	for lspName, client := range lsps {
-		diagnostics := client.GetDiagnostics()
-		if len(diagnostics) > 0 {
-			for location, diags := range diagnostics {
-				isCurrentFile := location.Path() == filePath
-
-				for _, diag := range diags {
-					formattedDiag := formatDiagnostic(location.Path(), diag, lspName)
-
-					if isCurrentFile {
-						fileDiagnostics = append(fileDiagnostics, formattedDiag)
-					} else {
-						projectDiagnostics = append(projectDiagnostics, formattedDiag)
-					}
+				if isCurrentFile {
+					fileDiagnostics = append(fileDiagnostics, entry)
+				} else {
+					projectDiagnostics = append(projectDiagnostics, entry)
 				}
 			}
 		}
 	}
 
-	sort.Slice(fileDiagnostics, func(i, j int) bool {
-		iIsError := strings.HasPrefix(fileDiagnostics[i], "Error")
-		jIsError := strings.HasPrefix(fileDiagnostics[j], "Error")
-		if iIsError != jIsError {
-			return iIsError // Errors come first
-		}
-		return fileDiagnostics[i] < fileDiagnostics[j] // Then alphabetically
-	})
-
-	sort.Slice(projectDiagnostics, func(i, j int) bool {
-		iIsError := strings.HasPrefix(projectDiagnostics[i], "Error")
-		jIsError := strings.HasPrefix(projectDiagnostics[j], "Error")
-		if iIsError != jIsError {
-			return iIsError
-		}
-		return projectDiagnostics[i] < projectDiagnostics[j]
-	})
+	sortDiagnostics(fileDiagnostics)
+	sortDiagnostics(projectDiagnostics)
 
 	output := ""
 
+	if len(fileDiagnostics) > 0 || len(projectDiagnostics) > 0 {
+		output += "\n<diagnostic_summary>\n"
+		output += fmt.Sprintf("Current file: %s\n", summarizeSeverities(fileDiagnostics))
+		output += fmt.Sprintf("Project: %s\n", summarizeSeverities(projectDiagnostics))
+		output += "</diagnostic_summary>\n"
+	}
+
 	if len(fileDiagnostics) > 0 {
 		output += "\n<file_diagnostics>\n"
-		if len(fileDiagnostics) > 10 {
-			output += strings.Join(fileDiagnostics[:10], "\n")
-			output += fmt.Sprintf("\n... and %d more diagnostics", len(fileDiagnostics)-10)
-		} else {
-			output += strings.Join(fileDiagnostics, "\n")
-		}
+		output += joinDiagnostics(fileDiagnostics, 10)
 		output += "\n</file_diagnostics>\n"
 	}
 
 	if len(projectDiagnostics) > 0 {
 		output += "\n<project_diagnostics>\n"
-		if len(projectDiagnostics) > 10 {
-			output += strings.Join(projectDiagnostics[:10], "\n")
-			output += fmt.Sprintf("\n... and %d more diagnostics", len(projectDiagnostics)-10)
-		} else {
-			output += strings.Join(projectDiagnostics, "\n")
-		}
+		output += joinDiagnostics(projectDiagnostics, 10)
 		output += "\n</project_diagnostics>\n"
 	}
 
-	if len(fileDiagnostics) > 0 || len(projectDiagnostics) > 0 {
-		fileErrors := countSeverity(fileDiagnostics, "Error")
-		fileWarnings := countSeverity(fileDiagnostics, "Warn")
-		projectErrors := countSeverity(projectDiagnostics, "Error")
-		projectWarnings := countSeverity(projectDiagnostics, "Warn")
+	return output
+}
 
-		output += "\n<diagnostic_summary>\n"
-		output += fmt.Sprintf("Current file: %d errors, %d warnings\n", fileErrors, fileWarnings)
-		output += fmt.Sprintf("Project: %d errors, %d warnings\n", projectErrors, projectWarnings)
-		output += "</diagnostic_summary>\n"
-	}
+func sortDiagnostics(diagnostics []diagnosticEntry) {
+	sort.Slice(diagnostics, func(i, j int) bool {
+		iRank, jRank := severityRank(diagnostics[i].severity), severityRank(diagnostics[j].severity)
+		if iRank != jRank {
+			return iRank < jRank // Errors come first
+		}
+		return diagnostics[i].text < diagnostics[j].text // Then alphabetically
+	})
+}
 
+func joinDiagnostics(diagnostics []diagnosticEntry, limit int) string {
+	lines := make([]string, 0, min(len(diagnostics), limit))
+	for _, diag := range diagnostics[:min(len(diagnostics), limit)] {
+		lines = append(lines, diag.text)
+	}
+	output := strings.Join(lines, "\n")
+	if len(diagnostics) > limit {
+		output += fmt.Sprintf("\n... and %d more diagnostics", len(diagnostics)-limit)
+	}
 	return output
 }
 
-func countSeverity(diagnostics []string, severity string) int {
+func summarizeSeverities(diagnostics []diagnosticEntry) string {
+	return fmt.Sprintf("%d errors, %d warnings, %d info, %d hints",
+		countSeverity(diagnostics, protocol.SeverityError),
+		countSeverity(diagnostics, protocol.SeverityWarning),
+		countSeverity(diagnostics, protocol.SeverityInformation),
+		countSeverity(diagnostics, protocol.SeverityHint),
+	)
+}
+
+func countSeverity(diagnostics []diagnosticEntry, severity protocol.DiagnosticSeverity) int {
 	count := 0
 	for _, diag := range diagnostics {
-		if strings.HasPrefix(diag, severity) {
+		if severityRank(diag.severity) == severityRank(severity) {
 			count++
 		}
 	}
 	return count
 }
+
+// formatDiagnostic renders a single diagnostic on one line. source names the LSP
+// client and is used when the diagnostic does not carry its own source.
+func formatDiagnostic(pth string, diagnostic protocol.Diagnostic, source string) string {
+	severity := "Info"
+	switch diagnostic.Severity {
+	case protocol.SeverityError:
+		severity = "Error"
+	case protocol.SeverityWarning:
+		severity = "Warn"
+	case protocol.SeverityHint:
+		severity = "Hint"
+	}
+
+	location := fmt.Sprintf("%s:%d:%d", pth, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1)
+
+	sourceInfo := ""
+	if diagnostic.Source != "" {
+		sourceInfo = diagnostic.Source
+	} else if source != "" {
+		sourceInfo = source
+	}
+
+	codeInfo := ""
+	if diagnostic.Code != nil {
+		codeInfo = fmt.Sprintf("[%v]", diagnostic.Code)
+	}
+
+	tagsInfo := ""
+	if len(diagnostic.Tags) > 0 {
+		tags := []string{}
+		for _, tag := range diagnostic.Tags {
+			switch tag {
+			case protocol.Unnecessary:
+				tags = append(tags, "unnecessary")
+			case protocol.Deprecated:
+				tags = append(tags, "deprecated")
+			}
+		}
+		if len(tags) > 0 {
+			tagsInfo = fmt.Sprintf(" (%s)", strings.Join(tags, ", "))
+		}
+	}
+
+	return fmt.Sprintf("%s: %s [%s]%s%s %s",
+		severity,
+		location,
+		sourceInfo,
+		codeInfo,
+		tagsInfo,
+		diagnostic.Message)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+func TestFormatDiagnostics_GroupsBySeverity(t *testing.T) {
+	diagnostic := func(severity protocol.DiagnosticSeverity, line uint32, msg string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line}},
+			Severity: severity,
+			Message:  msg,
+		}
+	}
+
+	diagnostics := map[string]map[protocol.DocumentUri][]protocol.Diagnostic{
+		"gopls": {
+			"file:///project/main.go": {
+				diagnostic(protocol.SeverityHint, 1, "hint one"),
+				diagnostic(protocol.SeverityWarning, 2, "warning one"),
+				diagnostic(protocol.SeverityError, 3, "error one"),
+				diagnostic(protocol.SeverityInformation, 4, "info one"),
+				diagnostic(protocol.SeverityError, 5, "error two"),
+			},
+			"file:///project/other.go": {
+				diagnostic(protocol.SeverityWarning, 1, "other warning"),
+				diagnostic(protocol.SeverityError, 2, "other error"),
+			},
+		},
+	}
+
+	output := formatDiagnostics("/project/main.go", diagnostics)
+
+	assert.Contains(t, output, "Current file: 2 errors, 1 warnings, 1 info, 1 hints")
+	assert.Contains(t, output, "Project: 1 errors, 1 warnings, 0 info, 0 hints")
+	assert.Less(t, strings.Index(output, "<diagnostic_summary>"), strings.Index(output, "<file_diagnostics>"))
+
+	start := strings.Index(output, "<file_diagnostics>\n") + len("<file_diagnostics>\n")
+	end := strings.Index(output, "\n</file_diagnostics>")
+	require.Greater(t, end, start)
+	lines := strings.Split(output[start:end], "\n")
+	require.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], "Error: /project/main.go:4:1"))
+	assert.True(t, strings.HasPrefix(lines[1], "Error: /project/main.go:6:1"))
+	assert.True(t, strings.HasPrefix(lines[2], "Warn: "))
+	assert.True(t, strings.HasPrefix(lines[3], "Info: "))
+	assert.True(t, strings.HasPrefix(lines[4], "Hint: "))
+
+	start = strings.Index(output, "<project_diagnostics>\n") + len("<project_diagnostics>\n")
+	end = strings.Index(output, "\n</project_diagnostics>")
+	require.Greater(t, end, start)
+	assert.Equal(t, []string{
+		"Error: /project/other.go:3:1 [gopls] other error",
+		"Warn: /project/other.go:2:1 [gopls] other warning",
+	}, strings.Split(output[start:end], "\n"))
+}
+
+func TestFormatDiagnostics_Empty(t *testing.T) {
+	assert.Empty(t, formatDiagnostics("/project/main.go", nil))
+}
+
+func TestFormatNewDiagnostics_ReportsIntroducedProblems(t *testing.T) {
+	diagnostic := func(severity protocol.DiagnosticSeverity, line uint32, msg string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line}},
+			Severity: severity,
+			Message:  msg,
+		}
+	}
+
+	before := fileDiagnosticCounts("/project/main.go", map[string]map[protocol.DocumentUri][]protocol.Diagnostic{
+		"gopls": {
+			"file:///project/main.go": {
+				diagnostic(protocol.SeverityWarning, 2, "unused variable x"),
+			},
+		},
+	})
+
+	// The edit inserted lines above the existing warning and broke a call.
+	after := map[string]map[protocol.DocumentUri][]protocol.Diagnostic{
+		"gopls": {
+			"file:///project/main.go": {
+				diagnostic(protocol.SeverityWarning, 5, "unused variable x"),
+				diagnostic(protocol.SeverityError, 3, "undefined: fooBar"),
+				diagnostic(protocol.SeverityHint, 4, "could be simplified"),
+			},
+			"file:///project/other.go": {
+				diagnostic(protocol.SeverityError, 1, "other error"),
+			},
+		},
+	}
+
+	output := formatNewDiagnostics("/project/main.go", before, after)
+
+	assert.Contains(t, output, "<new_diagnostics>")
+	assert.Contains(t, output, "Error: /project/main.go:4:1 [gopls] undefined: fooBar")
+	assert.NotContains(t, output, "unused variable x")
+	assert.NotContains(t, output, "could be simplified")
+	assert.NotContains(t, output, "other error")
+
+	assert.Empty(t, formatNewDiagnostics("/project/main.go", fileDiagnosticCounts("/project/main.go", after), after))
+}
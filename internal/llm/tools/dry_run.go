@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// dryRun reports whether tools should only show their changes. In dry-run
+// mode file tools return the diff of a change without asking for permission
+// to write it, as if the request had been denied, and bash only runs
+// read-only commands.
+func dryRun() bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.DryRun
+}
+
+// dryRunResult describes a change that dry-run mode kept from being written,
+// with its diff so the model can still see what it would have done.
+func dryRunResult(filePath, diff string) string {
+	return fmt.Sprintf("<result>\nDry run: %s was not changed. This is the change that would have been made:\n</result>\n\n%s", filePath, diff)
+}
+
+// dryRunWrappers are safe read-only commands that run or stop other
+// processes, so they could still change files in dry-run mode.
+var dryRunWrappers = []string{"env", "kill", "killall", "nice", "nohup", "time", "timeout"}
+
+// dryRunAllowed reports whether bash may run command in dry-run mode: a single
+// safe read-only command, without redirections, substitutions or other
+// commands chained to it.
+func dryRunAllowed(command string, isSafeReadOnly bool) bool {
+	if !isSafeReadOnly || hasSubstitutionOrRedirection(command) || strings.ContainsAny(command, ";&|\n") {
+		return false
+	}
+	return !slices.Contains(dryRunWrappers, strings.ToLower(strings.Fields(command)[0]))
+}
+
+// dryRunCommandResult describes a shell command that dry-run mode kept from
+// running, since it may change files.
+func dryRunCommandResult(command string) string {
+	return fmt.Sprintf("Dry run: the command was not run, since only read-only commands such as ls or git status run in dry-run mode:\n\n%s", command)
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func TestWriteToolDryRunReturnsDiffWithoutWriting(t *testing.T) {
+	root := t.TempDir()
+	useSandboxRoot(t, root)
+	cfg := config.Get()
+	t.Cleanup(func() { cfg.DryRun = false })
+	cfg.DryRun = true
+
+	filePath := filepath.Join(root, "pkg", "new.go")
+	input, err := json.Marshal(WriteParams{FilePath: filePath, Content: "package pkg\n"})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+
+	// No permission is requested in a dry run, so no service is needed
+	response, err := NewWriteTool(nil, nil, nil).Run(ctx, ToolCall{Name: WriteToolName, Input: string(input)})
+	require.NoError(t, err)
+	assert.False(t, response.IsError)
+	assert.Contains(t, response.Content, "Dry run")
+	assert.Contains(t, response.Content, "+package pkg")
+	assert.NoDirExists(t, filepath.Join(root, "pkg"))
+
+	var metadata WriteResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	assert.Equal(t, 1, metadata.Additions)
+}
+
+func TestBashToolDryRunRunsOnlyReadOnlyCommands(t *testing.T) {
+	cfg := config.Get()
+	t.Cleanup(func() { cfg.DryRun = false })
+	cfg.DryRun = true
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, t.Name())
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+	run := func(command string) ToolResponse {
+		input, err := json.Marshal(BashParams{Command: command})
+		require.NoError(t, err)
+		// Commands that would need permission are not run, so no service is
+		// needed
+		response, err := NewBashTool(nil).Run(ctx, ToolCall{Name: BashToolName, Input: string(input)})
+		require.NoError(t, err)
+		return response
+	}
+
+	for _, command := range []string{
+		fmt.Sprintf("touch %q", marker),
+		fmt.Sprintf("ls > %q", marker),
+		fmt.Sprintf("ls && touch %q", marker),
+		fmt.Sprintf("timeout 5 touch %q", marker),
+	} {
+		response := run(command)
+		assert.False(t, response.IsError)
+		assert.Contains(t, response.Content, "Dry run: the command was not run")
+		assert.NoFileExists(t, marker)
+	}
+
+	response := run("pwd")
+	assert.NotContains(t, response.Content, "Dry run")
+}
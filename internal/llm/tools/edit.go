@@ -135,10 +135,14 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		wd := config.WorkingDirectory()
 		params.FilePath = filepath.Join(wd, params.FilePath)
 	}
+	if err := checkSandbox(params.FilePath); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 
 	var response ToolResponse
 	var err error
 
+	diagnosticsBefore := diagnosticsBeforeEdit(params.FilePath, e.lspClients)
 	if params.OldString == "" {
 		response, err = e.createNewFile(ctx, params.FilePath, params.NewString)
 		if err != nil {
@@ -163,9 +167,8 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return response, nil
 	}
 
-	waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
 	text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-	text += getDiagnostics(params.FilePath, e.lspClients)
+	text += editDiagnostics(ctx, params.FilePath, e.lspClients, diagnosticsBefore)
 	response.Content = text
 	return response, nil
 }
@@ -181,11 +184,6 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		return ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
 	}
 
-	dir := filepath.Dir(filePath)
-	if err = os.MkdirAll(dir, 0o755); err != nil {
-		return ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
-	}
-
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
@@ -196,6 +194,17 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		content,
 		filePath,
 	)
+	if dryRun() {
+		return WithResponseMetadata(
+			NewTextResponse(dryRunResult(filePath, diff)),
+			EditResponseMetadata{
+				Diff:      diff,
+				Additions: additions,
+				Removals:  removals,
+			},
+		), nil
+	}
+
 	rootDir := config.WorkingDirectory()
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
@@ -203,11 +212,12 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 	}
 	p := e.permissions.Request(
 		permission.CreatePermissionRequest{
-			SessionID:   sessionID,
-			Path:        permissionPath,
-			ToolName:    EditToolName,
-			Action:      "write",
-			Description: fmt.Sprintf("Create file %s", filePath),
+			SessionID:    sessionID,
+			Path:         permissionPath,
+			ResourcePath: filePath,
+			ToolName:     EditToolName,
+			Action:       permission.ActionWrite,
+			Description:  fmt.Sprintf("Create file %s", filePath),
 			Params: EditPermissionsParams{
 				FilePath: filePath,
 				Diff:     diff,
@@ -218,10 +228,17 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	snapshot := snapshotFiles(filePath)
+	dir := filepath.Dir(filePath)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
+	}
+
 	err = os.WriteFile(filePath, []byte(content), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
+	recordEdit(sessionID, snapshot)
 
 	// File can't be in the history so we create a new file history
 	_, err = e.files.Create(ctx, sessionID, filePath, "")
@@ -307,6 +324,17 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		filePath,
 	)
 
+	if dryRun() {
+		return WithResponseMetadata(
+			NewTextResponse(dryRunResult(filePath, diff)),
+			EditResponseMetadata{
+				Diff:      diff,
+				Additions: additions,
+				Removals:  removals,
+			},
+		), nil
+	}
+
 	rootDir := config.WorkingDirectory()
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
@@ -314,11 +342,12 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 	}
 	p := e.permissions.Request(
 		permission.CreatePermissionRequest{
-			SessionID:   sessionID,
-			Path:        permissionPath,
-			ToolName:    EditToolName,
-			Action:      "write",
-			Description: fmt.Sprintf("Delete content from file %s", filePath),
+			SessionID:    sessionID,
+			Path:         permissionPath,
+			ResourcePath: filePath,
+			ToolName:     EditToolName,
+			Action:       permission.ActionWrite,
+			Description:  fmt.Sprintf("Delete content from file %s", filePath),
 			Params: EditPermissionsParams{
 				FilePath: filePath,
 				Diff:     diff,
@@ -329,10 +358,12 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	snapshot := snapshotFiles(filePath)
 	err = os.WriteFile(filePath, []byte(newContent), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
+	recordEdit(sessionID, snapshot)
 
 	// Check if file exists in history
 	file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
@@ -427,6 +458,17 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		newContent,
 		filePath,
 	)
+	if dryRun() {
+		return WithResponseMetadata(
+			NewTextResponse(dryRunResult(filePath, diff)),
+			EditResponseMetadata{
+				Diff:      diff,
+				Additions: additions,
+				Removals:  removals,
+			},
+		), nil
+	}
+
 	rootDir := config.WorkingDirectory()
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
@@ -434,11 +476,12 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 	}
 	p := e.permissions.Request(
 		permission.CreatePermissionRequest{
-			SessionID:   sessionID,
-			Path:        permissionPath,
-			ToolName:    EditToolName,
-			Action:      "write",
-			Description: fmt.Sprintf("Replace content in file %s", filePath),
+			SessionID:    sessionID,
+			Path:         permissionPath,
+			ResourcePath: filePath,
+			ToolName:     EditToolName,
+			Action:       permission.ActionWrite,
+			Description:  fmt.Sprintf("Replace content in file %s", filePath),
 			Params: EditPermissionsParams{
 				FilePath: filePath,
 				Diff:     diff,
@@ -449,10 +492,12 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	snapshot := snapshotFiles(filePath)
 	err = os.WriteFile(filePath, []byte(newContent), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
+	recordEdit(sessionID, snapshot)
 
 	// Check if file exists in history
 	file, err := e.files.GetByPathAndSession(ctx, filePath, sessionID)
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/lsp"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+// diagnosticsBeforeEdit records the diagnostics of filePath so that
+// editDiagnostics can report only those an edit introduces. It returns nil
+// unless editDiagnostics is set to "new".
+func diagnosticsBeforeEdit(filePath string, lsps map[string]*lsp.Client) map[string]int {
+	if config.Get().EditDiagnostics != config.EditDiagnosticsNew || len(lsps) == 0 {
+		return nil
+	}
+	return fileDiagnosticCounts(filePath, collectDiagnostics(lsps))
+}
+
+// editDiagnostics waits for the LSP clients to check filePath after an edit and
+// returns the diagnostics to append to the tool result, as selected by the
+// editDiagnostics setting. before is the result of diagnosticsBeforeEdit.
+func editDiagnostics(ctx context.Context, filePath string, lsps map[string]*lsp.Client, before map[string]int) string {
+	switch config.Get().EditDiagnostics {
+	case config.EditDiagnosticsOff:
+		return ""
+	case config.EditDiagnosticsNew:
+		waitForLspDiagnostics(ctx, filePath, lsps)
+		return formatNewDiagnostics(filePath, before, collectDiagnostics(lsps))
+	default:
+		waitForLspDiagnostics(ctx, filePath, lsps)
+		return getDiagnostics(filePath, lsps)
+	}
+}
+
+// diagnosticKey identifies a diagnostic independently of its position, since an
+// edit moves the lines that follow it.
+func diagnosticKey(lspName string, diagnostic protocol.Diagnostic) string {
+	return fmt.Sprintf("%s\x00%d\x00%s\x00%v\x00%s", lspName, diagnostic.Severity, diagnostic.Source, diagnostic.Code, diagnostic.Message)
+}
+
+// fileDiagnosticCounts counts the errors and warnings reported for filePath by
+// diagnostic key.
+func fileDiagnosticCounts(filePath string, diagnostics map[string]map[protocol.DocumentUri][]protocol.Diagnostic) map[string]int {
+	counts := make(map[string]int)
+	for lspName, clientDiagnostics := range diagnostics {
+		for uri, diags := range clientDiagnostics {
+			if uri.Path() != filePath {
+				continue
+			}
+			for _, diag := range diags {
+				if diag.Severity == protocol.SeverityError || diag.Severity == protocol.SeverityWarning {
+					counts[diagnosticKey(lspName, diag)]++
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// formatNewDiagnostics renders the errors and warnings for filePath that were
+// not already reported before the edit, or returns an empty string if there are
+// none.
+func formatNewDiagnostics(filePath string, before map[string]int, after map[string]map[protocol.DocumentUri][]protocol.Diagnostic) string {
+	seen := make(map[string]int, len(before))
+	var entries []diagnosticEntry
+	for lspName, clientDiagnostics := range after {
+		for uri, diags := range clientDiagnostics {
+			if uri.Path() != filePath {
+				continue
+			}
+			for _, diag := range diags {
+				if diag.Severity != protocol.SeverityError && diag.Severity != protocol.SeverityWarning {
+					continue
+				}
+				key := diagnosticKey(lspName, diag)
+				seen[key]++
+				if seen[key] <= before[key] {
+					continue
+				}
+				entries = append(entries, diagnosticEntry{
+					severity: diag.Severity,
+					text:     formatDiagnostic(uri.Path(), diag, lspName),
+				})
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+
+	sortDiagnostics(entries)
+	var output strings.Builder
+	output.WriteString("\n<new_diagnostics>\n")
+	output.WriteString("This edit introduced the following problems:\n")
+	output.WriteString(joinDiagnostics(entries, 10))
+	output.WriteString("\n</new_diagnostics>\n")
+	return output.String()
+}
@@ -3,9 +3,12 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -55,6 +58,7 @@ FEATURES:
 LIMITATIONS:
 - Maximum response size is 5MB
 - Only supports HTTP and HTTPS protocols
+- Only domains allowed by the configuration can be fetched
 - Cannot handle authentication or cookies
 - Some websites may block automated requests
 
@@ -67,9 +71,7 @@ TIPS:
 
 func NewFetchTool(permissions permission.Service) BaseTool {
 	return &fetchTool{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:      newFetchClient(30 * time.Second),
 		permissions: permissions,
 	}
 }
@@ -97,6 +99,24 @@ func (t *fetchTool) Info() ToolInfo {
 	}
 }
 
+// Retryable reports whether err is a network failure, which a retry may not
+// run into.
+func (t *fetchTool) Retryable(err error) bool {
+	return isTransientFetchError(err)
+}
+
+// isTransientFetchError reports whether err is a failure to reach a server or
+// to get its response, rather than a refused request or redirect.
+func isTransientFetchError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	var netErr net.Error
+	return urlErr.Timeout() || errors.As(urlErr.Err, &netErr) ||
+		errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF)
+}
+
 func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params FetchParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
@@ -116,19 +136,33 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return NewTextErrorResponse("URL must start with http:// or https://"), nil
 	}
 
+	u, err := url.Parse(params.URL)
+	if err != nil {
+		return NewTextErrorResponse("Invalid URL: " + err.Error()), nil
+	}
+	if err := checkFetchURL(u); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a new file")
 	}
 
+	cacheRequest := format + " " + params.URL
+	if response, ok := toolResultCache.get(ctx, FetchToolName, cacheRequest); ok {
+		return response, nil
+	}
+
 	p := t.permissions.Request(
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
 			Path:        config.WorkingDirectory(),
 			ToolName:    FetchToolName,
-			Action:      "fetch",
+			Action:      permission.ActionFetch,
 			Description: fmt.Sprintf("Fetch content from URL: %s", params.URL),
 			Params:      FetchPermissionsParams(params),
+			ToolCallID:  call.ID,
 		},
 	)
 
@@ -142,9 +176,7 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		if params.Timeout > maxTimeout {
 			params.Timeout = maxTimeout
 		}
-		client = &http.Client{
-			Timeout: time.Duration(params.Timeout) * time.Second,
-		}
+		client = newFetchClient(time.Duration(params.Timeout) * time.Second)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", params.URL, nil)
@@ -164,42 +196,46 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return NewTextErrorResponse(fmt.Sprintf("Request failed with status code: %d", resp.StatusCode)), nil
 	}
 
-	maxSize := int64(5 * 1024 * 1024) // 5MB
+	maxSize := int64(config.Get().Tools.Fetch.MaxBodyBytes)
 	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
 	if err != nil {
 		return NewTextErrorResponse("Failed to read response body: " + err.Error()), nil
 	}
 
-	content := string(body)
-	contentType := resp.Header.Get("Content-Type")
+	response := formatFetchedContent(format, resp.Header.Get("Content-Type"), string(body))
+	toolResultCache.put(ctx, FetchToolName, cacheRequest, response)
+	return response, nil
+}
 
+// formatFetchedContent converts a response body to the requested format.
+func formatFetchedContent(format, contentType, content string) ToolResponse {
 	switch format {
 	case "text":
 		if strings.Contains(contentType, "text/html") {
 			text, err := extractTextFromHTML(content)
 			if err != nil {
-				return NewTextErrorResponse("Failed to extract text from HTML: " + err.Error()), nil
+				return NewTextErrorResponse("Failed to extract text from HTML: " + err.Error())
 			}
-			return NewTextResponse(text), nil
+			return NewTextResponse(text)
 		}
-		return NewTextResponse(content), nil
+		return NewTextResponse(content)
 
 	case "markdown":
 		if strings.Contains(contentType, "text/html") {
 			markdown, err := convertHTMLToMarkdown(content)
 			if err != nil {
-				return NewTextErrorResponse("Failed to convert HTML to Markdown: " + err.Error()), nil
+				return NewTextErrorResponse("Failed to convert HTML to Markdown: " + err.Error())
 			}
-			return NewTextResponse(markdown), nil
+			return NewTextResponse(markdown)
 		}
 
-		return NewTextResponse("```\n" + content + "\n```"), nil
+		return NewTextResponse("```\n" + content + "\n```")
 
 	case "html":
-		return NewTextResponse(content), nil
+		return NewTextResponse(content)
 
 	default:
-		return NewTextResponse(content), nil
+		return NewTextResponse(content)
 	}
 }
 
@@ -0,0 +1,333 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+type FetchURLParams struct {
+	URL     string `json:"url"`
+	Timeout int    `json:"timeout,omitempty"`
+}
+
+type FetchURLResponseMetadata struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Title       string `json:"title,omitempty"`
+	Truncated   bool   `json:"truncated"`
+}
+
+type fetchURLTool struct {
+	client      *http.Client
+	permissions permission.Service
+}
+
+const (
+	FetchURLToolName        = "fetch_url"
+	fetchURLToolDescription = `Reads a web page and returns its main content as Markdown.
+
+WHEN TO USE THIS TOOL:
+- Use when you need to read a specific page, such as documentation, an article or a search result
+- Prefer it over fetch when you want the readable text of a page rather than its raw HTML
+
+HOW TO USE:
+- Provide the http or https URL of the page
+- Optionally set a timeout in seconds (default 30, max 120)
+
+FEATURES:
+- Navigation, headers, footers, sidebars, scripts and styles are removed
+- The main or article element is used when the page has one
+- Plain text, JSON and other text responses are returned as they are
+- Images, archives, PDFs and other binary responses are described instead of returned
+
+LIMITATIONS:
+- Responses are read up to a configured size (5MB by default) and cut there
+- Only domains allowed by the configuration can be fetched
+- Cannot handle authentication or cookies
+- Pages rendered with JavaScript may have little content`
+)
+
+// boilerplateSelector matches the elements of a page that are not part of its
+// content. Headers and footers are only removed outside of the content, where
+// they hold site navigation rather than a title or byline.
+const boilerplateSelector = "script, style, noscript, template, iframe, object, embed, svg, canvas, form, button, nav, aside, " +
+	"[role=navigation], [role=banner], [role=contentinfo], [role=complementary], [aria-hidden=true]"
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+func NewFetchURLTool(permissions permission.Service) BaseTool {
+	return &fetchURLTool{
+		client:      newFetchClient(30 * time.Second),
+		permissions: permissions,
+	}
+}
+
+func (t *fetchURLTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        FetchURLToolName,
+		Description: fetchURLToolDescription,
+		Parameters: map[string]any{
+			"url": map[string]any{
+				"type":        "string",
+				"description": "The URL of the page to read",
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": "Optional timeout in seconds (max 120)",
+			},
+		},
+		Required: []string{"url"},
+	}
+}
+
+// Retryable reports whether err is a network failure, which a retry may not
+// run into.
+func (t *fetchURLTool) Retryable(err error) bool {
+	return isTransientFetchError(err)
+}
+
+func (t *fetchURLTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params FetchURLParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("Failed to parse fetch_url parameters: " + err.Error()), nil
+	}
+
+	if params.URL == "" {
+		return NewTextErrorResponse("URL parameter is required"), nil
+	}
+
+	u, err := url.Parse(params.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewTextErrorResponse("URL must be an absolute http:// or https:// URL"), nil
+	}
+	if err := checkFetchURL(u); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for fetching a URL")
+	}
+
+	if response, ok := toolResultCache.get(ctx, FetchURLToolName, u.String()); ok {
+		return response, nil
+	}
+
+	p := t.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        config.WorkingDirectory(),
+			ToolName:    FetchURLToolName,
+			Action:      permission.ActionFetch,
+			Description: fmt.Sprintf("Fetch content from URL: %s", params.URL),
+			Params:      FetchPermissionsParams{URL: params.URL, Format: "markdown", Timeout: params.Timeout},
+			ToolCallID:  call.ID,
+		},
+	)
+
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	client := t.client
+	if params.Timeout > 0 {
+		client = newFetchClient(time.Duration(min(params.Timeout, 120)) * time.Second)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "cryoncode/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return NewTextErrorResponse(fmt.Sprintf("Request failed with status code: %d", resp.StatusCode)), nil
+	}
+
+	metadata := FetchURLResponseMetadata{URL: resp.Request.URL.String()}
+	body := bufio.NewReader(resp.Body)
+	metadata.ContentType = responseMediaType(resp.Header.Get("Content-Type"), body)
+
+	if !isTextMediaType(metadata.ContentType) {
+		return WithResponseMetadata(NewTextResponse(describeBinaryResponse(metadata.URL, metadata.ContentType, resp.ContentLength)), metadata), nil
+	}
+
+	maxBytes := config.Get().Tools.Fetch.MaxBodyBytes
+	data, err := io.ReadAll(io.LimitReader(body, int64(maxBytes)+1))
+	if err != nil {
+		return NewTextErrorResponse("Failed to read response body: " + err.Error()), nil
+	}
+	if len(data) > maxBytes {
+		data = data[:maxBytes]
+		metadata.Truncated = true
+	}
+	content := strings.ToValidUTF8(string(data), "")
+
+	if isHTMLMediaType(metadata.ContentType) {
+		title, markdown, err := htmlToReadableMarkdown(content)
+		if err != nil {
+			return NewTextErrorResponse("Failed to convert HTML to Markdown: " + err.Error()), nil
+		}
+		metadata.Title = title
+		content = markdown
+		if title != "" {
+			content = "# " + title + "\n\n" + content
+		}
+	}
+	if metadata.Truncated {
+		content += fmt.Sprintf("\n\n(The response was cut at %d bytes.)", maxBytes)
+	}
+
+	response := WithResponseMetadata(NewTextResponse(content), metadata)
+	toolResultCache.put(ctx, FetchURLToolName, u.String(), response)
+	return response, nil
+}
+
+// newFetchClient returns an HTTP client that only follows redirects to URLs
+// that may be fetched.
+func newFetchClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return checkFetchURL(req.URL)
+		},
+	}
+}
+
+// checkFetchURL returns an error if the host of u is blocked by
+// tools.fetch.blockedDomains or missing from tools.fetch.allowedDomains.
+func checkFetchURL(u *url.URL) error {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, domain := range cfg.Tools.Fetch.BlockedDomains {
+		if matchDomain(host, domain) {
+			return fmt.Errorf("fetching %s is blocked by the tools.fetch.blockedDomains entry %q", host, domain)
+		}
+	}
+	if len(cfg.Tools.Fetch.AllowedDomains) == 0 {
+		return nil
+	}
+	for _, domain := range cfg.Tools.Fetch.AllowedDomains {
+		if matchDomain(host, domain) {
+			return nil
+		}
+	}
+	return fmt.Errorf("fetching %s is not allowed, since it is not in tools.fetch.allowedDomains", host)
+}
+
+// matchDomain reports whether host is domain or one of its subdomains. A
+// leading "*." or "." in domain is ignored.
+func matchDomain(host, domain string) bool {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "*"), ".")
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// responseMediaType returns the media type of a response from its
+// Content-Type header, or sniffed from the start of the body when the header
+// is missing.
+func responseMediaType(contentType string, body *bufio.Reader) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return strings.ToLower(mediaType)
+	}
+	start, _ := body.Peek(512)
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(start))
+	return mediaType
+}
+
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// isTextMediaType reports whether a response of the media type is text that
+// can be returned to the model.
+func isTextMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || isHTMLMediaType(mediaType) {
+		return true
+	}
+	if strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-javascript",
+		"application/ecmascript", "application/yaml", "application/x-yaml", "application/toml", "application/x-sh":
+		return true
+	}
+	return false
+}
+
+// describeBinaryResponse stands in for a response that is not text.
+func describeBinaryResponse(pageURL, mediaType string, size int64) string {
+	if mediaType == "" {
+		mediaType = "unknown"
+	}
+	description := fmt.Sprintf("%s returned content of type %s", pageURL, mediaType)
+	if size >= 0 {
+		description += fmt.Sprintf(" (%d bytes)", size)
+	}
+	return description + ", which is not text and is not shown."
+}
+
+// htmlToReadableMarkdown returns the title of a page and its content as
+// Markdown, without navigation, scripts and other boilerplate.
+func htmlToReadableMarkdown(html string) (string, string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", "", err
+	}
+	title := strings.Join(strings.Fields(doc.Find("title").First().Text()), " ")
+
+	doc.Find(boilerplateSelector).Remove()
+	doc.Find("header, footer").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.Closest("main, article, [role=main]").Length() == 0
+	}).Remove()
+
+	content := doc.Find("main, [role=main]").First()
+	if content.Length() == 0 {
+		content = doc.Find("article").First()
+	}
+	if content.Length() == 0 {
+		content = doc.Find("body")
+	}
+	contentHTML, err := goquery.OuterHtml(content)
+	if err != nil {
+		return "", "", err
+	}
+
+	markdown, err := convertHTMLToMarkdown(contentHTML)
+	if err != nil {
+		return "", "", err
+	}
+	return title, strings.TrimSpace(blankLines.ReplaceAllString(markdown, "\n\n")), nil
+}
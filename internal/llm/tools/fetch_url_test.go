@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+const testPage = `<html>
+<head><title>Release notes</title><style>body { color: red }</style></head>
+<body>
+<header><nav><a href="/">Home</a> <a href="/docs">Docs</a></nav></header>
+<main>
+<article>
+<header><h1>Version 2.0</h1></header>
+<p>Adds <strong>streaming</strong> output.</p>
+</article>
+</main>
+<aside>Related posts</aside>
+<footer>Copyright</footer>
+<script>track()</script>
+</body>
+</html>`
+
+func fetchURL(t *testing.T, rawURL string) (ToolResponse, error) {
+	t.Helper()
+	// A session per test keeps results cached by one test from another
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(t.Name())
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, t.Name())
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+
+	input, err := json.Marshal(FetchURLParams{URL: rawURL})
+	require.NoError(t, err)
+	return NewFetchURLTool(permissions).Run(ctx, ToolCall{Name: FetchURLToolName, Input: string(input)})
+}
+
+func withFetchConfig(t *testing.T, fetch config.FetchConfig) {
+	t.Helper()
+	cfg := config.Get()
+	original := cfg.Tools.Fetch
+	t.Cleanup(func() { cfg.Tools.Fetch = original })
+	if fetch.MaxBodyBytes == 0 {
+		fetch.MaxBodyBytes = original.MaxBodyBytes
+	}
+	cfg.Tools.Fetch = fetch
+}
+
+func TestFetchURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	})
+	mux.HandleFunc("/notes.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("0123456789"))
+	})
+	mux.HandleFunc("/away", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://blocked.example.com/", http.StatusFound)
+	})
+	var counted int
+	mux.HandleFunc("/counted", func(w http.ResponseWriter, r *http.Request) {
+		counted++
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "request %d", counted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("html is converted to markdown without boilerplate", func(t *testing.T) {
+		response, err := fetchURL(t, server.URL+"/page")
+		require.NoError(t, err)
+		require.False(t, response.IsError, response.Content)
+		assert.Equal(t, "# Release notes\n\n# Version 2.0\n\nAdds **streaming** output.", response.Content)
+
+		var metadata FetchURLResponseMetadata
+		require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+		assert.Equal(t, "text/html", metadata.ContentType)
+		assert.Equal(t, "Release notes", metadata.Title)
+	})
+
+	t.Run("binary content is described", func(t *testing.T) {
+		response, err := fetchURL(t, server.URL+"/logo.png")
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/logo.png returned content of type image/png (8 bytes), which is not text and is not shown.", response.Content)
+	})
+
+	t.Run("body is cut at maxBodyBytes", func(t *testing.T) {
+		withFetchConfig(t, config.FetchConfig{MaxBodyBytes: 4})
+		response, err := fetchURL(t, server.URL+"/notes.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "0123\n\n(The response was cut at 4 bytes.)", response.Content)
+	})
+
+	t.Run("blocked domain", func(t *testing.T) {
+		withFetchConfig(t, config.FetchConfig{BlockedDomains: []string{"127.0.0.1"}})
+		response, err := fetchURL(t, server.URL+"/page")
+		require.NoError(t, err)
+		assert.True(t, response.IsError)
+		assert.Contains(t, response.Content, `blocked by the tools.fetch.blockedDomains entry "127.0.0.1"`)
+	})
+
+	t.Run("domain not allowed", func(t *testing.T) {
+		withFetchConfig(t, config.FetchConfig{AllowedDomains: []string{"example.com"}})
+		response, err := fetchURL(t, server.URL+"/page")
+		require.NoError(t, err)
+		assert.True(t, response.IsError)
+		assert.Contains(t, response.Content, "not in tools.fetch.allowedDomains")
+	})
+
+	t.Run("repeated fetches are cached", func(t *testing.T) {
+		for range 2 {
+			response, err := fetchURL(t, server.URL+"/counted")
+			require.NoError(t, err)
+			assert.Equal(t, "request 1", response.Content)
+		}
+		assert.Equal(t, 1, counted)
+	})
+
+	t.Run("redirects are checked", func(t *testing.T) {
+		withFetchConfig(t, config.FetchConfig{BlockedDomains: []string{"example.com"}})
+		_, err := fetchURL(t, server.URL+"/away")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "blocked by the tools.fetch.blockedDomains entry")
+	})
+}
+
+func TestFetchURLRetryable(t *testing.T) {
+	tool := NewFetchURLTool(permission.NewPermissionService()).(RetryableTool)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, err := fetchURL(t, server.URL)
+	require.Error(t, err)
+	assert.True(t, tool.Retryable(err), "an unreachable server is a transient failure")
+
+	redirect := httptest.NewServer(http.RedirectHandler("https://example.com/", http.StatusFound))
+	defer redirect.Close()
+	withFetchConfig(t, config.FetchConfig{BlockedDomains: []string{"example.com"}})
+	_, err = fetchURL(t, redirect.URL)
+	require.Error(t, err)
+	assert.False(t, tool.Retryable(err), "a blocked redirect stays blocked")
+}
+
+func TestMatchDomain(t *testing.T) {
+	tests := []struct {
+		host   string
+		domain string
+		match  bool
+	}{
+		{"example.com", "example.com", true},
+		{"docs.example.com", "example.com", true},
+		{"docs.example.com", "*.example.com", true},
+		{"docs.example.com", ".Example.com", true},
+		{"notexample.com", "example.com", false},
+		{"example.com", "docs.example.com", false},
+		{"example.com", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+" "+tt.domain, func(t *testing.T) {
+			assert.Equal(t, tt.match, matchDomain(tt.host, tt.domain))
+		})
+	}
+}
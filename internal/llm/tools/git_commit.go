@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/history"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/permission"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+type GitCommitParams struct {
+	Message    string `json:"message"`
+	IncludeAll bool   `json:"include_all"`
+}
+
+type GitCommitResponseMetadata struct {
+	Commit string   `json:"commit"`
+	Files  []string `json:"files"`
+}
+
+type gitCommitTool struct {
+	permissions permission.Service
+	sessions    session.Service
+	messages    message.Service
+	files       history.Service
+}
+
+const (
+	GitCommitToolName    = "git_commit"
+	gitCommitDescription = `Stages the files changed in this session with the Edit, Write, Patch and Apply Patch tools and commits them to the git repository of the working directory.
+
+WHEN TO USE THIS TOOL:
+- Use when the user asks you to commit the changes you made
+- Use after finishing a change that the user wants recorded in git
+
+HOW TO USE:
+- Provide a commit message: a short summary line, optionally followed by a blank line and a longer description
+- Leave the message empty to generate it from the session summary, if the session has been summarized
+- Set include_all to also commit changes that were already staged before, which are refused otherwise
+
+FEATURES:
+- Only the files this session changed are staged, including ones it created or deleted
+- Returns the hash of the new commit
+
+LIMITATIONS:
+- Files changed with the Bash tool are not tracked; stage them with git add through the Bash tool first and set include_all
+- Files outside the repository are skipped
+- Fails when the session has not changed any file or the changes are already committed
+
+TIPS:
+- Check git status with the Bash tool first if you are unsure what will be committed`
+)
+
+func NewGitCommitTool(permissions permission.Service, sessions session.Service, messages message.Service, files history.Service) BaseTool {
+	return &gitCommitTool{
+		permissions: permissions,
+		sessions:    sessions,
+		messages:    messages,
+		files:       files,
+	}
+}
+
+func (g *gitCommitTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        GitCommitToolName,
+		Description: gitCommitDescription,
+		Parameters: map[string]any{
+			"message": map[string]any{
+				"type":        "string",
+				"description": "The commit message; leave empty to generate it from the session summary",
+			},
+			"include_all": map[string]any{
+				"type":        "boolean",
+				"description": "Also commit changes that were staged before, instead of refusing to commit",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (g *gitCommitTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params GitCommitParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for committing changes")
+	}
+
+	root, err := git(ctx, config.WorkingDirectory(), "rev-parse", "--show-toplevel")
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("the working directory is not in a git repository: %s", err)), nil
+	}
+	root = strings.TrimSpace(root)
+
+	paths, err := g.sessionPaths(ctx, sessionID, root)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if len(paths) == 0 {
+		return NewTextErrorResponse("this session has not changed any file in the repository"), nil
+	}
+
+	staged, err := stagedPaths(ctx, root)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	unrelated := pathsNotIn(staged, paths)
+	if len(unrelated) > 0 && !params.IncludeAll {
+		return NewTextErrorResponse(fmt.Sprintf(
+			"changes to other files are already staged: %s. Set include_all to commit them too, or unstage them first",
+			strings.Join(unrelated, ", "))), nil
+	}
+	description := fmt.Sprintf("Commit the files changed in this session:\n\n- %s", strings.Join(paths, "\n- "))
+	if len(unrelated) > 0 {
+		description += fmt.Sprintf("\n\nand the files staged before:\n\n- %s", strings.Join(unrelated, "\n- "))
+	}
+
+	commitMessage := strings.TrimSpace(params.Message)
+	if commitMessage == "" {
+		commitMessage, err = g.summaryMessage(ctx, sessionID)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+	}
+
+	if dryRun() {
+		return NewTextResponse(fmt.Sprintf("Dry run: would commit %s with the message:\n\n%s",
+			strings.Join(append(paths, unrelated...), ", "), commitMessage)), nil
+	}
+
+	p := g.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        root,
+			ToolName:    GitCommitToolName,
+			Action:      permission.ActionCommit,
+			Description: fmt.Sprintf("%s\n\nwith the message:\n\n```\n%s\n```", description, commitMessage),
+			Params:      params,
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	if _, err := git(ctx, root, append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return ToolResponse{}, fmt.Errorf("error staging files: %w", err)
+	}
+	committed, err := stagedPaths(ctx, root)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if len(committed) == 0 {
+		return NewTextErrorResponse("there are no changes to commit; the session's changes are already committed"), nil
+	}
+	if _, err := git(ctx, root, "commit", "-q", "-m", commitMessage); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("git commit failed: %s", err)), nil
+	}
+	hash, err := git(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading the new commit: %w", err)
+	}
+	hash = strings.TrimSpace(hash)
+
+	subject, _, _ := strings.Cut(commitMessage, "\n")
+	return WithResponseMetadata(
+		NewTextResponse(fmt.Sprintf("Committed %d files as %s: %s\n\n%s",
+			len(committed), hash, subject, strings.Join(committed, "\n"))),
+		GitCommitResponseMetadata{
+			Commit: hash,
+			Files:  committed,
+		},
+	), nil
+}
+
+// sessionPaths returns the files of the repository at root that the session
+// changed, relative to root. Files that were created and then removed again,
+// which git doesn't know, are left out.
+func (g *gitCommitTool) sessionPaths(ctx context.Context, sessionID, root string) ([]string, error) {
+	files, err := g.files.ListLatestSessionFiles(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the session's files: %w", err)
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		resolvedRoot = root
+	}
+
+	var paths []string
+	for _, file := range files {
+		path := file.Path
+		if resolved, err := resolveExisting(path); err == nil {
+			path = resolved
+		}
+		rel, err := filepath.Rel(resolvedRoot, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if _, err := os.Stat(file.Path); os.IsNotExist(err) {
+			if tracked, _ := git(ctx, root, "ls-files", "--", rel); strings.TrimSpace(tracked) == "" {
+				continue
+			}
+		}
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// summaryMessage returns a commit message made from the session's summary,
+// with the session title as its summary line.
+func (g *gitCommitTool) summaryMessage(ctx context.Context, sessionID string) (string, error) {
+	s, err := g.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("error reading the session: %w", err)
+	}
+	if s.SummaryMessageID == "" {
+		return "", fmt.Errorf("message is required: this session has no summary to generate one from")
+	}
+	summary, err := g.messages.Get(ctx, s.SummaryMessageID)
+	if err != nil {
+		return "", fmt.Errorf("error reading the session summary: %w", err)
+	}
+	body := strings.TrimSpace(summary.Content().String())
+	if body == "" {
+		return "", fmt.Errorf("message is required: the session summary is empty")
+	}
+	subject := strings.TrimSpace(s.Title)
+	if subject == "" {
+		return body, nil
+	}
+	return subject + "\n\n" + body, nil
+}
+
+// stagedPaths returns the paths with staged changes, relative to root.
+func stagedPaths(ctx context.Context, root string) ([]string, error) {
+	output, err := git(ctx, root, "diff", "--cached", "--name-only", "--no-renames", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("error listing staged changes: %w", err)
+	}
+	var paths []string
+	for _, path := range strings.Split(output, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// pathsNotIn returns the paths that aren't in others.
+func pathsNotIn(paths, others []string) []string {
+	known := make(map[string]bool, len(others))
+	for _, path := range others {
+		known[path] = true
+	}
+	var missing []string
+	for _, path := range paths {
+		if !known[path] {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
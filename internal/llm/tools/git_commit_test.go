@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/history"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/permission"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+// sessionFiles is a history service that lists a fixed set of files.
+type sessionFiles struct {
+	history.Service
+	paths []string
+}
+
+func (s *sessionFiles) ListLatestSessionFiles(ctx context.Context, sessionID string) ([]history.File, error) {
+	files := make([]history.File, len(s.paths))
+	for i, path := range s.paths {
+		files[i] = history.File{SessionID: sessionID, Path: path}
+	}
+	return files, nil
+}
+
+type summarizedSession struct {
+	session.Service
+	title string
+}
+
+func (s *summarizedSession) Get(ctx context.Context, id string) (session.Session, error) {
+	return session.Session{ID: id, Title: s.title, SummaryMessageID: "summary"}, nil
+}
+
+type summaryMessages struct {
+	message.Service
+	summary string
+}
+
+func (m *summaryMessages) Get(ctx context.Context, id string) (message.Message, error) {
+	return message.Message{ID: id, Parts: []message.ContentPart{message.TextContent{Text: m.summary}}}, nil
+}
+
+// gitCommitRepo creates a repository with one commit of main.go and
+// README.md and makes it the working directory.
+func gitCommitRepo(t *testing.T) string {
+	t.Helper()
+	for _, env := range []string{
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	} {
+		key, value, _ := strings.Cut(env, "=")
+		t.Setenv(key, value)
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# readme\n"), 0o644))
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+
+	cfg := config.Get()
+	original := cfg.WorkingDir
+	t.Cleanup(func() { cfg.WorkingDir = original })
+	cfg.WorkingDir = dir
+	return dir
+}
+
+func runGitCommit(t *testing.T, tool BaseTool, params GitCommitParams) ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, t.Name())
+	response, err := tool.Run(ctx, ToolCall{Name: GitCommitToolName, Input: string(input)})
+	require.NoError(t, err)
+	return response
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := git(context.Background(), dir, args...)
+	require.NoError(t, err)
+	return strings.TrimSpace(output)
+}
+
+func TestGitCommitCommitsSessionFiles(t *testing.T) {
+	dir := gitCommitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "README.md")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.go"), []byte("package main\n"), 0o644))
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(t.Name())
+	files := &sessionFiles{paths: []string{
+		filepath.Join(dir, "main.go"),
+		filepath.Join(dir, "new.go"),
+		filepath.Join(dir, "README.md"),
+		filepath.Join(dir, "gone.go"),
+		filepath.Join(t.TempDir(), "outside.go"),
+	}}
+	tool := NewGitCommitTool(permissions, nil, nil, files)
+
+	response := runGitCommit(t, tool, GitCommitParams{Message: "Add main"})
+	require.False(t, response.IsError, response.Content)
+
+	var metadata GitCommitResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	assert.Equal(t, gitOutput(t, dir, "rev-parse", "HEAD"), metadata.Commit)
+	assert.Equal(t, []string{"README.md", "main.go", "new.go"}, metadata.Files)
+	assert.Contains(t, response.Content, metadata.Commit)
+	assert.Equal(t, "Add main", gitOutput(t, dir, "log", "-1", "--format=%B"))
+	assert.Equal(t, "?? other.go", gitOutput(t, dir, "status", "--porcelain"))
+
+	response = runGitCommit(t, tool, GitCommitParams{Message: "Again"})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "no changes to commit")
+}
+
+func TestGitCommitRefusesUnrelatedStagedChanges(t *testing.T) {
+	dir := gitCommitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# changed\n"), 0o644))
+	runGit(t, dir, "add", "README.md")
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(t.Name())
+	tool := NewGitCommitTool(permissions, nil, nil, &sessionFiles{paths: []string{filepath.Join(dir, "main.go")}})
+
+	response := runGitCommit(t, tool, GitCommitParams{Message: "Add main"})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "README.md")
+	assert.Equal(t, "initial", gitOutput(t, dir, "log", "-1", "--format=%s"))
+
+	response = runGitCommit(t, tool, GitCommitParams{Message: "Add main", IncludeAll: true})
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, "README.md\nmain.go", gitOutput(t, dir, "show", "--name-only", "--format=", "HEAD"))
+}
+
+func TestGitCommitMessageFromSummary(t *testing.T) {
+	dir := gitCommitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(t.Name())
+	files := &sessionFiles{paths: []string{filepath.Join(dir, "main.go")}}
+
+	tool := NewGitCommitTool(permissions, &summarizedSession{title: "Add main"}, &summaryMessages{}, files)
+	response := runGitCommit(t, tool, GitCommitParams{})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "message is required")
+
+	tool = NewGitCommitTool(permissions, &summarizedSession{title: "Add main"}, &summaryMessages{summary: "Added an empty main function."}, files)
+	response = runGitCommit(t, tool, GitCommitParams{})
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, "Add main\n\nAdded an empty main function.", gitOutput(t, dir, "log", "-1", "--format=%B"))
+}
+
+func TestGitCommitPermissionDenied(t *testing.T) {
+	dir := gitCommitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+
+	permissions := permission.NewPermissionService()
+	events := permissions.Subscribe(t.Context())
+	go func() {
+		for event := range events {
+			permissions.Deny(event.Payload)
+		}
+	}()
+	tool := NewGitCommitTool(permissions, nil, nil, &sessionFiles{paths: []string{filepath.Join(dir, "main.go")}})
+
+	input, err := json.Marshal(GitCommitParams{Message: "Add main"})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, t.Name())
+	_, err = tool.Run(ctx, ToolCall{Name: GitCommitToolName, Input: string(input)})
+	assert.ErrorIs(t, err, permission.ErrorPermissionDenied)
+	assert.Equal(t, "initial", gitOutput(t, dir, "log", "-1", "--format=%s"))
+	assert.Empty(t, gitOutput(t, dir, "diff", "--cached", "--name-only"))
+}
+
+func TestGitCommitIncludeAllListsStagedFiles(t *testing.T) {
+	dir := gitCommitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "release notés.md"), []byte("# notes\n"), 0o644))
+	runGit(t, dir, "add", "release notés.md")
+
+	permissions := permission.NewPermissionService()
+	events := permissions.Subscribe(t.Context())
+	descriptions := make(chan string, 1)
+	go func() {
+		for event := range events {
+			descriptions <- event.Payload.Description
+			permissions.Grant(event.Payload)
+		}
+	}()
+	tool := NewGitCommitTool(permissions, nil, nil, &sessionFiles{paths: []string{filepath.Join(dir, "main.go")}})
+
+	response := runGitCommit(t, tool, GitCommitParams{Message: "Add main"})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "release notés.md")
+
+	response = runGitCommit(t, tool, GitCommitParams{Message: "Add main", IncludeAll: true})
+	require.False(t, response.IsError, response.Content)
+	description := <-descriptions
+	assert.Contains(t, description, "- main.go")
+	assert.Contains(t, description, "- release notés.md")
+
+	var metadata GitCommitResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	assert.Equal(t, []string{"main.go", "release notés.md"}, metadata.Files)
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+
+	output, err := git(context.Background(), repo, "rev-parse", "--is-inside-work-tree")
+	require.NoError(t, err)
+	assert.Equal(t, "true\n", output)
+
+	_, err = git(context.Background(), repo, "rev-parse", "--verify", "no-such-branch")
+	assert.ErrorContains(t, err, "exit status")
+	assert.ErrorContains(t, err, "fatal:")
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,21 +15,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/fileutil"
+	"github.com/zhenbah/cryoncode/internal/lsp/watcher"
 )
 
 type GrepParams struct {
 	Pattern     string `json:"pattern"`
 	Path        string `json:"path"`
 	Include     string `json:"include"`
+	Exclude     string `json:"exclude"`
 	LiteralText bool   `json:"literal_text"`
+	IgnoreCase  bool   `json:"ignore_case"`
+	MaxResults  int    `json:"max_results"`
 }
 
 type grepMatch struct {
 	path     string
 	modTime  time.Time
 	lineNum  int
+	column   int
 	lineText string
 }
 
@@ -37,11 +44,19 @@ type GrepResponseMetadata struct {
 	Truncated       bool `json:"truncated"`
 }
 
+// grepOptions narrow down a search.
+type grepOptions struct {
+	include    string
+	exclude    string
+	ignoreCase bool
+	limit      int
+}
+
 type grepTool struct{}
 
 const (
 	GrepToolName    = "grep"
-	grepDescription = `Fast content search tool that finds files containing specific text or patterns, returning matching file paths sorted by modification time (newest first).
+	grepDescription = `Fast content search tool that finds the lines matching a pattern, using ripgrep when it is installed. Each match is reported as "file:line:column: text", with the most recently modified files first.
 
 WHEN TO USE THIS TOOL:
 - Use when you need to find files containing specific text or patterns
@@ -51,9 +66,11 @@ WHEN TO USE THIS TOOL:
 HOW TO USE:
 - Provide a regex pattern to search for within file contents
 - Set literal_text=true if you want to search for the exact text with special characters (recommended for non-regex users)
+- Set ignore_case=true for a case-insensitive search (searches are case-sensitive by default)
 - Optionally specify a starting directory (defaults to current working directory)
-- Optionally provide an include pattern to filter which files to search
-- Results are sorted with most recently modified files first
+- Optionally provide an include pattern to filter which files to search, and an exclude pattern to skip files
+- Optionally set max_results to get fewer or more matches (default 100, at most 1000)
+- Results are sorted with most recently modified files first, and by line within a file
 
 REGEX PATTERN SYNTAX (when literal_text=false):
 - Supports standard regular expression syntax
@@ -61,16 +78,19 @@ REGEX PATTERN SYNTAX (when literal_text=false):
 - 'log\..*Error' finds text starting with "log." and ending with "Error"
 - 'import\s+.*\s+from' finds import statements in JavaScript/TypeScript
 
-COMMON INCLUDE PATTERN EXAMPLES:
+COMMON INCLUDE AND EXCLUDE PATTERN EXAMPLES:
 - '*.js' - Only search JavaScript files
 - '*.{ts,tsx}' - Only search TypeScript files
 - '*.go' - Only search Go files
+- '*_test.go' as exclude - Skip Go test files
+- 'docs/**' as exclude - Skip everything under docs
 
 LIMITATIONS:
-- Results are limited to 100 files (newest first)
+- Results are limited to max_results matches (newest files first)
 - Performance depends on the number of files being searched
 - Very large binary files may be skipped
 - Hidden files (starting with '.') are skipped
+- Dependency and build output directories such as node_modules, vendor and dist are skipped
 
 TIPS:
 - For faster, more targeted searches, first use Glob to find relevant files, then use Grep
@@ -79,6 +99,14 @@ TIPS:
 - Use literal_text=true when searching for exact text containing special characters like dots, parentheses, etc.`
 )
 
+const (
+	// DefaultGrepMaxResults is the number of matches returned when max_results
+	// is not set
+	DefaultGrepMaxResults = 100
+	// MaxGrepMaxResults caps max_results
+	MaxGrepMaxResults = 1000
+)
+
 func NewGrepTool() BaseTool {
 	return &grepTool{}
 }
@@ -100,10 +128,22 @@ func (g *grepTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "File pattern to include in the search (e.g. \"*.js\", \"*.{ts,tsx}\")",
 			},
+			"exclude": map[string]any{
+				"type":        "string",
+				"description": "File pattern to exclude from the search (e.g. \"*_test.go\", \"docs/**\")",
+			},
 			"literal_text": map[string]any{
 				"type":        "boolean",
 				"description": "If true, the pattern will be treated as literal text with special regex characters escaped. Default is false.",
 			},
+			"ignore_case": map[string]any{
+				"type":        "boolean",
+				"description": "If true, the search is case-insensitive. Default is false.",
+			},
+			"max_results": map[string]any{
+				"type":        "number",
+				"description": "The maximum number of matches to return. Defaults to 100, at most 1000.",
+			},
 		},
 		Required: []string{"pattern"},
 	}
@@ -142,40 +182,38 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		searchPath = config.WorkingDirectory()
 	}
 
-	matches, truncated, err := searchFiles(searchPattern, searchPath, params.Include, 100)
+	limit := params.MaxResults
+	if limit <= 0 {
+		limit = DefaultGrepMaxResults
+	}
+	limit = min(limit, MaxGrepMaxResults)
+
+	matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, grepOptions{
+		include:    params.Include,
+		exclude:    params.Exclude,
+		ignoreCase: params.IgnoreCase,
+		limit:      limit,
+	})
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
 	}
 
-	var output string
+	var output strings.Builder
 	if len(matches) == 0 {
-		output = "No files found"
+		output.WriteString("No files found")
 	} else {
-		output = fmt.Sprintf("Found %d matches\n", len(matches))
-
-		currentFile := ""
+		fmt.Fprintf(&output, "Found %d matches\n", len(matches))
 		for _, match := range matches {
-			if currentFile != match.path {
-				if currentFile != "" {
-					output += "\n"
-				}
-				currentFile = match.path
-				output += fmt.Sprintf("%s:\n", match.path)
-			}
-			if match.lineNum > 0 {
-				output += fmt.Sprintf("  Line %d: %s\n", match.lineNum, match.lineText)
-			} else {
-				output += fmt.Sprintf("  %s\n", match.path)
-			}
+			fmt.Fprintf(&output, "%s:%d:%d: %s\n", match.path, match.lineNum, match.column, match.lineText)
 		}
 
 		if truncated {
-			output += "\n(Results are truncated. Consider using a more specific path or pattern.)"
+			output.WriteString("\n(Results are truncated. Consider using a more specific path or pattern.)")
 		}
 	}
 
 	return WithResponseMetadata(
-		NewTextResponse(output),
+		NewTextResponse(output.String()),
 		GrepResponseMetadata{
 			NumberOfMatches: len(matches),
 			Truncated:       truncated,
@@ -183,41 +221,58 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
-func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
-	matches, err := searchWithRipgrep(pattern, rootPath, include)
+func searchFiles(ctx context.Context, pattern, rootPath string, opts grepOptions) ([]grepMatch, bool, error) {
+	matches, err := searchWithRipgrep(ctx, pattern, rootPath, opts)
 	if err != nil {
-		matches, err = searchFilesWithRegex(pattern, rootPath, include)
+		matches, err = searchFilesWithRegex(pattern, rootPath, opts)
 		if err != nil {
 			return nil, false, err
 		}
 	}
 
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].modTime.After(matches[j].modTime)
+	// Newest files first, and matches in the order of the file within each
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if !a.modTime.Equal(b.modTime) {
+			return a.modTime.After(b.modTime)
+		}
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.lineNum < b.lineNum
 	})
 
-	truncated := len(matches) > limit
+	truncated := len(matches) > opts.limit
 	if truncated {
-		matches = matches[:limit]
+		matches = matches[:opts.limit]
 	}
 
 	return matches, truncated, nil
 }
 
-func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
+func searchWithRipgrep(ctx context.Context, pattern, path string, opts grepOptions) ([]grepMatch, error) {
 	_, err := exec.LookPath("rg")
 	if err != nil {
 		return nil, fmt.Errorf("ripgrep not found: %w", err)
 	}
 
-	// Use -n to show line numbers and include the matched line
-	args := []string{"-H", "-n", pattern}
-	if include != "" {
-		args = append(args, "--glob", include)
+	// Report the line and column of the first match on each matching line
+	args := []string{"--with-filename", "--no-heading", "--line-number", "--column", "--color", "never"}
+	if opts.ignoreCase {
+		args = append(args, "--ignore-case")
+	}
+	if opts.include != "" {
+		args = append(args, "--glob", opts.include)
 	}
-	args = append(args, path)
+	if opts.exclude != "" {
+		args = append(args, "--glob", "!"+opts.exclude)
+	}
+	for _, dir := range watcher.ExcludedDirNames() {
+		args = append(args, "--glob", "!"+dir)
+	}
+	args = append(args, "-e", pattern, "--", path)
 
-	cmd := exec.Command("rg", args...)
+	cmd := exec.CommandContext(ctx, "rg", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
@@ -228,15 +283,16 @@ func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
 	matches := make([]grepMatch, 0, len(lines))
+	modTimes := make(map[string]time.Time)
 
 	for _, line := range lines {
 		if line == "" {
 			continue
 		}
 
-		// Parse ripgrep output format: file:line:content
-		parts := strings.SplitN(line, ":", 3)
-		if len(parts) < 3 {
+		// Parse ripgrep output format: file:line:column:content
+		parts := strings.SplitN(line, ":", 4)
+		if len(parts) < 4 {
 			continue
 		}
 
@@ -245,74 +301,107 @@ func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
 		if err != nil {
 			continue
 		}
-		lineText := parts[2]
-
-		fileInfo, err := os.Stat(filePath)
+		column, err := strconv.Atoi(parts[2])
 		if err != nil {
-			continue // Skip files we can't access
+			continue
+		}
+
+		modTime, ok := modTimes[filePath]
+		if !ok {
+			fileInfo, err := os.Stat(filePath)
+			if err != nil {
+				continue // Skip files we can't access
+			}
+			modTime = fileInfo.ModTime()
+			modTimes[filePath] = modTime
 		}
 
 		matches = append(matches, grepMatch{
 			path:     filePath,
-			modTime:  fileInfo.ModTime(),
+			modTime:  modTime,
 			lineNum:  lineNum,
-			lineText: lineText,
+			column:   column,
+			lineText: parts[3],
 		})
 	}
 
 	return matches, nil
 }
 
-func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error) {
+func searchFilesWithRegex(pattern, rootPath string, opts grepOptions) ([]grepMatch, error) {
 	matches := []grepMatch{}
 
+	if opts.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
 	regex, err := regexp.Compile(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("invalid regex pattern: %w", err)
 	}
-
-	var includePattern *regexp.Regexp
-	if include != "" {
-		regexPattern := globToRegex(include)
-		includePattern, err = regexp.Compile(regexPattern)
-		if err != nil {
-			return nil, fmt.Errorf("invalid include pattern: %w", err)
+	for _, glob := range []string{opts.include, opts.exclude} {
+		if glob != "" && !doublestar.ValidatePattern(glob) {
+			return nil, fmt.Errorf("invalid file pattern: %s", glob)
 		}
 	}
 
-	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+	excludedDirs := make(map[string]bool)
+	for _, dir := range watcher.ExcludedDirNames() {
+		excludedDirs[dir] = true
+	}
+
+	err = filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
 
-		if info.IsDir() {
-			return nil // Skip directories
+		// Only the part below the search root decides what is skipped, so
+		// that searching inside an ignored directory still works
+		relPath, err := filepath.Rel(rootPath, path)
+		if err != nil {
+			return nil
+		}
+		if relPath == "." {
+			if d.IsDir() {
+				return nil
+			}
+			relPath = d.Name() // The search path is a single file
 		}
 
-		if fileutil.SkipHidden(path) {
+		if d.IsDir() {
+			if excludedDirs[d.Name()] || fileutil.SkipHidden(relPath) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		if includePattern != nil && !includePattern.MatchString(path) {
+		if fileutil.SkipHidden(relPath) {
+			return nil
+		}
+		if opts.include != "" && !matchGrepGlob(opts.include, relPath) {
+			return nil
+		}
+		if opts.exclude != "" && matchGrepGlob(opts.exclude, relPath) {
 			return nil
 		}
 
-		match, lineNum, lineText, err := fileContainsPattern(path, regex)
-		if err != nil {
+		fileMatches, err := findPatternInFile(path, regex)
+		if err != nil || len(fileMatches) == 0 {
 			return nil // Skip files we can't read
 		}
 
-		if match {
-			matches = append(matches, grepMatch{
-				path:     path,
-				modTime:  info.ModTime(),
-				lineNum:  lineNum,
-				lineText: lineText,
-			})
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		for _, match := range fileMatches {
+			match.modTime = info.ModTime()
+			matches = append(matches, match)
+		}
 
-			if len(matches) >= 200 {
-				return filepath.SkipAll
-			}
+		// One match more than the limit is enough to tell that results
+		// are truncated
+		if len(matches) > opts.limit {
+			return filepath.SkipAll
 		}
 
 		return nil
@@ -324,36 +413,42 @@ func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error
 	return matches, nil
 }
 
-func fileContainsPattern(filePath string, pattern *regexp.Regexp) (bool, int, string, error) {
+// findPatternInFile returns a match for each line of the file that matches
+// pattern, with the column of the first match on the line.
+func findPatternInFile(filePath string, pattern *regexp.Regexp) ([]grepMatch, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return false, 0, "", err
+		return nil, err
 	}
 	defer file.Close()
 
+	var matches []grepMatch
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
-		if pattern.MatchString(line) {
-			return true, lineNum, line, nil
+		if loc := pattern.FindStringIndex(line); loc != nil {
+			matches = append(matches, grepMatch{
+				path:     filePath,
+				lineNum:  lineNum,
+				column:   loc[0] + 1,
+				lineText: line,
+			})
 		}
 	}
 
-	return false, 0, "", scanner.Err()
+	return matches, scanner.Err()
 }
 
-func globToRegex(glob string) string {
-	regexPattern := strings.ReplaceAll(glob, ".", "\\.")
-	regexPattern = strings.ReplaceAll(regexPattern, "*", ".*")
-	regexPattern = strings.ReplaceAll(regexPattern, "?", ".")
-
-	re := regexp.MustCompile(`\{([^}]+)\}`)
-	regexPattern = re.ReplaceAllStringFunc(regexPattern, func(match string) string {
-		inner := match[1 : len(match)-1]
-		return "(" + strings.ReplaceAll(inner, ",", "|") + ")"
-	})
-
-	return regexPattern
+// matchGrepGlob reports whether a file matches an include or exclude pattern
+// the way ripgrep does: patterns without a slash match the file name, others
+// the path below the search root.
+func matchGrepGlob(pattern, relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if !strings.Contains(pattern, "/") {
+		relPath = filepath.Base(relPath)
+	}
+	matched, _ := doublestar.Match(pattern, relPath)
+	return matched
 }
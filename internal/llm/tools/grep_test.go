@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func grep(t *testing.T, params GrepParams) (ToolResponse, GrepResponseMetadata) {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	response, err := NewGrepTool().Run(context.Background(), ToolCall{Name: GrepToolName, Input: string(input)})
+	require.NoError(t, err)
+	var metadata GrepResponseMetadata
+	if response.Metadata != "" {
+		require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	}
+	return response, metadata
+}
+
+// writeGrepFiles creates files under dir, each one older than the one before.
+func writeGrepFiles(t *testing.T, dir string, files ...[2]string) {
+	t.Helper()
+	modTime := time.Now()
+	for _, file := range files {
+		path := filepath.Join(dir, file[0])
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(file[1]), 0o644))
+		modTime = modTime.Add(-time.Minute)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+}
+
+func TestGrepStructuredMatches(t *testing.T) {
+	dir := t.TempDir()
+	writeGrepFiles(t, dir,
+		[2]string{"main.go", "package main\n\nfunc main() {\n\tRun()\n}\n"},
+		[2]string{"run.go", "package main\n\n// Run runs\nfunc Run() {}\n"},
+		[2]string{"node_modules/dep/index.js", "Run()\n"},
+		[2]string{".hidden/run.go", "func Run() {}\n"},
+	)
+
+	response, metadata := grep(t, GrepParams{Pattern: "Run", Path: dir})
+	require.False(t, response.IsError, response.Content)
+	assert.Equal(t, 3, metadata.NumberOfMatches)
+	assert.False(t, metadata.Truncated)
+	assert.Equal(t, "Found 3 matches\n"+
+		filepath.Join(dir, "main.go")+":4:2: \tRun()\n"+
+		filepath.Join(dir, "run.go")+":3:4: // Run runs\n"+
+		filepath.Join(dir, "run.go")+":4:6: func Run() {}\n", response.Content)
+}
+
+func TestGrepOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeGrepFiles(t, dir,
+		[2]string{"a.go", "TODO: one\n"},
+		[2]string{"a_test.go", "todo: two\n"},
+		[2]string{"notes.md", "Todo: three\n"},
+	)
+
+	t.Run("case sensitive by default", func(t *testing.T) {
+		_, metadata := grep(t, GrepParams{Pattern: "todo", Path: dir})
+		assert.Equal(t, 1, metadata.NumberOfMatches)
+	})
+
+	t.Run("ignore case", func(t *testing.T) {
+		_, metadata := grep(t, GrepParams{Pattern: "todo", Path: dir, IgnoreCase: true})
+		assert.Equal(t, 3, metadata.NumberOfMatches)
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		response, metadata := grep(t, GrepParams{Pattern: "todo", Path: dir, IgnoreCase: true, Include: "*.go", Exclude: "*_test.go"})
+		assert.Equal(t, 1, metadata.NumberOfMatches)
+		assert.Contains(t, response.Content, filepath.Join(dir, "a.go")+":1:1: TODO: one")
+	})
+
+	t.Run("max results", func(t *testing.T) {
+		response, metadata := grep(t, GrepParams{Pattern: "todo", Path: dir, IgnoreCase: true, MaxResults: 2})
+		assert.Equal(t, 2, metadata.NumberOfMatches)
+		assert.True(t, metadata.Truncated)
+		assert.True(t, strings.HasSuffix(response.Content, "(Results are truncated. Consider using a more specific path or pattern.)"))
+	})
+}
+
+func TestSearchFilesWithRegex(t *testing.T) {
+	// The fallback used when ripgrep is not installed
+	dir := t.TempDir()
+	writeGrepFiles(t, dir,
+		[2]string{"src/app.ts", "const a = 1\nconst b = a\n"},
+		[2]string{"dist/app.js", "const a = 1\n"},
+		[2]string{"vendor/lib/lib.go", "const a = 1\n"},
+	)
+
+	matches, err := searchFilesWithRegex(`a\b`, dir, grepOptions{limit: 10})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, grepMatch{path: filepath.Join(dir, "src/app.ts"), lineNum: 1, column: 7, lineText: "const a = 1"}, withoutModTime(matches[0]))
+	assert.Equal(t, grepMatch{path: filepath.Join(dir, "src/app.ts"), lineNum: 2, column: 11, lineText: "const b = a"}, withoutModTime(matches[1]))
+
+	// Searching inside an excluded directory still works
+	matches, err = searchFilesWithRegex("const", filepath.Join(dir, "vendor"), grepOptions{limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	_, err = searchFilesWithRegex("const", dir, grepOptions{include: "[", limit: 10})
+	assert.Error(t, err)
+}
+
+func withoutModTime(match grepMatch) grepMatch {
+	match.modTime = time.Time{}
+	return match
+}
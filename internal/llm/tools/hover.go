@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/lsp"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+type HoverParams struct {
+	FilePath  string `json:"file_path"`
+	Line      int    `json:"line"`
+	Character int    `json:"character"`
+}
+
+type hoverTool struct {
+	lspClients map[string]*lsp.Client
+}
+
+const (
+	HoverToolName    = "hover"
+	hoverDescription = `Shows what the language server knows about the symbol at a position in a file: its type or signature and its documentation.
+WHEN TO USE THIS TOOL:
+- Use to check a function's signature, a variable's type or a symbol's docs without reading its source
+- Helpful before calling a function from another package or file
+HOW TO USE:
+- Provide the path of the file and the line and character of the symbol, both counted from 1 as the View tool shows them
+- Any character within the symbol's name works
+FEATURES:
+- Returns the hover contents as markdown, usually a code block with the signature followed by the docs
+LIMITATIONS:
+- Needs a language server for the file's language; results depend on what the server provides
+- Returns nothing for positions outside a symbol, such as whitespace or comments
+`
+)
+
+func NewHoverTool(lspClients map[string]*lsp.Client) BaseTool {
+	return &hoverTool{
+		lspClients,
+	}
+}
+
+func (h *hoverTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        HoverToolName,
+		Description: hoverDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file containing the symbol",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The line of the symbol, counted from 1",
+			},
+			"character": map[string]any{
+				"type":        "integer",
+				"description": "The character of the symbol within the line, counted from 1",
+			},
+		},
+		Required: []string{"file_path", "line", "character"},
+	}
+}
+
+func (h *hoverTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params HoverParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	if len(h.lspClients) == 0 {
+		return NewTextErrorResponse("no LSP clients available"), nil
+	}
+
+	filePath, err := lspFilePath(params.FilePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	position, err := lspPosition(filePath, params.Line, params.Character)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	clients := lspClientsSupporting(h.lspClients, "textDocument/hover")
+	if len(clients) == 0 {
+		return NewTextErrorResponse("no LSP server supports hover"), nil
+	}
+
+	result := fanOutLSP(ctx, clients, func(ctx context.Context, _ string, client *lsp.Client) (string, error) {
+		if err := client.OpenFile(ctx, filePath); err != nil {
+			return "", err
+		}
+		var hover json.RawMessage
+		err := client.Call(ctx, "textDocument/hover", protocol.HoverParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentUri("file://" + filePath)},
+				Position:     position,
+			},
+		}, &hover)
+		if err != nil {
+			return "", err
+		}
+		return hoverMarkdown(hover), nil
+	})
+
+	names := make([]string, 0, len(result.Results))
+	for name, contents := range result.Results {
+		if contents != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var output string
+	switch len(names) {
+	case 0:
+		output = fmt.Sprintf("No hover information at %s:%d:%d", filePath, params.Line, params.Character)
+	case 1:
+		output = result.Results[names[0]]
+	default:
+		sections := make([]string, 0, len(names))
+		for _, name := range names {
+			sections = append(sections, fmt.Sprintf("[%s]\n%s", name, result.Results[name]))
+		}
+		output = strings.Join(sections, "\n\n")
+	}
+	if note := result.Note(); note != "" {
+		output += "\n\n" + note
+	}
+	return NewTextResponse(output), nil
+}
+
+// hoverMarkdown returns the contents of a hover response as markdown. Servers
+// may answer with null, or with contents that are markup, a plain string, a
+// language-tagged code string or a list of those; empty contents give "".
+func hoverMarkdown(hover json.RawMessage) string {
+	var response struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if len(hover) == 0 || json.Unmarshal(hover, &response) != nil {
+		return ""
+	}
+	return strings.TrimSpace(markedStringMarkdown(response.Contents))
+}
+
+// markedStringMarkdown returns one of the forms hover contents come in as
+// markdown.
+func markedStringMarkdown(contents json.RawMessage) string {
+	var text string
+	if json.Unmarshal(contents, &text) == nil {
+		return text
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(contents, &list) == nil {
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			if part := strings.TrimSpace(markedStringMarkdown(item)); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	var object struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if json.Unmarshal(contents, &object) != nil || strings.TrimSpace(object.Value) == "" {
+		return ""
+	}
+	if object.Language != "" {
+		return fmt.Sprintf("```%s\n%s\n```", object.Language, strings.TrimSpace(object.Value))
+	}
+	return object.Value
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+func TestHoverMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		hover string
+		want  string
+	}{
+		{"null", `null`, ""},
+		{"markup", `{"contents": {"kind": "markdown", "value": "` + "```go\\nfunc Foo()\\n```" + `\n\nFoo does things."}}`, "```go\nfunc Foo()\n```\n\nFoo does things."},
+		{"empty markup", `{"contents": {"kind": "plaintext", "value": ""}}`, ""},
+		{"plain string", `{"contents": "int"}`, "int"},
+		{"language string", `{"contents": {"language": "go", "value": "var x int"}}`, "```go\nvar x int\n```"},
+		{"list", `{"contents": [{"language": "go", "value": "func Foo()"}, "", "Foo does things."]}`, "```go\nfunc Foo()\n```\n\nFoo does things."},
+		{"no contents", `{}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hoverMarkdown(json.RawMessage(tt.hover)))
+		})
+	}
+}
+
+func TestLSPPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\r\n\nvar s = \"😀é\" + x\n"), 0o644))
+
+	position, err := lspPosition(path, 1, 9)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.Position{Line: 0, Character: 8}, position)
+
+	// The emoji takes two UTF-16 code units
+	position, err = lspPosition(path, 3, 15)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.Position{Line: 2, Character: 15}, position)
+
+	_, err = lspPosition(path, 5, 1)
+	assert.EqualError(t, err, "line 5 is out of range, the file has 4 lines")
+	_, err = lspPosition(path, 1, 14)
+	assert.EqualError(t, err, "character 14 is out of range, line 1 has 12 characters")
+}
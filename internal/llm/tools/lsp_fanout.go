@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/logging"
+)
+
+// lspFanOutResult holds the per-client results of a fan-out, keyed by LSP name,
+// together with the clients that timed out or failed.
+type lspFanOutResult[T any] struct {
+	Results  map[string]T
+	TimedOut []string
+	Failed   map[string]error
+}
+
+// Partial reports whether some clients did not contribute a result.
+func (r lspFanOutResult[T]) Partial() bool {
+	return len(r.TimedOut) > 0 || len(r.Failed) > 0
+}
+
+// Note describes the clients missing from the results, or is empty when every
+// client answered.
+func (r lspFanOutResult[T]) Note() string {
+	if !r.Partial() {
+		return ""
+	}
+	var parts []string
+	if len(r.TimedOut) > 0 {
+		parts = append(parts, fmt.Sprintf("timed out: %s", strings.Join(r.TimedOut, ", ")))
+	}
+	if len(r.Failed) > 0 {
+		failed := make([]string, 0, len(r.Failed))
+		for name := range r.Failed {
+			failed = append(failed, name)
+		}
+		sort.Strings(failed)
+		parts = append(parts, fmt.Sprintf("failed: %s", strings.Join(failed, ", ")))
+	}
+	return fmt.Sprintf("Note: results are partial, some LSP servers did not respond (%s)", strings.Join(parts, "; "))
+}
+
+// fanOutLSP calls fn for every client using the configured concurrency limit and
+// per-client timeout.
+func fanOutLSP[C any, T any](ctx context.Context, clients map[string]C, fn func(ctx context.Context, name string, client C) (T, error)) lspFanOutResult[T] {
+	cfg := config.Get().LSPFanOut
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = config.DefaultLSPFanOutMaxConcurrency
+	}
+	timeout := cfg.ClientTimeoutSeconds
+	if timeout <= 0 {
+		timeout = config.DefaultLSPFanOutClientTimeoutSeconds
+	}
+	return fanOut(ctx, clients, maxConcurrency, time.Duration(timeout)*time.Second, fn)
+}
+
+// fanOut calls fn for every client with at most maxConcurrency calls in flight.
+// A call that exceeds timeout is abandoned and reported in TimedOut, so one slow
+// client cannot hold up the results of the others, but it keeps its slot until
+// fn returns.
+func fanOut[C any, T any](ctx context.Context, clients map[string]C, maxConcurrency int, timeout time.Duration, fn func(ctx context.Context, name string, client C) (T, error)) lspFanOutResult[T] {
+	result := lspFanOutResult[T]{
+		Results: make(map[string]T, len(clients)),
+		Failed:  make(map[string]error),
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrency)
+
+	for name, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				mu.Lock()
+				result.TimedOut = append(result.TimedOut, name)
+				mu.Unlock()
+				return
+			}
+
+			clientCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			type outcome struct {
+				value T
+				err   error
+			}
+			done := make(chan outcome, 1)
+			go func() {
+				// The slot is held until fn returns, even after the call has
+				// been abandoned, so a slow server still counts against
+				// maxConcurrency.
+				defer func() { <-sem }()
+				value, err := fn(clientCtx, name, client)
+				done <- outcome{value, err}
+			}()
+
+			select {
+			case out := <-done:
+				mu.Lock()
+				if out.err != nil {
+					result.Failed[name] = out.err
+				} else {
+					result.Results[name] = out.value
+				}
+				mu.Unlock()
+			case <-clientCtx.Done():
+				logging.Debug("LSP client did not respond in time", "name", name, "timeout", timeout)
+				mu.Lock()
+				result.TimedOut = append(result.TimedOut, name)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(result.TimedOut)
+	return result
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanOut_SlowClientTimesOut(t *testing.T) {
+	clients := map[string]time.Duration{
+		"fast1": 0,
+		"fast2": 10 * time.Millisecond,
+		"slow":  time.Hour,
+	}
+
+	start := time.Now()
+	result := fanOut(context.Background(), clients, 2, 100*time.Millisecond, func(ctx context.Context, name string, delay time.Duration) (string, error) {
+		select {
+		case <-time.After(delay):
+			return name + " result", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, map[string]string{"fast1": "fast1 result", "fast2": "fast2 result"}, result.Results)
+	assert.Equal(t, []string{"slow"}, result.TimedOut)
+	assert.True(t, result.Partial())
+	assert.Contains(t, result.Note(), "timed out: slow")
+}
+
+func TestFanOut_BoundsConcurrency(t *testing.T) {
+	clients := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+
+	var inFlight, maxInFlight atomic.Int32
+	result := fanOut(context.Background(), clients, 2, time.Second, func(ctx context.Context, name string, value int) (int, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if name == "e" {
+			return 0, errors.New("boom")
+		}
+		return value * 10, nil
+	})
+
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+	assert.Len(t, result.Results, 4)
+	assert.Contains(t, result.Failed, "e")
+	assert.Empty(t, result.TimedOut)
+	assert.Contains(t, result.Note(), "failed: e")
+}
+
+func TestFanOut_AbandonedCallKeepsItsSlot(t *testing.T) {
+	clients := map[string]time.Duration{"slow": 200 * time.Millisecond, "fast": 0}
+
+	var inFlight, maxInFlight atomic.Int32
+	result := fanOut(context.Background(), clients, 1, 50*time.Millisecond, func(ctx context.Context, name string, delay time.Duration) (string, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		// Ignore ctx like a server that doesn't honor cancellation
+		time.Sleep(delay)
+		return name, nil
+	})
+
+	assert.Equal(t, int32(1), maxInFlight.Load())
+	assert.Equal(t, []string{"slow"}, result.TimedOut)
+	assert.Equal(t, map[string]string{"fast": "fast"}, result.Results)
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/lsp"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+// lspFilePath makes a file path given to an LSP tool absolute and checks that
+// it is a file.
+func lspFilePath(filePath string) (string, error) {
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("file not found: %s", filePath)
+		}
+		return "", fmt.Errorf("error accessing file: %w", err)
+	}
+	if fileInfo.IsDir() {
+		return "", fmt.Errorf("path is a directory, not a file: %s", filePath)
+	}
+	return filePath, nil
+}
+
+// lspPosition converts a line and character of a file, both counted from 1 as
+// the View tool shows them, to an LSP position, whose character counts UTF-16
+// code units from 0.
+func lspPosition(filePath string, line, character int) (protocol.Position, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return protocol.Position{}, fmt.Errorf("error reading file: %w", err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return protocol.Position{}, fmt.Errorf("line %d is out of range, the file has %d lines", line, len(lines))
+	}
+	text := []rune(strings.TrimSuffix(lines[line-1], "\r"))
+	if character < 1 || character > len(text)+1 {
+		return protocol.Position{}, fmt.Errorf("character %d is out of range, line %d has %d characters", character, line, len(text))
+	}
+	return protocol.Position{
+		Line:      uint32(line - 1),
+		Character: uint32(len(utf16.Encode(text[:character-1]))),
+	}, nil
+}
+
+// lspClientsSupporting returns the clients whose server supports method.
+func lspClientsSupporting(clients map[string]*lsp.Client, method string) map[string]*lsp.Client {
+	supporting := make(map[string]*lsp.Client, len(clients))
+	for name, client := range clients {
+		if client.Supports(method) {
+			supporting[name] = client
+		}
+	}
+	return supporting
+}
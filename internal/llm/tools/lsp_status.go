@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/lsp"
+)
+
+type lspStatusTool struct {
+	lspClients func() map[string]*lsp.Client
+}
+
+const (
+	LSPStatusToolName    = "lsp_status"
+	lspStatusDescription = `Report the health of the configured language servers.
+WHEN TO USE THIS TOOL:
+- Use when diagnostics come back empty and you need to know whether the language server is actually running
+- Helpful for explaining why a language is not getting diagnostics
+HOW TO USE:
+- Call the tool without parameters
+- Results are displayed as a table with one row per configured server
+FEATURES:
+- Shows the command used to start each server
+- Shows the server state: ready, starting, error, disabled or not running
+- Shows how many files each server currently has open
+LIMITATIONS:
+- Only reports servers listed in the lsp section of the configuration
+- "not running" means the server failed to start or is still being initialized
+`
+)
+
+// NewLspStatusTool returns the lsp_status tool. lspClients returns a snapshot
+// of the running clients, since they can be restarted while the tool runs.
+func NewLspStatusTool(lspClients func() map[string]*lsp.Client) BaseTool {
+	return &lspStatusTool{
+		lspClients,
+	}
+}
+
+func (l *lspStatusTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        LSPStatusToolName,
+		Description: lspStatusDescription,
+		Parameters:  map[string]any{},
+		Required:    []string{},
+	}
+}
+
+func (l *lspStatusTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	servers := config.Get().LSP
+	if len(servers) == 0 {
+		return NewTextErrorResponse("no LSP servers configured"), nil
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	clients := l.lspClients()
+	var output strings.Builder
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCOMMAND\tSTATE\tOPEN FILES")
+	for _, name := range names {
+		server := servers[name]
+		command := strings.TrimSpace(strings.Join(append([]string{server.Command}, server.Args...), " "))
+
+		state := "not running"
+		openFiles := "-"
+		if server.Disabled {
+			state = "disabled"
+		} else if client, ok := clients[name]; ok {
+			state = client.GetServerState().String()
+			openFiles = fmt.Sprintf("%d", client.OpenFileCount())
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, command, state, openFiles)
+	}
+	w.Flush()
+
+	return NewTextResponse(strings.TrimRight(output.String(), "\n")), nil
+}
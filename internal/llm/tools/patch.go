@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/diff"
+	"github.com/zhenbah/cryoncode/internal/fileutil"
 	"github.com/zhenbah/cryoncode/internal/history"
 	"github.com/zhenbah/cryoncode/internal/logging"
 	"github.com/zhenbah/cryoncode/internal/lsp"
@@ -186,6 +189,22 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a patch")
 	}
 
+	// Keep every change, including moves, inside the sandbox root
+	for path, change := range commit.Changes {
+		if err := checkSandbox(path); err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		if change.MovePath != nil {
+			if err := checkSandbox(*change.MovePath); err != nil {
+				return NewTextErrorResponse(err.Error()), nil
+			}
+		}
+	}
+
+	if dryRun() {
+		return p.dryRun(commit), nil
+	}
+
 	// Request permission for all changes
 	for path, change := range commit.Changes {
 		switch change.Type {
@@ -194,11 +213,12 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			patchDiff, _, _ := diff.GenerateDiff("", *change.NewContent, path)
 			p := p.permissions.Request(
 				permission.CreatePermissionRequest{
-					SessionID:   sessionID,
-					Path:        dir,
-					ToolName:    PatchToolName,
-					Action:      "create",
-					Description: fmt.Sprintf("Create file %s", path),
+					SessionID:    sessionID,
+					Path:         dir,
+					ResourcePath: path,
+					ToolName:     PatchToolName,
+					Action:       permission.ActionCreate,
+					Description:  fmt.Sprintf("Create file %s", path),
 					Params: EditPermissionsParams{
 						FilePath: path,
 						Diff:     patchDiff,
@@ -221,11 +241,12 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			dir := filepath.Dir(path)
 			p := p.permissions.Request(
 				permission.CreatePermissionRequest{
-					SessionID:   sessionID,
-					Path:        dir,
-					ToolName:    PatchToolName,
-					Action:      "update",
-					Description: fmt.Sprintf("Update file %s", path),
+					SessionID:    sessionID,
+					Path:         dir,
+					ResourcePath: path,
+					ToolName:     PatchToolName,
+					Action:       permission.ActionUpdate,
+					Description:  fmt.Sprintf("Update file %s", path),
 					Params: EditPermissionsParams{
 						FilePath: path,
 						Diff:     patchDiff,
@@ -240,11 +261,12 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			patchDiff, _, _ := diff.GenerateDiff(*change.OldContent, "", path)
 			p := p.permissions.Request(
 				permission.CreatePermissionRequest{
-					SessionID:   sessionID,
-					Path:        dir,
-					ToolName:    PatchToolName,
-					Action:      "delete",
-					Description: fmt.Sprintf("Delete file %s", path),
+					SessionID:    sessionID,
+					Path:         dir,
+					ResourcePath: path,
+					ToolName:     PatchToolName,
+					Action:       permission.ActionDelete,
+					Description:  fmt.Sprintf("Delete file %s", path),
 					Params: EditPermissionsParams{
 						FilePath: path,
 						Diff:     patchDiff,
@@ -257,6 +279,24 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 	}
 
+	diagnosticsBefore := make(map[string]map[string]int)
+	for path := range commit.Changes {
+		absPath := path
+		if !filepath.IsAbs(absPath) {
+			absPath = filepath.Join(config.WorkingDirectory(), absPath)
+		}
+		diagnosticsBefore[absPath] = diagnosticsBeforeEdit(absPath, p.lspClients)
+	}
+
+	var snapshotPaths []string
+	for path, change := range commit.Changes {
+		snapshotPaths = append(snapshotPaths, absolutePatchPath(path))
+		if change.MovePath != nil {
+			snapshotPaths = append(snapshotPaths, absolutePatchPath(*change.MovePath))
+		}
+	}
+	snapshot := snapshotFiles(snapshotPaths...)
+
 	// Apply the changes to the filesystem
 	err = diff.ApplyCommit(commit, func(path string, content string) error {
 		absPath := path
@@ -278,11 +318,16 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			wd := config.WorkingDirectory()
 			absPath = filepath.Join(wd, absPath)
 		}
-		return os.Remove(absPath)
+		if config.Get().HardDelete {
+			return os.Remove(absPath)
+		}
+		_, err := fileutil.MoveToTrash(config.TrashDirectory(sessionID), absPath)
+		return err
 	})
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("failed to apply patch: %s", err)), nil
 	}
+	recordEdit(sessionID, snapshot)
 
 	// Update file history for all modified files
 	changedFiles := []string{}
@@ -345,17 +390,13 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		recordFileRead(absPath)
 	}
 
-	// Run LSP diagnostics on all changed files
-	for _, filePath := range changedFiles {
-		waitForLspDiagnostics(ctx, filePath, p.lspClients)
-	}
-
 	result := fmt.Sprintf("Patch applied successfully. %d files changed, %d additions, %d removals",
 		len(changedFiles), totalAdditions, totalRemovals)
 
+	// Run LSP diagnostics on all changed files
 	diagnosticsText := ""
 	for _, filePath := range changedFiles {
-		diagnosticsText += getDiagnostics(filePath, p.lspClients)
+		diagnosticsText += editDiagnostics(ctx, filePath, p.lspClients, diagnosticsBefore[filePath])
 	}
 
 	if diagnosticsText != "" {
@@ -370,3 +411,45 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			Removals:     totalRemovals,
 		}), nil
 }
+
+// absolutePatchPath resolves a path of a patch against the working directory.
+func absolutePatchPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(config.WorkingDirectory(), path)
+}
+
+// dryRun returns the diffs of the changes in commit without applying them.
+func (p *patchTool) dryRun(commit diff.Commit) ToolResponse {
+	paths := make([]string, 0, len(commit.Changes))
+	for path := range commit.Changes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var diffs []string
+	totalAdditions, totalRemovals := 0, 0
+	for _, path := range paths {
+		change := commit.Changes[path]
+		oldContent, newContent := "", ""
+		if change.OldContent != nil {
+			oldContent = *change.OldContent
+		}
+		if change.NewContent != nil {
+			newContent = *change.NewContent
+		}
+		patchDiff, additions, removals := diff.GenerateDiff(oldContent, newContent, path)
+		diffs = append(diffs, patchDiff)
+		totalAdditions += additions
+		totalRemovals += removals
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(dryRunResult(strings.Join(paths, ", "), strings.Join(diffs, "\n"))),
+		PatchResponseMetadata{
+			FilesChanged: paths,
+			Additions:    totalAdditions,
+			Removals:     totalRemovals,
+		})
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ErrTruncatedJSON is returned by RepairJSON for tool call arguments that end
+// before their strings, objects or arrays are closed, which happens when a
+// response is cut off at the output token limit.
+var ErrTruncatedJSON = errors.New("tool call arguments are truncated")
+
+// RepairJSON fixes malformations in tool call arguments that can be corrected
+// without guessing at content: a surrounding code fence, trailing commas,
+// unquoted keys, single-quoted strings and raw newlines in strings. Valid input
+// and input it can't repair are returned unchanged, so that the tool reports the
+// parse error. Input that was cut off is never completed, since that would run
+// the tool with partial content; ErrTruncatedJSON is returned instead.
+func RepairJSON(input string) (string, error) {
+	if json.Valid([]byte(input)) || strings.TrimSpace(input) == "" {
+		return input, nil
+	}
+	repaired, truncated := repairJSON(stripCodeFence(input))
+	if truncated {
+		return input, ErrTruncatedJSON
+	}
+	if !json.Valid([]byte(repaired)) {
+		return input, nil
+	}
+	return repaired, nil
+}
+
+// stripCodeFence removes a Markdown code fence wrapped around input.
+func stripCodeFence(input string) string {
+	trimmed := strings.TrimSpace(input)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return input
+	}
+	body := strings.TrimSuffix(trimmed, "```")
+	// Drop the opening fence along with its language tag
+	if _, rest, ok := strings.Cut(body, "\n"); ok {
+		return strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(body, "```"))
+}
+
+// repairJSON rewrites input as JSON and reports whether it ended inside a
+// string, object or array.
+func repairJSON(input string) (string, bool) {
+	var out strings.Builder
+	var open int
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '\'':
+			i = copyString(&out, runes, i)
+			if i >= len(runes) {
+				return out.String(), true
+			}
+		case r == '{' || r == '[':
+			open++
+			out.WriteRune(r)
+		case r == '}' || r == ']':
+			open--
+			out.WriteRune(r)
+		case r == ',':
+			if next := nextNonSpace(runes, i+1); next == '}' || next == ']' {
+				continue
+			}
+			out.WriteRune(r)
+		case isIdentStart(r):
+			end := i
+			for end < len(runes) && isIdentPart(runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			if nextNonSpace(runes, end) == ':' {
+				out.WriteString(`"` + word + `"`)
+			} else {
+				out.WriteString(word)
+			}
+			i = end - 1
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String(), open > 0
+}
+
+// copyString writes the string starting at runes[start] as a double-quoted JSON
+// string and returns the index of its closing quote, or len(runes) when the
+// string is never closed.
+func copyString(out *strings.Builder, runes []rune, start int) int {
+	quote := runes[start]
+	out.WriteRune('"')
+	for i := start + 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			if quote == '\'' && runes[i+1] == '\'' {
+				out.WriteRune('\'')
+			} else {
+				out.WriteRune(r)
+				out.WriteRune(runes[i+1])
+			}
+			i++
+		case r == quote:
+			out.WriteRune('"')
+			return i
+		case r == '"':
+			out.WriteString(`\"`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteRune('"')
+	return len(runes)
+}
+
+func nextNonSpace(runes []rune, start int) rune {
+	for i := start; i < len(runes); i++ {
+		if !unicode.IsSpace(runes[i]) {
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || r == '-'
+}
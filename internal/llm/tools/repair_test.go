@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"trailing comma in object", `{"path": "main.go",}`, `{"path": "main.go"}`},
+		{"trailing comma in array", `{"args": ["-v", "./...", ]}`, `{"args": ["-v", "./..." ]}`},
+		{"unquoted keys", `{path: "main.go", limit: 10}`, `{"path": "main.go", "limit": 10}`},
+		{"single quoted strings", `{'command': 'echo "hi" it\'s me'}`, `{"command": "echo \"hi\" it's me"}`},
+		{"raw newline in string", "{\"content\": \"line one\nline two\"}", `{"content": "line one\nline two"}`},
+		{"code fence", "```json\n{\"path\": \"main.go\",}\n```", `{"path": "main.go"}`},
+		{"code fence without language", "```{\"path\": \"main.go\"}```", `{"path": "main.go"}`},
+		{"literals are kept", `{recursive: true, depth: null, limit: -1.5e3,}`, `{"recursive": true, "depth": null, "limit": -1.5e3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, err := RepairJSON(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, repaired)
+			assert.True(t, json.Valid([]byte(repaired)))
+		})
+	}
+}
+
+func TestRepairJSONLeavesValidInputUntouched(t *testing.T) {
+	inputs := []string{
+		`{}`,
+		`{"command": "ls -la, then {cd}", "timeout": 1000}`,
+		`{"path": "it's", "nested": {"list": [1, 2, 3]}}`,
+		`  {"spaced": true}  `,
+	}
+	for _, input := range inputs {
+		repaired, err := RepairJSON(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, input, repaired)
+	}
+}
+
+func TestRepairJSONRejectsTruncatedInput(t *testing.T) {
+	inputs := []string{
+		`{"pattern": "*.go", "path": "src`,
+		`{"file_path": "a.go", "edits": [{"old": "x"},`,
+		`{"file_path": "a.go", "content": "package main\n\nfunc main() {\n`,
+		`{file_path: 'a.go', content: 'package`,
+	}
+	for _, input := range inputs {
+		repaired, err := RepairJSON(input)
+		assert.ErrorIs(t, err, ErrTruncatedJSON, input)
+		assert.Equal(t, input, repaired)
+	}
+}
+
+func TestRepairJSONGivesUp(t *testing.T) {
+	for _, input := range []string{"", "not json at all", `{"a" "b"}`} {
+		repaired, err := RepairJSON(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, input, repaired)
+	}
+}
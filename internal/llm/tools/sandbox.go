@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// checkSandbox returns an error if path is outside the sandbox root once ".."
+// elements and symlinks are resolved. Relative paths are resolved against the
+// working directory. Paths that don't exist yet are checked through their
+// closest existing parent.
+func checkSandbox(path string) error {
+	root := sandboxRoot()
+	if root == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+
+	resolvedRoot, err := resolveExisting(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the sandbox root %s: %w", root, err)
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if !withinRoots(resolved, []string{resolvedRoot}) {
+		if resolved != filepath.Clean(path) {
+			return fmt.Errorf("path %s resolves to %s, which is outside the sandbox root %s", path, resolved, root)
+		}
+		return fmt.Errorf("path %s is outside the sandbox root %s", path, root)
+	}
+	return nil
+}
+
+// sandboxRoot returns the configured sandbox root, or "" when the tools are
+// not confined because it is unset or the filesystem root.
+func sandboxRoot() string {
+	root := config.Get().SandboxRoot
+	if root == "" || filepath.Dir(root) == root {
+		return ""
+	}
+	return root
+}
+
+// resolveExisting cleans an absolute path and resolves the symlinks in the
+// part of it that exists, including dangling symlinks, which would create
+// their target when written to.
+func resolveExisting(path string) (string, error) {
+	path = filepath.Clean(path)
+	var missing []string
+	for range 255 {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		if target, err := os.Readlink(path); err == nil {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			path = filepath.Clean(target)
+			continue
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
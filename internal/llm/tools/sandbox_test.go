@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func useSandboxRoot(t *testing.T, root string) {
+	t.Helper()
+	cfg := config.Get()
+	original := cfg.SandboxRoot
+	t.Cleanup(func() { cfg.SandboxRoot = original })
+	cfg.SandboxRoot = root
+}
+
+func TestCheckSandbox(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	useSandboxRoot(t, root)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0o755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(root, "dangling")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "src"), filepath.Join(outside, "into-root")))
+
+	tests := []struct {
+		name    string
+		path    string
+		allowed bool
+	}{
+		{"file in root", filepath.Join(root, "main.go"), true},
+		{"new file in new directory", filepath.Join(root, "src", "pkg", "new.go"), true},
+		{"root itself", root, true},
+		{"parent escape", filepath.Join(root, "src", "..", "..", "other.go"), false},
+		{"absolute path outside", filepath.Join(outside, "file.go"), false},
+		{"symlinked directory", filepath.Join(root, "escape", "file.go"), false},
+		{"dangling symlink", filepath.Join(root, "dangling"), false},
+		{"symlink into root", filepath.Join(outside, "into-root", "file.go"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSandbox(tt.path)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "outside the sandbox root")
+			}
+		})
+	}
+}
+
+func TestCheckSandboxLiftedAtFilesystemRoot(t *testing.T) {
+	useSandboxRoot(t, "/")
+	assert.NoError(t, checkSandbox(filepath.Join(t.TempDir(), "file.go")))
+	assert.NoError(t, checkSandbox("/etc/hosts"))
+}
+
+func TestWriteToolRejectsPathOutsideSandbox(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	useSandboxRoot(t, root)
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+
+	input, err := json.Marshal(WriteParams{FilePath: filepath.Join(root, "escape", "file.txt"), Content: "hello"})
+	require.NoError(t, err)
+
+	// The check runs before permissions are requested, so no service is needed
+	response, err := NewWriteTool(nil, nil, nil).Run(context.Background(), ToolCall{Name: WriteToolName, Input: string(input)})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "outside the sandbox root")
+	assert.NoFileExists(t, filepath.Join(outside, "file.txt"))
+}
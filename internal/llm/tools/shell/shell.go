@@ -7,18 +7,25 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/zhenbah/cryoncode/internal/config"
 )
 
+// killGracePeriod is how long a timed out or cancelled command has to exit
+// after SIGTERM before it is killed.
+const killGracePeriod = 2 * time.Second
+
 type PersistentShell struct {
 	cmd          *exec.Cmd
 	stdin        *os.File
-	isAlive      bool
+	isAlive      atomic.Bool
 	cwd          string
 	mu           sync.Mutex
 	commandQueue chan *commandExecution
@@ -26,6 +33,7 @@ type PersistentShell struct {
 
 type commandExecution struct {
 	command    string
+	env        []string
 	timeout    time.Duration
 	resultChan chan commandResult
 	ctx        context.Context
@@ -51,7 +59,7 @@ func GetPersistentShell(workingDir string) *PersistentShell {
 
 	if shellInstance == nil {
 		shellInstance = newPersistentShell(workingDir)
-	} else if !shellInstance.isAlive {
+	} else if !shellInstance.isAlive.Load() {
 		shellInstance = newPersistentShell(shellInstance.cwd)
 	}
 
@@ -101,16 +109,16 @@ func newPersistentShell(cwd string) *PersistentShell {
 	shell := &PersistentShell{
 		cmd:          cmd,
 		stdin:        stdinPipe.(*os.File),
-		isAlive:      true,
 		cwd:          cwd,
 		commandQueue: make(chan *commandExecution, 10),
 	}
+	shell.isAlive.Store(true)
 
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
 				fmt.Fprintf(os.Stderr, "Panic in shell command processor: %v\n", r)
-				shell.isAlive = false
+				shell.isAlive.Store(false)
 				close(shell.commandQueue)
 			}
 		}()
@@ -122,7 +130,7 @@ func newPersistentShell(cwd string) *PersistentShell {
 		if err != nil {
 			// Log the error if needed
 		}
-		shell.isAlive = false
+		shell.isAlive.Store(false)
 		close(shell.commandQueue)
 	}()
 
@@ -131,16 +139,16 @@ func newPersistentShell(cwd string) *PersistentShell {
 
 func (s *PersistentShell) processCommands() {
 	for cmd := range s.commandQueue {
-		result := s.execCommand(cmd.command, cmd.timeout, cmd.ctx)
+		result := s.execCommand(cmd.command, cmd.env, cmd.timeout, cmd.ctx)
 		cmd.resultChan <- result
 	}
 }
 
-func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx context.Context) commandResult {
+func (s *PersistentShell) execCommand(command string, env []string, timeout time.Duration, ctx context.Context) commandResult {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.isAlive {
+	if !s.isAlive.Load() {
 		return commandResult{
 			stderr:   "Shell is not alive",
 			exitCode: 1,
@@ -162,12 +170,12 @@ func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx
 	}()
 
 	fullCommand := fmt.Sprintf(`
-eval %s < /dev/null > %s 2> %s
+%s < /dev/null > %s 2> %s
 EXEC_EXIT_CODE=$?
 pwd > %s
 echo $EXEC_EXIT_CODE > %s
 `,
-		shellQuote(command),
+		withEnv(command, env),
 		shellQuote(stdoutFile),
 		shellQuote(stderrFile),
 		shellQuote(cwdFile),
@@ -243,33 +251,59 @@ echo $EXEC_EXIT_CODE > %s
 	}
 }
 
+// killChildren stops the running command. Commands run in the persistent
+// shell's own process group, so rather than signalling the group, which would
+// take the shell down too, every process descended from the shell is sent
+// SIGTERM, and whatever is still running after killGracePeriod gets SIGKILL.
 func (s *PersistentShell) killChildren() {
 	if s.cmd == nil || s.cmd.Process == nil {
 		return
 	}
 
-	pgrepCmd := exec.Command("pgrep", "-P", fmt.Sprintf("%d", s.cmd.Process.Pid))
-	output, err := pgrepCmd.Output()
+	pids := descendants(s.cmd.Process.Pid)
+	for _, pid := range pids {
+		syscall.Kill(pid, syscall.SIGTERM)
+	}
+
+	deadline := time.Now().Add(killGracePeriod)
+	for len(pids) > 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		pids = slices.DeleteFunc(pids, func(pid int) bool {
+			return syscall.Kill(pid, 0) != nil
+		})
+	}
+	for _, pid := range pids {
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+}
+
+// descendants returns the children of pid, their children and so on.
+func descendants(pid int) []int {
+	output, err := exec.Command("pgrep", "-P", strconv.Itoa(pid)).Output()
 	if err != nil {
-		return
+		return nil
 	}
 
+	var pids []int
 	for pidStr := range strings.SplitSeq(string(output), "\n") {
-		if pidStr = strings.TrimSpace(pidStr); pidStr != "" {
-			var pid int
-			fmt.Sscanf(pidStr, "%d", &pid)
-			if pid > 0 {
-				proc, err := os.FindProcess(pid)
-				if err == nil {
-					proc.Signal(syscall.SIGTERM)
-				}
-			}
+		child, err := strconv.Atoi(strings.TrimSpace(pidStr))
+		if err != nil || child <= 0 {
+			continue
 		}
+		pids = append(pids, child)
+		pids = append(pids, descendants(child)...)
 	}
+	return pids
 }
 
 func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs int) (string, string, int, bool, error) {
-	if !s.isAlive {
+	return s.ExecWithEnv(ctx, command, nil, timeoutMs)
+}
+
+// ExecWithEnv runs command like Exec with the KEY=VALUE entries of env set
+// for that command only, so they don't stay in the shell for later ones.
+func (s *PersistentShell) ExecWithEnv(ctx context.Context, command string, env []string, timeoutMs int) (string, string, int, bool, error) {
+	if !s.isAlive.Load() {
 		return "", "Shell is not alive", 1, false, errors.New("shell is not alive")
 	}
 
@@ -278,6 +312,7 @@ func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs in
 	resultChan := make(chan commandResult)
 	s.commandQueue <- &commandExecution{
 		command:    command,
+		env:        env,
 		timeout:    timeout,
 		resultChan: resultChan,
 		ctx:        ctx,
@@ -287,18 +322,48 @@ func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs in
 	return result.stdout, result.stderr, result.exitCode, result.interrupted, result.err
 }
 
+// Cwd returns the shell's current directory, waiting for a running command to
+// finish since it may change it.
+func (s *PersistentShell) Cwd() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cwd
+}
+
 func (s *PersistentShell) Close() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.isAlive {
+	if !s.isAlive.Load() {
 		return
 	}
 
 	s.stdin.Write([]byte("exit\n"))
 
 	s.cmd.Process.Kill()
-	s.isAlive = false
+	s.isAlive.Store(false)
+}
+
+// withEnv returns the shell code that evaluates command with the KEY=VALUE
+// entries of env exported to it and its children. The variables are local to
+// a function, so they are restored once the command is done, while the command
+// still runs in the shell itself and keeps its cd and other changes. Entries
+// whose key isn't a valid variable name are skipped.
+func withEnv(command string, env []string) string {
+	var keys, assignments []string
+	for _, entry := range env {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !config.ValidEnvName(key) {
+			continue
+		}
+		keys = append(keys, key)
+		assignments = append(assignments, key+"="+shellQuote(value))
+	}
+	if len(keys) == 0 {
+		return "eval " + shellQuote(command)
+	}
+	return fmt.Sprintf("cryoncode_with_env() {\nlocal %s\nexport %s\neval %s\n}\ncryoncode_with_env",
+		strings.Join(keys, " "), strings.Join(assignments, " "), shellQuote(command))
 }
 
 func shellQuote(s string) string {
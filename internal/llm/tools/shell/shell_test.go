@@ -0,0 +1,85 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecTimeoutKillsGrandchildren(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	sh := GetPersistentShell(t.TempDir())
+	require.NotNil(t, sh)
+
+	// Let the login shell finish starting up before timing a command
+	_, _, _, _, err := sh.Exec(context.Background(), "true", 0)
+	require.NoError(t, err)
+
+	// The command's own child would still create the marker if only the
+	// shell's direct children were stopped
+	command := fmt.Sprintf("sh -c 'sleep 1; touch %s'", shellQuote(marker))
+	_, _, _, interrupted, err := sh.Exec(context.Background(), command, 200)
+	require.NoError(t, err)
+	assert.True(t, interrupted)
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.NoFileExists(t, marker)
+
+	stdout, _, exitCode, _, err := sh.Exec(context.Background(), "echo alive", 1000)
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "alive\n", stdout)
+}
+
+func TestExecWithEnvSetsVariablesForOneCommand(t *testing.T) {
+	sh := GetPersistentShell(t.TempDir())
+	require.NotNil(t, sh)
+
+	env := []string{"CRYONCODE_TEST_CI=true", "CRYONCODE_TEST_QUOTED=it's here", "1INVALID=x"}
+	stdout, _, exitCode, _, err := sh.ExecWithEnv(context.Background(), `sh -c 'echo "$CRYONCODE_TEST_CI $CRYONCODE_TEST_QUOTED"'`, env, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "true it's here\n", stdout)
+
+	stdout, _, _, _, err = sh.Exec(context.Background(), `echo "[$CRYONCODE_TEST_CI]"`, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", stdout)
+}
+
+func TestExecWithEnvDoesNotLeak(t *testing.T) {
+	for _, path := range []string{"/bin/bash", "/bin/sh"} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			if _, err := exec.LookPath(path); err != nil {
+				t.Skipf("%s is not installed", path)
+			}
+			t.Setenv("SHELL", path)
+			dir := t.TempDir()
+			sh := newPersistentShell(dir)
+			require.NotNil(t, sh)
+			defer sh.Close()
+
+			_, _, _, _, err := sh.Exec(context.Background(), "export CRYONCODE_TEST_OUTER=outer", 1000)
+			require.NoError(t, err)
+
+			// The variables reach the command's children, and the command can
+			// still change the shell's directory
+			env := []string{"CRYONCODE_TEST_NEW=new", "CRYONCODE_TEST_OUTER=inner"}
+			stdout, _, exitCode, _, err := sh.ExecWithEnv(context.Background(), `cd .. && sh -c 'echo "$CRYONCODE_TEST_NEW $CRYONCODE_TEST_OUTER"'`, env, 1000)
+			require.NoError(t, err)
+			assert.Equal(t, 0, exitCode)
+			assert.Equal(t, "new inner\n", stdout)
+			assert.Equal(t, filepath.Dir(dir), sh.cwd)
+
+			// Neither the shell nor later children see them afterwards
+			stdout, _, _, _, err = sh.Exec(context.Background(), `echo "[$CRYONCODE_TEST_NEW]"; sh -c 'echo "[$CRYONCODE_TEST_NEW] $CRYONCODE_TEST_OUTER"'`, 1000)
+			require.NoError(t, err)
+			assert.Equal(t, "[]\n[] outer\n", stdout)
+		})
+	}
+}
@@ -178,6 +178,12 @@ func (t *sourcegraphTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 	if params.ContextWindow <= 0 {
 		params.ContextWindow = 10 // Default context window
 	}
+
+	cacheRequest := fmt.Sprintf("%s count=%d context_window=%d", params.Query, params.Count, params.ContextWindow)
+	if response, ok := toolResultCache.get(ctx, SourcegraphToolName, cacheRequest); ok {
+		return response, nil
+	}
+
 	client := t.client
 	if params.Timeout > 0 {
 		maxTimeout := 120 // 2 minutes
@@ -249,7 +255,9 @@ func (t *sourcegraphTool) Run(ctx context.Context, call ToolCall) (ToolResponse,
 		return NewTextErrorResponse("Failed to format results: " + err.Error()), nil
 	}
 
-	return NewTextResponse(formattedResults), nil
+	response := NewTextResponse(formattedResults)
+	toolResultCache.put(ctx, SourcegraphToolName, cacheRequest, response)
+	return response, nil
 }
 
 func formatSourcegraphResults(result map[string]any, contextWindow int) (string, error) {
@@ -71,6 +71,14 @@ type BaseTool interface {
 	Run(ctx context.Context, params ToolCall) (ToolResponse, error)
 }
 
+// RetryableTool is implemented by tools whose calls can fail transiently, such
+// as on a network error. Retryable reports whether an error returned by Run is
+// such a failure, which may not recur when the call is run again.
+type RetryableTool interface {
+	BaseTool
+	Retryable(err error) bool
+}
+
 func GetContextValues(ctx context.Context) (string, string) {
 	sessionID := ctx.Value(SessionIDContextKey)
 	messageID := ctx.Value(MessageIDContextKey)
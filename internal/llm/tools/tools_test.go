@@ -0,0 +1,17 @@
+package tools
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// TestMain loads a configuration rooted in the system temp directory, since
+// several tools resolve empty or relative paths against config.WorkingDirectory.
+func TestMain(m *testing.M) {
+	if _, err := config.Load(os.TempDir(), false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/diff"
+	"github.com/zhenbah/cryoncode/internal/fileutil"
+	"github.com/zhenbah/cryoncode/internal/history"
+	"github.com/zhenbah/cryoncode/internal/logging"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+// maxEditSnapshots is how many edits of a session can be undone.
+const maxEditSnapshots = 50
+
+var (
+	// ErrNothingToUndo is returned by UndoLastEdit when the session has no
+	// edits left to undo.
+	ErrNothingToUndo = errors.New("no edits to undo")
+	// ErrUndoDryRun is returned by UndoLastEdit in dry-run mode, which keeps
+	// files from being changed.
+	ErrUndoDryRun = errors.New("dry run is on, the edit was not undone")
+)
+
+// fileSnapshot is the content of a file before an edit.
+type fileSnapshot struct {
+	Path    string
+	Content string
+	Existed bool
+}
+
+// editSnapshots keeps, for each session, the files as they were before each
+// of its most recent edits, newest last. An edit of the patch tool may cover
+// several files. Snapshots live in memory for as long as the app runs.
+var editSnapshots = struct {
+	mu       sync.Mutex
+	sessions map[string][][]fileSnapshot
+}{sessions: make(map[string][][]fileSnapshot)}
+
+// snapshotFiles reads the current content of the files an edit is about to
+// change, for recordEdit once the edit is written. Files that don't exist yet
+// are removed on undo. It returns nil if a file can't be read, leaving the
+// edit without an undo.
+func snapshotFiles(paths ...string) []fileSnapshot {
+	snapshot := make([]fileSnapshot, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			logging.Debug("Cannot snapshot file before edit", "path", path, "error", err)
+			return nil
+		}
+		snapshot = append(snapshot, fileSnapshot{Path: path, Content: string(content), Existed: err == nil})
+	}
+	return snapshot
+}
+
+// recordEdit adds the snapshot taken by snapshotFiles before an edit to the
+// session's undo history, so that UndoLastEdit can restore it. It is called
+// only once the edit has been written, so a failed edit isn't undone.
+func recordEdit(sessionID string, snapshot []fileSnapshot) {
+	if len(snapshot) == 0 {
+		return
+	}
+	editSnapshots.mu.Lock()
+	defer editSnapshots.mu.Unlock()
+	stack := append(editSnapshots.sessions[sessionID], snapshot)
+	if len(stack) > maxEditSnapshots {
+		stack = stack[len(stack)-maxEditSnapshots:]
+	}
+	editSnapshots.sessions[sessionID] = stack
+}
+
+// lastEditSnapshot returns the snapshot of the most recent edit of a session.
+func lastEditSnapshot(sessionID string) ([]fileSnapshot, bool) {
+	editSnapshots.mu.Lock()
+	defer editSnapshots.mu.Unlock()
+	stack := editSnapshots.sessions[sessionID]
+	if len(stack) == 0 {
+		return nil, false
+	}
+	return stack[len(stack)-1], true
+}
+
+// dropLastEditSnapshot removes the snapshot of the most recent edit of a
+// session once it has been restored.
+func dropLastEditSnapshot(sessionID string) {
+	editSnapshots.mu.Lock()
+	defer editSnapshots.mu.Unlock()
+	stack := editSnapshots.sessions[sessionID]
+	if len(stack) <= 1 {
+		delete(editSnapshots.sessions, sessionID)
+		return
+	}
+	editSnapshots.sessions[sessionID] = stack[:len(stack)-1]
+}
+
+// UndoLastEdit restores the files changed by the most recent edit of a
+// session made with the edit, write, patch or apply_patch tool, after asking
+// for permission to overwrite or delete each of them. Calling it again undoes
+// the edit before that. It returns the restored paths, or ErrUndoDryRun in
+// dry-run mode.
+func UndoLastEdit(ctx context.Context, sessionID string, permissions permission.Service, files history.Service) ([]string, error) {
+	snapshot, ok := lastEditSnapshot(sessionID)
+	if !ok {
+		return nil, ErrNothingToUndo
+	}
+	if dryRun() {
+		return nil, ErrUndoDryRun
+	}
+	diffs, err := undoDiffs(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every file is checked before any is restored, so that a denial leaves
+	// the edit whole
+	paths := make([]string, 0, len(snapshot))
+	for i, file := range snapshot {
+		p := permissions.Request(
+			permission.CreatePermissionRequest{
+				SessionID:    sessionID,
+				Path:         filepath.Dir(file.Path),
+				ToolName:     UndoLastEditToolName,
+				Action:       permission.ActionDelete,
+				Description:  fmt.Sprintf("Undo the last edit of %s", file.Path),
+				ResourcePath: file.Path,
+				Params: EditPermissionsParams{
+					FilePath: file.Path,
+					Diff:     diffs[i],
+				},
+			},
+		)
+		if !p {
+			return nil, permission.ErrorPermissionDenied
+		}
+		paths = append(paths, file.Path)
+	}
+
+	for _, file := range snapshot {
+		if err := restoreSnapshot(sessionID, file); err != nil {
+			return nil, err
+		}
+		if _, err := files.CreateVersion(ctx, sessionID, file.Path, file.Content); err != nil {
+			logging.Debug("Error creating file history version", "error", err)
+		}
+		recordFileWrite(file.Path)
+		recordFileRead(file.Path)
+	}
+	dropLastEditSnapshot(sessionID)
+	return paths, nil
+}
+
+// undoDiffs returns, for each file of a snapshot, the diff from its current
+// content back to the snapshot.
+func undoDiffs(snapshot []fileSnapshot) ([]string, error) {
+	diffs := make([]string, 0, len(snapshot))
+	for _, file := range snapshot {
+		current, err := os.ReadFile(file.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading %s: %w", file.Path, err)
+		}
+		restoreDiff, _, _ := diff.GenerateDiff(string(current), file.Content, file.Path)
+		diffs = append(diffs, restoreDiff)
+	}
+	return diffs, nil
+}
+
+// restoreSnapshot puts a file back as it was. A file the edit created is
+// moved to the trash, or removed with hardDelete.
+func restoreSnapshot(sessionID string, file fileSnapshot) error {
+	if !file.Existed {
+		if _, err := os.Stat(file.Path); os.IsNotExist(err) {
+			return nil
+		}
+		if config.Get().HardDelete {
+			if err := os.Remove(file.Path); err != nil {
+				return fmt.Errorf("error removing %s: %w", file.Path, err)
+			}
+			return nil
+		}
+		if _, err := fileutil.MoveToTrash(config.TrashDirectory(sessionID), file.Path); err != nil {
+			return fmt.Errorf("error removing %s: %w", file.Path, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	if err := os.WriteFile(file.Path, []byte(file.Content), 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %w", file.Path, err)
+	}
+	return nil
+}
+
+type undoLastEditTool struct {
+	permissions permission.Service
+	files       history.Service
+}
+
+const (
+	UndoLastEditToolName    = "undo_last_edit"
+	undoLastEditDescription = `Undoes the most recent change made in this session with the Edit, Write, Patch or Apply Patch tool, putting the changed files back as they were.
+
+WHEN TO USE THIS TOOL:
+- Use when your last edit was wrong and you want to start again from the previous content
+- Call it again to undo earlier edits, newest first
+
+HOW TO USE:
+- Call it without parameters
+
+LIMITATIONS:
+- Only edits made with the Edit, Write, Patch and Apply Patch tools in this session can be undone, not changes made with the Bash tool
+- Files created by the edit are removed
+- Changes made to the files after the edit are lost`
+)
+
+func NewUndoLastEditTool(permissions permission.Service, files history.Service) BaseTool {
+	return &undoLastEditTool{
+		permissions: permissions,
+		files:       files,
+	}
+}
+
+func (u *undoLastEditTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        UndoLastEditToolName,
+		Description: undoLastEditDescription,
+		Parameters:  map[string]any{},
+		Required:    []string{},
+	}
+}
+
+func (u *undoLastEditTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for undoing an edit")
+	}
+
+	if snapshot, ok := lastEditSnapshot(sessionID); ok && dryRun() {
+		diffs, err := undoDiffs(snapshot)
+		if err != nil {
+			return ToolResponse{}, err
+		}
+		paths := make([]string, 0, len(snapshot))
+		for _, file := range snapshot {
+			paths = append(paths, file.Path)
+		}
+		return NewTextResponse(dryRunResult(strings.Join(paths, ", "), strings.Join(diffs, "\n"))), nil
+	}
+
+	paths, err := UndoLastEdit(ctx, sessionID, u.permissions, u.files)
+	if errors.Is(err, ErrNothingToUndo) {
+		return NewTextErrorResponse("There are no edits to undo in this session"), nil
+	}
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	return NewTextResponse(fmt.Sprintf("Restored %s", strings.Join(paths, ", "))), nil
+}
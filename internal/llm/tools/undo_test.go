@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/history"
+	"github.com/zhenbah/cryoncode/internal/permission"
+)
+
+// versionRecorder is a history service that only records new versions.
+type versionRecorder struct {
+	history.Service
+	versions []string
+}
+
+func (r *versionRecorder) GetByPathAndSession(ctx context.Context, path, sessionID string) (history.File, error) {
+	return history.File{}, sql.ErrNoRows
+}
+
+func (r *versionRecorder) Create(ctx context.Context, sessionID, path, content string) (history.File, error) {
+	return r.CreateVersion(ctx, sessionID, path, content)
+}
+
+func (r *versionRecorder) CreateVersion(ctx context.Context, sessionID, path, content string) (history.File, error) {
+	r.versions = append(r.versions, content)
+	return history.File{}, nil
+}
+
+func TestUndoLastEditRestoresEditsInReverseOrder(t *testing.T) {
+	cfg := config.Get()
+	t.Cleanup(func() { cfg.HardDelete = false })
+	cfg.HardDelete = true
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "main.go")
+	created := filepath.Join(dir, "new.go")
+	require.NoError(t, os.WriteFile(existing, []byte("v1"), 0o644))
+
+	sessionID := t.Name()
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(sessionID)
+	files := &versionRecorder{}
+
+	recordEdit(sessionID, snapshotFiles(existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v2"), 0o644))
+	recordEdit(sessionID, snapshotFiles(existing, created))
+	require.NoError(t, os.WriteFile(existing, []byte("v3"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0o644))
+
+	paths, err := UndoLastEdit(context.Background(), sessionID, permissions, files)
+	require.NoError(t, err)
+	assert.Equal(t, []string{existing, created}, paths)
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+	assert.NoFileExists(t, created)
+
+	_, err = UndoLastEdit(context.Background(), sessionID, permissions, files)
+	require.NoError(t, err)
+	content, err = os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+	assert.Equal(t, []string{"v2", "", "v1"}, files.versions)
+
+	_, err = UndoLastEdit(context.Background(), sessionID, permissions, files)
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+}
+
+func TestUndoLastEditKeepsSnapshotWhenDenied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o644))
+	sessionID := t.Name()
+	recordEdit(sessionID, snapshotFiles(path))
+	require.NoError(t, os.WriteFile(path, []byte("v2"), 0o644))
+
+	permissions := permission.NewPermissionService()
+	events := permissions.Subscribe(t.Context())
+	go func() {
+		for event := range events {
+			permissions.Deny(event.Payload)
+		}
+	}()
+
+	_, err := UndoLastEdit(context.Background(), sessionID, permissions, &versionRecorder{})
+	assert.ErrorIs(t, err, permission.ErrorPermissionDenied)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+	_, ok := lastEditSnapshot(sessionID)
+	assert.True(t, ok)
+}
+
+func TestUndoLastEditAsksToDeleteEachFile(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(first, []byte("a1"), 0o644))
+	sessionID := t.Name()
+	recordEdit(sessionID, snapshotFiles(first, second))
+	require.NoError(t, os.WriteFile(first, []byte("a2"), 0o644))
+	require.NoError(t, os.WriteFile(second, []byte("b"), 0o644))
+
+	permissions := permission.NewPermissionService()
+	events := permissions.Subscribe(t.Context())
+	var requests []permission.PermissionRequest
+	go func() {
+		for event := range events {
+			requests = append(requests, event.Payload)
+			// A grant scoped to the first file doesn't cover the second
+			if event.Payload.ResourcePath == first {
+				permissions.Grant(event.Payload)
+			} else {
+				permissions.Deny(event.Payload)
+			}
+		}
+	}()
+
+	_, err := UndoLastEdit(context.Background(), sessionID, permissions, &versionRecorder{})
+	assert.ErrorIs(t, err, permission.ErrorPermissionDenied)
+	require.Len(t, requests, 2)
+	for i, path := range []string{first, second} {
+		assert.Equal(t, permission.ActionDelete, requests[i].Action)
+		assert.Equal(t, path, requests[i].ResourcePath)
+	}
+	content, err := os.ReadFile(first)
+	require.NoError(t, err)
+	assert.Equal(t, "a2", string(content), "nothing is restored unless every file is allowed")
+}
+
+func TestUndoLastEditDryRun(t *testing.T) {
+	cfg := config.Get()
+	t.Cleanup(func() { cfg.DryRun = false })
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("v1\n"), 0o644))
+	sessionID := t.Name()
+	recordEdit(sessionID, snapshotFiles(path))
+	require.NoError(t, os.WriteFile(path, []byte("v2\n"), 0o644))
+	cfg.DryRun = true
+
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, sessionID)
+	// No permission is requested in a dry run, so no service is needed
+	response, err := NewUndoLastEditTool(nil, nil).Run(ctx, ToolCall{Name: UndoLastEditToolName, Input: "{}"})
+	require.NoError(t, err)
+	assert.Contains(t, response.Content, "Dry run")
+	assert.Contains(t, response.Content, "+v1")
+
+	_, err = UndoLastEdit(context.Background(), sessionID, nil, nil)
+	assert.ErrorIs(t, err, ErrUndoDryRun)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v2\n", string(content))
+	_, ok := lastEditSnapshot(sessionID)
+	assert.True(t, ok)
+}
+
+func TestFailedWriteIsNotRecorded(t *testing.T) {
+	// A dangling link looks like a new file, but can't be written through
+	dir := t.TempDir()
+	path := filepath.Join(dir, "link.go")
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing", "target.go"), path))
+	sessionID := t.Name()
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession(sessionID)
+
+	input, err := json.Marshal(WriteParams{FilePath: path, Content: "x"})
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, sessionID)
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+	_, err = NewWriteTool(nil, permissions, &versionRecorder{}).Run(ctx, ToolCall{Name: WriteToolName, Input: string(input)})
+	require.Error(t, err)
+
+	_, ok := lastEditSnapshot(sessionID)
+	assert.False(t, ok)
+}
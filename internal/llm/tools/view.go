@@ -16,9 +16,11 @@ import (
 )
 
 type ViewParams struct {
-	FilePath string `json:"file_path"`
-	Offset   int    `json:"offset"`
-	Limit    int    `json:"limit"`
+	FilePath  string `json:"file_path"`
+	Offset    int    `json:"offset"`
+	Limit     int    `json:"limit"`
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
 }
 
 type viewTool struct {
@@ -46,16 +48,18 @@ HOW TO USE:
 - Provide the path to the file you want to view
 - Optionally specify an offset to start reading from a specific line
 - Optionally specify a limit to control how many lines are read
+- Or specify start_line and end_line (1-based, inclusive) to read just that range, with 3 lines of context around it
 
 FEATURES:
 - Displays file contents with line numbers for easy reference
 - Can read from any position in a file using the offset parameter
+- Reads a line range with start_line and end_line, clamped to the file, and reports the total line count
 - Handles large files by limiting the number of lines read
 - Automatically truncates very long lines for better display
 - Suggests similar file names when the requested file isn't found
 
 LIMITATIONS:
-- Maximum file size is 250KB
+- Maximum file size is 250KB, except when reading a line range
 - Default reading limit is 2000 lines
 - Lines longer than 2000 characters are truncated
 - Cannot display binary files or images
@@ -64,9 +68,14 @@ LIMITATIONS:
 TIPS:
 - Use with Glob tool to first find files you want to view
 - For code exploration, first use Grep to find relevant files, then View to examine them
-- When viewing large files, use the offset parameter to read specific sections`
+- When viewing large files, use the offset parameter to read specific sections
+- When you know the lines you need, such as from a Grep match or an error message, read just them with start_line and end_line`
 )
 
+// ViewRangeContextLines is the number of lines shown around a requested line
+// range.
+const ViewRangeContextLines = 3
+
 func NewViewTool(lspClients map[string]*lsp.Client) BaseTool {
 	return &viewTool{
 		lspClients,
@@ -90,6 +99,14 @@ func (v *viewTool) Info() ToolInfo {
 				"type":        "integer",
 				"description": "The number of lines to read (defaults to 2000)",
 			},
+			"start_line": map[string]any{
+				"type":        "integer",
+				"description": "The first line of a range to read (1-based); use instead of offset and limit",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "The last line of a range to read (1-based, inclusive); defaults to start_line",
+			},
 		},
 		Required: []string{"file_path"},
 	}
@@ -150,6 +167,10 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 	}
 
+	if params.StartLine > 0 || params.EndLine > 0 {
+		return v.viewRange(ctx, filePath, params)
+	}
+
 	// Check file size
 	if fileInfo.Size() > MaxReadSize {
 		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes",
@@ -196,6 +217,52 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
+// viewRange reads the lines from StartLine to EndLine, clamped to the file,
+// with ViewRangeContextLines lines around them. Only the lines read are kept
+// in memory, so any size of file can be read this way.
+func (v *viewTool) viewRange(ctx context.Context, filePath string, params ViewParams) (ToolResponse, error) {
+	start, end := params.StartLine, params.EndLine
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 {
+		end = start
+	}
+	if end < start {
+		return NewTextErrorResponse(fmt.Sprintf("end_line (%d) is before start_line (%d)", end, start)), nil
+	}
+	if isImage, imageType := isImageFile(filePath); isImage {
+		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type: %s\nUse a different tool to process images", imageType)), nil
+	}
+
+	offset := max(start-1-ViewRangeContextLines, 0)
+	content, lineCount, err := readTextFile(filePath, offset, end+ViewRangeContextLines-offset)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading file: %w", err)
+	}
+	if start > lineCount {
+		return NewTextErrorResponse(fmt.Sprintf("start_line %d is past the end of the file, which has %d lines", start, lineCount)), nil
+	}
+	end = min(end, lineCount)
+	shownEnd := min(end+ViewRangeContextLines, lineCount)
+
+	notifyLspOpenFile(ctx, filePath, v.lspClients)
+	output := fmt.Sprintf("Lines %d-%d of %d (requested %d-%d", offset+1, shownEnd, lineCount, start, end)
+	if offset+1 < start || shownEnd > end {
+		output += fmt.Sprintf(", with %d lines of context", ViewRangeContextLines)
+	}
+	output += ")\n<file>\n" + addLineNumbers(content, offset+1) + "\n</file>\n"
+	output += getDiagnostics(filePath, v.lspClients)
+	recordFileRead(filePath)
+	return WithResponseMetadata(
+		NewTextResponse(output),
+		ViewResponseMetadata{
+			FilePath: filePath,
+			Content:  content,
+		},
+	), nil
+}
+
 func addLineNumbers(content string, startLine int) string {
 	if content == "" {
 		return ""
@@ -247,10 +314,10 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 		}
 	}
 
+	// lineCount is now the number of lines skipped, which is less than offset
+	// in a shorter file
 	var lines []string
-	lineCount = offset
-
-	for scanner.Scan() && len(lines) < limit {
+	for len(lines) < limit && scanner.Scan() {
 		lineCount++
 		lineText := scanner.Text()
 		if len(lineText) > MaxLineLength {
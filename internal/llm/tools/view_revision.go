@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+type ViewRevisionParams struct {
+	FilePath string `json:"file_path"`
+	Ref      string `json:"ref"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+}
+
+type ViewRevisionResponseMetadata struct {
+	FilePath string `json:"file_path"`
+	Ref      string `json:"ref"`
+	Commit   string `json:"commit"`
+	Content  string `json:"content"`
+}
+
+type viewRevisionTool struct{}
+
+const (
+	ViewRevisionToolName    = "view_revision"
+	viewRevisionDescription = `Reads a file as it was at a git revision, with line numbers, so you can compare the current code against its history.
+
+WHEN TO USE THIS TOOL:
+- Use to see what a file looked like before recent changes
+- Helpful for comparing the working copy with a branch, tag or commit
+- Good for recovering the previous version of code that was changed
+
+HOW TO USE:
+- Provide the path to the file, as it is named in the repository
+- Provide a git ref: a commit hash, a branch or tag name, or an expression such as HEAD~1 or main@{yesterday}
+- Optionally specify an offset and a limit, as with the View tool
+
+LIMITATIONS:
+- Only files in the git repository of the working directory can be read
+- Maximum file size is 250KB
+- Lines longer than 2000 characters are truncated
+- Binary files are not displayed
+
+TIPS:
+- Use the View tool to read the current version of the file
+- Use the Bash tool with git log to find the commit you are interested in`
+)
+
+func NewViewRevisionTool() BaseTool {
+	return &viewRevisionTool{}
+}
+
+func (v *viewRevisionTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ViewRevisionToolName,
+		Description: viewRevisionDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file to read",
+			},
+			"ref": map[string]any{
+				"type":        "string",
+				"description": "The git revision to read the file at, e.g. HEAD~1, a branch name or a commit hash",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "The line number to start reading from (0-based)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "The number of lines to read (defaults to 2000)",
+			},
+		},
+		Required: []string{"file_path", "ref"},
+	}
+}
+
+func (v *viewRevisionTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ViewRevisionParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	if params.Ref == "" {
+		return NewTextErrorResponse("ref is required"), nil
+	}
+	// Refs are passed to git as arguments, so they must not look like options
+	if strings.HasPrefix(params.Ref, "-") {
+		return NewTextErrorResponse(fmt.Sprintf("invalid ref: %s", params.Ref)), nil
+	}
+	if params.Offset < 0 {
+		return NewTextErrorResponse(fmt.Sprintf("offset must not be negative, got %d", params.Offset)), nil
+	}
+	if params.Limit < 0 {
+		return NewTextErrorResponse(fmt.Sprintf("limit must be positive, got %d", params.Limit)), nil
+	}
+	if params.Limit == 0 {
+		params.Limit = DefaultReadLimit
+	}
+
+	repoRoot, err := git(ctx, config.WorkingDirectory(), "rev-parse", "--show-toplevel")
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("the working directory is not in a git repository: %s", err)), nil
+	}
+	repoRoot = strings.TrimSpace(repoRoot)
+
+	filePath := params.FilePath
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+	}
+	// Compare physical paths, since git reports the repository root with
+	// symlinks resolved. The file itself may be a symlink tracked by git, so
+	// only its directory is resolved.
+	resolvedDir, err := resolveExisting(filepath.Dir(filePath))
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error resolving %s: %w", filePath, err)
+	}
+	resolvedPath := filepath.Join(resolvedDir, filepath.Base(filePath))
+	resolvedRoot, err := resolveExisting(repoRoot)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error resolving %s: %w", repoRoot, err)
+	}
+	relPath, err := filepath.Rel(resolvedRoot, resolvedPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return NewTextErrorResponse(fmt.Sprintf("path %s is outside the git repository %s", filePath, repoRoot)), nil
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	commit, err := git(ctx, repoRoot, "rev-parse", "--verify", "--quiet", "--end-of-options", params.Ref+"^{commit}")
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("unknown revision: %s", params.Ref)), nil
+	}
+	commit = strings.TrimSpace(commit)
+
+	object := commit + ":" + relPath
+	objectType, err := git(ctx, repoRoot, "cat-file", "-t", object)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("file %s does not exist at %s", relPath, params.Ref)), nil
+	}
+	if strings.TrimSpace(objectType) != "blob" {
+		return NewTextErrorResponse(fmt.Sprintf("path %s is not a file at %s", relPath, params.Ref)), nil
+	}
+	size, err := git(ctx, repoRoot, "cat-file", "-s", object)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading %s: %w", object, err)
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(size)); err == nil && n > MaxReadSize {
+		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes",
+			n, MaxReadSize)), nil
+	}
+
+	content, err := git(ctx, repoRoot, "cat-file", "blob", object)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading %s: %w", object, err)
+	}
+	if strings.IndexByte(content, 0) >= 0 {
+		return NewTextErrorResponse(fmt.Sprintf("%s is a binary file at %s", relPath, params.Ref)), nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if params.Offset >= len(lines) {
+		return NewTextErrorResponse(fmt.Sprintf("offset %d is past the end of the file, which has %d lines", params.Offset, len(lines))), nil
+	}
+	end := min(params.Offset+params.Limit, len(lines))
+	shown := lines[params.Offset:end]
+	for i, line := range shown {
+		if len(line) > MaxLineLength {
+			shown[i] = line[:MaxLineLength] + "..."
+		}
+	}
+
+	shownContent := strings.Join(shown, "\n")
+	output := "<file>\n"
+	output += addLineNumbers(shownContent, params.Offset+1)
+	if end < len(lines) {
+		output += fmt.Sprintf("\n\n(File has more lines. Use 'offset' parameter to read beyond line %d)", end)
+	}
+	output += "\n</file>\n"
+
+	return WithResponseMetadata(
+		NewTextResponse(output),
+		ViewRevisionResponseMetadata{
+			FilePath: filePath,
+			Ref:      params.Ref,
+			Commit:   commit,
+			Content:  shownContent,
+		},
+	), nil
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func viewRevision(t *testing.T, params ViewRevisionParams) ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	response, err := NewViewRevisionTool().Run(context.Background(), ToolCall{Name: ViewRevisionToolName, Input: string(input)})
+	require.NoError(t, err)
+	return response
+}
+
+func TestViewRevisionReadsPreviousCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	cfg := config.Get()
+	original := cfg.WorkingDir
+	t.Cleanup(func() { cfg.WorkingDir = original })
+	cfg.WorkingDir = repo
+
+	runGit(t, repo, "init", "-q")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "pkg"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "pkg", "main.go"), []byte("package main\n\nfunc old() {}\n"), 0o644))
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-q", "-m", "first")
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "pkg", "main.go"), []byte("package main\n\nfunc renamed() {}\n"), 0o644))
+	runGit(t, repo, "commit", "-q", "-am", "second")
+
+	response := viewRevision(t, ViewRevisionParams{FilePath: "pkg/main.go", Ref: "HEAD~1"})
+	require.False(t, response.IsError, response.Content)
+	assert.Contains(t, response.Content, "     3|func old() {}")
+	assert.NotContains(t, response.Content, "renamed")
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: filepath.Join(repo, "pkg", "main.go"), Ref: "HEAD"})
+	require.False(t, response.IsError, response.Content)
+	assert.Contains(t, response.Content, "func renamed() {}")
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: "pkg/missing.go", Ref: "HEAD~1"})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "does not exist at HEAD~1")
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: "pkg/main.go", Ref: "no-such-branch"})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "unknown revision")
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: "pkg/main.go", Ref: "--output=/tmp/x"})
+	assert.True(t, response.IsError)
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: "pkg/main.go", Ref: "HEAD", Offset: -1})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "offset must not be negative")
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: "pkg/main.go", Ref: "HEAD", Limit: -5})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "limit must be positive")
+
+	response = viewRevision(t, ViewRevisionParams{FilePath: "../outside.go", Ref: "HEAD"})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "outside the git repository")
+}
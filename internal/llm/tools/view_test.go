@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func view(t *testing.T, params ViewParams) ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	response, err := NewViewTool(nil).Run(context.Background(), ToolCall{Name: ViewToolName, Input: string(input)})
+	require.NoError(t, err)
+	return response
+}
+
+func TestViewLineRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	var content strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(content.String()), 0o644))
+
+	response := view(t, ViewParams{FilePath: path, StartLine: 900, EndLine: 902})
+	require.False(t, response.IsError, response.Content)
+	assert.Contains(t, response.Content, "Lines 897-905 of 1000 (requested 900-902, with 3 lines of context)")
+	assert.Contains(t, response.Content, "   897|line 897\n")
+	assert.Contains(t, response.Content, "   905|line 905\n</file>")
+	assert.NotContains(t, response.Content, "line 896\n")
+	assert.NotContains(t, response.Content, "line 906")
+
+	// Ranges are clamped to the file
+	response = view(t, ViewParams{FilePath: path, StartLine: 998, EndLine: 2000})
+	require.False(t, response.IsError, response.Content)
+	assert.Contains(t, response.Content, "Lines 995-1000 of 1000 (requested 998-1000, with 3 lines of context)")
+	assert.Contains(t, response.Content, "  1000|line 1000\n</file>")
+
+	response = view(t, ViewParams{FilePath: path, StartLine: 1, EndLine: 2})
+	assert.Contains(t, response.Content, "Lines 1-5 of 1000 (requested 1-2, with 3 lines of context)")
+
+	response = view(t, ViewParams{FilePath: path, StartLine: 1200})
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "the file, which has 1000 lines")
+
+	response = view(t, ViewParams{FilePath: path, StartLine: 10, EndLine: 5})
+	assert.True(t, response.IsError)
+}
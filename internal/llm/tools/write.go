@@ -115,6 +115,9 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	if !filepath.IsAbs(filePath) {
 		filePath = filepath.Join(config.WorkingDirectory(), filePath)
 	}
+	if err := checkSandbox(filePath); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 
 	fileInfo, err := os.Stat(filePath)
 	if err == nil {
@@ -137,11 +140,6 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, fmt.Errorf("error checking file: %w", err)
 	}
 
-	dir := filepath.Dir(filePath)
-	if err = os.MkdirAll(dir, 0o755); err != nil {
-		return ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
-	}
-
 	oldContent := ""
 	if fileInfo != nil && !fileInfo.IsDir() {
 		oldBytes, readErr := os.ReadFile(filePath)
@@ -161,6 +159,16 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		filePath,
 	)
 
+	if dryRun() {
+		return WithResponseMetadata(NewTextResponse(dryRunResult(filePath, diff)),
+			WriteResponseMetadata{
+				Diff:      diff,
+				Additions: additions,
+				Removals:  removals,
+			},
+		), nil
+	}
+
 	rootDir := config.WorkingDirectory()
 	permissionPath := filepath.Dir(filePath)
 	if strings.HasPrefix(filePath, rootDir) {
@@ -168,11 +176,12 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	}
 	p := w.permissions.Request(
 		permission.CreatePermissionRequest{
-			SessionID:   sessionID,
-			Path:        permissionPath,
-			ToolName:    WriteToolName,
-			Action:      "write",
-			Description: fmt.Sprintf("Create file %s", filePath),
+			SessionID:    sessionID,
+			Path:         permissionPath,
+			ResourcePath: filePath,
+			ToolName:     WriteToolName,
+			Action:       permission.ActionWrite,
+			Description:  fmt.Sprintf("Create file %s", filePath),
 			Params: WritePermissionsParams{
 				FilePath: filePath,
 				Diff:     diff,
@@ -183,10 +192,18 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	snapshot := snapshotFiles(filePath)
+	dir := filepath.Dir(filePath)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
+	}
+
+	diagnosticsBefore := diagnosticsBeforeEdit(filePath, w.lspClients)
 	err = os.WriteFile(filePath, []byte(params.Content), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error writing file: %w", err)
 	}
+	recordEdit(sessionID, snapshot)
 
 	// Check if file exists in history
 	file, err := w.files.GetByPathAndSession(ctx, filePath, sessionID)
@@ -212,11 +229,10 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
-	waitForLspDiagnostics(ctx, filePath, w.lspClients)
 
 	result := fmt.Sprintf("File successfully written: %s", filePath)
 	result = fmt.Sprintf("<result>\n%s\n</result>", result)
-	result += getDiagnostics(filePath, w.lspClients)
+	result += editDiagnostics(ctx, filePath, w.lspClients, diagnosticsBefore)
 	return WithResponseMetadata(NewTextResponse(result),
 		WriteResponseMetadata{
 			Diff:      diff,
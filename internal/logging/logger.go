@@ -156,7 +156,10 @@ func WriteRequestMessage(sessionId string, requestSeqId int, message string) str
 	}
 	filename := fmt.Sprintf("%d_request.json", requestSeqId)
 
-	return AppendToSessionLogFile(sessionId, filename, message)
+	filePath := AppendToSessionLogFile(sessionId, filename, message)
+	// Every request adds logs, so long sessions are kept within the limits too
+	PruneMessageLogs()
+	return filePath
 }
 
 func AppendToStreamSessionLogJson(sessionId string, requestSeqId int, jsonableChunk any) string {
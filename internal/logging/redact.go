@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// credentialPatterns match credentials in free text, such as error
+	// messages and response bodies. Each keeps the name of the credential in
+	// its first group and replaces the value that follows it.
+	credentialPatterns = []*regexp.Regexp{
+		// Authorization headers and bearer tokens
+		regexp.MustCompile(`(?i)(\b(?:proxy-)?authorization"?\s*[:=]\s*"?)[^"\r\n,\]]+`),
+		regexp.MustCompile(`(?i)(\b(?:bearer|basic)\s+)[A-Za-z0-9\-._~+/]{8,}=*`),
+		// Named keys and tokens in JSON, query strings and key=value pairs
+		regexp.MustCompile(`(?i)(\b(?:x-)?(?:api[_-]?key|access[_-]?token|refresh[_-]?token|id[_-]?token|client[_-]?secret|secret|password|token)"?\s*[:=]\s*"?)[^"&\s,}]+`),
+		regexp.MustCompile(`(?i)([?&]key=)[^&\s"]+`),
+	}
+
+	// keyPatterns match API keys and tokens that are recognizable by their
+	// format, wherever they appear.
+	keyPatterns = regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_\-]{16,}|xai-[A-Za-z0-9]{16,}|gsk_[A-Za-z0-9]{16,}|gh[opsur]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|AIza[0-9A-Za-z_\-]{30,}|AKIA[0-9A-Z]{16})`)
+)
+
+// Redact returns v with the API keys, bearer tokens and authorization headers
+// in it replaced, so that it can be logged without leaking credentials.
+// Headers keep their names, strings and errors are scrubbed of anything that
+// looks like a credential, and other values are formatted and scrubbed.
+func Redact(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return RedactString(v)
+	case []byte:
+		return RedactString(string(v))
+	case error:
+		return RedactString(v.Error())
+	case http.Header:
+		return redactHeader(v)
+	case fmt.Stringer:
+		return RedactString(v.String())
+	default:
+		return RedactString(fmt.Sprintf("%+v", v))
+	}
+}
+
+// RedactString replaces the credentials in s.
+func RedactString(s string) string {
+	for _, pattern := range credentialPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return keyPatterns.ReplaceAllString(s, redacted)
+}
+
+// redactHeader returns a copy of header with the values of the headers that
+// carry credentials replaced.
+func redactHeader(header http.Header) http.Header {
+	clean := make(http.Header, len(header))
+	for name, values := range header {
+		if isCredentialHeader(name) {
+			clean[name] = []string{redacted}
+			continue
+		}
+		cleanValues := make([]string, len(values))
+		for i, value := range values {
+			cleanValues[i] = RedactString(value)
+		}
+		clean[name] = cleanValues
+	}
+	return clean
+}
+
+func isCredentialHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range []string{"authorization", "api-key", "apikey", "token", "cookie", "secret"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
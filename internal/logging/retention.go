@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MessageRetention limits the request and response logs kept under
+// MessageDir. A zero limit is not applied.
+type MessageRetention struct {
+	MaxFiles int
+	MaxAge   time.Duration
+	MaxBytes int64
+}
+
+// MessageLogRetention is applied by PruneMessageLogs.
+var MessageLogRetention MessageRetention
+
+// PruneMessageLogs removes the oldest logs under MessageDir until the rest are
+// within the limits of MessageLogRetention.
+func PruneMessageLogs() {
+	if MessageDir == "" {
+		return
+	}
+	sessionLogMutex.Lock()
+	defer sessionLogMutex.Unlock()
+
+	removed, err := pruneMessageLogs(MessageDir, MessageLogRetention, time.Now())
+	if err != nil {
+		Warn("Failed to prune message logs", "dirpath", MessageDir, "error", err)
+		return
+	}
+	if removed > 0 {
+		Debug("Pruned message logs", "dirpath", MessageDir, "removed", removed)
+	}
+}
+
+type messageLogFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// pruneMessageLogs keeps the newest files under dir that are younger than
+// MaxAge, up to MaxFiles of them and MaxBytes in total, and removes the others
+// along with the session directories they leave empty. It returns the number
+// of files removed.
+func pruneMessageLogs(dir string, retention MessageRetention, now time.Time) (int, error) {
+	var files []messageLogFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Removed since the directory was read
+			return nil
+		}
+		files = append(files, messageLogFile{path: path, modTime: info.ModTime(), size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	var kept, removed int
+	var size int64
+	for _, file := range files {
+		size += file.size
+		expired := retention.MaxAge > 0 && now.Sub(file.modTime) > retention.MaxAge
+		tooMany := retention.MaxFiles > 0 && kept >= retention.MaxFiles
+		tooLarge := retention.MaxBytes > 0 && size > retention.MaxBytes
+		if !expired && !tooMany && !tooLarge {
+			kept++
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return removed, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// Fails for the directories that still hold logs
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMessageLog(t *testing.T, dir, name string, size int, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func remainingMessageLogs(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		require.NoError(t, err)
+		if path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return names
+}
+
+func TestPruneMessageLogs(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writeMessageLog(t, dir, "aaaaaaaa/1_request.json", 100, now.Add(-10*24*time.Hour))
+		writeMessageLog(t, dir, "aaaaaaaa/1_response.json", 100, now.Add(-10*24*time.Hour+time.Second))
+		writeMessageLog(t, dir, "bbbbbbbb/1_request.json", 100, now.Add(-2*time.Hour))
+		writeMessageLog(t, dir, "bbbbbbbb/1_response.json", 100, now.Add(-time.Hour))
+		return dir
+	}
+
+	tests := []struct {
+		name      string
+		retention MessageRetention
+		removed   int
+		remaining []string
+	}{
+		{
+			name:      "no limits",
+			retention: MessageRetention{},
+			removed:   0,
+			remaining: []string{"aaaaaaaa", "aaaaaaaa/1_request.json", "aaaaaaaa/1_response.json", "bbbbbbbb", "bbbbbbbb/1_request.json", "bbbbbbbb/1_response.json"},
+		},
+		{
+			name:      "old files and empty sessions are removed",
+			retention: MessageRetention{MaxAge: 7 * 24 * time.Hour},
+			removed:   2,
+			remaining: []string{"bbbbbbbb", "bbbbbbbb/1_request.json", "bbbbbbbb/1_response.json"},
+		},
+		{
+			name:      "newest files are kept",
+			retention: MessageRetention{MaxFiles: 3},
+			removed:   1,
+			remaining: []string{"aaaaaaaa", "aaaaaaaa/1_response.json", "bbbbbbbb", "bbbbbbbb/1_request.json", "bbbbbbbb/1_response.json"},
+		},
+		{
+			name:      "files beyond the size limit are removed",
+			retention: MessageRetention{MaxBytes: 250},
+			removed:   2,
+			remaining: []string{"bbbbbbbb", "bbbbbbbb/1_request.json", "bbbbbbbb/1_response.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setup(t)
+			removed, err := pruneMessageLogs(dir, tt.retention, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.removed, removed)
+			assert.Equal(t, tt.remaining, remainingMessageLogs(t, dir))
+		})
+	}
+}
@@ -3,7 +3,9 @@ package logging
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -44,40 +46,21 @@ var defaultLogData = &LogData{
 type writer struct{}
 
 func (w *writer) Write(p []byte) (int, error) {
+	// The JSON handler writes records as objects, the text handler as logfmt
+	if bytes.HasPrefix(bytes.TrimSpace(p), []byte("{")) {
+		if err := writeJSONRecords(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
 	d := logfmt.NewDecoder(bytes.NewReader(p))
 
 	for d.ScanRecord() {
-		msg := LogMessage{
-			ID:   fmt.Sprintf("%d", time.Now().UnixNano()),
-			Time: time.Now(),
-		}
+		msg := newLogMessage()
 		for d.ScanKeyval() {
-			switch string(d.Key()) {
-			case "time":
-				parsed, err := time.Parse(time.RFC3339, string(d.Value()))
-				if err != nil {
-					return 0, fmt.Errorf("parsing time: %w", err)
-				}
-				msg.Time = parsed
-			case "level":
-				msg.Level = strings.ToLower(string(d.Value()))
-			case "msg":
-				msg.Message = string(d.Value())
-			default:
-				if string(d.Key()) == persistKeyArg {
-					msg.Persist = true
-				} else if string(d.Key()) == PersistTimeArg {
-					parsed, err := time.ParseDuration(string(d.Value()))
-					if err != nil {
-						continue
-					}
-					msg.PersistTime = parsed
-				} else {
-					msg.Attributes = append(msg.Attributes, Attr{
-						Key:   string(d.Key()),
-						Value: string(d.Value()),
-					})
-				}
+			if err := msg.setField(string(d.Key()), string(d.Value())); err != nil {
+				return 0, err
 			}
 		}
 		defaultLogData.Add(msg)
@@ -88,6 +71,82 @@ func (w *writer) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// writeJSONRecords adds the records written by slog's JSON handler, keeping
+// their attributes in the order they were logged in.
+func writeJSONRecords(p []byte) error {
+	d := json.NewDecoder(bytes.NewReader(p))
+	for d.More() {
+		var record json.RawMessage
+		if err := d.Decode(&record); err != nil {
+			return fmt.Errorf("parsing record: %w", err)
+		}
+		fields := json.NewDecoder(bytes.NewReader(record))
+		if _, err := fields.Token(); err != nil {
+			return fmt.Errorf("parsing record: %w", err)
+		}
+		msg := newLogMessage()
+		for fields.More() {
+			key, err := fields.Token()
+			if err != nil {
+				return fmt.Errorf("parsing record: %w", err)
+			}
+			var raw json.RawMessage
+			if err := fields.Decode(&raw); err != nil {
+				return fmt.Errorf("parsing record: %w", err)
+			}
+			// Strings are shown without their quotes, other values as JSON
+			value := string(raw)
+			var text string
+			if json.Unmarshal(raw, &text) == nil {
+				value = text
+			}
+			if err := msg.setField(fmt.Sprint(key), value); err != nil {
+				return err
+			}
+		}
+		defaultLogData.Add(msg)
+	}
+	return nil
+}
+
+func newLogMessage() LogMessage {
+	return LogMessage{
+		ID:   fmt.Sprintf("%d", time.Now().UnixNano()),
+		Time: time.Now(),
+	}
+}
+
+// setField sets a field of a log record from its key and formatted value.
+func (msg *LogMessage) setField(key, value string) error {
+	switch key {
+	case "time":
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("parsing time: %w", err)
+		}
+		msg.Time = parsed
+	case "level":
+		msg.Level = strings.ToLower(value)
+	case "msg":
+		msg.Message = value
+	case persistKeyArg:
+		msg.Persist = true
+	case PersistTimeArg:
+		// The text handler formats durations, the JSON handler writes nanoseconds
+		if parsed, err := time.ParseDuration(value); err == nil {
+			msg.PersistTime = parsed
+		} else if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+			msg.PersistTime = time.Duration(nanos)
+		}
+	default:
+		msg.Attributes = append(msg.Attributes, Attr{
+			Key:   key,
+			Value: value,
+		})
+	}
+	return nil
+}
+
 func NewWriter() *writer {
 	w := &writer{}
 	return w
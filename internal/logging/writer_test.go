@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterParsesTextAndJSON(t *testing.T) {
+	handlers := map[string]slog.Handler{
+		"text": slog.NewTextHandler(NewWriter(), nil),
+		"json": slog.NewJSONHandler(NewWriter(), nil),
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			logger := slog.New(handler)
+			logger.Warn("Retrying request", "attempt", 2, "error", "rate limited", persistKeyArg, true, PersistTimeArg, 1500*time.Millisecond)
+
+			messages := List()
+			require.NotEmpty(t, messages)
+			msg := messages[len(messages)-1]
+			assert.Equal(t, "warn", msg.Level)
+			assert.Equal(t, "Retrying request", msg.Message)
+			assert.Equal(t, []Attr{{Key: "attempt", Value: "2"}, {Key: "error", Value: "rate limited"}}, msg.Attributes)
+			assert.True(t, msg.Persist)
+			assert.Equal(t, 1500*time.Millisecond, msg.PersistTime)
+			assert.WithinDuration(t, time.Now(), msg.Time, time.Minute)
+		})
+	}
+}
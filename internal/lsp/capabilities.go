@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+// UnsupportedMethodError is returned for a request the server didn't
+// advertise support for in its capabilities. The request is not sent.
+type UnsupportedMethodError struct {
+	Method string
+}
+
+func (e *UnsupportedMethodError) Error() string {
+	return fmt.Sprintf("server does not support %s", e.Method)
+}
+
+// methodCapabilities maps requests to the server capability that advertises
+// them. Requests not listed here are always sent.
+var methodCapabilities = map[string]string{
+	"textDocument/hover":                     "hoverProvider",
+	"textDocument/declaration":               "declarationProvider",
+	"textDocument/definition":                "definitionProvider",
+	"textDocument/typeDefinition":            "typeDefinitionProvider",
+	"textDocument/implementation":            "implementationProvider",
+	"textDocument/references":                "referencesProvider",
+	"textDocument/documentSymbol":            "documentSymbolProvider",
+	"textDocument/codeAction":                "codeActionProvider",
+	"textDocument/formatting":                "documentFormattingProvider",
+	"textDocument/rangeFormatting":           "documentRangeFormattingProvider",
+	"textDocument/rename":                    "renameProvider",
+	"textDocument/prepareRename":             "renameProvider",
+	"textDocument/inlayHint":                 "inlayHintProvider",
+	"textDocument/foldingRange":              "foldingRangeProvider",
+	"textDocument/selectionRange":            "selectionRangeProvider",
+	"textDocument/semanticTokens/full":       "semanticTokensProvider",
+	"textDocument/semanticTokens/full/delta": "semanticTokensProvider",
+	"textDocument/semanticTokens/range":      "semanticTokensProvider",
+	"textDocument/prepareCallHierarchy":      "callHierarchyProvider",
+	"textDocument/prepareTypeHierarchy":      "typeHierarchyProvider",
+	"workspace/symbol":                       "workspaceSymbolProvider",
+}
+
+// capabilityProviders returns which of the provider capabilities a server
+// advertised. A provider is advertised with true or with its options, and not
+// advertised when it is missing, null or false.
+func capabilityProviders(capabilities protocol.ServerCapabilities) map[string]bool {
+	data, err := json.Marshal(capabilities)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	providers := make(map[string]bool)
+	for _, capability := range methodCapabilities {
+		switch value := fields[capability].(type) {
+		case nil:
+		case bool:
+			providers[capability] = value
+		default:
+			providers[capability] = true
+		}
+	}
+	return providers
+}
+
+// registeredCapability returns the provider capability that a dynamic
+// registration of method stands for, if requests are gated on it. A
+// registration such as textDocument/semanticTokens covers every request under
+// it.
+func registeredCapability(method string) (string, bool) {
+	if capability, ok := methodCapabilities[method]; ok {
+		return capability, true
+	}
+	for request, capability := range methodCapabilities {
+		if strings.HasPrefix(request, method+"/") {
+			return capability, true
+		}
+	}
+	return "", false
+}
+
+func (c *Client) setProviders(providers map[string]bool) {
+	c.providersMu.Lock()
+	defer c.providersMu.Unlock()
+	c.providers = providers
+}
+
+// registerProviders records the providers a server registers through
+// client/registerCapability, so that their requests are sent.
+func (c *Client) registerProviders(params json.RawMessage) error {
+	var registerParams protocol.RegistrationParams
+	if err := json.Unmarshal(params, &registerParams); err != nil {
+		return err
+	}
+
+	c.providersMu.Lock()
+	defer c.providersMu.Unlock()
+	for _, reg := range registerParams.Registrations {
+		capability, ok := registeredCapability(reg.Method)
+		if !ok {
+			continue
+		}
+		if c.registrations == nil {
+			c.registrations = make(map[string]string)
+		}
+		c.registrations[reg.ID] = capability
+	}
+	return nil
+}
+
+// unregisterProviders handles client/unregisterCapability, dropping the
+// providers registered before under the given IDs.
+func (c *Client) unregisterProviders(params json.RawMessage) (any, error) {
+	var unregisterParams protocol.UnregistrationParams
+	if err := json.Unmarshal(params, &unregisterParams); err != nil {
+		return nil, err
+	}
+
+	c.providersMu.Lock()
+	defer c.providersMu.Unlock()
+	for _, unreg := range unregisterParams.Unregisterations {
+		delete(c.registrations, unreg.ID)
+	}
+	return nil, nil
+}
+
+// Capabilities returns the capabilities the server advertised on
+// initialization.
+func (c *Client) Capabilities() protocol.ServerCapabilities {
+	return c.capabilities
+}
+
+// Supports reports whether the server advertised support for a request on
+// initialization or registered it since. Before the client is initialized
+// every request is considered supported.
+func (c *Client) Supports(method string) bool {
+	capability, ok := methodCapabilities[method]
+	if !ok {
+		return true
+	}
+
+	c.providersMu.RLock()
+	defer c.providersMu.RUnlock()
+	if c.providers == nil || c.providers[capability] {
+		return true
+	}
+	for _, registered := range c.registrations {
+		if registered == capability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+func TestCapabilityProviders(t *testing.T) {
+	var capabilities protocol.ServerCapabilities
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"definitionProvider": true,
+		"referencesProvider": false,
+		"renameProvider": {"prepareProvider": true},
+		"codeActionProvider": {"codeActionKinds": ["quickfix"]},
+		"documentFormattingProvider": {}
+	}`), &capabilities))
+
+	client := &Client{capabilities: capabilities, providers: capabilityProviders(capabilities)}
+	for method, want := range map[string]bool{
+		"textDocument/definition":  true,
+		"textDocument/references":  false,
+		"textDocument/rename":      true,
+		"textDocument/codeAction":  true,
+		"textDocument/formatting":  true,
+		"textDocument/inlayHint":   false,
+		"textDocument/hover":       false,
+		"textDocument/didOpen":     true,
+		"workspace/executeCommand": true,
+	} {
+		assert.Equal(t, want, client.Supports(method), method)
+	}
+}
+
+func TestSupportsBeforeInitialization(t *testing.T) {
+	client := &Client{}
+	assert.True(t, client.Supports("textDocument/inlayHint"))
+}
+
+func TestCallUnsupportedMethodIsNotSent(t *testing.T) {
+	// The client has no connection to a server, so sending would fail
+	client := &Client{providers: map[string]bool{"definitionProvider": true}}
+	_, err := client.InlayHint(context.Background(), protocol.InlayHintParams{})
+
+	var unsupported *UnsupportedMethodError
+	require.ErrorAs(t, err, &unsupported)
+	assert.Equal(t, "textDocument/inlayHint", unsupported.Method)
+	assert.EqualError(t, err, "server does not support textDocument/inlayHint")
+}
+
+func TestSupportsDynamicRegistration(t *testing.T) {
+	client := &Client{}
+	client.setProviders(map[string]bool{"definitionProvider": true})
+	assert.False(t, client.Supports("textDocument/inlayHint"))
+	assert.False(t, client.Supports("textDocument/semanticTokens/full"))
+
+	require.NoError(t, client.registerProviders(json.RawMessage(`{"registrations": [
+		{"id": "1", "method": "textDocument/inlayHint", "registerOptions": {}},
+		{"id": "2", "method": "textDocument/semanticTokens", "registerOptions": {}},
+		{"id": "3", "method": "workspace/didChangeWatchedFiles", "registerOptions": {}}
+	]}`)))
+	assert.True(t, client.Supports("textDocument/inlayHint"))
+	assert.True(t, client.Supports("textDocument/semanticTokens/full"))
+	assert.True(t, client.Supports("textDocument/semanticTokens/range"))
+
+	_, err := client.unregisterProviders(json.RawMessage(`{"unregisterations": [
+		{"id": "1", "method": "textDocument/inlayHint"}
+	]}`))
+	require.NoError(t, err)
+	assert.False(t, client.Supports("textDocument/inlayHint"))
+	assert.True(t, client.Supports("textDocument/semanticTokens/full"))
+	assert.True(t, client.Supports("textDocument/definition"))
+}
@@ -48,14 +48,26 @@ type Client struct {
 	openFiles   map[string]*OpenFileInfo
 	openFilesMu sync.RWMutex
 
+	// How the server wants changes to open files sent
+	syncKind protocol.TextDocumentSyncKind
+
+	// What the server advertised on initialization, and the providers it
+	// registered since by registration ID
+	capabilities  protocol.ServerCapabilities
+	providers     map[string]bool
+	registrations map[string]string
+	providersMu   sync.RWMutex
+
 	// Server state
 	serverState atomic.Value
 }
 
-func NewClient(ctx context.Context, command string, args ...string) (*Client, error) {
+// NewClient starts an LSP server. Its environment is ours with the KEY=VALUE
+// entries of env added, which override variables of the same name.
+func NewClient(ctx context.Context, command string, env []string, args ...string) (*Client, error) {
 	cmd := exec.CommandContext(ctx, command, args...)
 	// Copy env
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), env...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -209,6 +221,10 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
 
+	c.syncKind = textDocumentSyncKind(result.Capabilities.TextDocumentSync)
+	c.capabilities = result.Capabilities
+	c.setProviders(capabilityProviders(result.Capabilities))
+
 	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
 		return nil, fmt.Errorf("initialized notification failed: %w", err)
 	}
@@ -216,7 +232,13 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	// Register handlers
 	c.RegisterServerRequestHandler("workspace/applyEdit", HandleApplyEdit)
 	c.RegisterServerRequestHandler("workspace/configuration", HandleWorkspaceConfiguration)
-	c.RegisterServerRequestHandler("client/registerCapability", HandleRegisterCapability)
+	c.RegisterServerRequestHandler("client/registerCapability", func(params json.RawMessage) (any, error) {
+		if err := c.registerProviders(params); err != nil {
+			return nil, err
+		}
+		return HandleRegisterCapability(params)
+	})
+	c.RegisterServerRequestHandler("client/unregisterCapability", c.unregisterProviders)
 	c.RegisterNotificationHandler("window/showMessage", HandleServerMessage)
 	c.RegisterNotificationHandler("textDocument/publishDiagnostics",
 		func(params json.RawMessage) { HandleDiagnostics(c, params) })
@@ -270,6 +292,19 @@ const (
 	StateError
 )
 
+func (s ServerState) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
 // GetServerState returns the current state of the LSP server
 func (c *Client) GetServerState() ServerState {
 	if val := c.serverState.Load(); val != nil {
@@ -284,17 +319,14 @@ func (c *Client) SetServerState(state ServerState) {
 }
 
 // WaitForServerReady waits for the server to be ready by polling the server
-// with a simple request until it responds successfully or times out
+// with a simple request until it responds successfully or the context is done.
+// Callers bound the wait with a deadline on ctx.
 func (c *Client) WaitForServerReady(ctx context.Context) error {
 	cnf := config.Get()
 
 	// Set initial state
 	c.SetServerState(StateStarting)
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
 	// Try to ping the server with a simple request
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
@@ -583,6 +615,7 @@ func (c *Client) pingWithServerCapabilities(ctx context.Context) error {
 type OpenFileInfo struct {
 	Version int32
 	URI     protocol.DocumentUri
+	text    string // The content the server has, to send only what changed
 }
 
 func (c *Client) OpenFile(ctx context.Context, filepath string) error {
@@ -618,6 +651,7 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 	c.openFiles[uri] = &OpenFileInfo{
 		Version: 1,
 		URI:     protocol.DocumentUri(uri),
+		text:    string(content),
 	}
 	c.openFilesMu.Unlock()
 
@@ -639,8 +673,19 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 		return fmt.Errorf("cannot notify change for unopened file: %s", filepath)
 	}
 
+	text := string(content)
+
+	// Servers that sync incrementally get only the changed range
+	var change protocol.TextDocumentContentChangeEvent
+	if c.syncKind == protocol.Incremental {
+		change.Value = incrementalChange(fileInfo.text, text)
+	} else {
+		change.Value = protocol.TextDocumentContentChangeWholeDocument{Text: text}
+	}
+
 	// Increment version
 	fileInfo.Version++
+	fileInfo.text = text
 	version := fileInfo.Version
 	c.openFilesMu.Unlock()
 
@@ -651,13 +696,7 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 			},
 			Version: version,
 		},
-		ContentChanges: []protocol.TextDocumentContentChangeEvent{
-			{
-				Value: protocol.TextDocumentContentChangeWholeDocument{
-					Text: string(content),
-				},
-			},
-		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{change},
 	}
 
 	return c.Notify(ctx, "textDocument/didChange", params)
@@ -702,6 +741,13 @@ func (c *Client) IsFileOpen(filepath string) bool {
 	return exists
 }
 
+// OpenFileCount returns the number of files currently opened in the LSP server
+func (c *Client) OpenFileCount() int {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	return len(c.openFiles)
+}
+
 // CloseAllFiles closes all currently open files
 func (c *Client) CloseAllFiles(ctx context.Context) {
 	cnf := config.Get()
@@ -0,0 +1,33 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+// TestMain loads a configuration, which the message handler of every client
+// reads.
+func TestMain(m *testing.M) {
+	if _, err := config.Load(os.TempDir(), false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestNewClientPassesEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	client, err := NewClient(context.Background(), "sh", []string{"GOFLAGS=-tags=integration", "OUT=" + out}, "-c", `printf %s "$GOFLAGS" > "$OUT"`)
+	require.NoError(t, err)
+	assert.Contains(t, client.Cmd.Env, "GOFLAGS=-tags=integration")
+	require.NoError(t, client.Cmd.Wait())
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "-tags=integration", string(got))
+}
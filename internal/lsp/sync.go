@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"unicode/utf8"
+
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+// textDocumentSyncKind returns how a server wants changes to open documents
+// sent, from the textDocumentSync field of its capabilities. It is either a
+// TextDocumentSyncKind number or a TextDocumentSyncOptions object.
+func textDocumentSyncKind(capability any) protocol.TextDocumentSyncKind {
+	switch sync := capability.(type) {
+	case float64:
+		return protocol.TextDocumentSyncKind(sync)
+	case map[string]any:
+		if change, ok := sync["change"].(float64); ok {
+			return protocol.TextDocumentSyncKind(change)
+		}
+	}
+	return protocol.Full
+}
+
+// incrementalChange returns the change that turns oldText into newText: the
+// range between their common prefix and suffix, replaced by the part of
+// newText in between. Editing a large document usually changes a small part
+// of it, so this is much shorter than the whole document.
+func incrementalChange(oldText, newText string) protocol.TextDocumentContentChangePartial {
+	prefix := 0
+	for prefix < len(oldText) && prefix < len(newText) && oldText[prefix] == newText[prefix] {
+		prefix++
+	}
+	// Don't split a character or a \r\n line break
+	for prefix > 0 && prefix < len(oldText) && !utf8.RuneStart(oldText[prefix]) {
+		prefix--
+	}
+	if prefix > 0 && oldText[prefix-1] == '\r' {
+		prefix--
+	}
+
+	suffix := 0
+	for suffix < len(oldText)-prefix && suffix < len(newText)-prefix &&
+		oldText[len(oldText)-1-suffix] == newText[len(newText)-1-suffix] {
+		suffix++
+	}
+	oldEnd, newEnd := len(oldText)-suffix, len(newText)-suffix
+	for oldEnd < len(oldText) && (!utf8.RuneStart(oldText[oldEnd]) || (oldEnd > 0 && oldText[oldEnd] == '\n' && oldText[oldEnd-1] == '\r')) {
+		oldEnd++
+		newEnd++
+	}
+
+	start := positionAt(oldText, prefix)
+	end := positionAt(oldText, oldEnd)
+	return protocol.TextDocumentContentChangePartial{
+		Range: &protocol.Range{Start: start, End: end},
+		Text:  newText[prefix:newEnd],
+	}
+}
+
+// positionAt returns the LSP position of a byte offset of text: its line,
+// counting \n, \r\n and \r as line breaks, and its UTF-16 column.
+func positionAt(text string, offset int) protocol.Position {
+	var line, character uint32
+	for i, r := range text[:offset] {
+		switch {
+		case r == '\r' && i+1 < len(text) && text[i+1] == '\n':
+			// The \n that follows ends the line
+		case r == '\n' || r == '\r':
+			line++
+			character = 0
+		case r >= 0x10000:
+			character += 2
+		default:
+			character++
+		}
+	}
+	return protocol.Position{Line: line, Character: character}
+}
@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
+)
+
+func TestTextDocumentSyncKind(t *testing.T) {
+	for name, tt := range map[string]struct {
+		capability string
+		want       protocol.TextDocumentSyncKind
+	}{
+		"number":          {`2`, protocol.Incremental},
+		"options":         {`{"openClose": true, "change": 2}`, protocol.Incremental},
+		"full options":    {`{"change": 1}`, protocol.Full},
+		"missing":         {`null`, protocol.Full},
+		"options no kind": {`{"openClose": true}`, protocol.Full},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var capability any
+			assert.NoError(t, json.Unmarshal([]byte(tt.capability), &capability))
+			assert.Equal(t, tt.want, textDocumentSyncKind(capability))
+		})
+	}
+}
+
+func TestIncrementalChange(t *testing.T) {
+	pos := func(line, character uint32) protocol.Position {
+		return protocol.Position{Line: line, Character: character}
+	}
+	tests := []struct {
+		name       string
+		old, new   string
+		start, end protocol.Position
+		text       string
+	}{
+		{"replace word", "one\ntwo\nthree\n", "one\nTWO\nthree\n", pos(1, 0), pos(1, 3), "TWO"},
+		{"insert line", "one\nthree\n", "one\nfour\nthree\n", pos(1, 0), pos(1, 0), "four\n"},
+		{"delete line", "one\nfour\nthree\n", "one\nthree\n", pos(1, 0), pos(2, 0), ""},
+		{"append", "one\n", "one\ntwo\n", pos(1, 0), pos(1, 0), "two\n"},
+		{"unchanged", "one\n", "one\n", pos(1, 0), pos(1, 0), ""},
+		{"utf-16 columns", "é😀a\n", "é😀b\n", pos(0, 3), pos(0, 4), "b"},
+		{"shared lead byte", "ä\n", "ö\n", pos(0, 0), pos(0, 1), "ö"},
+		{"crlf", "one\r\ntwo\r\n", "one\ntwo\r\n", pos(0, 3), pos(1, 0), "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := incrementalChange(tt.old, tt.new)
+			assert.Equal(t, tt.start, change.Range.Start)
+			assert.Equal(t, tt.end, change.Range.End)
+			assert.Equal(t, tt.text, change.Text)
+		})
+	}
+}
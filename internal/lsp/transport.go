@@ -188,6 +188,10 @@ func (c *Client) handleMessages() {
 
 // Call makes a request and waits for the response
 func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	if !c.Supports(method) {
+		return &UnsupportedMethodError{Method: method}
+	}
+
 	cnf := config.Get()
 	id := c.nextID.Add(1)
 
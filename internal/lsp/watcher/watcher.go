@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -791,6 +792,17 @@ var (
 	maxFileSize int64 = 5 * 1024 * 1024
 )
 
+// ExcludedDirNames returns the sorted names of the directories that are never
+// watched, for other walks of the workspace to skip as well
+func ExcludedDirNames() []string {
+	names := make([]string, 0, len(excludedDirNames))
+	for name := range excludedDirNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // shouldExcludeDir returns true if the directory should be excluded from watching/opening
 func shouldExcludeDir(dirPath string) bool {
 	dirName := filepath.Base(dirPath)
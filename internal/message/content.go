@@ -2,7 +2,9 @@ package message
 
 import (
 	"encoding/base64"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/zhenbah/cryoncode/internal/llm/models"
@@ -81,6 +83,21 @@ func (bc BinaryContent) String(provider models.ModelProvider) string {
 
 func (BinaryContent) isPart() {}
 
+// FileContent is the text of a file attached to a user message, kept apart
+// from what the user typed.
+type FileContent struct {
+	Path string `json:"path"`
+	Text string `json:"text"`
+}
+
+// String wraps the text in a file tag naming its path, as it is sent to the
+// model.
+func (fc FileContent) String() string {
+	return fmt.Sprintf("<file path=%q>\n%s\n</file>", fc.Path, strings.TrimSuffix(fc.Text, "\n"))
+}
+
+func (FileContent) isPart() {}
+
 type ToolCall struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -101,6 +118,14 @@ type ToolResult struct {
 
 func (ToolResult) isPart() {}
 
+// Citations lists the sources an answer is based on, such as the pages found
+// by xAI Live Search.
+type Citations struct {
+	URLs []string `json:"urls"`
+}
+
+func (Citations) isPart() {}
+
 type Finish struct {
 	Reason FinishReason `json:"reason"`
 	Time   int64        `json:"time"`
@@ -156,6 +181,16 @@ func (m *Message) BinaryContent() []BinaryContent {
 	return binaryContents
 }
 
+func (m *Message) FileContent() []FileContent {
+	fileContents := make([]FileContent, 0)
+	for _, part := range m.Parts {
+		if c, ok := part.(FileContent); ok {
+			fileContents = append(fileContents, c)
+		}
+	}
+	return fileContents
+}
+
 func (m *Message) ToolCalls() []ToolCall {
 	toolCalls := make([]ToolCall, 0)
 	for _, part := range m.Parts {
@@ -176,6 +211,15 @@ func (m *Message) ToolResults() []ToolResult {
 	return toolResults
 }
 
+func (m *Message) Citations() []string {
+	for _, part := range m.Parts {
+		if c, ok := part.(Citations); ok {
+			return c.URLs
+		}
+	}
+	return nil
+}
+
 func (m *Message) IsFinished() bool {
 	for _, part := range m.Parts {
 		if _, ok := part.(Finish); ok {
@@ -307,6 +351,21 @@ func (m *Message) SetToolResults(tr []ToolResult) {
 	}
 }
 
+// SetCitations replaces the citations of the message; none removes them.
+func (m *Message) SetCitations(urls []string) {
+	parts := make([]ContentPart, 0, len(m.Parts)+1)
+	for _, part := range m.Parts {
+		if _, ok := part.(Citations); ok {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	m.Parts = parts
+	if len(urls) > 0 {
+		m.Parts = append(m.Parts, Citations{URLs: urls})
+	}
+}
+
 func (m *Message) AddFinish(reason FinishReason) {
 	// remove any existing finish part
 	for i, part := range m.Parts {
@@ -0,0 +1,47 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCitations(t *testing.T) {
+	msg := Message{Role: Assistant, Parts: []ContentPart{TextContent{Text: "Grok 3 was released in February [1]."}}}
+	assert.Empty(t, msg.Citations())
+
+	msg.SetCitations([]string{"https://x.ai/news/grok-3"})
+	msg.SetCitations([]string{"https://x.ai/news/grok-3", "https://example.com/grok"})
+	msg.AddFinish(FinishReasonEndTurn)
+
+	data, err := MarshalParts(msg.Parts)
+	require.NoError(t, err)
+	parts, err := UnmarshalParts(data)
+	require.NoError(t, err)
+
+	restored := Message{Parts: parts}
+	assert.Equal(t, []string{"https://x.ai/news/grok-3", "https://example.com/grok"}, restored.Citations())
+	assert.True(t, restored.IsFinished())
+
+	restored.SetCitations(nil)
+	assert.Empty(t, restored.Citations())
+	assert.Len(t, restored.Parts, 2)
+}
+
+func TestFileContent(t *testing.T) {
+	msg := Message{Role: User, Parts: []ContentPart{
+		TextContent{Text: "look at @main.go"},
+		FileContent{Path: "main.go", Text: "package main\n"},
+	}}
+
+	data, err := MarshalParts(msg.Parts)
+	require.NoError(t, err)
+	parts, err := UnmarshalParts(data)
+	require.NoError(t, err)
+
+	restored := Message{Parts: parts}
+	assert.Equal(t, "look at @main.go", restored.Content().String())
+	require.Len(t, restored.FileContent(), 1)
+	assert.Equal(t, "<file path=\"main.go\">\npackage main\n</file>", restored.FileContent()[0].String())
+}
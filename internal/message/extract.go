@@ -0,0 +1,71 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNoTextExtractor is returned by ExtractText for attachments of a type
+// without a registered extractor.
+var ErrNoTextExtractor = errors.New("no text extractor for this file type")
+
+// TextExtractor returns the text of a file, for models that can't read the
+// file itself.
+type TextExtractor func(content []byte) (string, error)
+
+// textExtractors maps media types, without parameters, to their extractor.
+var textExtractors = map[string]TextExtractor{
+	"application/pdf": extractPDFText,
+	"text/plain":      extractPlainText,
+	"text/markdown":   extractPlainText,
+}
+
+// RegisterTextExtractor adds or replaces the extractor for a media type, such
+// as "application/vnd.oasis.opendocument.text".
+func RegisterTextExtractor(mediaType string, extract TextExtractor) {
+	textExtractors[strings.ToLower(mediaType)] = extract
+}
+
+// mediaType returns the media type of the attachment without parameters such
+// as the charset.
+func (a Attachment) mediaType() string {
+	if mediaType, _, err := mime.ParseMediaType(a.MimeType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(a.MimeType)
+}
+
+// IsImage reports whether the attachment is an image.
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.mediaType(), "image/")
+}
+
+// IsPDF reports whether the attachment is a PDF.
+func (a Attachment) IsPDF() bool {
+	return a.mediaType() == "application/pdf"
+}
+
+// HasTextExtractor reports whether the text of the attachment can be extracted.
+func (a Attachment) HasTextExtractor() bool {
+	_, ok := textExtractors[a.mediaType()]
+	return ok
+}
+
+// ExtractText returns the text of the attachment.
+func (a Attachment) ExtractText() (string, error) {
+	extract, ok := textExtractors[a.mediaType()]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNoTextExtractor, a.MimeType)
+	}
+	return extract(a.Content)
+}
+
+func extractPlainText(content []byte) (string, error) {
+	if !utf8.Valid(content) {
+		return "", errors.New("file is not valid UTF-8 text")
+	}
+	return string(content), nil
+}
@@ -0,0 +1,75 @@
+package message
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPDF builds a PDF with one uncompressed and one compressed content stream.
+func testPDF(t *testing.T) []byte {
+	t.Helper()
+	first := "BT /F1 12 Tf 72 712 Td (Quarterly report) Tj 0 -14 Td [(Rev) 30 (enue) -300 (grew)] TJ ET"
+	second := `BT 1 0 0 1 72 600 Tm (Second page \(draft\)) Tj T* <FEFF00E9007400E9> Tj ET`
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write([]byte(second))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(first), first)
+	fmt.Fprintf(&pdf, "5 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("6 0 obj\n<< /Subtype /Image /Length 12 >>\nstream\n(not text) Tj\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractText(t *testing.T) {
+	pdf := Attachment{FileName: "report.pdf", MimeType: "application/pdf", Content: testPDF(t)}
+	require.True(t, pdf.HasTextExtractor())
+	assert.False(t, pdf.IsImage())
+	text, err := pdf.ExtractText()
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly report\nRevenue grew\nSecond page (draft)\nété", text)
+
+	notes := Attachment{FileName: "notes.md", MimeType: "text/plain; charset=utf-8", Content: []byte("# Notes\n")}
+	text, err = notes.ExtractText()
+	require.NoError(t, err)
+	assert.Equal(t, "# Notes\n", text)
+
+	image := Attachment{FileName: "shot.png", MimeType: "image/png", Content: []byte("png")}
+	assert.True(t, image.IsImage())
+	_, err = image.ExtractText()
+	assert.ErrorIs(t, err, ErrNoTextExtractor)
+}
+
+func TestExtractTextErrors(t *testing.T) {
+	_, err := Attachment{MimeType: "application/pdf", Content: []byte("not a pdf")}.ExtractText()
+	assert.Error(t, err)
+
+	_, err = Attachment{MimeType: "application/pdf", Content: []byte("%PDF-1.4\n%%EOF\n")}.ExtractText()
+	assert.ErrorContains(t, err, "no text found")
+
+	_, err = Attachment{MimeType: "text/plain", Content: []byte{0xff, 0xfe}}.ExtractText()
+	assert.Error(t, err)
+}
+
+func TestRegisterTextExtractor(t *testing.T) {
+	const mediaType = "application/x-test"
+	t.Cleanup(func() { delete(textExtractors, mediaType) })
+
+	RegisterTextExtractor(mediaType, func(content []byte) (string, error) {
+		return string(bytes.ToUpper(content)), nil
+	})
+	text, err := Attachment{MimeType: mediaType, Content: []byte("shout")}.ExtractText()
+	require.NoError(t, err)
+	assert.Equal(t, "SHOUT", text)
+}
@@ -23,6 +23,7 @@ type Service interface {
 	pubsub.Suscriber[Message]
 	Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error)
 	Update(ctx context.Context, message Message) error
+	Import(ctx context.Context, message Message) (Message, error)
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
 	Delete(ctx context.Context, id string) error
@@ -121,6 +122,39 @@ func (s *service) Update(ctx context.Context, message Message) error {
 	return nil
 }
 
+// Import stores a message exported from another data directory as it was,
+// keeping its ID and timestamps.
+func (s *service) Import(ctx context.Context, message Message) (Message, error) {
+	parts, err := marshallParts(message.Parts)
+	if err != nil {
+		return Message{}, err
+	}
+	finishedAt := sql.NullInt64{}
+	if f := message.FinishPart(); f != nil {
+		finishedAt.Int64 = f.Time
+		finishedAt.Valid = true
+	}
+	dbMessage, err := s.q.ImportMessage(ctx, db.ImportMessageParams{
+		ID:         message.ID,
+		SessionID:  message.SessionID,
+		Role:       string(message.Role),
+		Parts:      string(parts),
+		Model:      sql.NullString{String: string(message.Model), Valid: message.Model != ""},
+		CreatedAt:  message.CreatedAt,
+		UpdatedAt:  message.UpdatedAt,
+		FinishedAt: finishedAt,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	message, err = s.fromDBItem(dbMessage)
+	if err != nil {
+		return Message{}, err
+	}
+	s.Publish(pubsub.CreatedEvent, message)
+	return message, nil
+}
+
 func (s *service) Get(ctx context.Context, id string) (Message, error) {
 	dbMessage, err := s.q.GetMessage(ctx, id)
 	if err != nil {
@@ -160,6 +194,16 @@ func (s *service) fromDBItem(item db.Message) (Message, error) {
 	}, nil
 }
 
+// MarshalParts encodes message parts the way they are stored.
+func MarshalParts(parts []ContentPart) ([]byte, error) {
+	return marshallParts(parts)
+}
+
+// UnmarshalParts decodes message parts encoded with MarshalParts.
+func UnmarshalParts(data []byte) ([]ContentPart, error) {
+	return unmarshallParts(data)
+}
+
 type partType string
 
 const (
@@ -167,8 +211,10 @@ const (
 	textType       partType = "text"
 	imageURLType   partType = "image_url"
 	binaryType     partType = "binary"
+	fileType       partType = "file"
 	toolCallType   partType = "tool_call"
 	toolResultType partType = "tool_result"
+	citationsType  partType = "citations"
 	finishType     partType = "finish"
 )
 
@@ -192,10 +238,14 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 			typ = imageURLType
 		case BinaryContent:
 			typ = binaryType
+		case FileContent:
+			typ = fileType
 		case ToolCall:
 			typ = toolCallType
 		case ToolResult:
 			typ = toolResultType
+		case Citations:
+			typ = citationsType
 		case Finish:
 			typ = finishType
 		default:
@@ -253,6 +303,12 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case fileType:
+			part := FileContent{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		case toolCallType:
 			part := ToolCall{}
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
@@ -265,6 +321,12 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case citationsType:
+			part := Citations{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		case finishType:
 			part := Finish{}
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
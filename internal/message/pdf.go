@@ -0,0 +1,367 @@
+package message
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFStreamSize caps the decompressed size of a single PDF stream.
+const maxPDFStreamSize = 16 << 20
+
+var (
+	// pdfStreamLength matches a direct stream length, but not a reference
+	// to a length object.
+	pdfStreamLength = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	// pdfFilter matches the filters of a stream.
+	pdfFilter = regexp.MustCompile(`/Filter\s*(\[[^\]]*\]|/\w+)`)
+	// pdfNonContent matches the dictionaries of streams that hold images,
+	// fonts or metadata instead of page content.
+	pdfNonContent = regexp.MustCompile(`/Type\s*/(XRef|ObjStm|Metadata)|/Subtype\s*/(Image|XML|Type1C|CIDFontType0C|OpenType)|/Length[123]\b`)
+)
+
+// extractPDFText returns the text drawn by the content streams of a PDF.
+// It reads uncompressed and Flate-compressed streams and decodes strings as
+// PDFDocEncoding or UTF-16. Text in fonts with a custom encoding comes out
+// garbled, and scanned pages have no text at all.
+func extractPDFText(content []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte("%PDF-")) {
+		return "", errors.New("file is not a PDF")
+	}
+
+	var w pdfTextWriter
+	rest := content
+	for {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		// Skip the endstream keyword of the previous stream
+		if start >= 3 && string(rest[start-3:start]) == "end" {
+			rest = rest[start+len("stream"):]
+			continue
+		}
+		dict := rest[:start]
+		if obj := bytes.LastIndex(dict, []byte("obj")); obj >= 0 {
+			dict = dict[obj:]
+		}
+		data := rest[start+len("stream"):]
+		data = bytes.TrimPrefix(data, []byte("\r"))
+		data = bytes.TrimPrefix(data, []byte("\n"))
+
+		end := -1
+		if m := pdfStreamLength.FindSubmatch(dict); m != nil && m[2] == nil {
+			if n, err := strconv.Atoi(string(m[1])); err == nil && n <= len(data) {
+				end = n
+			}
+		}
+		if end < 0 {
+			end = bytes.Index(data, []byte("endstream"))
+			if end < 0 {
+				break
+			}
+		}
+		stream := data[:end]
+		rest = data[end:]
+
+		if pdfNonContent.Match(dict) {
+			continue
+		}
+		if decoded, ok := decodePDFStream(dict, stream); ok {
+			w.content(decoded)
+		}
+	}
+
+	result := w.text()
+	if result == "" {
+		return "", errors.New("no text found in the PDF, it may be scanned or use an unsupported encoding")
+	}
+	return result, nil
+}
+
+// decodePDFStream undoes the filter of a stream, if it is one that can be
+// decoded.
+func decodePDFStream(dict, stream []byte) ([]byte, bool) {
+	m := pdfFilter.FindSubmatch(dict)
+	if m == nil {
+		return stream, true
+	}
+	filters := strings.Fields(strings.Trim(string(m[1]), "[]"))
+	if len(filters) != 1 || filters[0] != "/FlateDecode" {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, false
+	}
+	// Keep what was decoded before an error, as streams are often cut short
+	decoded, _ := io.ReadAll(io.LimitReader(r, maxPDFStreamSize))
+	return decoded, len(decoded) > 0
+}
+
+// pdfTextWriter collects the text of content streams.
+type pdfTextWriter struct {
+	strings.Builder
+	lastY float64
+}
+
+// newline ends the current line, if any.
+func (w *pdfTextWriter) newline() {
+	if s := w.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		w.WriteByte('\n')
+	}
+}
+
+// space separates words, unless they are already separated.
+func (w *pdfTextWriter) space() {
+	if s := w.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.WriteByte(' ')
+	}
+}
+
+// content writes the text shown by the operators of a content stream.
+func (w *pdfTextWriter) content(data []byte) {
+	type operand struct {
+		text     string
+		number   float64
+		isString bool
+	}
+	var operands []operand
+	numbers := func() []float64 {
+		var ns []float64
+		for _, o := range operands {
+			if !o.isString {
+				ns = append(ns, o.number)
+			}
+		}
+		return ns
+	}
+	show := func(kerning bool) {
+		for _, o := range operands {
+			switch {
+			case o.isString:
+				w.WriteString(o.text)
+			case kerning && o.number < -200:
+				// A large negative adjustment in a TJ array is a word gap
+				w.space()
+			}
+		}
+	}
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case isPDFWhitespace(c):
+			i++
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := readPDFLiteral(data[i:])
+			operands = append(operands, operand{text: decodePDFString(s), isString: true})
+			i += n
+		case c == '<' && i+1 < len(data) && data[i+1] == '<', c == '>' && i+1 < len(data) && data[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return
+			}
+			operands = append(operands, operand{text: decodePDFString(decodePDFHex(data[i+1 : i+end])), isString: true})
+			i += end + 1
+		case c == '[' || c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+			i++
+		case c == '/':
+			i++
+			for i < len(data) && !isPDFWhitespace(data[i]) && !isPDFDelimiter(data[i]) {
+				i++
+			}
+			operands = append(operands, operand{})
+		default:
+			start := i
+			for i < len(data) && !isPDFWhitespace(data[i]) && !isPDFDelimiter(data[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			word := string(data[start:i])
+			if n, err := strconv.ParseFloat(word, 64); err == nil {
+				operands = append(operands, operand{number: n})
+				continue
+			}
+
+			switch word {
+			case "Tj":
+				show(false)
+			case "TJ":
+				show(true)
+			case "'", `"`:
+				w.newline()
+				if len(operands) > 0 {
+					show(false)
+				}
+			case "T*", "ET":
+				w.newline()
+			case "Td", "TD":
+				if ns := numbers(); len(ns) == 2 && ns[1] != 0 {
+					w.newline()
+				} else if len(ns) == 2 && ns[0] > 0 {
+					w.space()
+				}
+			case "Tm":
+				if ns := numbers(); len(ns) == 6 {
+					if ns[5] != w.lastY {
+						w.newline()
+					}
+					w.lastY = ns[5]
+				}
+			case "ID":
+				// Skip the data of an inline image
+				end := bytes.Index(data[i:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				i += end + 2
+			}
+			operands = operands[:0]
+		}
+	}
+}
+
+// text returns the collected text with surrounding whitespace trimmed from
+// each line and blank lines removed.
+func (w *pdfTextWriter) text() string {
+	var lines []string
+	for _, line := range strings.Split(w.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// readPDFLiteral reads a literal string starting at its opening parenthesis
+// and returns its bytes and the number of bytes consumed.
+func readPDFLiteral(data []byte) ([]byte, int) {
+	var s []byte
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				s = append(s, c)
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, i + 1
+			}
+			s = append(s, c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				return s, i
+			}
+			switch e := data[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b':
+				s = append(s, '\b')
+			case 'f':
+				s = append(s, '\f')
+			case '\r':
+				// A backslash at the end of a line continues the string
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n, j := 0, i
+					for ; j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7'; j++ {
+						n = n*8 + int(data[j]-'0')
+					}
+					s = append(s, byte(n))
+					i = j - 1
+				} else {
+					s = append(s, e)
+				}
+			}
+		default:
+			s = append(s, c)
+		}
+	}
+	return s, len(data)
+}
+
+// decodePDFHex decodes the digits of a hex string, ignoring whitespace. A
+// missing final digit is taken as zero.
+func decodePDFHex(digits []byte) []byte {
+	var s []byte
+	var b byte
+	half := false
+	for _, c := range digits {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			s = append(s, b<<4|v)
+		} else {
+			b = v
+		}
+		half = !half
+	}
+	if half {
+		s = append(s, b<<4)
+	}
+	return s
+}
+
+// decodePDFString decodes a string as UTF-16 if it starts with a byte order
+// mark, or else as PDFDocEncoding, which matches Latin-1 for printable text.
+func decodePDFString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var b strings.Builder
+	for _, c := range s {
+		if c >= 0x20 || c == '\t' || c == '\n' {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
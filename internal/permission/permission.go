@@ -4,15 +4,58 @@ import (
 	"errors"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/google/uuid"
 	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/logging"
 	"github.com/zhenbah/cryoncode/internal/pubsub"
 )
 
 var ErrorPermissionDenied = errors.New("permission denied")
 
+// AutoApprovedEvent is published for requests that a toolPermissions policy
+// allowed without a prompt, so the TUI can note them.
+const AutoApprovedEvent pubsub.EventType = "auto_approved"
+
+// Tool categories that toolPermissions policies can be set for
+const (
+	CategoryRead    = "read"
+	CategoryWrite   = "write"
+	CategoryExecute = "execute"
+)
+
+// Actions of permission requests
+const (
+	ActionRead    = "read"
+	ActionFetch   = "fetch"
+	ActionWrite   = "write"
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionCommit  = "commit"
+	ActionExecute = "execute"
+)
+
+// actions holds the risk and category of every known action, so that the
+// two are decided in one place.
+var actions = map[string]struct {
+	risk     config.RiskLevel
+	category string
+}{
+	ActionRead:    {config.RiskLow, CategoryRead},
+	ActionFetch:   {config.RiskLow, CategoryRead},
+	ActionWrite:   {config.RiskMedium, CategoryWrite},
+	ActionCreate:  {config.RiskMedium, CategoryWrite},
+	ActionUpdate:  {config.RiskMedium, CategoryWrite},
+	ActionDelete:  {config.RiskHigh, CategoryWrite},
+	ActionCommit:  {config.RiskHigh, CategoryWrite},
+	ActionExecute: {config.RiskHigh, CategoryExecute},
+}
+
 type CreatePermissionRequest struct {
 	SessionID   string `json:"session_id"`
 	ToolName    string `json:"tool_name"`
@@ -20,16 +63,26 @@ type CreatePermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// ResourcePath is the file the request changes, if it changes one, for
+	// matching grants scoped to a path pattern
+	ResourcePath string `json:"resource_path,omitempty"`
+	// ToolCallID identifies the tool call making the request, so that a call
+	// that was granted is not asked about again when it is retried
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 type PermissionRequest struct {
-	ID          string `json:"id"`
-	SessionID   string `json:"session_id"`
-	ToolName    string `json:"tool_name"`
-	Description string `json:"description"`
-	Action      string `json:"action"`
-	Params      any    `json:"params"`
-	Path        string `json:"path"`
+	ID           string `json:"id"`
+	SessionID    string `json:"session_id"`
+	ToolName     string `json:"tool_name"`
+	Description  string `json:"description"`
+	Action       string `json:"action"`
+	Params       any    `json:"params"`
+	Path         string `json:"path"`
+	ResourcePath string `json:"resource_path,omitempty"`
+	// PathPattern scopes a session grant to the files it matches, instead of
+	// the request's tool, action and directory; see MatchPathPattern
+	PathPattern string `json:"path_pattern,omitempty"`
 }
 
 type Service interface {
@@ -39,6 +92,7 @@ type Service interface {
 	Deny(permission PermissionRequest)
 	Request(opts CreatePermissionRequest) bool
 	AutoApproveSession(sessionID string)
+	SetUnattended(decision config.PermissionPolicy, timeout time.Duration)
 }
 
 type permissionService struct {
@@ -47,8 +101,18 @@ type permissionService struct {
 	sessionPermissions  []PermissionRequest
 	pendingRequests     sync.Map
 	autoApproveSessions []string
+	grantedCalls        sync.Map
+
+	// unattended is set when no TUI answers requests; those left unanswered
+	// for unattendedTimeout are allowed or denied by unattendedAllow
+	unattended        bool
+	unattendedAllow   bool
+	unattendedTimeout time.Duration
 }
 
+// GrantPersistant grants a request and the ones like it for the rest of the
+// session: those of the same tool, action and directory, or with a
+// PathPattern, those of the same category whose resource path matches it.
 func (s *permissionService) GrantPersistant(permission PermissionRequest) {
 	respCh, ok := s.pendingRequests.Load(permission.ID)
 	if ok {
@@ -71,10 +135,89 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 	}
 }
 
+// Risk scores a permission request by its action. Reads are low risk, file
+// writes medium, and shell commands, deletions, commits and anything unknown
+// high.
+func Risk(opts CreatePermissionRequest) config.RiskLevel {
+	if a, ok := actions[opts.Action]; ok {
+		return a.risk
+	}
+	return config.RiskHigh
+}
+
+// Category groups a permission request by its action: fetches are reads,
+// file changes and commits writes, and shell commands and anything unknown
+// execution.
+func Category(opts CreatePermissionRequest) string {
+	if a, ok := actions[opts.Action]; ok {
+		return a.category
+	}
+	return CategoryExecute
+}
+
+// Policy returns the toolPermissions policy for a request: the one set for
+// its tool, else the one set for its category, else "" when neither is set.
+// Names are matched case-insensitively, since viper lowercases map keys.
+func Policy(opts CreatePermissionRequest) config.PermissionPolicy {
+	cfg := config.Get()
+	if cfg == nil {
+		return ""
+	}
+	if policy, ok := cfg.ToolPermissions[strings.ToLower(opts.ToolName)]; ok {
+		return policy
+	}
+	return cfg.ToolPermissions[Category(opts)]
+}
+
+// Request asks for a permission and reports whether it was granted. A request
+// from a tool call that was already granted, when the call is retried, is
+// granted again without asking.
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
+	if opts.ToolCallID == "" {
+		return s.request(opts)
+	}
+	call := opts.SessionID + "/" + opts.ToolCallID
+	if _, ok := s.grantedCalls.Load(call); ok {
+		return true
+	}
+	granted := s.request(opts)
+	if granted {
+		s.grantedCalls.Store(call, true)
+	}
+	return granted
+}
+
+func (s *permissionService) request(opts CreatePermissionRequest) bool {
+	policy := Policy(opts)
+	if policy == config.PermissionDeny {
+		logging.Debug("Denying tool call by policy", "tool", opts.ToolName, "action", opts.Action)
+		return false
+	}
 	if slices.Contains(s.autoApproveSessions, opts.SessionID) {
 		return true
 	}
+	if policy == config.PermissionAllow {
+		logging.Debug("Allowing tool call by policy", "tool", opts.ToolName, "action", opts.Action)
+		s.Publish(AutoApprovedEvent, PermissionRequest{
+			ID:          uuid.New().String(),
+			Path:        opts.Path,
+			SessionID:   opts.SessionID,
+			ToolName:    opts.ToolName,
+			Description: opts.Description,
+			Action:      opts.Action,
+			Params:      opts.Params,
+
+			ResourcePath: opts.ResourcePath,
+		})
+		return true
+	}
+	// An explicit ask prompts even below the auto-approval threshold
+	if threshold := config.Get().AutoApproveBelow; threshold != "" && policy == "" {
+		if risk := Risk(opts); risk.Rank() < threshold.Rank() {
+			logging.Debug("Auto-approving tool call", "tool", opts.ToolName, "action", opts.Action, "risk", risk, "threshold", threshold)
+			return true
+		}
+	}
 	dir := filepath.Dir(opts.Path)
 	if dir == "." {
 		dir = config.WorkingDirectory()
@@ -87,10 +230,21 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		Description: opts.Description,
 		Action:      opts.Action,
 		Params:      opts.Params,
+
+		ResourcePath: opts.ResourcePath,
 	}
 
 	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
+		if p.SessionID != permission.SessionID {
+			continue
+		}
+		if p.PathPattern != "" {
+			if permission.ResourcePath != "" && Category(CreatePermissionRequest{Action: p.Action}) == Category(opts) && MatchPathPattern(p.PathPattern, permission.ResourcePath) {
+				return true
+			}
+			continue
+		}
+		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.Path == permission.Path {
 			return true
 		}
 	}
@@ -102,15 +256,79 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 
 	s.Publish(pubsub.CreatedEvent, permission)
 
-	// Wait for the response with a timeout
-	resp := <-respCh
-	return resp
+	if !s.unattended {
+		return <-respCh
+	}
+	select {
+	case resp := <-respCh:
+		return resp
+	case <-time.After(s.unattendedTimeout):
+		logging.Info("Permission request unanswered, deciding by the headless policy", "tool", opts.ToolName, "action", opts.Action, "allow", s.unattendedAllow)
+		return s.unattendedAllow
+	}
+}
+
+// MatchPathPattern reports whether path is matched by a pattern of a
+// path-scoped grant. Relative patterns are relative to the working directory.
+// A pattern without wildcards matches that file or everything under that
+// directory; otherwise it is a glob where ** matches any number of
+// directories.
+func MatchPathPattern(pattern, path string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || path == "" {
+		return false
+	}
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	if filepath.IsAbs(path) {
+		path = filepath.Clean(path)
+	} else {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+	if !filepath.IsAbs(pattern) {
+		rel, err := filepath.Rel(config.WorkingDirectory(), path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		path = rel
+	}
+	path = filepath.ToSlash(path)
+	if !strings.ContainsAny(pattern, "*?[{") {
+		return pattern == "." || path == pattern || strings.HasPrefix(path, strings.TrimSuffix(pattern, "/")+"/")
+	}
+	matched, _ := doublestar.Match(pattern, path)
+	return matched
+}
+
+// DefaultPathPattern suggests the pattern for a path-scoped grant of a
+// request for path: its directory, relative to the working directory when it
+// is inside it.
+func DefaultPathPattern(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+	dir := filepath.Dir(path)
+	if rel, err := filepath.Rel(config.WorkingDirectory(), dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		if rel == "." {
+			return "**"
+		}
+		return filepath.ToSlash(rel) + "/"
+	}
+	return filepath.ToSlash(dir) + "/"
 }
 
 func (s *permissionService) AutoApproveSession(sessionID string) {
 	s.autoApproveSessions = append(s.autoApproveSessions, sessionID)
 }
 
+// SetUnattended makes requests that no one answers within timeout be allowed
+// or denied by decision, for runs without a TUI. Until it is called, requests
+// wait for an answer indefinitely.
+func (s *permissionService) SetUnattended(decision config.PermissionPolicy, timeout time.Duration) {
+	s.unattended = true
+	s.unattendedAllow = decision == config.PermissionAllow
+	s.unattendedTimeout = timeout
+}
+
 func NewPermissionService() Service {
 	return &permissionService{
 		Broker:             pubsub.NewBroker[PermissionRequest](),
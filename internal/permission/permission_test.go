@@ -0,0 +1,260 @@
+package permission
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
+)
+
+func TestMain(m *testing.M) {
+	if _, err := config.Load(os.TempDir(), false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRisk(t *testing.T) {
+	assert.Equal(t, config.RiskLow, Risk(CreatePermissionRequest{ToolName: "fetch", Action: "fetch"}))
+	assert.Equal(t, config.RiskMedium, Risk(CreatePermissionRequest{ToolName: "write", Action: "write"}))
+	assert.Equal(t, config.RiskMedium, Risk(CreatePermissionRequest{ToolName: "patch", Action: "update"}))
+	assert.Equal(t, config.RiskHigh, Risk(CreatePermissionRequest{ToolName: "patch", Action: "delete"}))
+	assert.Equal(t, config.RiskHigh, Risk(CreatePermissionRequest{ToolName: "bash", Action: "execute"}))
+	assert.Equal(t, config.RiskHigh, Risk(CreatePermissionRequest{ToolName: "mcp", Action: "unknown"}))
+}
+
+func TestRiskAndCategoryAgree(t *testing.T) {
+	tests := []struct {
+		action   string
+		risk     config.RiskLevel
+		category string
+	}{
+		{ActionRead, config.RiskLow, CategoryRead},
+		{ActionFetch, config.RiskLow, CategoryRead},
+		{ActionWrite, config.RiskMedium, CategoryWrite},
+		{ActionCreate, config.RiskMedium, CategoryWrite},
+		{ActionUpdate, config.RiskMedium, CategoryWrite},
+		{ActionDelete, config.RiskHigh, CategoryWrite},
+		{ActionCommit, config.RiskHigh, CategoryWrite},
+		{ActionExecute, config.RiskHigh, CategoryExecute},
+		{"unknown", config.RiskHigh, CategoryExecute},
+	}
+	for _, tt := range tests {
+		opts := CreatePermissionRequest{Action: tt.action}
+		assert.Equal(t, tt.risk, Risk(opts), tt.action)
+		assert.Equal(t, tt.category, Category(opts), tt.action)
+	}
+}
+
+func TestRequestAutoApprovesBelowThreshold(t *testing.T) {
+	previous := config.Get().AutoApproveBelow
+	config.Get().AutoApproveBelow = config.RiskMedium
+	t.Cleanup(func() { config.Get().AutoApproveBelow = previous })
+
+	s := NewPermissionService()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+
+	assert.True(t, s.Request(CreatePermissionRequest{SessionID: "s", ToolName: "fetch", Action: "fetch", Path: "/tmp/x"}))
+	select {
+	case event := <-events:
+		t.Fatalf("low-risk call prompted: %+v", event.Payload)
+	default:
+	}
+
+	result := make(chan bool, 1)
+	go func() {
+		result <- s.Request(CreatePermissionRequest{SessionID: "s", ToolName: "bash", Action: "execute", Path: "/tmp/x"})
+	}()
+	select {
+	case event := <-events:
+		assert.Equal(t, "bash", event.Payload.ToolName)
+		s.Deny(event.Payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("high-risk call was not prompted")
+	}
+	require.False(t, <-result)
+}
+
+func TestPolicy(t *testing.T) {
+	previous := config.Get().ToolPermissions
+	config.Get().ToolPermissions = map[string]config.PermissionPolicy{
+		"read":    config.PermissionAllow,
+		"execute": config.PermissionDeny,
+		"bash":    config.PermissionAsk,
+	}
+	t.Cleanup(func() { config.Get().ToolPermissions = previous })
+
+	assert.Equal(t, config.PermissionAllow, Policy(CreatePermissionRequest{ToolName: "fetch", Action: "fetch"}))
+	assert.Equal(t, config.PermissionAsk, Policy(CreatePermissionRequest{ToolName: "Bash", Action: "execute"}))
+	assert.Equal(t, config.PermissionDeny, Policy(CreatePermissionRequest{ToolName: "mcp_tool", Action: "execute"}))
+	assert.Equal(t, config.PermissionPolicy(""), Policy(CreatePermissionRequest{ToolName: "write", Action: "write"}))
+	assert.Equal(t, CategoryWrite, Category(CreatePermissionRequest{ToolName: "git_commit", Action: "commit"}))
+}
+
+func TestRequestFollowsToolPermissions(t *testing.T) {
+	cfg := config.Get()
+	previousPolicies, previousThreshold := cfg.ToolPermissions, cfg.AutoApproveBelow
+	cfg.ToolPermissions = map[string]config.PermissionPolicy{
+		"read":  config.PermissionAllow,
+		"bash":  config.PermissionDeny,
+		"patch": config.PermissionAsk,
+	}
+	cfg.AutoApproveBelow = config.RiskHigh
+	t.Cleanup(func() { cfg.ToolPermissions, cfg.AutoApproveBelow = previousPolicies, previousThreshold })
+
+	s := NewPermissionService()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+
+	assert.True(t, s.Request(CreatePermissionRequest{SessionID: "s", ToolName: "fetch", Action: "fetch", Path: "/tmp/x"}))
+	event := <-events
+	assert.Equal(t, AutoApprovedEvent, event.Type)
+	assert.Equal(t, "fetch", event.Payload.ToolName)
+
+	assert.False(t, s.Request(CreatePermissionRequest{SessionID: "s", ToolName: "bash", Action: "execute", Path: "/tmp/x"}))
+	s.AutoApproveSession("auto")
+	assert.False(t, s.Request(CreatePermissionRequest{SessionID: "auto", ToolName: "bash", Action: "execute", Path: "/tmp/x"}))
+
+	// An explicit ask prompts even though the call is below the threshold
+	result := make(chan bool, 1)
+	go func() {
+		result <- s.Request(CreatePermissionRequest{SessionID: "s", ToolName: "patch", Action: "update", Path: "/tmp/x"})
+	}()
+	select {
+	case event := <-events:
+		assert.Equal(t, pubsub.CreatedEvent, event.Type)
+		s.Grant(event.Payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("call with an ask policy was not prompted")
+	}
+	require.True(t, <-result)
+}
+
+func TestRequestReusesGrantOfRetriedCall(t *testing.T) {
+	s := NewPermissionService()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+
+	request := CreatePermissionRequest{SessionID: "s", ToolName: "fetch", Action: "fetch", Path: "/tmp/x", ToolCallID: "call"}
+	result := make(chan bool, 1)
+	go func() { result <- s.Request(request) }()
+	select {
+	case event := <-events:
+		s.Grant(event.Payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first attempt was not prompted")
+	}
+	require.True(t, <-result)
+
+	// The retry is granted without a prompt, other calls are still asked
+	assert.True(t, s.Request(request))
+	request.ToolCallID = "other"
+	go func() { result <- s.Request(request) }()
+	select {
+	case event := <-events:
+		s.Deny(event.Payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("another call was not prompted")
+	}
+	assert.False(t, <-result)
+}
+
+func TestMatchPathPattern(t *testing.T) {
+	wd := config.WorkingDirectory()
+	file := filepath.Join(wd, "src", "app", "main.go")
+
+	assert.True(t, MatchPathPattern("src/", file))
+	assert.True(t, MatchPathPattern("src", file))
+	assert.True(t, MatchPathPattern("src/**", file))
+	assert.True(t, MatchPathPattern("src/**/*.go", file))
+	assert.True(t, MatchPathPattern("**", file))
+	assert.True(t, MatchPathPattern(filepath.Join(wd, "src")+"/", file))
+	assert.True(t, MatchPathPattern("src/", "src/main.go"))
+	assert.False(t, MatchPathPattern("config/", file))
+	assert.False(t, MatchPathPattern("sr", file))
+	assert.False(t, MatchPathPattern("src/*.go", file))
+	assert.False(t, MatchPathPattern("**", filepath.Join(filepath.Dir(wd), "other", "main.go")))
+	assert.False(t, MatchPathPattern("", file))
+	assert.False(t, MatchPathPattern("src/", filepath.Join(wd, "src")+"/../secrets/key"))
+	assert.False(t, MatchPathPattern(filepath.Join(wd, "src")+"/", filepath.Join(wd, "src")+"/../secrets/key"))
+	assert.False(t, MatchPathPattern("src/", "src/../secrets/key"))
+	assert.True(t, MatchPathPattern("secrets/", filepath.Join(wd, "src")+"/../secrets/key"))
+	assert.True(t, MatchPathPattern(filepath.Join(wd, "src", "..", "secrets")+"/", filepath.Join(wd, "secrets", "key")))
+
+	assert.Equal(t, "src/app/", DefaultPathPattern(file))
+	assert.Equal(t, "**", DefaultPathPattern(filepath.Join(wd, "main.go")))
+	assert.Equal(t, "/elsewhere/", DefaultPathPattern("/elsewhere/main.go"))
+}
+
+func TestRequestMatchesPathScopedGrant(t *testing.T) {
+	wd := config.WorkingDirectory()
+	s := NewPermissionService()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+
+	request := func(opts CreatePermissionRequest) (bool, bool) {
+		result := make(chan bool, 1)
+		go func() { result <- s.Request(opts) }()
+		select {
+		case r := <-result:
+			return r, false
+		case event := <-events:
+			s.Deny(event.Payload)
+			return <-result, true
+		case <-time.After(5 * time.Second):
+			t.Fatal("request neither returned nor prompted")
+			return false, false
+		}
+	}
+
+	granted, prompted := request(CreatePermissionRequest{SessionID: "s", ToolName: "edit", Action: "write", Path: wd, ResourcePath: filepath.Join(wd, "src", "a.go")})
+	require.True(t, prompted)
+	require.False(t, granted)
+	s.GrantPersistant(PermissionRequest{SessionID: "s", ToolName: "edit", Action: "write", PathPattern: "src/"})
+
+	granted, prompted = request(CreatePermissionRequest{SessionID: "s", ToolName: "write", Action: "write", Path: wd, ResourcePath: filepath.Join(wd, "src", "pkg", "b.go")})
+	assert.True(t, granted)
+	assert.False(t, prompted)
+	granted, prompted = request(CreatePermissionRequest{SessionID: "s", ToolName: "patch", Action: "create", Path: wd, ResourcePath: "src/c.go"})
+	assert.True(t, granted)
+	assert.False(t, prompted)
+
+	_, prompted = request(CreatePermissionRequest{SessionID: "s", ToolName: "edit", Action: "write", Path: wd, ResourcePath: filepath.Join(wd, "config", "c.json")})
+	assert.True(t, prompted, "files outside the pattern still ask")
+	_, prompted = request(CreatePermissionRequest{SessionID: "s", ToolName: "bash", Action: "execute", Path: wd})
+	assert.True(t, prompted, "other categories still ask")
+	_, prompted = request(CreatePermissionRequest{SessionID: "other", ToolName: "edit", Action: "write", Path: wd, ResourcePath: filepath.Join(wd, "src", "a.go")})
+	assert.True(t, prompted, "other sessions still ask")
+}
+
+func TestRequestUnattended(t *testing.T) {
+	opts := CreatePermissionRequest{SessionID: "s", ToolName: "bash", Action: "execute", Path: "/tmp/x"}
+
+	s := NewPermissionService()
+	s.SetUnattended(config.PermissionDeny, 0)
+	assert.False(t, s.Request(opts))
+	s.SetUnattended(config.PermissionAllow, 0)
+	assert.True(t, s.Request(opts))
+
+	// An answer within the timeout wins over the decision
+	s.SetUnattended(config.PermissionAllow, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+	go func() {
+		event := <-events
+		s.Deny(event.Payload)
+	}()
+	assert.False(t, s.Request(opts))
+}
@@ -20,6 +20,9 @@ type Session struct {
 	Cost             float64
 	CreatedAt        int64
 	UpdatedAt        int64
+	// ContinuedFromSessionID is the session this one was started from by
+	// summarizing it, if any.
+	ContinuedFromSessionID string
 }
 
 type Service interface {
@@ -27,9 +30,12 @@ type Service interface {
 	Create(ctx context.Context, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	CreateContinuedSession(ctx context.Context, fromSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
+	ListChildren(ctx context.Context, parentSessionID string) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
+	Import(ctx context.Context, session Session) (Session, error)
 	Delete(ctx context.Context, id string) error
 }
 
@@ -79,6 +85,22 @@ func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string
 	return session, nil
 }
 
+// CreateContinuedSession creates a top-level session that carries on from
+// fromSessionID, which is left as it is.
+func (s *service) CreateContinuedSession(ctx context.Context, fromSessionID, title string) (Session, error) {
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:                     uuid.New().String(),
+		Title:                  title,
+		ContinuedFromSessionID: sql.NullString{String: fromSessionID, Valid: true},
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
 func (s *service) Delete(ctx context.Context, id string) error {
 	session, err := s.Get(ctx, id)
 	if err != nil {
@@ -132,18 +154,73 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+// MostRecentlyUpdated returns the session that was updated last, or false if
+// there are no sessions. Sessions updated in the same second are told apart by
+// their creation time.
+func MostRecentlyUpdated(sessions []Session) (Session, bool) {
+	if len(sessions) == 0 {
+		return Session{}, false
+	}
+	latest := sessions[0]
+	for _, s := range sessions[1:] {
+		if s.UpdatedAt > latest.UpdatedAt || s.UpdatedAt == latest.UpdatedAt && s.CreatedAt > latest.CreatedAt {
+			latest = s
+		}
+	}
+	return latest, true
+}
+
+// ListChildren returns the task and title sessions started from a session,
+// oldest first.
+func (s *service) ListChildren(ctx context.Context, parentSessionID string) ([]Session, error) {
+	dbSessions, err := s.q.ListChildSessions(ctx, sql.NullString{String: parentSessionID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
+// Import stores a session exported from another data directory as it was,
+// keeping its ID and timestamps. The message count is kept up to date as its
+// messages are imported.
+func (s *service) Import(ctx context.Context, session Session) (Session, error) {
+	dbSession, err := s.q.ImportSession(ctx, db.ImportSessionParams{
+		ID:                     session.ID,
+		ParentSessionID:        sql.NullString{String: session.ParentSessionID, Valid: session.ParentSessionID != ""},
+		Title:                  session.Title,
+		PromptTokens:           session.PromptTokens,
+		CompletionTokens:       session.CompletionTokens,
+		Cost:                   session.Cost,
+		SummaryMessageID:       sql.NullString{String: session.SummaryMessageID, Valid: session.SummaryMessageID != ""},
+		ContinuedFromSessionID: sql.NullString{String: session.ContinuedFromSessionID, Valid: session.ContinuedFromSessionID != ""},
+		UpdatedAt:              session.UpdatedAt,
+		CreatedAt:              session.CreatedAt,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session = s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
 func (s service) fromDBItem(item db.Session) Session {
 	return Session{
-		ID:               item.ID,
-		ParentSessionID:  item.ParentSessionID.String,
-		Title:            item.Title,
-		MessageCount:     item.MessageCount,
-		PromptTokens:     item.PromptTokens,
-		CompletionTokens: item.CompletionTokens,
-		SummaryMessageID: item.SummaryMessageID.String,
-		Cost:             item.Cost,
-		CreatedAt:        item.CreatedAt,
-		UpdatedAt:        item.UpdatedAt,
+		ID:                     item.ID,
+		ParentSessionID:        item.ParentSessionID.String,
+		Title:                  item.Title,
+		MessageCount:           item.MessageCount,
+		PromptTokens:           item.PromptTokens,
+		CompletionTokens:       item.CompletionTokens,
+		SummaryMessageID:       item.SummaryMessageID.String,
+		Cost:                   item.Cost,
+		CreatedAt:              item.CreatedAt,
+		UpdatedAt:              item.UpdatedAt,
+		ContinuedFromSessionID: item.ContinuedFromSessionID.String,
 	}
 }
 
@@ -0,0 +1,21 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMostRecentlyUpdated(t *testing.T) {
+	_, ok := MostRecentlyUpdated(nil)
+	assert.False(t, ok)
+
+	latest, ok := MostRecentlyUpdated([]Session{
+		{ID: "newest-created", CreatedAt: 300, UpdatedAt: 300},
+		{ID: "recently-updated", CreatedAt: 100, UpdatedAt: 500},
+		{ID: "old", CreatedAt: 50, UpdatedAt: 60},
+		{ID: "same-second", CreatedAt: 90, UpdatedAt: 500},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "recently-updated", latest.ID)
+}
@@ -2,7 +2,10 @@ package chat
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
@@ -25,6 +28,12 @@ type SessionClearedMsg struct{}
 
 type EditorFocusMsg bool
 
+// DiffViewChangedMsg is sent when tui.diffView is changed, so that diffs
+// already shown are laid out again.
+type DiffViewChangedMsg struct {
+	View config.DiffView
+}
+
 func header(width int) string {
 	return lipgloss.JoinVertical(
 		lipgloss.Top,
@@ -35,6 +44,41 @@ func header(width int) string {
 	)
 }
 
+// loadBanner returns the text configured by tui.banner. When the setting names
+// an existing file, relative to the working directory or starting with "~/",
+// the file's contents are used instead.
+func loadBanner(value string) string {
+	if value == "" {
+		return ""
+	}
+	path := value
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		return strings.TrimRight(string(data), "\r\n")
+	}
+	return value
+}
+
+func banner(width int, text string) string {
+	t := theme.CurrentTheme()
+
+	return styles.BaseStyle().
+		Width(width-2).
+		Foreground(t.Text()).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Warning()).
+		BorderBackground(t.Background()).
+		Padding(0, 1).
+		Render(text)
+}
+
 func lspsConfigured(width int) string {
 	cfg := config.Get()
 	title := "LSP Configuration"
@@ -13,6 +13,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/zhenbah/cryoncode/internal/app"
+	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/logging"
 	"github.com/zhenbah/cryoncode/internal/message"
 	"github.com/zhenbah/cryoncode/internal/session"
@@ -31,11 +32,20 @@ type editorCmp struct {
 	textarea    textarea.Model
 	attachments []message.Attachment
 	deleteMode  bool
+
+	// history holds the sent prompts. While recalling, historyIndex is the
+	// recalled prompt and draft the unsent text to return to; otherwise
+	// historyIndex is history.Len().
+	history      *promptHistory
+	historyIndex int
+	draft        string
 }
 
 type EditorKeyMaps struct {
 	Send       key.Binding
 	OpenEditor key.Binding
+	Previous   key.Binding
+	Next       key.Binding
 }
 
 type bluredEditorKeyMaps struct {
@@ -58,6 +68,14 @@ var editorMaps = EditorKeyMaps{
 		key.WithKeys("ctrl+e"),
 		key.WithHelp("ctrl+e", "open editor"),
 	),
+	Previous: key.NewBinding(
+		key.WithKeys("up"),
+		key.WithHelp("↑", "previous prompt"),
+	),
+	Next: key.NewBinding(
+		key.WithKeys("down"),
+		key.WithHelp("↓", "next prompt"),
+	),
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -108,6 +126,8 @@ func (m *editorCmp) openEditor() tea.Cmd {
 		os.Remove(tmpfile.Name())
 		attachments := m.attachments
 		m.attachments = nil
+		m.history.Add(string(content))
+		m.resetRecall()
 		return SendMsg{
 			Text:        string(content),
 			Attachments: attachments,
@@ -132,6 +152,8 @@ func (m *editorCmp) send() tea.Cmd {
 	if value == "" {
 		return nil
 	}
+	m.history.Add(value)
+	m.resetRecall()
 	return tea.Batch(
 		util.CmdHandler(SendMsg{
 			Text:        value,
@@ -151,6 +173,10 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.textarea.SetValue(modifiedValue)
 		return m, nil
+	case dialog.PromptTemplateExpandedMsg:
+		m.textarea.SetValue(msg.Text)
+		m.textarea.CursorEnd()
+		return m, m.textarea.Focus()
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
 			m.session = msg
@@ -198,6 +224,14 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.deleteMode = false
 			return m, nil
 		}
+		if m.textarea.Focused() && key.Matches(msg, editorMaps.Previous) && m.onFirstRow() {
+			m.recall(m.historyIndex - 1)
+			return m, nil
+		}
+		if m.textarea.Focused() && key.Matches(msg, editorMaps.Next) && m.onLastRow() && m.historyIndex < m.history.Len() {
+			m.recall(m.historyIndex + 1)
+			return m, nil
+		}
 		// Hanlde Enter key
 		if m.textarea.Focused() && key.Matches(msg, editorMaps.Send) {
 			value := m.textarea.Value()
@@ -216,6 +250,40 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// recall replaces the editor content with the prompt at index i of the
+// history, or with the draft when i is past the newest prompt.
+func (m *editorCmp) recall(i int) {
+	if i < 0 || i > m.history.Len() {
+		return
+	}
+	if m.historyIndex == m.history.Len() {
+		m.draft = m.textarea.Value()
+	}
+	m.historyIndex = i
+	if i == m.history.Len() {
+		m.textarea.SetValue(m.draft)
+		return
+	}
+	m.textarea.SetValue(m.history.At(i))
+}
+
+func (m *editorCmp) resetRecall() {
+	m.historyIndex = m.history.Len()
+	m.draft = ""
+}
+
+// onFirstRow reports whether the cursor is on the first visual row, where up
+// recalls the previous prompt instead of moving the cursor.
+func (m *editorCmp) onFirstRow() bool {
+	return m.textarea.Line() == 0 && m.textarea.LineInfo().RowOffset == 0
+}
+
+// onLastRow reports whether the cursor is on the last visual row.
+func (m *editorCmp) onLastRow() bool {
+	info := m.textarea.LineInfo()
+	return m.textarea.Line() == m.textarea.LineCount()-1 && info.RowOffset == info.Height-1
+}
+
 func (m *editorCmp) View() string {
 	t := theme.CurrentTheme()
 
@@ -311,8 +379,11 @@ func CreateTextArea(existing *textarea.Model) textarea.Model {
 
 func NewEditorCmp(app *app.App) tea.Model {
 	ta := CreateTextArea(nil)
+	history := loadPromptHistory(config.PromptHistoryPath(), config.Get().TUI.PromptHistorySize)
 	return &editorCmp{
-		app:      app,
-		textarea: ta,
+		app:          app,
+		textarea:     ta,
+		history:      history,
+		historyIndex: history.Len(),
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"slices"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -11,6 +12,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/zhenbah/cryoncode/internal/app"
+	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/message"
 	"github.com/zhenbah/cryoncode/internal/pubsub"
 	"github.com/zhenbah/cryoncode/internal/session"
@@ -36,14 +38,29 @@ type messagesCmp struct {
 	spinner       spinner.Model
 	rendering     bool
 	attachments   viewport.Model
+
+	// banner is the tui.banner text, shown on the initial screen until the
+	// first message appears.
+	banner string
+
+	// While paused, message events are buffered instead of rendered so the
+	// user can read without the view scrolling.
+	paused   bool
+	buffered []pubsub.Event[message.Message]
+
+	// showThinking expands the reasoning of messages, which is collapsed to
+	// a single line by default.
+	showThinking bool
 }
 type renderFinishedMsg struct{}
 
 type MessageKeys struct {
-	PageDown     key.Binding
-	PageUp       key.Binding
-	HalfPageUp   key.Binding
-	HalfPageDown key.Binding
+	PageDown       key.Binding
+	PageUp         key.Binding
+	HalfPageUp     key.Binding
+	HalfPageDown   key.Binding
+	PauseStream    key.Binding
+	ToggleThinking key.Binding
 }
 
 var messageKeys = MessageKeys{
@@ -63,6 +80,14 @@ var messageKeys = MessageKeys{
 		key.WithKeys("ctrl+d", "ctrl+d"),
 		key.WithHelp("ctrl+d", "½ page down"),
 	),
+	PauseStream: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "pause/resume output"),
+	),
+	ToggleThinking: key.NewBinding(
+		key.WithKeys("ctrl+y"),
+		key.WithHelp("ctrl+y", "show/hide thinking"),
+	),
 }
 
 func (m *messagesCmp) Init() tea.Cmd {
@@ -72,7 +97,7 @@ func (m *messagesCmp) Init() tea.Cmd {
 func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	switch msg := msg.(type) {
-	case dialog.ThemeChangedMsg:
+	case dialog.ThemeChangedMsg, DiffViewChangedMsg:
 		m.rerender()
 		return m, nil
 	case SessionSelectedMsg:
@@ -86,9 +111,19 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.messages = make([]message.Message, 0)
 		m.currentMsgID = ""
 		m.rendering = false
+		m.paused = false
+		m.buffered = nil
 		return m, nil
 
 	case tea.KeyMsg:
+		if key.Matches(msg, messageKeys.PauseStream) {
+			m.togglePause()
+			return m, nil
+		}
+		if key.Matches(msg, messageKeys.ToggleThinking) {
+			m.toggleThinking()
+			return m, nil
+		}
 		if key.Matches(msg, messageKeys.PageUp) || key.Matches(msg, messageKeys.PageDown) ||
 			key.Matches(msg, messageKeys.HalfPageUp) || key.Matches(msg, messageKeys.HalfPageDown) {
 			u, cmd := m.viewport.Update(msg)
@@ -108,50 +143,12 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 	case pubsub.Event[message.Message]:
-		needsRerender := false
-		if msg.Type == pubsub.CreatedEvent {
-			if msg.Payload.SessionID == m.session.ID {
-
-				messageExists := false
-				for _, v := range m.messages {
-					if v.ID == msg.Payload.ID {
-						messageExists = true
-						break
-					}
-				}
-
-				if !messageExists {
-					if len(m.messages) > 0 {
-						lastMsgID := m.messages[len(m.messages)-1].ID
-						delete(m.cachedContent, lastMsgID)
-					}
-
-					m.messages = append(m.messages, msg.Payload)
-					delete(m.cachedContent, m.currentMsgID)
-					m.currentMsgID = msg.Payload.ID
-					needsRerender = true
-				}
-			}
-			// There are tool calls from the child task
-			for _, v := range m.messages {
-				for _, c := range v.ToolCalls() {
-					if c.ID == msg.Payload.SessionID {
-						delete(m.cachedContent, v.ID)
-						needsRerender = true
-					}
-				}
-			}
-		} else if msg.Type == pubsub.UpdatedEvent && msg.Payload.SessionID == m.session.ID {
-			for i, v := range m.messages {
-				if v.ID == msg.Payload.ID {
-					m.messages[i] = msg.Payload
-					delete(m.cachedContent, msg.Payload.ID)
-					needsRerender = true
-					break
-				}
-			}
+		if m.paused {
+			m.buffered = append(m.buffered, msg)
+			break
 		}
-		if needsRerender {
+		if m.applyMessageEvent(msg) {
+			m.banner = ""
 			m.renderView()
 			if len(m.messages) > 0 {
 				if (msg.Type == pubsub.CreatedEvent) ||
@@ -168,6 +165,97 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// applyMessageEvent updates the message list from a message event and reports
+// whether the view needs to be rendered again.
+func (m *messagesCmp) applyMessageEvent(msg pubsub.Event[message.Message]) bool {
+	needsRerender := false
+	if msg.Type == pubsub.CreatedEvent {
+		if msg.Payload.SessionID == m.session.ID {
+
+			messageExists := false
+			for _, v := range m.messages {
+				if v.ID == msg.Payload.ID {
+					messageExists = true
+					break
+				}
+			}
+
+			if !messageExists {
+				if len(m.messages) > 0 {
+					lastMsgID := m.messages[len(m.messages)-1].ID
+					delete(m.cachedContent, lastMsgID)
+				}
+
+				m.messages = append(m.messages, msg.Payload)
+				delete(m.cachedContent, m.currentMsgID)
+				m.currentMsgID = msg.Payload.ID
+				needsRerender = true
+			}
+		}
+		// There are tool calls from the child task
+		for _, v := range m.messages {
+			for _, c := range v.ToolCalls() {
+				if c.ID == msg.Payload.SessionID {
+					delete(m.cachedContent, v.ID)
+					needsRerender = true
+				}
+			}
+		}
+	} else if msg.Type == pubsub.UpdatedEvent && msg.Payload.SessionID == m.session.ID {
+		for i, v := range m.messages {
+			if v.ID == msg.Payload.ID {
+				m.messages[i] = msg.Payload
+				delete(m.cachedContent, msg.Payload.ID)
+				needsRerender = true
+				break
+			}
+		}
+	} else if msg.Type == pubsub.DeletedEvent && msg.Payload.SessionID == m.session.ID {
+		for i, v := range m.messages {
+			if v.ID == msg.Payload.ID {
+				m.messages = slices.Delete(m.messages, i, i+1)
+				delete(m.cachedContent, msg.Payload.ID)
+				needsRerender = true
+				break
+			}
+		}
+	}
+	return needsRerender
+}
+
+// togglePause pauses or resumes rendering of incoming message events. On
+// resume, the events buffered while paused are applied in order.
+func (m *messagesCmp) togglePause() {
+	if !m.paused {
+		m.paused = true
+		return
+	}
+
+	m.paused = false
+	needsRerender := false
+	for _, event := range m.buffered {
+		if m.applyMessageEvent(event) {
+			needsRerender = true
+		}
+	}
+	m.buffered = nil
+	if needsRerender {
+		m.renderView()
+		m.viewport.GotoBottom()
+	}
+}
+
+// toggleThinking expands or collapses the reasoning of all messages.
+func (m *messagesCmp) toggleThinking() {
+	m.showThinking = !m.showThinking
+	for _, msg := range m.messages {
+		if msg.ReasoningContent().Thinking != "" {
+			delete(m.cachedContent, msg.ID)
+		}
+	}
+	m.renderView()
+}
+
 func (m *messagesCmp) IsAgentWorking() bool {
 	return m.app.CoderAgent.IsSessionBusy(m.session.ID)
 }
@@ -192,27 +280,28 @@ func (m *messagesCmp) renderView() {
 	if m.width == 0 {
 		return
 	}
+	width := m.contentWidth()
 	for inx, msg := range m.messages {
 		switch msg.Role {
 		case message.User:
-			if cache, ok := m.cachedContent[msg.ID]; ok && cache.width == m.width {
+			if cache, ok := m.cachedContent[msg.ID]; ok && cache.width == width {
 				m.uiMessages = append(m.uiMessages, cache.content...)
 				continue
 			}
 			userMsg := renderUserMessage(
 				msg,
 				msg.ID == m.currentMsgID,
-				m.width,
+				width,
 				pos,
 			)
 			m.uiMessages = append(m.uiMessages, userMsg)
 			m.cachedContent[msg.ID] = cacheItem{
-				width:   m.width,
+				width:   width,
 				content: []uiMessage{userMsg},
 			}
 			pos += userMsg.height + 1 // + 1 for spacing
 		case message.Assistant:
-			if cache, ok := m.cachedContent[msg.ID]; ok && cache.width == m.width {
+			if cache, ok := m.cachedContent[msg.ID]; ok && cache.width == width {
 				m.uiMessages = append(m.uiMessages, cache.content...)
 				continue
 			}
@@ -225,7 +314,8 @@ func (m *messagesCmp) renderView() {
 				m.app.Messages,
 				m.currentMsgID,
 				isSummary,
-				m.width,
+				m.showThinking,
+				width,
 				pos,
 			)
 			for _, msg := range assistantMessages {
@@ -233,7 +323,7 @@ func (m *messagesCmp) renderView() {
 				pos += msg.height + 1 // + 1 for spacing
 			}
 			m.cachedContent[msg.ID] = cacheItem{
-				width:   m.width,
+				width:   width,
 				content: assistantMessages,
 			}
 		}
@@ -243,7 +333,7 @@ func (m *messagesCmp) renderView() {
 	for _, v := range m.uiMessages {
 		messages = append(messages, lipgloss.JoinVertical(lipgloss.Left, v.content),
 			baseStyle.
-				Width(m.width).
+				Width(width).
 				Render(
 					"",
 				),
@@ -254,9 +344,14 @@ func (m *messagesCmp) renderView() {
 		baseStyle.
 			Width(m.width).
 			Render(
-				lipgloss.JoinVertical(
-					lipgloss.Top,
-					messages...,
+				lipgloss.PlaceHorizontal(
+					m.width,
+					m.contentPosition(),
+					lipgloss.JoinVertical(
+						lipgloss.Top,
+						messages...,
+					),
+					lipgloss.WithWhitespaceBackground(theme.CurrentTheme().Background()),
 				),
 			),
 	)
@@ -353,7 +448,9 @@ func (m *messagesCmp) working() string {
 
 		task := "Thinking..."
 		lastMessage := m.messages[len(m.messages)-1]
-		if hasToolsWithoutResponse(m.messages) {
+		if m.paused {
+			task = "Paused, press ctrl+p to resume..."
+		} else if hasToolsWithoutResponse(m.messages) {
 			task = "Waiting for tool response..."
 		} else if hasUnfinishedToolCalls(m.messages) {
 			task = "Building tool call..."
@@ -403,16 +500,35 @@ func (m *messagesCmp) help() string {
 func (m *messagesCmp) initialScreen() string {
 	baseStyle := styles.BaseStyle()
 
+	sections := []string{header(m.width), ""}
+	if m.banner != "" {
+		sections = append(sections, banner(m.width, m.banner), "")
+	}
+	sections = append(sections, lspsConfigured(m.width))
+
 	return baseStyle.Width(m.width).Render(
-		lipgloss.JoinVertical(
-			lipgloss.Top,
-			header(m.width),
-			"",
-			lspsConfigured(m.width),
-		),
+		lipgloss.JoinVertical(lipgloss.Top, sections...),
 	)
 }
 
+// contentWidth returns the width messages are rendered at: the width of the
+// component, capped by tui.maxContentWidth.
+func (m *messagesCmp) contentWidth() int {
+	if maxWidth := config.Get().TUI.MaxContentWidth; maxWidth > 0 && maxWidth < m.width {
+		return maxWidth
+	}
+	return m.width
+}
+
+// contentPosition returns where messages narrower than the component are
+// placed.
+func (m *messagesCmp) contentPosition() lipgloss.Position {
+	if config.Get().TUI.ContentAlign == config.ContentAlignLeft {
+		return lipgloss.Left
+	}
+	return lipgloss.Center
+}
+
 func (m *messagesCmp) rerender() {
 	for _, msg := range m.messages {
 		delete(m.cachedContent, msg.ID)
@@ -443,6 +559,8 @@ func (m *messagesCmp) SetSession(session session.Session) tea.Cmd {
 		return nil
 	}
 	m.session = session
+	m.paused = false
+	m.buffered = nil
 	messages, err := m.app.Messages.List(context.Background(), session.ID)
 	if err != nil {
 		return util.ReportError(err)
@@ -450,6 +568,7 @@ func (m *messagesCmp) SetSession(session session.Session) tea.Cmd {
 	m.messages = messages
 	if len(m.messages) > 0 {
 		m.currentMsgID = m.messages[len(m.messages)-1].ID
+		m.banner = ""
 	}
 	delete(m.cachedContent, m.currentMsgID)
 	m.rendering = true
@@ -465,6 +584,8 @@ func (m *messagesCmp) BindingKeys() []key.Binding {
 		m.viewport.KeyMap.PageUp,
 		m.viewport.KeyMap.HalfPageUp,
 		m.viewport.KeyMap.HalfPageDown,
+		messageKeys.PauseStream,
+		messageKeys.ToggleThinking,
 	}
 }
 
@@ -477,11 +598,16 @@ func NewMessagesCmp(app *app.App) tea.Model {
 	vp.KeyMap.PageDown = messageKeys.PageDown
 	vp.KeyMap.HalfPageUp = messageKeys.HalfPageUp
 	vp.KeyMap.HalfPageDown = messageKeys.HalfPageDown
+	var bannerText string
+	if cfg := config.Get(); cfg != nil {
+		bannerText = loadBanner(cfg.TUI.Banner)
+	}
 	return &messagesCmp{
 		app:           app,
 		cachedContent: make(map[string]cacheItem),
 		viewport:      vp,
 		spinner:       s,
 		attachments:   attachmets,
+		banner:        bannerText,
 	}
 }
@@ -0,0 +1,202 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/app"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/message"
+	"github.com/zhenbah/cryoncode/internal/pubsub"
+	"github.com/zhenbah/cryoncode/internal/session"
+)
+
+func TestMessagesCmp_PauseBuffersUntilResume(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	m := NewMessagesCmp(&app.App{}).(*messagesCmp)
+	m.session = session.Session{ID: "session"}
+	m.SetSize(80, 40)
+
+	assistant := func(text string) message.Message {
+		return message.Message{
+			ID:        "assistant",
+			SessionID: "session",
+			Role:      message.Assistant,
+			Parts:     []message.ContentPart{message.TextContent{Text: text}},
+		}
+	}
+	send := func(msg tea.Msg) {
+		_, _ = m.Update(msg)
+	}
+	view := func() string {
+		return ansi.Strip(m.viewport.View())
+	}
+
+	send(pubsub.Event[message.Message]{Type: pubsub.CreatedEvent, Payload: assistant("first")})
+	assert.Contains(t, view(), "first")
+
+	send(tea.KeyMsg{Type: tea.KeyCtrlP})
+	require.True(t, m.paused)
+
+	send(pubsub.Event[message.Message]{Type: pubsub.UpdatedEvent, Payload: assistant("first second")})
+	send(pubsub.Event[message.Message]{Type: pubsub.UpdatedEvent, Payload: assistant("first second third")})
+
+	assert.Len(t, m.buffered, 2)
+	assert.Equal(t, "first", m.messages[0].Content().String())
+	assert.NotContains(t, view(), "second")
+
+	send(tea.KeyMsg{Type: tea.KeyCtrlP})
+	require.False(t, m.paused)
+
+	assert.Empty(t, m.buffered)
+	assert.Equal(t, "first second third", m.messages[0].Content().String())
+	rendered := view()
+	assert.Contains(t, rendered, "first second third")
+	assert.Less(t, strings.Index(rendered, "first"), strings.Index(rendered, "third"))
+}
+
+func TestMessagesCmp_BannerOnInitialScreen(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	t.Cleanup(func() { config.Get().TUI.Banner = "" })
+
+	config.Get().TUI.Banner = "PRODUCTION: follow the change policy"
+	m := NewMessagesCmp(&app.App{}).(*messagesCmp)
+	m.session = session.Session{ID: "session"}
+	m.SetSize(80, 40)
+	assert.Contains(t, ansi.Strip(m.initialScreen()), "PRODUCTION: follow the change policy")
+
+	_, _ = m.Update(pubsub.Event[message.Message]{Type: pubsub.CreatedEvent, Payload: message.Message{
+		ID:        "user",
+		SessionID: "session",
+		Role:      message.User,
+		Parts:     []message.ContentPart{message.TextContent{Text: "hello"}},
+	}})
+	assert.NotContains(t, ansi.Strip(m.initialScreen()), "PRODUCTION")
+
+	bannerFile := filepath.Join(t.TempDir(), "banner.txt")
+	require.NoError(t, os.WriteFile(bannerFile, []byte("dev environment\n"), 0o644))
+	config.Get().TUI.Banner = bannerFile
+	m = NewMessagesCmp(&app.App{}).(*messagesCmp)
+	m.SetSize(80, 40)
+	view := ansi.Strip(m.initialScreen())
+	assert.Contains(t, view, "dev environment")
+	assert.NotContains(t, view, "banner.txt")
+}
+
+func TestMessagesCmp_MaxContentWidth(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	tui := &config.Get().TUI
+	t.Cleanup(func() {
+		tui.MaxContentWidth = 0
+		tui.ContentAlign = config.ContentAlignCenter
+	})
+
+	long := strings.Repeat("lorem ipsum dolor sit amet ", 20)
+	render := func() []string {
+		m := NewMessagesCmp(&app.App{}).(*messagesCmp)
+		m.session = session.Session{ID: "session"}
+		m.SetSize(120, 60)
+		_, _ = m.Update(pubsub.Event[message.Message]{Type: pubsub.CreatedEvent, Payload: message.Message{
+			ID:        "assistant",
+			SessionID: "session",
+			Role:      message.Assistant,
+			Parts:     []message.ContentPart{message.TextContent{Text: long}},
+		}})
+		return strings.Split(ansi.Strip(m.viewport.View()), "\n")
+	}
+	// textColumns returns the first and last column with text in lines.
+	textColumns := func(lines []string) (int, int) {
+		first, last := -1, -1
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			start := strings.Index(line, trimmed)
+			end := start + ansi.StringWidth(trimmed)
+			if first == -1 || start < first {
+				first = start
+			}
+			last = max(last, end)
+		}
+		return first, last
+	}
+
+	first, last := textColumns(render())
+	assert.Greater(t, last-first, 60, "without a cap, messages use the full width")
+
+	tui.MaxContentWidth = 50
+	first, last = textColumns(render())
+	assert.LessOrEqual(t, last-first, 50)
+	assert.GreaterOrEqual(t, first, 30, "capped messages are centered")
+
+	tui.ContentAlign = config.ContentAlignLeft
+	first, last = textColumns(render())
+	assert.LessOrEqual(t, last-first, 50)
+	assert.Less(t, first, 5)
+}
+
+func TestMessagesCmp_ToggleThinking(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	m := NewMessagesCmp(&app.App{}).(*messagesCmp)
+	m.session = session.Session{ID: "session"}
+	m.SetSize(80, 40)
+	view := func() string {
+		return ansi.Strip(m.viewport.View())
+	}
+
+	_, _ = m.Update(pubsub.Event[message.Message]{Type: pubsub.CreatedEvent, Payload: message.Message{
+		ID:        "assistant",
+		SessionID: "session",
+		Role:      message.Assistant,
+		Parts: []message.ContentPart{
+			message.ReasoningContent{Thinking: "The user wants a greeting"},
+			message.TextContent{Text: "Hello!"},
+		},
+	}})
+	assert.Contains(t, view(), "Thinking (5 words, ctrl+y to show)")
+	assert.NotContains(t, view(), "wants a greeting")
+	assert.Contains(t, view(), "Hello!")
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlY})
+	assert.Contains(t, view(), "Thinking (ctrl+y to hide)")
+	assert.Contains(t, view(), "wants a greeting")
+	assert.Contains(t, view(), "Hello!")
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlY})
+	assert.NotContains(t, view(), "wants a greeting")
+}
+
+func TestMessagesCmp_DeletedMessage(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	m := NewMessagesCmp(&app.App{}).(*messagesCmp)
+	m.session = session.Session{ID: "session"}
+	m.SetSize(80, 40)
+
+	user := message.Message{
+		ID:        "user",
+		SessionID: "session",
+		Role:      message.User,
+		Parts:     []message.ContentPart{message.TextContent{Text: "a very long paste"}},
+	}
+	_, _ = m.Update(pubsub.Event[message.Message]{Type: pubsub.CreatedEvent, Payload: user})
+	assert.Contains(t, ansi.Strip(m.viewport.View()), "a very long paste")
+
+	_, _ = m.Update(pubsub.Event[message.Message]{Type: pubsub.DeletedEvent, Payload: user})
+	assert.Empty(t, m.messages)
+	assert.NotContains(t, ansi.Strip(m.viewport.View()), "a very long paste")
+}
@@ -25,6 +25,7 @@ type uiMessageType int
 const (
 	userMessageType uiMessageType = iota
 	assistantMessageType
+	thinkingMessageType
 	toolMessageType
 
 	maxResultHeight = 10
@@ -86,8 +87,15 @@ func renderUserMessage(msg message.Message, isFocused bool, width int, position
 		MarginLeft(1).
 		Background(t.TextMuted()).
 		Foreground(t.Text())
+	var attached []string
 	for _, attachment := range msg.BinaryContent() {
-		file := filepath.Base(attachment.Path)
+		attached = append(attached, attachment.Path)
+	}
+	for _, file := range msg.FileContent() {
+		attached = append(attached, file.Path)
+	}
+	for _, path := range attached {
+		file := filepath.Base(path)
 		var filename string
 		if len(file) > 10 {
 			filename = fmt.Sprintf(" %s %s...", styles.DocumentIcon, file[0:7])
@@ -113,6 +121,40 @@ func renderUserMessage(msg message.Message, isFocused bool, width int, position
 	return userMsg
 }
 
+// formatCitations renders the sources of an answer as numbered footnotes, for
+// the [n] markers that answers cite them with. URLs are code spans, since the
+// renderer would repeat autolinks.
+func formatCitations(urls []string) string {
+	var b strings.Builder
+	b.WriteString("**Sources**\n")
+	for i, url := range urls {
+		fmt.Fprintf(&b, "\n- \\[%d\\] `%s`", i+1, url)
+	}
+	return b.String()
+}
+
+// renderThinking renders the reasoning of a message as a block that shows a
+// single summary line while collapsed.
+func renderThinking(thinking string, expanded bool, width int) string {
+	t := theme.CurrentTheme()
+	style := styles.BaseStyle().
+		Width(width - 1).
+		BorderLeft(true).
+		Foreground(t.TextMuted()).
+		BorderForeground(t.TextMuted()).
+		BorderStyle(lipgloss.ThickBorder())
+
+	toggle := messageKeys.ToggleThinking.Help().Key
+	if !expanded {
+		header := fmt.Sprintf(" ▸ Thinking (%d words, %s to show)", len(strings.Fields(thinking)), toggle)
+		return style.Render(header)
+	}
+
+	header := fmt.Sprintf(" ▾ Thinking (%s to hide)", toggle)
+	body := styles.ForceReplaceBackgroundWithLipgloss(toMarkdown(thinking, false, width), t.Background())
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, header, strings.TrimSuffix(body, "\n")))
+}
+
 // Returns multiple uiMessages because of the tool calls
 func renderAssistantMessage(
 	msg message.Message,
@@ -121,12 +163,12 @@ func renderAssistantMessage(
 	messagesService message.Service, // We need this to get the task tool messages
 	focusedUIMessageId string,
 	isSummary bool,
+	showThinking bool,
 	width int,
 	position int,
 ) []uiMessage {
 	messages := []uiMessage{}
 	content := msg.Content().String()
-	thinking := msg.IsThinking()
 	thinkingContent := msg.ReasoningContent().Thinking
 	finished := msg.IsFinished()
 	finishData := msg.FinishPart()
@@ -165,6 +207,18 @@ func renderAssistantMessage(
 			)
 		}
 	}
+	if thinkingContent != "" {
+		thinking := renderThinking(thinkingContent, showThinking, width)
+		messages = append(messages, uiMessage{
+			ID:          msg.ID,
+			messageType: thinkingMessageType,
+			position:    position,
+			height:      lipgloss.Height(thinking),
+			content:     thinking,
+		})
+		position += lipgloss.Height(thinking)
+		position++ // for the space
+	}
 	if content != "" || (finished && finishData.Reason == message.FinishReasonEndTurn) {
 		if content == "" {
 			content = "*Finished without output*"
@@ -172,6 +226,9 @@ func renderAssistantMessage(
 		if isSummary {
 			info = append(info, baseStyle.Width(width-1).Foreground(t.TextMuted()).Render(" (summary)"))
 		}
+		if citations := msg.Citations(); len(citations) > 0 {
+			content += "\n\n" + formatCitations(citations)
+		}
 
 		content = renderMessage(content, false, true, width, info...)
 		messages = append(messages, uiMessage{
@@ -181,11 +238,8 @@ func renderAssistantMessage(
 			height:      lipgloss.Height(content),
 			content:     content,
 		})
-		position += messages[0].height
+		position += lipgloss.Height(content)
 		position++ // for the space
-	} else if thinking && thinkingContent != "" {
-		// Render the thinking content
-		content = renderMessage(thinkingContent, false, msg.ID == focusedUIMessageId, width)
 	}
 
 	for i, toolCall := range msg.ToolCalls() {
@@ -226,6 +280,8 @@ func toolName(name string) string {
 		return "Edit"
 	case tools.FetchToolName:
 		return "Fetch"
+	case tools.FetchURLToolName:
+		return "Fetch URL"
 	case tools.GlobToolName:
 		return "Glob"
 	case tools.GrepToolName:
@@ -236,10 +292,22 @@ func toolName(name string) string {
 		return "Sourcegraph"
 	case tools.ViewToolName:
 		return "View"
+	case tools.ViewRevisionToolName:
+		return "View Revision"
 	case tools.WriteToolName:
 		return "Write"
 	case tools.PatchToolName:
 		return "Patch"
+	case tools.ApplyPatchToolName:
+		return "Apply Patch"
+	case tools.UndoLastEditToolName:
+		return "Undo Edit"
+	case tools.HoverToolName:
+		return "Hover"
+	case tools.CallHierarchyToolName:
+		return "Call Hierarchy"
+	case tools.GitCommitToolName:
+		return "Git Commit"
 	}
 	return name
 }
@@ -254,6 +322,8 @@ func getToolAction(name string) string {
 		return "Preparing edit..."
 	case tools.FetchToolName:
 		return "Writing fetch..."
+	case tools.FetchURLToolName:
+		return "Reading page..."
 	case tools.GlobToolName:
 		return "Finding files..."
 	case tools.GrepToolName:
@@ -262,12 +332,20 @@ func getToolAction(name string) string {
 		return "Listing directory..."
 	case tools.SourcegraphToolName:
 		return "Searching code..."
-	case tools.ViewToolName:
+	case tools.ViewToolName, tools.ViewRevisionToolName:
 		return "Reading file..."
 	case tools.WriteToolName:
 		return "Preparing write..."
-	case tools.PatchToolName:
+	case tools.PatchToolName, tools.ApplyPatchToolName:
 		return "Preparing patch..."
+	case tools.UndoLastEditToolName:
+		return "Undoing edit..."
+	case tools.HoverToolName:
+		return "Looking up symbol..."
+	case tools.CallHierarchyToolName:
+		return "Finding calls..."
+	case tools.GitCommitToolName:
+		return "Preparing commit..."
 	}
 	return "Working..."
 }
@@ -350,6 +428,41 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		json.Unmarshal([]byte(toolCall.Input), &params)
 		filePath := removeWorkingDirPrefix(params.FilePath)
 		return renderParams(paramWidth, filePath)
+	case tools.ApplyPatchToolName:
+		var params tools.ApplyPatchParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		filePath := removeWorkingDirPrefix(params.FilePath)
+		return renderParams(paramWidth, filePath)
+	case tools.HoverToolName:
+		var params tools.HoverParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		position := fmt.Sprintf("%s:%d:%d", removeWorkingDirPrefix(params.FilePath), params.Line, params.Character)
+		return renderParams(paramWidth, position)
+	case tools.CallHierarchyToolName:
+		var params tools.CallHierarchyParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := []string{
+			fmt.Sprintf("%s:%d:%d", removeWorkingDirPrefix(params.FilePath), params.Line, params.Character),
+		}
+		if params.Direction != "" {
+			toolParams = append(toolParams, "direction", params.Direction)
+		}
+		if params.Depth != 0 {
+			toolParams = append(toolParams, "depth", fmt.Sprint(params.Depth))
+		}
+		return renderParams(paramWidth, toolParams...)
+	case tools.GitCommitToolName:
+		var params tools.GitCommitParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		subject, _, _ := strings.Cut(strings.TrimSpace(params.Message), "\n")
+		if subject == "" {
+			subject = "message from session summary"
+		}
+		toolParams := []string{subject}
+		if params.IncludeAll {
+			toolParams = append(toolParams, "include_all", "true")
+		}
+		return renderParams(paramWidth, toolParams...)
 	case tools.FetchToolName:
 		var params tools.FetchParams
 		json.Unmarshal([]byte(toolCall.Input), &params)
@@ -364,6 +477,16 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 			toolParams = append(toolParams, "timeout", (time.Duration(params.Timeout) * time.Second).String())
 		}
 		return renderParams(paramWidth, toolParams...)
+	case tools.FetchURLToolName:
+		var params tools.FetchURLParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := []string{
+			params.URL,
+		}
+		if params.Timeout != 0 {
+			toolParams = append(toolParams, "timeout", (time.Duration(params.Timeout) * time.Second).String())
+		}
+		return renderParams(paramWidth, toolParams...)
 	case tools.GlobToolName:
 		var params tools.GlobParams
 		json.Unmarshal([]byte(toolCall.Input), &params)
@@ -388,9 +511,15 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		if params.Include != "" {
 			toolParams = append(toolParams, "include", params.Include)
 		}
+		if params.Exclude != "" {
+			toolParams = append(toolParams, "exclude", params.Exclude)
+		}
 		if params.LiteralText {
 			toolParams = append(toolParams, "literal", "true")
 		}
+		if params.IgnoreCase {
+			toolParams = append(toolParams, "ignore case", "true")
+		}
 		return renderParams(paramWidth, toolParams...)
 	case tools.LSToolName:
 		var params tools.LSParams
@@ -417,6 +546,24 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		if params.Offset != 0 {
 			toolParams = append(toolParams, "offset", fmt.Sprintf("%d", params.Offset))
 		}
+		if params.StartLine != 0 || params.EndLine != 0 {
+			toolParams = append(toolParams, "lines", fmt.Sprintf("%d-%d", params.StartLine, max(params.EndLine, params.StartLine)))
+		}
+		return renderParams(paramWidth, toolParams...)
+	case tools.ViewRevisionToolName:
+		var params tools.ViewRevisionParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := []string{
+			removeWorkingDirPrefix(params.FilePath),
+			"ref",
+			params.Ref,
+		}
+		if params.Limit != 0 {
+			toolParams = append(toolParams, "limit", fmt.Sprintf("%d", params.Limit))
+		}
+		if params.Offset != 0 {
+			toolParams = append(toolParams, "offset", fmt.Sprintf("%d", params.Offset))
+		}
 		return renderParams(paramWidth, toolParams...)
 	case tools.WriteToolName:
 		var params tools.WriteParams
@@ -459,16 +606,19 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 			t.Background(),
 		)
 	case tools.BashToolName:
+		if rows, ok := parseTable(resultContent); ok {
+			return renderTable(rows, width)
+		}
 		resultContent = fmt.Sprintf("```bash\n%s\n```", resultContent)
 		return styles.ForceReplaceBackgroundWithLipgloss(
 			toMarkdown(resultContent, true, width),
 			t.Background(),
 		)
-	case tools.EditToolName:
+	case tools.EditToolName, tools.ApplyPatchToolName:
 		metadata := tools.EditResponseMetadata{}
 		json.Unmarshal([]byte(response.Metadata), &metadata)
 		truncDiff := truncateHeight(metadata.Diff, maxResultHeight)
-		formattedDiff, _ := diff.FormatDiff(truncDiff, diff.WithTotalWidth(width))
+		formattedDiff, _ := diff.FormatDiff(truncDiff, diff.ViewOptions(width)...)
 		return formattedDiff
 	case tools.FetchToolName:
 		var params tools.FetchParams
@@ -485,7 +635,18 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 			toMarkdown(resultContent, true, width),
 			t.Background(),
 		)
-	case tools.GlobToolName:
+	case tools.HoverToolName:
+		return styles.ForceReplaceBackgroundWithLipgloss(
+			toMarkdown(resultContent, true, width),
+			t.Background(),
+		)
+	case tools.FetchURLToolName:
+		resultContent = fmt.Sprintf("```markdown\n%s\n```", resultContent)
+		return styles.ForceReplaceBackgroundWithLipgloss(
+			toMarkdown(resultContent, true, width),
+			t.Background(),
+		)
+	case tools.GlobToolName, tools.CallHierarchyToolName, tools.GitCommitToolName:
 		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(resultContent)
 	case tools.GrepToolName:
 		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(resultContent)
@@ -507,6 +668,15 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 			toMarkdown(resultContent, true, width),
 			t.Background(),
 		)
+	case tools.ViewRevisionToolName:
+		metadata := tools.ViewRevisionResponseMetadata{}
+		json.Unmarshal([]byte(response.Metadata), &metadata)
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(metadata.FilePath)), ".")
+		resultContent = fmt.Sprintf("```%s\n%s\n```", ext, truncateHeight(metadata.Content, maxResultHeight))
+		return styles.ForceReplaceBackgroundWithLipgloss(
+			toMarkdown(resultContent, true, width),
+			t.Background(),
+		)
 	case tools.WriteToolName:
 		params := tools.WriteParams{}
 		json.Unmarshal([]byte(toolCall.Input), &params)
@@ -524,6 +694,9 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 			t.Background(),
 		)
 	default:
+		if rows, ok := parseTable(resultContent); ok {
+			return renderTable(rows, width)
+		}
 		resultContent = fmt.Sprintf("```text\n%s\n```", resultContent)
 		return styles.ForceReplaceBackgroundWithLipgloss(
 			toMarkdown(resultContent, true, width),
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zhenbah/cryoncode/internal/logging"
+)
+
+// promptHistory holds the prompts sent from the editor, oldest first, and
+// keeps them in a file so they can be recalled in later runs.
+type promptHistory struct {
+	path    string
+	size    int
+	prompts []string
+}
+
+// loadPromptHistory reads the history kept in path, keeping at most size
+// prompts. A size of zero or less disables the history.
+func loadPromptHistory(path string, size int) *promptHistory {
+	h := &promptHistory{path: path, size: size}
+	if size <= 0 {
+		return h
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.Warn("Failed to read prompt history", "path", path, "error", err)
+		}
+		return h
+	}
+	if err := json.Unmarshal(data, &h.prompts); err != nil {
+		logging.Warn("Ignoring invalid prompt history", "path", path, "error", err)
+		h.prompts = nil
+	}
+	h.trim()
+	return h
+}
+
+// Add records a sent prompt and saves the history. Blank prompts and repeats
+// of the previous prompt are not recorded.
+func (h *promptHistory) Add(prompt string) {
+	if h.size <= 0 || strings.TrimSpace(prompt) == "" {
+		return
+	}
+	if len(h.prompts) > 0 && h.prompts[len(h.prompts)-1] == prompt {
+		return
+	}
+	h.prompts = append(h.prompts, prompt)
+	h.trim()
+	if err := h.save(); err != nil {
+		logging.Warn("Failed to save prompt history", "path", h.path, "error", err)
+	}
+}
+
+// Len returns the number of prompts in the history.
+func (h *promptHistory) Len() int {
+	return len(h.prompts)
+}
+
+// At returns the i-th prompt, oldest first.
+func (h *promptHistory) At(i int) string {
+	return h.prompts[i]
+}
+
+func (h *promptHistory) trim() {
+	if len(h.prompts) > h.size {
+		h.prompts = h.prompts[len(h.prompts)-h.size:]
+	}
+}
+
+func (h *promptHistory) save() error {
+	data, err := json.Marshal(h.prompts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, data, 0o600)
+}
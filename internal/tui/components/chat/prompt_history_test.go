@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptHistory_PersistsAndCaps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt-history.json")
+
+	h := loadPromptHistory(path, 3)
+	for _, prompt := range []string{"one", "", "  \n", "two", "two", "three", "four"} {
+		h.Add(prompt)
+	}
+	require.Equal(t, 3, h.Len())
+	assert.Equal(t, []string{"two", "three", "four"}, h.prompts, "blank prompts and repeats are skipped, oldest dropped")
+
+	reloaded := loadPromptHistory(path, 2)
+	assert.Equal(t, []string{"three", "four"}, reloaded.prompts)
+
+	disabled := loadPromptHistory(path, 0)
+	disabled.Add("five")
+	assert.Zero(t, disabled.Len())
+}
+
+func TestEditorCmp_RecallsPrompts(t *testing.T) {
+	history := loadPromptHistory(filepath.Join(t.TempDir(), "prompt-history.json"), 10)
+	history.Add("first prompt")
+	history.Add("second prompt")
+
+	m := &editorCmp{textarea: CreateTextArea(nil), history: history, historyIndex: history.Len()}
+	m.SetSize(80, 5)
+	m.textarea.SetValue("draft")
+	press := func(k tea.KeyType) {
+		_, _ = m.Update(tea.KeyMsg{Type: k})
+	}
+
+	press(tea.KeyUp)
+	assert.Equal(t, "second prompt", m.textarea.Value())
+	press(tea.KeyUp)
+	assert.Equal(t, "first prompt", m.textarea.Value())
+	press(tea.KeyUp)
+	assert.Equal(t, "first prompt", m.textarea.Value(), "stops at the oldest prompt")
+
+	press(tea.KeyDown)
+	assert.Equal(t, "second prompt", m.textarea.Value())
+	press(tea.KeyDown)
+	assert.Equal(t, "draft", m.textarea.Value(), "going past the newest prompt restores the draft")
+}
@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/zhenbah/cryoncode/internal/tui/styles"
+	"github.com/zhenbah/cryoncode/internal/tui/theme"
+)
+
+const (
+	minTableRows = 3
+	// Two columns split on spaces are too easily prose with double spaces
+	// between sentences, so space-aligned output needs one more column
+	minTabColumns   = 2
+	minSpaceColumns = 3
+	maxTableColumns = 12
+	tableColumnGap  = 2
+)
+
+// columnGap separates the columns of space-aligned output, such as that of
+// ls -l or docker ps, where single spaces can occur inside a cell.
+var columnGap = regexp.MustCompile(`\s{2,}`)
+
+// sizePattern matches numbers and sizes such as 12, 3.5, 4K or 1.2MB, which
+// are right-aligned.
+var sizePattern = regexp.MustCompile(`^[-+]?\d+(\.\d+)?[a-zA-Z%]{0,3}$`)
+
+// parseTable splits tool output into rows of cells when it is clearly tabular:
+// at least minTableRows lines that all split into the same number of columns,
+// either on tabs or on runs of two or more spaces. Blank lines are ignored.
+func parseTable(content string) ([][]string, bool) {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < minTableRows {
+		return nil, false
+	}
+
+	minColumns := minSpaceColumns
+	split := func(line string) []string {
+		return columnGap.Split(strings.TrimSpace(line), -1)
+	}
+	if allContain(lines, "\t") {
+		minColumns = minTabColumns
+		split = func(line string) []string {
+			cells := strings.Split(strings.TrimSpace(line), "\t")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			return cells
+		}
+	}
+
+	rows := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		cells := split(line)
+		if len(cells) < minColumns || len(cells) > maxTableColumns {
+			return nil, false
+		}
+		if len(rows) > 0 && len(cells) != len(rows[0]) {
+			return nil, false
+		}
+		rows = append(rows, cells)
+	}
+	return rows, true
+}
+
+func allContain(lines []string, s string) bool {
+	for _, line := range lines {
+		if !strings.Contains(line, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// alignTable lays out rows with padded columns. Columns whose cells below the
+// first row are all numbers or sizes are right-aligned. Lines are cut at
+// width.
+func alignTable(rows [][]string, width int) []string {
+	columns := len(rows[0])
+	widths := make([]int, columns)
+	numeric := make([]bool, columns)
+	for c := range columns {
+		numeric[c] = true
+		for r, row := range rows {
+			widths[c] = max(widths[c], ansi.StringWidth(row[c]))
+			if r > 0 && !sizePattern.MatchString(row[c]) {
+				numeric[c] = false
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(rows))
+	gap := strings.Repeat(" ", tableColumnGap)
+	for _, row := range rows {
+		var line strings.Builder
+		for c, cell := range row {
+			padding := strings.Repeat(" ", widths[c]-ansi.StringWidth(cell))
+			switch {
+			case numeric[c]:
+				line.WriteString(padding + cell)
+			case c < columns-1:
+				line.WriteString(cell + padding)
+			default:
+				line.WriteString(cell)
+			}
+			if c < columns-1 {
+				line.WriteString(gap)
+			}
+		}
+		lines = append(lines, ansi.Truncate(line.String(), width, "…"))
+	}
+	return lines
+}
+
+// hasHeader reports whether the first row looks like column names, which
+// unlike data rows rarely contain digits.
+func hasHeader(rows [][]string) bool {
+	for _, cell := range rows[0] {
+		if strings.ContainsAny(cell, "0123456789") {
+			return false
+		}
+	}
+	return true
+}
+
+// renderTable renders tabular tool output with aligned columns and the header
+// row, if any, in bold.
+func renderTable(rows [][]string, width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	lines := alignTable(rows, width)
+	header := hasHeader(rows)
+
+	rendered := make([]string, len(lines))
+	for i, line := range lines {
+		style := baseStyle.Width(width).Foreground(t.TextMuted())
+		if i == 0 && header {
+			style = style.Bold(true).Foreground(t.Text())
+		}
+		rendered[i] = style.Render(line)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rendered...)
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		columns int
+		tabular bool
+	}{
+		{
+			name:    "tab separated test results",
+			content: "ok\tgithub.com/x/config\t0.012s\nFAIL\tgithub.com/x/tui\t1.5s\nok\tgithub.com/x/db\t0.3s\n",
+			columns: 3,
+			tabular: true,
+		},
+		{
+			name:    "space aligned listing",
+			content: "NAME        SIZE   MODIFIED\nmain.go     1.2K   2024-05-01\n\nREADME.md   14K    2024-04-11",
+			columns: 3,
+			tabular: true,
+		},
+		{
+			name:    "prose",
+			content: "Build finished.  No errors.\nAll tests passed.  Done.\nSee the log.  Bye.",
+		},
+		{
+			name:    "ragged columns",
+			content: "a  b  c\nd  e\nf  g  h",
+		},
+		{
+			name:    "too few rows",
+			content: "NAME  SIZE  MODE\nmain.go  1K  0644",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, ok := parseTable(tt.content)
+			assert.Equal(t, tt.tabular, ok)
+			if ok {
+				assert.Len(t, rows[0], tt.columns)
+			}
+		})
+	}
+}
+
+func TestRenderTableAlignsColumns(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	rows, ok := parseTable("NAME\tSIZE\tKIND\nmain.go\t1.2K\tfile\ninternal\t96\tdirectory\nREADME.md\t14K\tfile")
+	require.True(t, ok)
+	lines := strings.Split(ansi.Strip(renderTable(rows, 80)), "\n")
+	require.Len(t, lines, 4)
+
+	expected := []string{
+		"NAME       SIZE  KIND",
+		"main.go    1.2K  file",
+		"internal     96  directory",
+		"README.md   14K  file",
+	}
+	for i, line := range lines {
+		assert.Equal(t, expected[i], strings.TrimRight(line, " "))
+	}
+}
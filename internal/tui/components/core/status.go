@@ -8,6 +8,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/llm/agent"
 	"github.com/zhenbah/cryoncode/internal/llm/models"
 	"github.com/zhenbah/cryoncode/internal/lsp"
 	"github.com/zhenbah/cryoncode/internal/lsp/protocol"
@@ -29,6 +30,11 @@ type statusCmp struct {
 	messageTTL time.Duration
 	lspClients map[string]*lsp.Client
 	session    session.Session
+	// usage is the running usage of the response being generated for the
+	// session, until the final usage is saved to it
+	usage *agent.AgentEvent
+	// promptTokens is the estimated size of the session's last prompt
+	promptTokens int64
 }
 
 // clearMessageCmd is a command that clears status messages after a timeout
@@ -48,15 +54,29 @@ func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		return m, nil
 	case chat.SessionSelectedMsg:
+		if msg.ID != m.session.ID {
+			m.promptTokens = 0
+		}
 		m.session = msg
+		m.usage = nil
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.usage = nil
+		m.promptTokens = 0
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if m.session.ID == msg.Payload.ID {
 				m.session = msg.Payload
+				m.usage = nil
 			}
 		}
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.Type == agent.AgentEventTypeUsage && msg.Payload.SessionID == m.session.ID {
+			m.usage = &msg.Payload
+		}
+		if msg.Payload.Type == agent.AgentEventTypePromptTokens && msg.Payload.SessionID == m.session.ID {
+			m.promptTokens = msg.Payload.PromptTokens
+		}
 	case util.InfoMsg:
 		m.info = msg
 		ttl := msg.TTL
@@ -84,8 +104,30 @@ func getHelpWidget() string {
 		Render(helpText)
 }
 
-func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
-	// Format tokens in human-readable format (e.g., 110K, 1.2M)
+// formatTokensAndCost formats the context usage and the session's spend. The
+// spend is shown as "—" when the model's prices are unknown and nothing was
+// spent with a priced model.
+func formatTokensAndCost(tokens, contextWindow int64, cost float64, priced bool) string {
+	formattedTokens := formatTokenCount(tokens)
+
+	// Format cost with $ symbol and 2 decimal places
+	formattedCost := "—"
+	if priced || cost > 0 {
+		formattedCost = fmt.Sprintf("$%.2f", cost)
+	}
+
+	percentage := (float64(tokens) / float64(contextWindow)) * 100
+	if percentage > 80 {
+		// add the warning icon and percentage
+		formattedTokens = fmt.Sprintf("%s(%d%%)", styles.WarningIcon, int(percentage))
+	}
+
+	return fmt.Sprintf("Context: %s, Cost: %s", formattedTokens, formattedCost)
+}
+
+// formatTokenCount formats a number of tokens in human-readable form, such as
+// 110K or 1.2M.
+func formatTokenCount(tokens int64) string {
 	var formattedTokens string
 	switch {
 	case tokens >= 1_000_000:
@@ -103,17 +145,7 @@ func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
 	if strings.HasSuffix(formattedTokens, ".0M") {
 		formattedTokens = strings.Replace(formattedTokens, ".0M", "M", 1)
 	}
-
-	// Format cost with $ symbol and 2 decimal places
-	formattedCost := fmt.Sprintf("$%.2f", cost)
-
-	percentage := (float64(tokens) / float64(contextWindow)) * 100
-	if percentage > 80 {
-		// add the warning icon and percentage
-		formattedTokens = fmt.Sprintf("%s(%d%%)", styles.WarningIcon, int(percentage))
-	}
-
-	return fmt.Sprintf("Context: %s, Cost: %s", formattedTokens, formattedCost)
+	return formattedTokens
 }
 
 func (m statusCmp) View() string {
@@ -124,10 +156,31 @@ func (m statusCmp) View() string {
 	// Initialize the help widget
 	status := getHelpWidget()
 
+	// Make it hard to mistake the diffs of a dry run for real edits
+	dryRunWidth := 0
+	if config.Get().DryRun {
+		dryRun := styles.Padded().
+			Background(t.Warning()).
+			Foreground(t.Background()).
+			Bold(true).
+			Render("DRY RUN")
+		dryRunWidth = lipgloss.Width(dryRun)
+		status += dryRun
+	}
+
 	tokenInfoWidth := 0
 	if m.session.ID != "" {
 		totalTokens := m.session.PromptTokens + m.session.CompletionTokens
-		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost)
+		cost := m.session.Cost
+		if m.usage != nil {
+			usage := m.usage.Usage
+			totalTokens = usage.InputTokens + usage.CacheCreationTokens + usage.OutputTokens + usage.CacheReadTokens
+			cost += m.usage.Cost
+		}
+		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, cost, model.HasPricing())
+		if m.promptTokens > 0 {
+			tokens = fmt.Sprintf("Prompt: ~%s, %s", formatTokenCount(m.promptTokens), tokens)
+		}
 		tokensStyle := styles.Padded().
 			Background(t.Text()).
 			Foreground(t.BackgroundSecondary())
@@ -143,7 +196,7 @@ func (m statusCmp) View() string {
 		Background(t.BackgroundDarker()).
 		Render(m.projectDiagnostics())
 
-	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-tokenInfoWidth)
+	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-tokenInfoWidth-dryRunWidth)
 
 	if m.info.Msg != "" {
 		infoStyle := styles.Padded().
@@ -2,6 +2,7 @@ package dialog
 
 import (
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	utilComponents "github.com/zhenbah/cryoncode/internal/tui/components/util"
@@ -63,6 +64,8 @@ type CommandDialog interface {
 
 type commandDialogCmp struct {
 	listView utilComponents.SimpleList[Command]
+	filter   textinput.Model
+	commands []Command
 	width    int
 	height   int
 }
@@ -84,7 +87,7 @@ var commandKeys = commandKeyMap{
 }
 
 func (c *commandDialogCmp) Init() tea.Cmd {
-	return c.listView.Init()
+	return tea.Batch(c.listView.Init(), textinput.Blink)
 }
 
 func (c *commandDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -107,6 +110,14 @@ func (c *commandDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		c.height = msg.Height
 	}
 
+	query := c.filter.Value()
+	f, cmd := c.filter.Update(msg)
+	c.filter = f
+	cmds = append(cmds, cmd)
+	if c.filter.Value() != query {
+		c.listView.SetItems(filterCommands(c.commands, c.filter.Value()))
+	}
+
 	u, cmd := c.listView.Update(msg)
 	c.listView = u.(utilComponents.SimpleList[Command])
 	cmds = append(cmds, cmd)
@@ -120,9 +131,8 @@ func (c *commandDialogCmp) View() string {
 
 	maxWidth := 40
 
-	commands := c.listView.GetItems()
-
-	for _, cmd := range commands {
+	// Size the dialog for every command so it doesn't resize while filtering
+	for _, cmd := range c.commands {
 		if len(cmd.Title) > maxWidth-4 {
 			maxWidth = len(cmd.Title) + 4
 		}
@@ -134,6 +144,10 @@ func (c *commandDialogCmp) View() string {
 	}
 
 	c.listView.SetMaxWidth(maxWidth)
+	c.filter.Width = maxWidth - 4
+	c.filter.PromptStyle = c.filter.PromptStyle.Foreground(t.Primary()).Background(t.Background())
+	c.filter.TextStyle = c.filter.TextStyle.Foreground(t.Text()).Background(t.Background())
+	c.filter.PlaceholderStyle = c.filter.PlaceholderStyle.Foreground(t.TextMuted()).Background(t.Background())
 
 	title := baseStyle.
 		Foreground(t.Primary()).
@@ -145,6 +159,7 @@ func (c *commandDialogCmp) View() string {
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
 		title,
+		baseStyle.Width(maxWidth).Padding(0, 1).Render(c.filter.View()),
 		baseStyle.Width(maxWidth).Render(""),
 		baseStyle.Width(maxWidth).Render(c.listView.View()),
 		baseStyle.Width(maxWidth).Render(""),
@@ -163,6 +178,8 @@ func (c *commandDialogCmp) BindingKeys() []key.Binding {
 }
 
 func (c *commandDialogCmp) SetCommands(commands []Command) {
+	c.commands = commands
+	c.filter.Reset()
 	c.listView.SetItems(commands)
 }
 
@@ -171,10 +188,15 @@ func NewCommandDialogCmp() CommandDialog {
 	listView := utilComponents.NewSimpleList[Command](
 		[]Command{},
 		10,
-		"No commands available",
-		true,
+		"No matching commands",
+		false,
 	)
+	filter := textinput.New()
+	filter.Placeholder = "Type to filter commands..."
+	filter.Prompt = "> "
+	filter.Focus()
 	return &commandDialogCmp{
 		listView: listView,
+		filter:   filter,
 	}
 }
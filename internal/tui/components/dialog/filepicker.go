@@ -222,21 +222,22 @@ func (f *filepickerCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (f *filepickerCmp) addAttachmentToMessage() (tea.Model, tea.Cmd) {
-	modeInfo := GetSelectedModel(config.Get())
-	if !modeInfo.SupportsAttachments {
-		logging.ErrorPersist(fmt.Sprintf("Model %s doesn't support attachments", modeInfo.Name))
-		return f, nil
-	}
-
 	selectedFilePath := f.selectedFile
 	if !isExtSupported(selectedFilePath) {
 		logging.ErrorPersist("Unsupported file")
 		return f, nil
 	}
 
+	// Documents are sent as their text, so only images need model support
+	modeInfo := GetSelectedModel(config.Get())
+	if isImageExt(selectedFilePath) && !modeInfo.SupportsAttachments {
+		logging.ErrorPersist(fmt.Sprintf("Model %s doesn't support image attachments", modeInfo.Name))
+		return f, nil
+	}
+
 	isFileLarge, err := image.ValidateFileSize(selectedFilePath, maxAttachmentSize)
 	if err != nil {
-		logging.ErrorPersist("unable to read the image")
+		logging.ErrorPersist("unable to read the file")
 		return f, nil
 	}
 	if isFileLarge {
@@ -392,7 +393,7 @@ func (f *filepickerCmp) getCurrentFileBelowCursor() {
 
 	dir := f.dirs[f.cursor]
 	filename := dir.Name()
-	if !dir.IsDir() && isExtSupported(filename) {
+	if !dir.IsDir() && isImageExt(filename) {
 		fullPath := f.cwdDetails.directory + "/" + dir.Name()
 
 		go func() {
@@ -466,6 +467,17 @@ func IsHidden(file string) (bool, error) {
 }
 
 func isExtSupported(path string) bool {
+	return isImageExt(path) || isDocumentExt(path)
+}
+
+func isImageExt(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	return (ext == ".jpg" || ext == ".jpeg" || ext == ".webp" || ext == ".png")
 }
+
+// isDocumentExt reports whether the file is a document whose text is
+// extracted and added to the prompt
+func isDocumentExt(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return (ext == ".pdf" || ext == ".txt" || ext == ".md")
+}
@@ -0,0 +1,144 @@
+package dialog
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Scores used by fuzzyScore. Matching the start of the target or of a word
+// outweighs a run of consecutive characters. Skipped characters cost a little,
+// up to fuzzyMaxGapPenalty per gap, so that tighter matches rank first without
+// long words hiding a later word boundary.
+const (
+	fuzzyMatchScore       = 1
+	fuzzyPrefixBonus      = 8
+	fuzzyWordBonus        = 6
+	fuzzyConsecutiveBonus = 4
+	fuzzyMaxGapPenalty    = 3
+)
+
+// fuzzyScore reports whether every character of query appears in target in
+// order, ignoring case, and scores the best such match. An empty query matches
+// everything with a score of 0.
+func fuzzyScore(query, target string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(target)
+	if len(q) == 0 {
+		return 0, true
+	}
+	if len(q) > len(t) {
+		return 0, false
+	}
+
+	lower := []rune(strings.ToLower(target))
+	if len(lower) != len(t) {
+		// Lowercasing changed the length; fall back to matching the original
+		lower = t
+	}
+
+	// best[j] is the best score of the query prefix matched so far with its
+	// last character at target index j, or -1 if there is no such match.
+	const none = -1 << 30
+	best := make([]int, len(t))
+	for j := range t {
+		best[j] = none
+		if lower[j] == q[0] {
+			best[j] = charScore(t, j) - gapPenalty(j)
+		}
+	}
+
+	for i := 1; i < len(q); i++ {
+		next := make([]int, len(t))
+		for j := range t {
+			next[j] = none
+			if lower[j] != q[i] {
+				continue
+			}
+			for k := j - 1; k >= 0; k-- {
+				if best[k] == none {
+					continue
+				}
+				score := best[k] + charScore(t, j)
+				if k == j-1 {
+					score += fuzzyConsecutiveBonus
+				} else {
+					score -= gapPenalty(j - k - 1)
+				}
+				next[j] = max(next[j], score)
+			}
+		}
+		best = next
+	}
+
+	score := slices.Max(best)
+	if score == none {
+		return 0, false
+	}
+	return score, true
+}
+
+func gapPenalty(skipped int) int {
+	return min(skipped, fuzzyMaxGapPenalty)
+}
+
+// charScore scores matching the character at index j of target.
+func charScore(target []rune, j int) int {
+	switch {
+	case j == 0:
+		return fuzzyMatchScore + fuzzyPrefixBonus
+	case isWordStart(target, j):
+		return fuzzyMatchScore + fuzzyWordBonus
+	}
+	return fuzzyMatchScore
+}
+
+// isWordStart reports whether target[j] starts a word: it follows a separator
+// or is an upper case letter following a lower case one.
+func isWordStart(target []rune, j int) bool {
+	prev, cur := target[j-1], target[j]
+	if unicode.IsUpper(cur) && unicode.IsLower(prev) {
+		return true
+	}
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && (unicode.IsLetter(cur) || unicode.IsDigit(cur))
+}
+
+// filterCommands returns the commands whose title fuzzy matches query, best
+// match first. Commands that only match by description follow those that
+// match by title. Ties keep their original order.
+func filterCommands(commands []Command, query string) []Command {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return commands
+	}
+
+	type scored struct {
+		command Command
+		byTitle bool
+		score   int
+	}
+	var matches []scored
+	for _, command := range commands {
+		if score, ok := fuzzyScore(query, command.Title); ok {
+			matches = append(matches, scored{command: command, byTitle: true, score: score})
+		} else if score, ok := fuzzyScore(query, command.Description); ok {
+			matches = append(matches, scored{command: command, score: score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b scored) int {
+		if a.byTitle != b.byTitle {
+			if a.byTitle {
+				return -1
+			}
+			return 1
+		}
+		return b.score - a.score
+	})
+
+	filtered := make([]Command, len(matches))
+	for i, match := range matches {
+		filtered[i] = match.command
+	}
+	return filtered
+}
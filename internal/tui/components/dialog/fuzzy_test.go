@@ -0,0 +1,61 @@
+package dialog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("grt", "generate release tag")
+	assert.True(t, ok)
+	_, ok = fuzzyScore("GRT", "generate release tag")
+	assert.True(t, ok, "matching ignores case")
+	_, ok = fuzzyScore("tgr", "generate release tag")
+	assert.False(t, ok, "characters must appear in order")
+	_, ok = fuzzyScore("grtx", "generate release tag")
+	assert.False(t, ok)
+
+	score, ok := fuzzyScore("", "anything")
+	assert.True(t, ok)
+	assert.Zero(t, score)
+
+	higher := func(query, better, worse string) {
+		t.Helper()
+		b, okB := fuzzyScore(query, better)
+		w, okW := fuzzyScore(query, worse)
+		assert.True(t, okB && okW)
+		assert.Greater(t, b, w, "%q should rank %q above %q", query, better, worse)
+	}
+	higher("grt", "generate release tag", "program rate")
+	higher("ini", "Initialize Project", "Compact Session: summarize")
+	higher("comp", "Compact Session", "Accompany")
+	higher("rt", "user:release-tag", "user:report")
+	higher("st", "switchTheme", "best")
+}
+
+func TestFilterCommands(t *testing.T) {
+	commands := []Command{
+		{ID: "1", Title: "Initialize Project"},
+		{ID: "2", Title: "Compact Session", Description: "Summarize the current session"},
+		{ID: "3", Title: "user:generate-release-tag"},
+		{ID: "4", Title: "user:greet"},
+		{ID: "5", Title: "Show trash", Description: "List files that can be restored"},
+	}
+	ids := func(commands []Command) []string {
+		result := make([]string, len(commands))
+		for i, command := range commands {
+			result[i] = command.ID
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids(filterCommands(commands, "")))
+	assert.Equal(t, []string{"3", "4"}, ids(filterCommands(commands, "grt")))
+	assert.Equal(t, "3", filterCommands(commands, "grt")[0].ID)
+	matched := ids(filterCommands(commands, "res"))
+	assert.Len(t, matched, 3)
+	assert.Equal(t, "3", matched[0], "title matches rank above description matches")
+	assert.ElementsMatch(t, []string{"2", "5"}, matched[1:])
+	assert.Empty(t, filterCommands(commands, "zzz"))
+}
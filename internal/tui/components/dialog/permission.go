@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -23,10 +24,12 @@ type PermissionAction string
 const (
 	PermissionAllow           PermissionAction = "allow"
 	PermissionAllowForSession PermissionAction = "allow_session"
+	PermissionAllowForPath    PermissionAction = "allow_path"
 	PermissionDeny            PermissionAction = "deny"
 )
 
-// PermissionResponseMsg represents the user's response to a permission request
+// PermissionResponseMsg represents the user's response to a permission request.
+// For PermissionAllowForPath, the permission carries the path pattern to grant.
 type PermissionResponseMsg struct {
 	Permission permission.PermissionRequest
 	Action     PermissionAction
@@ -45,6 +48,7 @@ type permissionsMapping struct {
 	EnterSpace   key.Binding
 	Allow        key.Binding
 	AllowSession key.Binding
+	AllowPath    key.Binding
 	Deny         key.Binding
 	Tab          key.Binding
 }
@@ -70,6 +74,10 @@ var permissionsKeys = permissionsMapping{
 		key.WithKeys("s"),
 		key.WithHelp("s", "allow for session"),
 	),
+	AllowPath: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "allow for path"),
+	),
 	Deny: key.NewBinding(
 		key.WithKeys("d"),
 		key.WithHelp("d", "deny"),
@@ -87,7 +95,12 @@ type permissionDialogCmp struct {
 	permission      permission.PermissionRequest
 	windowSize      tea.WindowSizeMsg
 	contentViewPort viewport.Model
-	selectedOption  int // 0: Allow, 1: Allow for session, 2: Deny
+	selectedOption  int // Index into options()
+
+	// editingPattern is set while the path pattern of an allow for path
+	// response is entered
+	editingPattern bool
+	patternInput   textinput.Model
 
 	diffCache     map[string]string
 	markdownCache map[string]string
@@ -108,20 +121,26 @@ func (p *permissionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		p.markdownCache = make(map[string]string)
 		p.diffCache = make(map[string]string)
 	case tea.KeyMsg:
+		if p.editingPattern {
+			return p, p.updatePatternInput(msg)
+		}
+		options := p.options()
 		switch {
 		case key.Matches(msg, permissionsKeys.Right) || key.Matches(msg, permissionsKeys.Tab):
-			p.selectedOption = (p.selectedOption + 1) % 3
+			p.selectedOption = (p.selectedOption + 1) % len(options)
 			return p, nil
 		case key.Matches(msg, permissionsKeys.Left):
-			p.selectedOption = (p.selectedOption + 2) % 3
+			p.selectedOption = (p.selectedOption + len(options) - 1) % len(options)
 		case key.Matches(msg, permissionsKeys.EnterSpace):
-			return p, p.selectCurrentOption()
+			return p, p.respond(options[p.selectedOption])
 		case key.Matches(msg, permissionsKeys.Allow):
-			return p, util.CmdHandler(PermissionResponseMsg{Action: PermissionAllow, Permission: p.permission})
+			return p, p.respond(PermissionAllow)
 		case key.Matches(msg, permissionsKeys.AllowSession):
-			return p, util.CmdHandler(PermissionResponseMsg{Action: PermissionAllowForSession, Permission: p.permission})
+			return p, p.respond(PermissionAllowForSession)
+		case key.Matches(msg, permissionsKeys.AllowPath) && p.permission.ResourcePath != "":
+			return p, p.respond(PermissionAllowForPath)
 		case key.Matches(msg, permissionsKeys.Deny):
-			return p, util.CmdHandler(PermissionResponseMsg{Action: PermissionDeny, Permission: p.permission})
+			return p, p.respond(PermissionDeny)
 		default:
 			// Pass other keys to viewport
 			viewPort, cmd := p.contentViewPort.Update(msg)
@@ -133,59 +152,99 @@ func (p *permissionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return p, tea.Batch(cmds...)
 }
 
-func (p *permissionDialogCmp) selectCurrentOption() tea.Cmd {
-	var action PermissionAction
-
-	switch p.selectedOption {
-	case 0:
-		action = PermissionAllow
-	case 1:
-		action = PermissionAllowForSession
-	case 2:
-		action = PermissionDeny
+// options returns the responses offered for the current request. Allowing by
+// path needs a request for a file.
+func (p *permissionDialogCmp) options() []PermissionAction {
+	if p.permission.ResourcePath == "" {
+		return []PermissionAction{PermissionAllow, PermissionAllowForSession, PermissionDeny}
 	}
+	return []PermissionAction{PermissionAllow, PermissionAllowForSession, PermissionAllowForPath, PermissionDeny}
+}
 
+// respond answers the request with action. Allowing by path first asks for
+// the pattern, suggesting the directory of the file.
+func (p *permissionDialogCmp) respond(action PermissionAction) tea.Cmd {
+	if action == PermissionAllowForPath {
+		p.editingPattern = true
+		p.patternInput.SetValue(permission.DefaultPathPattern(p.permission.ResourcePath))
+		p.patternInput.CursorEnd()
+		p.patternInput.Focus()
+		return textinput.Blink
+	}
 	return util.CmdHandler(PermissionResponseMsg{Action: action, Permission: p.permission})
 }
 
-func (p *permissionDialogCmp) renderButtons() string {
+// updatePatternInput handles keys while the path pattern is entered: enter
+// grants it, esc goes back to the other options.
+func (p *permissionDialogCmp) updatePatternInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		pattern := strings.TrimSpace(p.patternInput.Value())
+		if pattern == "" {
+			return nil
+		}
+		p.editingPattern = false
+		p.patternInput.Blur()
+		granted := p.permission
+		granted.PathPattern = pattern
+		return util.CmdHandler(PermissionResponseMsg{Action: PermissionAllowForPath, Permission: granted})
+	case tea.KeyEsc:
+		p.editingPattern = false
+		p.patternInput.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	p.patternInput, cmd = p.patternInput.Update(msg)
+	return cmd
+}
+
+// renderPatternInput shows the path pattern being entered in place of the
+// buttons.
+func (p *permissionDialogCmp) renderPatternInput() string {
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
 
-	allowStyle := baseStyle
-	allowSessionStyle := baseStyle
-	denyStyle := baseStyle
-	spacerStyle := baseStyle.Background(t.Background())
+	label := baseStyle.Foreground(t.TextMuted()).Bold(true).Render("Allow for files matching: ")
+	hint := baseStyle.Foreground(t.TextMuted()).Render("  enter: allow  esc: back")
+	p.patternInput.Width = max(p.width-lipgloss.Width(label)-lipgloss.Width(hint)-6, 10)
+	p.patternInput.TextStyle = p.patternInput.TextStyle.Background(t.Background()).Foreground(t.Primary())
+	p.patternInput.PromptStyle = p.patternInput.PromptStyle.Background(t.Background())
+	p.patternInput.Cursor.Style = p.patternInput.Cursor.Style.Background(t.Background())
 
-	// Style the selected button
-	switch p.selectedOption {
-	case 0:
-		allowStyle = allowStyle.Background(t.Primary()).Foreground(t.Background())
-		allowSessionStyle = allowSessionStyle.Background(t.Background()).Foreground(t.Primary())
-		denyStyle = denyStyle.Background(t.Background()).Foreground(t.Primary())
-	case 1:
-		allowStyle = allowStyle.Background(t.Background()).Foreground(t.Primary())
-		allowSessionStyle = allowSessionStyle.Background(t.Primary()).Foreground(t.Background())
-		denyStyle = denyStyle.Background(t.Background()).Foreground(t.Primary())
-	case 2:
-		allowStyle = allowStyle.Background(t.Background()).Foreground(t.Primary())
-		allowSessionStyle = allowSessionStyle.Background(t.Background()).Foreground(t.Primary())
-		denyStyle = denyStyle.Background(t.Primary()).Foreground(t.Background())
+	return lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		label,
+		baseStyle.Render(p.patternInput.View()),
+		hint,
+	)
+}
+
+func (p *permissionDialogCmp) renderButtons() string {
+	if p.editingPattern {
+		return p.renderPatternInput()
 	}
 
-	allowButton := allowStyle.Padding(0, 1).Render("Allow (a)")
-	allowSessionButton := allowSessionStyle.Padding(0, 1).Render("Allow for session (s)")
-	denyButton := denyStyle.Padding(0, 1).Render("Deny (d)")
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	spacerStyle := baseStyle.Background(t.Background())
 
-	content := lipgloss.JoinHorizontal(
-		lipgloss.Left,
-		allowButton,
-		spacerStyle.Render("  "),
-		allowSessionButton,
-		spacerStyle.Render("  "),
-		denyButton,
-		spacerStyle.Render("  "),
-	)
+	labels := map[PermissionAction]string{
+		PermissionAllow:           "Allow (a)",
+		PermissionAllowForSession: "Allow for session (s)",
+		PermissionAllowForPath:    "Allow for path (p)",
+		PermissionDeny:            "Deny (d)",
+	}
+	var buttons []string
+	for i, action := range p.options() {
+		// Style the selected button
+		style := baseStyle.Background(t.Background()).Foreground(t.Primary())
+		if i == p.selectedOption {
+			style = baseStyle.Background(t.Primary()).Foreground(t.Background())
+		}
+		buttons = append(buttons, style.Padding(0, 1).Render(labels[action]), spacerStyle.Render("  "))
+	}
+
+	content := lipgloss.JoinHorizontal(lipgloss.Left, buttons...)
 
 	remainingWidth := p.width - lipgloss.Width(content)
 	if remainingWidth > 0 {
@@ -229,7 +288,7 @@ func (p *permissionDialogCmp) renderHeader() string {
 	switch p.permission.ToolName {
 	case tools.BashToolName:
 		headerParts = append(headerParts, baseStyle.Foreground(t.TextMuted()).Width(p.width).Bold(true).Render("Command"))
-	case tools.EditToolName:
+	case tools.EditToolName, tools.ApplyPatchToolName:
 		params := p.permission.Params.(tools.EditPermissionsParams)
 		fileKey := baseStyle.Foreground(t.TextMuted()).Bold(true).Render("File")
 		filePath := baseStyle.
@@ -260,7 +319,7 @@ func (p *permissionDialogCmp) renderHeader() string {
 			),
 			baseStyle.Render(strings.Repeat(" ", p.width)),
 		)
-	case tools.FetchToolName:
+	case tools.FetchToolName, tools.FetchURLToolName:
 		headerParts = append(headerParts, baseStyle.Foreground(t.TextMuted()).Width(p.width).Bold(true).Render("URL"))
 	}
 
@@ -293,7 +352,7 @@ func (p *permissionDialogCmp) renderBashContent() string {
 func (p *permissionDialogCmp) renderEditContent() string {
 	if pr, ok := p.permission.Params.(tools.EditPermissionsParams); ok {
 		diff := p.GetOrSetDiff(p.permission.ID, func() (string, error) {
-			return diff.FormatDiff(pr.Diff, diff.WithTotalWidth(p.contentViewPort.Width))
+			return diff.FormatDiff(pr.Diff, diff.ViewOptions(p.contentViewPort.Width)...)
 		})
 
 		p.contentViewPort.SetContent(diff)
@@ -305,7 +364,7 @@ func (p *permissionDialogCmp) renderEditContent() string {
 func (p *permissionDialogCmp) renderPatchContent() string {
 	if pr, ok := p.permission.Params.(tools.EditPermissionsParams); ok {
 		diff := p.GetOrSetDiff(p.permission.ID, func() (string, error) {
-			return diff.FormatDiff(pr.Diff, diff.WithTotalWidth(p.contentViewPort.Width))
+			return diff.FormatDiff(pr.Diff, diff.ViewOptions(p.contentViewPort.Width)...)
 		})
 
 		p.contentViewPort.SetContent(diff)
@@ -318,7 +377,7 @@ func (p *permissionDialogCmp) renderWriteContent() string {
 	if pr, ok := p.permission.Params.(tools.WritePermissionsParams); ok {
 		// Use the cache for diff rendering
 		diff := p.GetOrSetDiff(p.permission.ID, func() (string, error) {
-			return diff.FormatDiff(pr.Diff, diff.WithTotalWidth(p.contentViewPort.Width))
+			return diff.FormatDiff(pr.Diff, diff.ViewOptions(p.contentViewPort.Width)...)
 		})
 
 		p.contentViewPort.SetContent(diff)
@@ -406,13 +465,13 @@ func (p *permissionDialogCmp) render() string {
 	switch p.permission.ToolName {
 	case tools.BashToolName:
 		contentFinal = p.renderBashContent()
-	case tools.EditToolName:
+	case tools.EditToolName, tools.ApplyPatchToolName:
 		contentFinal = p.renderEditContent()
-	case tools.PatchToolName:
+	case tools.PatchToolName, tools.UndoLastEditToolName:
 		contentFinal = p.renderPatchContent()
 	case tools.WriteToolName:
 		contentFinal = p.renderWriteContent()
-	case tools.FetchToolName:
+	case tools.FetchToolName, tools.FetchURLToolName:
 		contentFinal = p.renderFetchContent()
 	default:
 		contentFinal = p.renderDefaultContent()
@@ -456,13 +515,13 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	case tools.BashToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.4)
 		p.height = int(float64(p.windowSize.Height) * 0.3)
-	case tools.EditToolName:
+	case tools.EditToolName, tools.ApplyPatchToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.8)
 		p.height = int(float64(p.windowSize.Height) * 0.8)
-	case tools.WriteToolName:
+	case tools.WriteToolName, tools.UndoLastEditToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.8)
 		p.height = int(float64(p.windowSize.Height) * 0.8)
-	case tools.FetchToolName:
+	case tools.FetchToolName, tools.FetchURLToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.4)
 		p.height = int(float64(p.windowSize.Height) * 0.3)
 	default:
@@ -474,6 +533,9 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 
 func (p *permissionDialogCmp) SetPermissions(permission permission.PermissionRequest) tea.Cmd {
 	p.permission = permission
+	p.editingPattern = false
+	p.patternInput.Blur()
+	p.selectedOption = min(p.selectedOption, len(p.options())-1)
 	return p.SetSize()
 }
 
@@ -513,8 +575,13 @@ func NewPermissionDialogCmp() PermissionDialogCmp {
 	// Create viewport for content
 	contentViewport := viewport.New(0, 0)
 
+	patternInput := textinput.New()
+	patternInput.Prompt = ""
+	patternInput.Placeholder = "src/**"
+
 	return &permissionDialogCmp{
 		contentViewPort: contentViewport,
+		patternInput:    patternInput,
 		selectedOption:  0, // Default to "Allow"
 		diffCache:       make(map[string]string),
 		markdownCache:   make(map[string]string),
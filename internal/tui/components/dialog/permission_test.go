@@ -0,0 +1,49 @@
+package dialog
+
+import (
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/permission"
+	"github.com/zhenbah/cryoncode/internal/tui/theme"
+)
+
+func TestPermissionDialogAllowForPath(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	require.NoError(t, theme.SetTheme("cryoncode"))
+
+	dialog := NewPermissionDialogCmp()
+	dialog.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	request := permission.PermissionRequest{
+		ID:           "1",
+		ToolName:     "custom",
+		Action:       "write",
+		Path:         config.WorkingDirectory(),
+		ResourcePath: filepath.Join(config.WorkingDirectory(), "src", "main.go"),
+	}
+	dialog.SetPermissions(request)
+	assert.Contains(t, ansi.Strip(dialog.View()), "Allow for path (p)")
+
+	_, cmd := dialog.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	require.NotNil(t, cmd)
+	assert.Contains(t, ansi.Strip(dialog.View()), "Allow for files matching: src/")
+
+	// Letters go to the pattern instead of choosing other options
+	dialog.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	_, cmd = dialog.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	request.PathPattern = "src/d"
+	assert.Equal(t, PermissionResponseMsg{Action: PermissionAllowForPath, Permission: request}, cmd())
+
+	// Requests without a file don't offer it
+	dialog.SetPermissions(permission.PermissionRequest{ID: "2", ToolName: "fetch", Action: "fetch"})
+	assert.NotContains(t, ansi.Strip(dialog.View()), "Allow for path")
+	_, cmd = dialog.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	assert.Nil(t, cmd)
+}
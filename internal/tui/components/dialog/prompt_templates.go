@@ -0,0 +1,93 @@
+package dialog
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/tui/util"
+)
+
+// PromptTemplatePrefix is the command ID prefix of prompt templates
+const PromptTemplatePrefix = "template:"
+
+// templateVarPattern is a regex pattern to find template variables in the format {{name}}
+var templateVarPattern = regexp.MustCompile(`{{\s*([A-Za-z_][A-Za-z0-9_]*)\s*}}`)
+
+// TemplateVariables returns the unique variable names of a template in the order they first appear
+func TemplateVariables(template string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range templateVarPattern.FindAllStringSubmatch(template, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// ExpandTemplate replaces the variables of a template with their values.
+// Variables without a value are left in place.
+func ExpandTemplate(template string, values map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := templateVarPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// LoadPromptTemplates creates a command for each prompt template in the config
+func LoadPromptTemplates() []Command {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.PromptTemplates))
+	for name := range cfg.PromptTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	commands := make([]Command, 0, len(names))
+	for _, name := range names {
+		template := cfg.PromptTemplates[name]
+		commands = append(commands, Command{
+			ID:          PromptTemplatePrefix + name,
+			Title:       PromptTemplatePrefix + name,
+			Description: "Prompt template: " + summarizeTemplate(template),
+			Handler: func(cmd Command) tea.Cmd {
+				if argNames := TemplateVariables(template); len(argNames) > 0 {
+					// Ask for the variables, the expansion happens when the dialog closes
+					return util.CmdHandler(ShowMultiArgumentsDialogMsg{
+						CommandID: cmd.ID,
+						Content:   template,
+						ArgNames:  argNames,
+					})
+				}
+				return util.CmdHandler(PromptTemplateExpandedMsg{Text: template})
+			},
+		})
+	}
+	return commands
+}
+
+// summarizeTemplate shortens a template to its first line for the command description
+func summarizeTemplate(template string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(template), "\n")
+	if runes := []rune(line); len(runes) > 60 {
+		line = string(runes[:57]) + "..."
+	}
+	return line
+}
+
+// PromptTemplateExpandedMsg is sent when a prompt template has been filled in
+// and its text should be put in the editor
+type PromptTemplateExpandedMsg struct {
+	Text string
+}
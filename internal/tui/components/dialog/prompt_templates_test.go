@@ -0,0 +1,51 @@
+package dialog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	assert.Equal(t, []string{"file", "concern"}, TemplateVariables("Review {{file}} for {{ concern }} in {{file}}"))
+	assert.Empty(t, TemplateVariables("No variables, not even {{1st}} or { {x} }"))
+}
+
+func TestExpandTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]string
+		expected string
+	}{
+		{
+			name:     "substitutes every occurrence",
+			template: "Write tests for {{function}} in {{file}}, then run the tests of {{file}}",
+			values:   map[string]string{"function": "Parse", "file": "parser.go"},
+			expected: "Write tests for Parse in parser.go, then run the tests of parser.go",
+		},
+		{
+			name:     "allows spaces inside braces",
+			template: "Explain {{ topic }}",
+			values:   map[string]string{"topic": "channels"},
+			expected: "Explain channels",
+		},
+		{
+			name:     "keeps variables without a value",
+			template: "Compare {{a}} with {{b}}",
+			values:   map[string]string{"a": "v1"},
+			expected: "Compare v1 with {{b}}",
+		},
+		{
+			name:     "doesn't expand placeholders inside values",
+			template: "{{a}} and {{b}}",
+			values:   map[string]string{"a": "{{b}}", "b": "$B"},
+			expected: "{{b}} and $B",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExpandTemplate(tt.template, tt.values))
+		})
+	}
+}
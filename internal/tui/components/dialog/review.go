@@ -0,0 +1,246 @@
+package dialog
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zhenbah/cryoncode/internal/diff"
+	"github.com/zhenbah/cryoncode/internal/tui/layout"
+	"github.com/zhenbah/cryoncode/internal/tui/styles"
+	"github.com/zhenbah/cryoncode/internal/tui/theme"
+	"github.com/zhenbah/cryoncode/internal/tui/util"
+)
+
+// ShowReviewDialogMsg opens the review dialog with the output of git diff
+type ShowReviewDialogMsg struct {
+	Diff   string
+	Staged bool
+}
+
+// ReviewStagedMsg is sent to switch the review dialog between the changes in
+// the working tree and the staged ones
+type ReviewStagedMsg struct {
+	Staged bool
+}
+
+// CloseReviewDialogMsg is sent when the review dialog is closed
+type CloseReviewDialogMsg struct{}
+
+// ReviewDialogCmp interface for the dialog that shows uncommitted changes
+type ReviewDialogCmp interface {
+	tea.Model
+	layout.Bindings
+	SetDiff(diffText string, staged bool)
+}
+
+type reviewKeyMap struct {
+	Staged   key.Binding
+	Up       key.Binding
+	Down     key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	Escape   key.Binding
+}
+
+var reviewKeys = reviewKeyMap{
+	Staged: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "toggle staged changes"),
+	),
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "scroll up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "scroll down"),
+	),
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup", "b"),
+		key.WithHelp("pgup/b", "page up"),
+	),
+	PageDown: key.NewBinding(
+		key.WithKeys("pgdown", "f", " "),
+		key.WithHelp("pgdn/f", "page down"),
+	),
+	Escape: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc/q", "close"),
+	),
+}
+
+type reviewDialogCmp struct {
+	width    int
+	height   int
+	diffText string
+	staged   bool
+	files    int
+	viewport viewport.Model
+
+	// renderedWidth is the width the diff was rendered at, 0 when it has to
+	// be rendered again
+	renderedWidth int
+}
+
+func (r *reviewDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (r *reviewDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.width = int(float64(msg.Width) * 0.9)
+		r.height = int(float64(msg.Height) * 0.9)
+		r.renderedWidth = 0
+	case ThemeChangedMsg:
+		r.renderedWidth = 0
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, reviewKeys.Escape):
+			return r, util.CmdHandler(CloseReviewDialogMsg{})
+		case key.Matches(msg, reviewKeys.Staged):
+			return r, util.CmdHandler(ReviewStagedMsg{Staged: !r.staged})
+		case key.Matches(msg, reviewKeys.Up):
+			r.viewport.LineUp(1)
+		case key.Matches(msg, reviewKeys.Down):
+			r.viewport.LineDown(1)
+		case key.Matches(msg, reviewKeys.PageUp):
+			r.viewport.ViewUp()
+		case key.Matches(msg, reviewKeys.PageDown):
+			r.viewport.ViewDown()
+		}
+	}
+	return r, nil
+}
+
+func (r *reviewDialogCmp) SetDiff(diffText string, staged bool) {
+	r.diffText = diffText
+	r.staged = staged
+	r.files = len(diff.SplitFileDiffs(diffText))
+	r.renderedWidth = 0
+	r.viewport.GotoTop()
+}
+
+// renderDiff renders each file of the diff under a header with its name, in
+// the layout the diff view config picks for width.
+func (r *reviewDialogCmp) renderDiff(width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	files := diff.SplitFileDiffs(r.diffText)
+	if len(files) == 0 {
+		message := "No unstaged changes"
+		if r.staged {
+			message = "No staged changes"
+		}
+		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(message)
+	}
+
+	headerStyle := baseStyle.Width(width).Bold(true).Foreground(t.Primary())
+	noteStyle := baseStyle.Width(width).Foreground(t.TextMuted())
+	sections := make([]string, 0, len(files))
+	for _, fileDiff := range files {
+		parsed, err := diff.ParseUnifiedDiff(fileDiff)
+		if err != nil {
+			sections = append(sections, noteStyle.Render(fmt.Sprintf("Error parsing diff: %v", err)))
+			continue
+		}
+		name := cmp.Or(parsed.NewFile, parsed.OldFile, gitDiffFileName(fileDiff))
+		section := headerStyle.Render(name)
+		if len(parsed.Hunks) == 0 {
+			// Binary files and mode changes have no lines to show
+			section += "\n" + noteStyle.Render("No text changes")
+		} else {
+			rendered, err := diff.FormatDiff(fileDiff, diff.ViewOptions(width)...)
+			if err != nil {
+				rendered = noteStyle.Render(fmt.Sprintf("Error formatting diff: %v", err))
+			}
+			section += "\n" + strings.TrimSuffix(rendered, "\n")
+		}
+		sections = append(sections, section)
+	}
+	return strings.Join(sections, "\n"+baseStyle.Width(width).Render("")+"\n")
+}
+
+// gitDiffFileName returns the new name from the "diff --git a/old b/new"
+// line of a file's diff.
+func gitDiffFileName(fileDiff string) string {
+	header, _, _ := strings.Cut(fileDiff, "\n")
+	if _, name, ok := strings.Cut(header, " b/"); ok {
+		return name
+	}
+	return strings.TrimPrefix(header, "diff --git ")
+}
+
+func (r *reviewDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	contentWidth := max(r.width-4, 1)
+
+	titleText := "Unstaged Changes"
+	if r.staged {
+		titleText = "Staged Changes"
+	}
+	if r.files > 0 {
+		unit := "files"
+		if r.files == 1 {
+			unit = "file"
+		}
+		titleText += fmt.Sprintf(" (%d %s)", r.files, unit)
+	}
+	title := baseStyle.
+		Bold(true).
+		Width(contentWidth).
+		Foreground(t.Primary()).
+		Render(titleText)
+
+	otherSide := "staged"
+	if r.staged {
+		otherSide = "unstaged"
+	}
+	footer := baseStyle.
+		Width(contentWidth).
+		Foreground(t.TextMuted()).
+		Render(fmt.Sprintf("s: show %s changes  ↑/↓: scroll  esc: close", otherSide))
+
+	r.viewport.Width = contentWidth
+	r.viewport.Height = max(r.height-lipgloss.Height(title)-lipgloss.Height(footer)-4, 1)
+	if r.renderedWidth != contentWidth {
+		r.viewport.SetContent(r.renderDiff(contentWidth))
+		r.renderedWidth = contentWidth
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		baseStyle.Width(contentWidth).Render(""),
+		lipgloss.NewStyle().Background(t.Background()).Render(r.viewport.View()),
+		baseStyle.Width(contentWidth).Render(""),
+		footer,
+	)
+
+	return baseStyle.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(r.width).
+		Render(content)
+}
+
+func (r *reviewDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(reviewKeys)
+}
+
+// NewReviewDialogCmp creates the dialog that shows the output of git diff
+func NewReviewDialogCmp() ReviewDialogCmp {
+	return &reviewDialogCmp{
+		viewport: viewport.New(0, 0),
+	}
+}
@@ -0,0 +1,59 @@
+package dialog
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/tui/theme"
+)
+
+const reviewDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package old
++package main
+diff --git a/logo.png b/logo.png
+index 3333333..4444444 100644
+Binary files a/logo.png and b/logo.png differ
+`
+
+func TestReviewDialogShowsEachFile(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	require.NoError(t, theme.SetTheme("cryoncode"))
+
+	review := NewReviewDialogCmp()
+	review.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	review.SetDiff(reviewDiff, false)
+
+	view := ansi.Strip(review.View())
+	assert.Contains(t, view, "Unstaged Changes (2 files)")
+	assert.Contains(t, view, "main.go")
+	assert.Contains(t, view, "package main")
+	assert.Contains(t, view, "logo.png")
+	assert.Contains(t, view, "No text changes")
+
+	review.SetDiff("", true)
+	view = ansi.Strip(review.View())
+	assert.Contains(t, view, "Staged Changes")
+	assert.Contains(t, view, "No staged changes")
+}
+
+func TestReviewDialogTogglesStaged(t *testing.T) {
+	review := NewReviewDialogCmp()
+	review.SetDiff(reviewDiff, true)
+
+	_, cmd := review.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	require.NotNil(t, cmd)
+	assert.Equal(t, ReviewStagedMsg{Staged: false}, cmd())
+
+	_, cmd = review.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	require.NotNil(t, cmd)
+	assert.Equal(t, CloseReviewDialogMsg{}, cmd())
+}
@@ -125,9 +125,11 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		p.completionDialog = context.(dialog.CompletionDialog)
 		cmds = append(cmds, contextCmd)
 
-		// Doesn't forward event if enter key is pressed
+		// Doesn't forward keys that select a completion, so that the editor
+		// doesn't also send the message or recall a prompt
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
-			if keyMsg.String() == "enter" {
+			switch keyMsg.String() {
+			case "enter", "up", "down":
 				return p, tea.Batch(cmds...)
 			}
 		}
@@ -0,0 +1,126 @@
+package theme
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// AutoThemeName selects the dark or light variant of the default theme to
+// match the terminal background.
+const AutoThemeName = "auto"
+
+// autoBaseTheme is the theme whose variants the auto theme switches between.
+const autoBaseTheme = "cryoncode"
+
+// Mode 2031 asks the terminal to report color scheme changes. Terminals that
+// support it send CSI ? 997 ; 1 n when they switch to a dark background and
+// CSI ? 997 ; 2 n when they switch to a light one; others ignore the request.
+const (
+	EnableBackgroundReports  = "\x1b[?2031h"
+	DisableBackgroundReports = "\x1b[?2031l"
+)
+
+// Bubble Tea reports CSI sequences it does not know as messages that print as
+// "?CSI[<bytes after ESC [>]?".
+var (
+	darkBackgroundReport  = fmt.Sprintf("?CSI%+v?", []byte("?997;1n"))
+	lightBackgroundReport = fmt.Sprintf("?CSI%+v?", []byte("?997;2n"))
+)
+
+type themeVariants struct {
+	dark  Theme
+	light Theme
+}
+
+// RegisterThemeVariants registers fixed dark and light variants of a theme as
+// "<name>-dark" and "<name>-light".
+func RegisterThemeVariants(name string, dark, light Theme) {
+	RegisterTheme(name+"-dark", dark)
+	RegisterTheme(name+"-light", light)
+
+	globalManager.mu.Lock()
+	defer globalManager.mu.Unlock()
+	globalManager.variants[name] = themeVariants{dark: dark, light: light}
+}
+
+// SetDarkBackground records whether the terminal background is dark. It reports
+// whether the active theme changed as a result. Reports are ignored once the
+// background has been forced.
+func SetDarkBackground(dark bool) bool {
+	globalManager.mu.Lock()
+	defer globalManager.mu.Unlock()
+
+	if globalManager.backgroundForced {
+		return false
+	}
+	lipgloss.SetHasDarkBackground(dark)
+
+	changed := !globalManager.backgroundDetected || globalManager.darkBackground != dark
+	globalManager.darkBackground = dark
+	globalManager.backgroundDetected = true
+	return changed && globalManager.currentName == AutoThemeName
+}
+
+// ForceBackground overrides the detected terminal background for every
+// adaptive color: themes, the auto theme, markdown and diffs. It is meant for
+// terminals that misreport their background.
+func ForceBackground(dark bool) {
+	globalManager.mu.Lock()
+	defer globalManager.mu.Unlock()
+
+	lipgloss.SetHasDarkBackground(dark)
+	globalManager.darkBackground = dark
+	globalManager.backgroundDetected = true
+	globalManager.backgroundForced = true
+}
+
+// ParseBackgroundReport reports whether msg is a terminal's notification that
+// its background changed, and if so whether the new background is dark.
+func ParseBackgroundReport(msg any) (dark bool, ok bool) {
+	stringer, isStringer := msg.(fmt.Stringer)
+	if !isStringer {
+		return false, false
+	}
+	switch stringer.String() {
+	case darkBackgroundReport:
+		return true, true
+	case lightBackgroundReport:
+		return false, true
+	}
+	return false, false
+}
+
+// autoTheme returns the variant of autoBaseTheme that matches the terminal
+// background. The caller must hold the manager lock.
+func (m *Manager) autoTheme() Theme {
+	variants, ok := m.variants[autoBaseTheme]
+	if !ok {
+		return nil
+	}
+	if m.darkBackground {
+		return variants.dark
+	}
+	return variants.light
+}
+
+// fixedVariant returns a copy of t that uses its dark or light colors on every
+// terminal.
+func fixedVariant(t Theme, dark bool) Theme {
+	variant := &BaseTheme{}
+	fields := reflect.ValueOf(variant).Elem()
+	source := reflect.ValueOf(t)
+	themeType := reflect.TypeOf((*Theme)(nil)).Elem()
+
+	for i := range themeType.NumMethod() {
+		method := themeType.Method(i).Name
+		color := source.MethodByName(method).Call(nil)[0].Interface().(lipgloss.AdaptiveColor)
+		value := color.Light
+		if dark {
+			value = color.Dark
+		}
+		fields.FieldByName(method + "Color").Set(reflect.ValueOf(lipgloss.AdaptiveColor{Dark: value, Light: value}))
+	}
+	return variant
+}
@@ -0,0 +1,55 @@
+package theme
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type csiMsg []byte
+
+func (m csiMsg) String() string {
+	return fmt.Sprintf("?CSI%+v?", []byte(m)[2:])
+}
+
+func TestAutoThemeFollowsBackground(t *testing.T) {
+	previous := CurrentThemeName()
+	t.Cleanup(func() { _ = UseTheme(previous) })
+
+	cryoncode := GetTheme("cryoncode")
+	require.NotNil(t, cryoncode)
+	assert.Contains(t, AvailableThemes(), AutoThemeName)
+
+	SetDarkBackground(true)
+	require.NoError(t, UseTheme(AutoThemeName))
+	assert.Equal(t, AutoThemeName, CurrentThemeName())
+	assert.Equal(t, cryoncode.Background().Dark, CurrentTheme().Background().Dark)
+	assert.Equal(t, cryoncode.Background().Dark, CurrentTheme().Background().Light)
+
+	assert.True(t, SetDarkBackground(false))
+	assert.Equal(t, cryoncode.Primary().Light, CurrentTheme().Primary().Dark)
+	assert.Equal(t, cryoncode.Primary().Light, CurrentTheme().Primary().Light)
+	assert.Same(t, GetTheme("cryoncode-light"), CurrentTheme())
+
+	assert.False(t, SetDarkBackground(false), "an unchanged background does not change the theme")
+
+	require.NoError(t, UseTheme("cryoncode"))
+	assert.False(t, SetDarkBackground(true), "only the auto theme follows the background")
+}
+
+func TestParseBackgroundReport(t *testing.T) {
+	dark, ok := ParseBackgroundReport(csiMsg("\x1b[?997;1n"))
+	assert.True(t, ok)
+	assert.True(t, dark)
+
+	dark, ok = ParseBackgroundReport(csiMsg("\x1b[?997;2n"))
+	assert.True(t, ok)
+	assert.False(t, dark)
+
+	_, ok = ParseBackgroundReport(csiMsg("\x1b[?1004h"))
+	assert.False(t, ok)
+	_, ok = ParseBackgroundReport("?997;1n")
+	assert.False(t, ok)
+}
@@ -272,6 +272,8 @@ func NewCryoncodeTheme() *CryoncodeTheme {
 
 func init() {
 	// Register the Cryoncode theme with the theme manager
-	RegisterTheme("cryoncode", NewCryoncodeTheme())
+	cryoncode := NewCryoncodeTheme()
+	RegisterTheme("cryoncode", cryoncode)
+	RegisterThemeVariants("cryoncode", fixedVariant(cryoncode, true), fixedVariant(cryoncode, false))
 }
 
@@ -0,0 +1,96 @@
+package theme
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/viper"
+)
+
+// CustomThemeName is the name the theme loaded from tui.customThemePath is
+// registered under.
+const CustomThemeName = "custom"
+
+// colorValue matches the color formats lipgloss understands: hex colors and
+// ANSI color numbers.
+var colorValue = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3}|[0-9]{1,3})$`)
+
+// LoadCustomTheme reads a theme from a JSON or TOML file. The file must define
+// every color of the Theme interface, keyed by the method name in camelCase
+// (for example "background" or "diffAddedBg"). A color is either a single value
+// used on dark and light terminals, or an object with "dark" and "light" keys.
+func LoadCustomTheme(path string) (Theme, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read custom theme %s: %w", path, err)
+	}
+
+	theme := &BaseTheme{}
+	fields := reflect.ValueOf(theme).Elem()
+	themeType := reflect.TypeOf((*Theme)(nil)).Elem()
+
+	var missing, invalid []string
+	for i := range themeType.NumMethod() {
+		method := themeType.Method(i).Name
+		key := colorKey(method)
+		if !v.IsSet(key) {
+			missing = append(missing, key)
+			continue
+		}
+		color, err := parseColor(v.Get(key))
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s (%v)", key, err))
+			continue
+		}
+		fields.FieldByName(method + "Color").Set(reflect.ValueOf(color))
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing colors: %s", strings.Join(missing, ", ")))
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, fmt.Sprintf("invalid colors: %s", strings.Join(invalid, ", ")))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("custom theme %s is incomplete; %s", path, strings.Join(problems, "; "))
+	}
+	return theme, nil
+}
+
+func colorKey(method string) string {
+	runes := []rune(method)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func parseColor(value any) (lipgloss.AdaptiveColor, error) {
+	switch value := value.(type) {
+	case int, int64, float64:
+		// ANSI color numbers written without quotes
+		return parseColor(fmt.Sprint(value))
+	case string:
+		if !colorValue.MatchString(value) {
+			return lipgloss.AdaptiveColor{}, fmt.Errorf("%q is not a hex color or ANSI color number", value)
+		}
+		return lipgloss.AdaptiveColor{Dark: value, Light: value}, nil
+	case map[string]any:
+		dark, darkOK := value["dark"].(string)
+		light, lightOK := value["light"].(string)
+		if !darkOK || !lightOK {
+			return lipgloss.AdaptiveColor{}, fmt.Errorf("both dark and light must be set")
+		}
+		for _, color := range []string{dark, light} {
+			if !colorValue.MatchString(color) {
+				return lipgloss.AdaptiveColor{}, fmt.Errorf("%q is not a hex color or ANSI color number", color)
+			}
+		}
+		return lipgloss.AdaptiveColor{Dark: dark, Light: light}, nil
+	}
+	return lipgloss.AdaptiveColor{}, fmt.Errorf("expected a color or an object with dark and light colors")
+}
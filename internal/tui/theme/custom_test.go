@@ -0,0 +1,87 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// themeColors returns every color of theme keyed as in a custom theme file.
+func themeColors(theme Theme) map[string]lipgloss.AdaptiveColor {
+	colors := make(map[string]lipgloss.AdaptiveColor)
+	value := reflect.ValueOf(theme)
+	themeType := reflect.TypeOf((*Theme)(nil)).Elem()
+	for i := range themeType.NumMethod() {
+		method := themeType.Method(i).Name
+		color := value.MethodByName(method).Call(nil)[0].Interface().(lipgloss.AdaptiveColor)
+		colors[colorKey(method)] = color
+	}
+	return colors
+}
+
+func writeThemeFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0o644))
+	return path
+}
+
+func TestLoadCustomThemeJSON(t *testing.T) {
+	expected := themeColors(NewDraculaTheme())
+	file := make(map[string]any, len(expected))
+	for key, color := range expected {
+		file[key] = map[string]string{"dark": color.Dark, "light": color.Light}
+	}
+	// A single value applies to both dark and light terminals.
+	file["background"] = "#101010"
+	expected["background"] = lipgloss.AdaptiveColor{Dark: "#101010", Light: "#101010"}
+
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+
+	theme, err := LoadCustomTheme(writeThemeFile(t, "theme.json", data))
+	require.NoError(t, err)
+	assert.Equal(t, expected, themeColors(theme))
+}
+
+func TestLoadCustomThemeTOML(t *testing.T) {
+	var content strings.Builder
+	for key := range themeColors(NewDraculaTheme()) {
+		fmt.Fprintf(&content, "%s = \"#abcdef\"\n", key)
+	}
+	content.WriteString("\n[syntaxKeyword]\ndark = \"#ff79c6\"\nlight = \"212\"\n")
+	withoutKeyword := strings.Replace(content.String(), "syntaxKeyword = \"#abcdef\"\n", "", 1)
+
+	theme, err := LoadCustomTheme(writeThemeFile(t, "theme.toml", []byte(withoutKeyword)))
+	require.NoError(t, err)
+	assert.Equal(t, lipgloss.AdaptiveColor{Dark: "#abcdef", Light: "#abcdef"}, theme.DiffAddedBg())
+	assert.Equal(t, lipgloss.AdaptiveColor{Dark: "#ff79c6", Light: "212"}, theme.SyntaxKeyword())
+}
+
+func TestLoadCustomThemeMissingColors(t *testing.T) {
+	file := make(map[string]any)
+	for key := range themeColors(NewDraculaTheme()) {
+		file[key] = "#ffffff"
+	}
+	delete(file, "diffAddedBg")
+	delete(file, "syntaxKeyword")
+	file["text"] = "white"
+	file["primary"] = map[string]string{"dark": "#000000"}
+
+	data, err := json.Marshal(file)
+	require.NoError(t, err)
+
+	_, err = LoadCustomTheme(writeThemeFile(t, "theme.json", data))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing colors: diffAddedBg, syntaxKeyword")
+	assert.Contains(t, err.Error(), "primary (both dark and light must be set)")
+	assert.Contains(t, err.Error(), `text ("white" is not a hex color or ANSI color number)`)
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/zhenbah/cryoncode/internal/config"
 	"github.com/zhenbah/cryoncode/internal/logging"
 )
@@ -15,13 +16,22 @@ import (
 // It maintains a registry of available themes and tracks the currently active theme.
 type Manager struct {
 	themes      map[string]Theme
+	variants    map[string]themeVariants
 	currentName string
 	mu          sync.RWMutex
+
+	// darkBackground is the terminal background the auto theme matches. It is
+	// detected when the auto theme is first used unless backgroundDetected is
+	// already set, and never changes once backgroundForced is set.
+	darkBackground     bool
+	backgroundDetected bool
+	backgroundForced   bool
 }
 
 // Global instance of the theme manager
 var globalManager = &Manager{
 	themes:      make(map[string]Theme),
+	variants:    make(map[string]themeVariants),
 	currentName: "",
 }
 
@@ -39,19 +49,14 @@ func RegisterTheme(name string, theme Theme) {
 	}
 }
 
-// SetTheme changes the active theme to the one with the specified name.
+// SetTheme changes the active theme to the one with the specified name and
+// saves it to the config file.
 // Returns an error if the theme doesn't exist.
 func SetTheme(name string) error {
-	globalManager.mu.Lock()
-	defer globalManager.mu.Unlock()
-
-	delete(styles.Registry, "charm")
-	if _, exists := globalManager.themes[name]; !exists {
-		return fmt.Errorf("theme '%s' not found", name)
+	if err := UseTheme(name); err != nil {
+		return err
 	}
 
-	globalManager.currentName = name
-
 	// Update the config file using viper
 	if err := updateConfigTheme(name); err != nil {
 		// Log the error but don't fail the theme change
@@ -61,6 +66,29 @@ func SetTheme(name string) error {
 	return nil
 }
 
+// UseTheme changes the active theme without saving it to the config file.
+// Returns an error if the theme doesn't exist.
+func UseTheme(name string) error {
+	globalManager.mu.Lock()
+	defer globalManager.mu.Unlock()
+
+	delete(styles.Registry, "charm")
+	if name == AutoThemeName {
+		if _, exists := globalManager.variants[autoBaseTheme]; !exists {
+			return fmt.Errorf("theme '%s' not found", name)
+		}
+		if !globalManager.backgroundDetected {
+			globalManager.darkBackground = lipgloss.HasDarkBackground()
+			globalManager.backgroundDetected = true
+		}
+	} else if _, exists := globalManager.themes[name]; !exists {
+		return fmt.Errorf("theme '%s' not found", name)
+	}
+
+	globalManager.currentName = name
+	return nil
+}
+
 // CurrentTheme returns the currently active theme.
 // If no theme is set, it returns nil.
 func CurrentTheme() Theme {
@@ -70,6 +98,9 @@ func CurrentTheme() Theme {
 	if globalManager.currentName == "" {
 		return nil
 	}
+	if globalManager.currentName == AutoThemeName {
+		return globalManager.autoTheme()
+	}
 
 	return globalManager.themes[globalManager.currentName]
 }
@@ -87,10 +118,13 @@ func AvailableThemes() []string {
 	globalManager.mu.RLock()
 	defer globalManager.mu.RUnlock()
 
-	names := make([]string, 0, len(globalManager.themes))
+	names := make([]string, 0, len(globalManager.themes)+1)
 	for name := range globalManager.themes {
 		names = append(names, name)
 	}
+	if _, exists := globalManager.variants[autoBaseTheme]; exists {
+		names = append(names, AutoThemeName)
+	}
 	slices.SortFunc(names, func(a, b string) int {
 		if a == "cryoncode" {
 			return -1
@@ -108,6 +142,9 @@ func GetTheme(name string) Theme {
 	globalManager.mu.RLock()
 	defer globalManager.mu.RUnlock()
 
+	if name == AutoThemeName {
+		return globalManager.autoTheme()
+	}
 	return globalManager.themes[name]
 }
 
@@ -0,0 +1,89 @@
+package theme
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/logging"
+)
+
+// configReloadDebounce is how long the config files must stay unchanged before
+// they are read again, so that an editor's burst of writes causes one reload.
+const configReloadDebounce = 250 * time.Millisecond
+
+// WatchConfig watches the config files for external edits to the theme. When
+// the theme on disk changes, it is applied with UseTheme and its name is sent on
+// the returned channel. Other config changes are ignored. The channel is closed
+// when ctx is done.
+func WatchConfig(ctx context.Context) <-chan string {
+	ch := make(chan string)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logging.Warn("Failed to watch config files for theme changes", "error", err)
+		close(ch)
+		return ch
+	}
+
+	// Watch the directories rather than the files, since editors often replace
+	// the file on save and a local config may not exist yet.
+	files := make(map[string]bool)
+	for _, file := range config.ConfigFiles() {
+		file = filepath.Clean(file)
+		files[file] = true
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			logging.Debug("Failed to watch config directory", "dir", filepath.Dir(file), "error", err)
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		defer watcher.Close()
+
+		timer := time.NewTimer(configReloadDebounce)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(event.Name)] {
+					timer.Reset(configReloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logging.Debug("Config watcher error", "error", err)
+			case <-timer.C:
+				name, err := config.ReadTheme()
+				if err != nil {
+					logging.Debug("Failed to reload theme from config", "error", err)
+					continue
+				}
+				if name == CurrentThemeName() {
+					continue
+				}
+				if err := UseTheme(name); err != nil {
+					logging.Warn("Theme from config file is not available", "theme", name, "error", err)
+					continue
+				}
+				select {
+				case ch <- name:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch
+}
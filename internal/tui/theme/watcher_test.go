@@ -0,0 +1,56 @@
+package theme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhenbah/cryoncode/internal/config"
+)
+
+func TestWatchConfigAppliesThemeChanges(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	workingDir := t.TempDir()
+	_, err := config.Load(workingDir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, UseTheme("cryoncode"))
+	t.Cleanup(func() { UseTheme("cryoncode") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := WatchConfig(ctx)
+
+	localConfig := filepath.Join(workingDir, ".cryoncode.json")
+	writeTheme := func(name string) {
+		require.NoError(t, os.WriteFile(localConfig, []byte(`{"tui": {"theme": "`+name+`"}}`), 0o644))
+	}
+
+	// A burst of writes is applied once, with the final theme.
+	writeTheme("gruvbox")
+	writeTheme("monokai")
+	writeTheme("dracula")
+	select {
+	case name := <-changes:
+		assert.Equal(t, "dracula", name)
+		assert.Equal(t, "dracula", CurrentThemeName())
+	case <-time.After(5 * time.Second):
+		t.Fatal("theme change was not detected")
+	}
+
+	// Non-theme changes and unknown themes are ignored.
+	require.NoError(t, os.WriteFile(localConfig, []byte(`{"tui": {"theme": "dracula"}, "debug": true}`), 0o644))
+	writeTheme("does-not-exist")
+	select {
+	case name := <-changes:
+		t.Fatalf("unexpected theme change to %s", name)
+	case <-time.After(4 * configReloadDebounce):
+	}
+	assert.Equal(t, "dracula", CurrentThemeName())
+}
@@ -2,15 +2,22 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/zhenbah/cryoncode/internal/app"
 	"github.com/zhenbah/cryoncode/internal/config"
+	"github.com/zhenbah/cryoncode/internal/fileutil"
 	"github.com/zhenbah/cryoncode/internal/llm/agent"
+	"github.com/zhenbah/cryoncode/internal/llm/tools"
 	"github.com/zhenbah/cryoncode/internal/logging"
 	"github.com/zhenbah/cryoncode/internal/permission"
 	"github.com/zhenbah/cryoncode/internal/pubsub"
@@ -37,6 +44,18 @@ type keyMap struct {
 
 type startCompactSessionMsg struct{}
 
+type startContinueSessionMsg struct{}
+
+type restoreTrashMsg struct{}
+
+type purgeTrashMsg struct{}
+
+type undoLastEditMsg struct{}
+
+// sessionEnvCommandID identifies the arguments dialog of the command that sets
+// the current session's bash environment.
+const sessionEnvCommandID = "session_env"
+
 const (
 	quitKey = "q"
 )
@@ -80,6 +99,28 @@ var keys = keyMap{
 	),
 }
 
+// applyKeybindings replaces the keys of the actions set in the keybindings
+// config. Actions that are not set keep their default keys.
+func applyKeybindings(km *keyMap, keybindings map[string]string) {
+	bindings := map[string]*key.Binding{
+		"logs":          &km.Logs,
+		"quit":          &km.Quit,
+		"switchSession": &km.SwitchSession,
+		"commands":      &km.Commands,
+		"filepicker":    &km.Filepicker,
+		"models":        &km.Models,
+		"switchTheme":   &km.SwitchTheme,
+	}
+	for action, k := range keybindings {
+		binding, ok := bindings[action]
+		if !ok {
+			continue
+		}
+		binding.SetKeys(k)
+		binding.SetHelp(k, binding.Help().Desc)
+	}
+}
+
 var helpEsc = key.NewBinding(
 	key.WithKeys("?"),
 	key.WithHelp("?", "toggle help"),
@@ -136,8 +177,14 @@ type appModel struct {
 	showMultiArgumentsDialog bool
 	multiArgumentsDialog     dialog.MultiArgumentsDialogCmp
 
+	showReviewDialog bool
+	reviewDialog     dialog.ReviewDialogCmp
+
 	isCompacting      bool
 	compactingMessage string
+
+	// resumeLastSession opens the most recently updated session on startup
+	resumeLastSession bool
 }
 
 func (a appModel) Init() tea.Cmd {
@@ -176,12 +223,52 @@ func (a appModel) Init() tea.Cmd {
 		return dialog.ShowInitDialogMsg{Show: shouldShow}
 	})
 
+	if a.resumeLastSession {
+		cmds = append(cmds, a.resumeLastSessionCmd())
+	}
+
+	// Warn about MCP servers whose tools could not be registered
+	if failed := agent.FailedMcpServers(); len(failed) > 0 {
+		cmds = append(cmds, util.CmdHandler(util.InfoMsg{
+			Type: util.InfoTypeWarn,
+			Msg:  fmt.Sprintf("MCP servers not responding, their tools are unavailable: %s", strings.Join(failed, ", ")),
+			TTL:  30 * time.Second,
+		}))
+	}
+
 	return tea.Batch(cmds...)
 }
 
+// resumeLastSessionCmd opens the most recently updated session. Without any
+// sessions the chat page starts a new one as usual.
+func (a appModel) resumeLastSessionCmd() tea.Cmd {
+	return func() tea.Msg {
+		sessions, err := a.app.Sessions.List(context.Background())
+		if err != nil {
+			return util.InfoMsg{
+				Type: util.InfoTypeError,
+				Msg:  "Failed to resume the last session: " + err.Error(),
+			}
+		}
+		latest, ok := session.MostRecentlyUpdated(sessions)
+		if !ok {
+			return nil
+		}
+		return chat.SessionSelectedMsg(latest)
+	}
+}
+
 func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
+	if dark, ok := theme.ParseBackgroundReport(msg); ok {
+		if !theme.SetDarkBackground(dark) {
+			return a, nil
+		}
+		a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(dialog.ThemeChangedMsg{ThemeName: theme.CurrentThemeName()})
+		return a, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		msg.Height -= 1 // Make space for the status bar
@@ -212,6 +299,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.filepicker = filepicker.(dialog.FilepickerCmp)
 		cmds = append(cmds, filepickerCmd)
 
+		review, reviewCmd := a.reviewDialog.Update(msg)
+		a.reviewDialog = review.(dialog.ReviewDialogCmp)
+		cmds = append(cmds, reviewCmd)
+
 		a.initDialog.SetSize(msg.Width, msg.Height)
 
 		if a.showMultiArgumentsDialog {
@@ -273,6 +364,13 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Permission
 	case pubsub.Event[permission.PermissionRequest]:
+		if msg.Type == permission.AutoApprovedEvent {
+			return a, util.CmdHandler(util.InfoMsg{
+				Type: util.InfoTypeInfo,
+				Msg:  fmt.Sprintf("Auto-approved %s (%s)", msg.Payload.ToolName, msg.Payload.Action),
+				TTL:  3 * time.Second,
+			})
+		}
 		a.showPermissions = true
 		return a, a.permissions.SetPermissions(msg.Payload)
 	case dialog.PermissionResponseMsg:
@@ -280,7 +378,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.Action {
 		case dialog.PermissionAllow:
 			a.app.Permissions.Grant(msg.Permission)
-		case dialog.PermissionAllowForSession:
+		case dialog.PermissionAllowForSession, dialog.PermissionAllowForPath:
 			a.app.Permissions.GrantPersistant(msg.Permission)
 		case dialog.PermissionDeny:
 			a.app.Permissions.Deny(msg.Permission)
@@ -303,6 +401,18 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showCommandDialog = false
 		return a, nil
 
+	case dialog.ShowReviewDialogMsg:
+		a.reviewDialog.SetDiff(msg.Diff, msg.Staged)
+		a.showReviewDialog = true
+		return a, nil
+
+	case dialog.ReviewStagedMsg:
+		return a, a.loadReview(msg.Staged)
+
+	case dialog.CloseReviewDialogMsg:
+		a.showReviewDialog = false
+		return a, nil
+
 	case startCompactSessionMsg:
 		// Start compacting the current session
 		a.isCompacting = true
@@ -320,8 +430,50 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return nil
 		}
 
+	case chat.DiffViewChangedMsg:
+		a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
+		return a, tea.Batch(cmd, util.ReportInfo(fmt.Sprintf("Diff view: %s", msg.View)))
+
+	case startContinueSessionMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to continue")
+		}
+		// The summary is written in the background and reported by agent events
+		if err := a.app.CoderAgent.ContinueInNewSession(context.Background(), a.selectedSession.ID); err != nil {
+			return a, util.ReportError(err)
+		}
+		a.isCompacting = true
+		a.compactingMessage = "Starting summarization..."
+		return a, nil
+
+	case restoreTrashMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to restore files for")
+		}
+		return a, a.restoreTrash(a.selectedSession.ID)
+
+	case undoLastEditMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to undo an edit in")
+		}
+		return a, a.undoLastEdit(a.selectedSession.ID)
+
+	case purgeTrashMsg:
+		if a.selectedSession.ID == "" {
+			return a, util.ReportWarn("No active session to empty the trash for")
+		}
+		if err := fileutil.PurgeTrash(config.TrashDirectory(a.selectedSession.ID)); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo("Trash emptied")
+
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
+		if payload.Type == agent.AgentEventTypeUsage || payload.Type == agent.AgentEventTypePromptTokens {
+			s, _ := a.status.Update(msg)
+			a.status = s.(core.StatusCmp)
+			return a, nil
+		}
 		if payload.Error != nil {
 			a.isCompacting = false
 			return a, util.ReportError(payload.Error)
@@ -331,12 +483,15 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if payload.Done && payload.Type == agent.AgentEventTypeSummarize {
 			a.isCompacting = false
+			if payload.SessionID != "" && payload.SessionID != a.selectedSession.ID {
+				return a, a.openContinuedSession(payload.SessionID)
+			}
 			return a, util.ReportInfo("Session summarization complete")
 		} else if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSession.ID != "" {
 			model := a.app.CoderAgent.Model()
 			contextWindow := model.ContextWindow
 			tokens := a.selectedSession.CompletionTokens + a.selectedSession.PromptTokens
-			if (tokens >= int64(float64(contextWindow)*0.95)) && config.Get().AutoCompact {
+			if (tokens >= int64(float64(contextWindow)*0.95)) && config.Get().AutoCompact && config.Get().ContextStrategy != config.ContextStrategyTruncate {
 				return a, util.CmdHandler(startCompactSessionMsg{})
 			}
 		}
@@ -349,6 +504,8 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case dialog.ThemeChangedMsg:
 		a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
+		review, _ := a.reviewDialog.Update(msg)
+		a.reviewDialog = review.(dialog.ReviewDialogCmp)
 		a.showThemeDialog = false
 		return a, tea.Batch(cmd, util.ReportInfo("Theme changed to: "+msg.ThemeName))
 
@@ -424,6 +581,33 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Close multi-arguments dialog
 		a.showMultiArgumentsDialog = false
 
+		if msg.CommandID == sessionEnvCommandID {
+			if !msg.Submit {
+				return a, nil
+			}
+			if a.selectedSession.ID == "" {
+				return a, util.ReportWarn("No active session to set the environment for")
+			}
+			env := strings.Fields(msg.Args["VARIABLES"])
+			for _, entry := range env {
+				if key, _, ok := strings.Cut(entry, "="); !ok || !config.ValidEnvName(key) {
+					return a, util.ReportWarn(fmt.Sprintf("Not a KEY=VALUE variable: %s", entry))
+				}
+			}
+			tools.SetSessionEnv(a.selectedSession.ID, env)
+			if len(env) == 0 {
+				return a, util.ReportInfo("Session environment cleared")
+			}
+			return a, util.ReportInfo(fmt.Sprintf("Session environment set: %s", strings.Join(env, " ")))
+		}
+
+		// Prompt templates are expanded into the editor instead of being sent
+		if msg.Submit && strings.HasPrefix(msg.CommandID, dialog.PromptTemplatePrefix) {
+			return a, util.CmdHandler(dialog.PromptTemplateExpandedMsg{
+				Text: dialog.ExpandTemplate(msg.Content, msg.Args),
+			})
+		}
+
 		// If submitted, replace all named arguments and run the command
 		if msg.Submit {
 			content := msg.Content
@@ -473,6 +657,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.showMultiArgumentsDialog {
 				a.showMultiArgumentsDialog = false
 			}
+			if a.showReviewDialog {
+				a.showReviewDialog = false
+			}
 			return a, nil
 		case key.Matches(msg, keys.SwitchSession):
 			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showCommandDialog {
@@ -490,7 +677,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return a, nil
 		case key.Matches(msg, keys.Commands):
-			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showSessionDialog && !a.showThemeDialog && !a.showFilepicker {
+			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showSessionDialog && !a.showThemeDialog && !a.showFilepicker && !a.showReviewDialog {
 				// Show commands dialog
 				if len(a.commands) == 0 {
 					return a, util.ReportWarn("No commands available")
@@ -656,6 +843,16 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if a.showReviewDialog {
+		d, reviewCmd := a.reviewDialog.Update(msg)
+		a.reviewDialog = d.(dialog.ReviewDialogCmp)
+		cmds = append(cmds, reviewCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
 	s, _ := a.status.Update(msg)
 	a.status = s.(core.StatusCmp)
 	a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
@@ -677,6 +874,145 @@ func (a *appModel) findCommand(id string) (dialog.Command, bool) {
 	return dialog.Command{}, false
 }
 
+// restoreTrash moves the files deleted in a session back to where they were and
+// records the restored content in the file history.
+func (a appModel) restoreTrash(sessionID string) tea.Cmd {
+	return func() tea.Msg {
+		trashDir := config.TrashDirectory(sessionID)
+		entries, err := fileutil.ListTrash(trashDir)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		if len(entries) == 0 {
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: "No deleted files to restore"}
+		}
+
+		restored := 0
+		var failed []string
+		for _, entry := range entries {
+			if err := fileutil.RestoreFromTrash(trashDir, entry); err != nil {
+				logging.Warn("Failed to restore file from trash", "path", entry.OriginalPath, "error", err)
+				failed = append(failed, entry.OriginalPath)
+				continue
+			}
+			restored++
+			content, err := os.ReadFile(entry.OriginalPath)
+			if err != nil {
+				continue
+			}
+			if _, err := a.app.History.CreateVersion(context.Background(), sessionID, entry.OriginalPath, string(content)); err != nil {
+				logging.Debug("Error creating file history version", "error", err)
+			}
+		}
+		if len(failed) > 0 {
+			return util.InfoMsg{
+				Type: util.InfoTypeWarn,
+				Msg:  fmt.Sprintf("Restored %d file(s), could not restore: %s", restored, strings.Join(failed, ", ")),
+			}
+		}
+		return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Restored %d file(s)", restored)}
+	}
+}
+
+// undoLastEdit restores the files changed by the most recent edit of a
+// session. The permission prompt for the restore is shown like any other, so
+// the work happens outside the update loop.
+func (a appModel) undoLastEdit(sessionID string) tea.Cmd {
+	return func() tea.Msg {
+		paths, err := tools.UndoLastEdit(context.Background(), sessionID, a.app.Permissions, a.app.History)
+		switch {
+		case errors.Is(err, tools.ErrNothingToUndo):
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: "No edits to undo"}
+		case errors.Is(err, permission.ErrorPermissionDenied):
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: "Undo cancelled"}
+		case errors.Is(err, tools.ErrUndoDryRun):
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: "Dry run is on, the edit was not undone"}
+		case err != nil:
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Restored %s", strings.Join(paths, ", "))}
+	}
+}
+
+// loadReview runs git diff in the working directory for the review dialog,
+// for the staged changes when staged is set. git can take a while in large
+// repositories, so the work happens outside the update loop.
+func (a appModel) loadReview(staged bool) tea.Cmd {
+	return func() tea.Msg {
+		args := []string{
+			"-C", config.WorkingDirectory(), "diff", "--no-color", "--no-ext-diff",
+			"--src-prefix=a/", "--dst-prefix=b/",
+			fmt.Sprintf("-U%d", config.Get().TUI.DiffContextLines),
+		}
+		if staged {
+			args = append(args, "--staged")
+		}
+		output, err := exec.Command("git", args...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				err = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: "Failed to run git diff: " + err.Error()}
+		}
+		return dialog.ShowReviewDialogMsg{Diff: string(output), Staged: staged}
+	}
+}
+
+// nextDiffView returns the diff layout that follows view: auto, then split,
+// then unified.
+func nextDiffView(view config.DiffView) config.DiffView {
+	switch view {
+	case config.DiffViewAuto:
+		return config.DiffViewSplit
+	case config.DiffViewSplit:
+		return config.DiffViewUnified
+	default:
+		return config.DiffViewAuto
+	}
+}
+
+// restartableLSPServers returns the names of the enabled LSP servers, sorted.
+func restartableLSPServers() []string {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+	var names []string
+	for name, lspConfig := range cfg.LSP {
+		if !lspConfig.Disabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restartLSPServer shuts down an LSP server and starts it again. Starting a
+// server can take a while, so the work happens outside the update loop.
+func (a appModel) restartLSPServer(name string) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.app.RestartLSPClient(context.Background(), name); err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Failed to restart LSP server %s: %s", name, err)}
+		}
+		return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Restarted LSP server %s", name)}
+	}
+}
+
+// openContinuedSession switches to a session started by summarizing the
+// selected one.
+func (a appModel) openContinuedSession(sessionID string) tea.Cmd {
+	continued, err := a.app.Sessions.Get(context.Background(), sessionID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	info := util.ReportInfo("Continued in a new session; the original is kept in the session list")
+	if a.currentPage != page.ChatPage {
+		return info
+	}
+	return tea.Batch(util.CmdHandler(chat.SessionSelectedMsg(continued)), info)
+}
+
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 	if a.app.CoderAgent.IsBusy() {
 		// For now we don't move to any page if the agent is busy
@@ -772,6 +1108,9 @@ func (a appModel) View() string {
 		if a.showPermissions {
 			bindings = append(bindings, a.permissions.BindingKeys()...)
 		}
+		if a.showReviewDialog {
+			bindings = append(bindings, a.reviewDialog.BindingKeys()...)
+		}
 		if a.currentPage == page.LogsPage {
 			bindings = append(bindings, logsKeyReturnKey)
 		}
@@ -880,6 +1219,21 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showReviewDialog {
+		overlay := a.reviewDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showMultiArgumentsDialog {
 		overlay := a.multiArgumentsDialog.View()
 		row := lipgloss.Height(appView) / 2
@@ -898,7 +1252,11 @@ func (a appModel) View() string {
 	return appView
 }
 
-func New(app *app.App) tea.Model {
+func New(app *app.App, resumeLastSession bool) tea.Model {
+	if cfg := config.Get(); cfg != nil {
+		applyKeybindings(&keys, cfg.Keybindings)
+	}
+
 	startPage := page.ChatPage
 	model := &appModel{
 		currentPage:   startPage,
@@ -912,13 +1270,15 @@ func New(app *app.App) tea.Model {
 		permissions:   dialog.NewPermissionDialogCmp(),
 		initDialog:    dialog.NewInitDialogCmp(),
 		themeDialog:   dialog.NewThemeDialogCmp(),
+		reviewDialog:  dialog.NewReviewDialogCmp(),
 		app:           app,
 		commands:      []dialog.Command{},
 		pages: map[page.PageID]tea.Model{
 			page.ChatPage: page.NewChatPage(app),
 			page.LogsPage: page.NewLogsPage(),
 		},
-		filepicker: dialog.NewFilepickerCmp(app),
+		filepicker:        dialog.NewFilepickerCmp(app),
+		resumeLastSession: resumeLastSession,
 	}
 
 	model.RegisterCommand(dialog.Command{
@@ -951,6 +1311,127 @@ If there are Cursor rules (in .cursor/rules/ or .cursorrules) or Copilot rules (
 			}
 		},
 	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "continue_new_session",
+		Title:       "Continue in New Session",
+		Description: "Summarize the current session into a new linked session, keeping the original intact",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(startContinueSessionMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "restore_deleted",
+		Title:       "Restore Deleted Files",
+		Description: "Restore the files deleted in the current session from the trash",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(restoreTrashMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "undo_last_edit",
+		Title:       "Undo Last Edit",
+		Description: "Restore the files changed by the most recent edit in the current session",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(undoLastEditMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "purge_trash",
+		Title:       "Empty Trash",
+		Description: "Permanently delete the files deleted in the current session",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(purgeTrashMsg{})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          sessionEnvCommandID,
+		Title:       "Set Session Environment",
+		Description: "Set KEY=VALUE variables for the bash commands of the current session",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(dialog.ShowMultiArgumentsDialogMsg{
+				CommandID: sessionEnvCommandID,
+				ArgNames:  []string{"VARIABLES"},
+			})
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "step_by_step",
+		Title:       "Toggle Step-by-Step Reasoning",
+		Description: "Ask models without native reasoning to think step by step",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return func() tea.Msg {
+				if app.CoderAgent.IsBusy() {
+					return util.InfoMsg{
+						Type: util.InfoTypeWarn,
+						Msg:  "Agent is busy, please wait...",
+					}
+				}
+				enabled := !config.Get().Agents[config.AgentCoder].StepByStep
+				if err := config.UpdateAgentStepByStep(config.AgentCoder, enabled); err != nil {
+					return util.InfoMsg{
+						Type: util.InfoTypeError,
+						Msg:  err.Error(),
+					}
+				}
+				// Recreate the provider so the new system prompt takes effect
+				if _, err := app.CoderAgent.Update(config.AgentCoder, app.CoderAgent.Model().ID); err != nil {
+					return util.InfoMsg{
+						Type: util.InfoTypeError,
+						Msg:  err.Error(),
+					}
+				}
+				status := "disabled"
+				if enabled {
+					status = "enabled"
+				}
+				return util.InfoMsg{
+					Type: util.InfoTypeInfo,
+					Msg:  fmt.Sprintf("Step-by-step reasoning %s", status),
+				}
+			}
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "toggle_diff_view",
+		Title:       "Toggle Diff View",
+		Description: "Switch diffs between auto, side-by-side (split) and unified layouts",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return func() tea.Msg {
+				view := nextDiffView(config.Get().TUI.DiffView)
+				if err := config.UpdateDiffView(view); err != nil {
+					return util.InfoMsg{
+						Type: util.InfoTypeError,
+						Msg:  err.Error(),
+					}
+				}
+				return chat.DiffViewChangedMsg{View: view}
+			}
+		},
+	})
+	model.RegisterCommand(dialog.Command{
+		ID:          "review_changes",
+		Title:       "Review Changes",
+		Description: "Show the uncommitted changes from git diff; press s for the staged ones",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return model.loadReview(false)
+		},
+	})
+	for _, name := range restartableLSPServers() {
+		model.RegisterCommand(dialog.Command{
+			ID:          "restart_lsp_" + name,
+			Title:       "Restart LSP Server: " + name,
+			Description: fmt.Sprintf("Shut down the %s language server and start it again", name),
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				return tea.Batch(
+					util.ReportInfo(fmt.Sprintf("Restarting LSP server %s...", name)),
+					model.restartLSPServer(name),
+				)
+			},
+		})
+	}
+	for _, cmd := range dialog.LoadPromptTemplates() {
+		model.RegisterCommand(cmd)
+	}
 	// Load custom commands
 	customCommands, err := dialog.LoadCustomCommands()
 	if err != nil {